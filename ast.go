@@ -0,0 +1,746 @@
+package hype
+
+//go:generate go run ast_schema_gen.go
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/markbates/clam"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// ASTVersion is the version of the JSON schema of ASTDocument.
+// Fields, node types, and data keys may be added to a version,
+// but are never removed, or changed; a change that would break
+// a consumer of the schema is a new version.
+//
+// The JSON Schema of each version is in schema/, and is
+// generated from the Go types by `go generate`. Documents of
+// earlier versions are still decoded.
+//
+//   - 1: the first version
+//   - 2: positions have an approx field
+const ASTVersion = 2
+
+// ASTKind is the kind of an ASTNode.
+type ASTKind string
+
+const (
+	// ASTElement is an HTML element, or a hype tag, such as
+	// <cmd>. Its type is the hype type of the element.
+	ASTElement ASTKind = "element"
+
+	// ASTText is text, as it is written in the HTML.
+	ASTText ASTKind = "text"
+
+	// ASTComment is an HTML comment.
+	ASTComment ASTKind = "comment"
+
+	// ASTRaw is a node of a type that is not in the schema,
+	// such as a custom tag, that is written as its HTML.
+	ASTRaw ASTKind = "raw"
+)
+
+// ASTDocument is the stable, versioned, JSON representation
+// of a Document, and its tree of nodes, as written by
+// `hype encode -format=ast`.
+//
+// Unlike the JSON of Document.MarshalJSON, which follows the
+// Go types, the fields of ASTDocument only change with
+// ASTVersion. ASTDocument.Document reconstructs the Document.
+type ASTDocument struct {
+	Version     int            `json:"version" desc:"Version of the schema; see ASTVersion."`
+	Filename    string         `json:"filename,omitempty" desc:"File the document was parsed from."`
+	Title       string         `json:"title,omitempty" desc:"Title of the document."`
+	FrontMatter map[string]any `json:"front_matter,omitempty" desc:"Typed values of the front matter of the document."`
+	Nodes       []ASTNode      `json:"nodes" desc:"Nodes of the document."`
+}
+
+// ASTNode is a node of an ASTDocument.
+type ASTNode struct {
+	Kind  ASTKind           `json:"kind" enum:"element,text,comment,raw" desc:"Kind of the node."`
+	Type  string            `json:"type,omitempty" desc:"Hype type of an element, such as cmd, heading, or element for a plain HTML element."`
+	Tag   string            `json:"tag,omitempty" desc:"HTML tag of an element, such as h1, or cmd. Empty for the root of the document."`
+	Attrs map[string]string `json:"attrs,omitempty" desc:"Attributes of an element."`
+	Text  string            `json:"text,omitempty" desc:"Text of a text, comment, or raw node."`
+	Pos   *Pos              `json:"pos,omitempty" desc:"Position of an element in its source file."`
+	Data  map[string]any    `json:"data,omitempty" desc:"Values of an element, by type, that are not in its attributes, or nodes."`
+	Nodes []ASTNode         `json:"nodes,omitempty" desc:"Child nodes of an element."`
+}
+
+// ASTTypes returns the types of the elements of the
+// schema, and the keys of their data, if they have any.
+func ASTTypes() map[string][]string {
+	m := map[string][]string{}
+
+	for name, t := range astTypes {
+		m[name] = append([]string{}, t.data...)
+	}
+
+	return m
+}
+
+// astType decodes an element of a type of the schema.
+type astType struct {
+	data   []string // the keys of the data of the type
+	decode func(el *Element, data astData) (Node, error)
+}
+
+// astTypes are the types of the elements of the schema, by
+// name. Names, and data keys, must not change within a version.
+var astTypes = map[string]astType{
+	"element": {decode: func(el *Element, _ astData) (Node, error) { return el, nil }},
+	"body":    {decode: func(el *Element, _ astData) (Node, error) { return &Body{Element: el}, nil }},
+	"cmd": {
+		data: []string{"args", "env", "expected_exit", "timeout"},
+		decode: func(el *Element, d astData) (Node, error) {
+			c := &Cmd{
+				Element:      el,
+				Args:         d.strings("args"),
+				Env:          d.strings("env"),
+				ExpectedExit: d.int("expected_exit"),
+				Timeout:      d.duration("timeout"),
+			}
+
+			if rr := ByType[*CmdResult](el.Nodes); len(rr) > 0 {
+				c.res = rr[0]
+			}
+
+			return c, nil
+		},
+	},
+	"cmd_result": {
+		data: []string{"args", "duration", "exit", "stderr", "stdout"},
+		decode: func(el *Element, d astData) (Node, error) {
+			return &CmdResult{
+				Element: el,
+				Result: &clam.Result{
+					Args:     d.strings("args"),
+					Exit:     d.int("exit"),
+					Stdout:   []byte(d.string("stdout")),
+					Stderr:   []byte(d.string("stderr")),
+					Duration: d.duration("duration"),
+				},
+			}, nil
+		},
+	},
+	"code": {
+		data: []string{"lang", "snippet", "src"},
+		decode: func(el *Element, d astData) (Node, error) {
+			code := &SourceCode{Element: el, Lang: d.string("lang"), Src: d.string("src")}
+
+			if sd := d.object("snippet"); sd != nil {
+				code.Snippet = Snippet{
+					Content: el.Nodes.String(),
+					File:    sd.string("file"),
+					Lang:    sd.string("lang"),
+					Name:    sd.string("name"),
+					Start:   sd.int("start"),
+					End:     sd.int("end"),
+				}
+			}
+
+			return code, nil
+		},
+	},
+	"diagram": {
+		data: []string{"lang", "source", "svg"},
+		decode: func(el *Element, d astData) (Node, error) {
+			return &Diagram{
+				Element: el,
+				Lang:    d.string("lang"),
+				Source:  d.string("source"),
+				SVG:     d.string("svg"),
+				figure:  inFigure(el.Parent),
+			}, nil
+		},
+	},
+	"fenced_code": {decode: func(el *Element, _ astData) (Node, error) { return &FencedCode{Element: el}, nil }},
+	"figcaption":  {decode: func(el *Element, _ astData) (Node, error) { return &Figcaption{Element: el}, nil }},
+	"figure": {
+		data: []string{"label", "number", "section_id", "style"},
+		decode: func(el *Element, d astData) (Node, error) {
+			return &Figure{
+				Element:   el,
+				Pos:       d.int("number"),
+				SectionID: d.int("section_id"),
+				label:     d.string("label"),
+				style:     d.string("style"),
+			}, nil
+		},
+	},
+	"heading": {decode: func(el *Element, _ astData) (Node, error) { return NewHeading(el) }},
+	"image":   {decode: func(el *Element, _ astData) (Node, error) { return &Image{Element: el}, nil }},
+	"include": {decode: func(el *Element, _ astData) (Node, error) { return &Include{Element: el}, nil }},
+	"inline_code": {decode: func(el *Element, _ astData) (Node, error) {
+		return &InlineCode{Element: el}, nil
+	}},
+	"li": {
+		data: []string{"list"},
+		decode: func(el *Element, d astData) (Node, error) {
+			return &LI{Element: el, Type: d.string("list")}, nil
+		},
+	},
+	"link": {decode: func(el *Element, _ astData) (Node, error) { return &Link{Element: el}, nil }},
+	"mermaid": {
+		data: []string{"render", "rendered", "source", "svg"},
+		decode: func(el *Element, d astData) (Node, error) {
+			return &Mermaid{
+				Element:  el,
+				Source:   d.string("source"),
+				Rendered: d.string("rendered"),
+				Render:   d.string("render"),
+				SVG:      d.string("svg"),
+				figure:   inFigure(el.Parent),
+			}, nil
+		},
+	},
+	"metadata": {
+		data: []string{"values"},
+		decode: func(el *Element, d astData) (Node, error) {
+			md := &Metadata{Element: el}
+			for k, v := range d.stringMap("values") {
+				if err := md.Set(k, v); err != nil {
+					return nil, err
+				}
+			}
+			return md, nil
+		},
+	},
+	"now": {decode: func(el *Element, _ astData) (Node, error) { return &Now{Element: el}, nil }},
+	"ol":  {decode: func(el *Element, _ astData) (Node, error) { return &OL{Element: el}, nil }},
+	"p":   {decode: func(el *Element, _ astData) (Node, error) { return &Paragraph{Element: el}, nil }},
+	"page": {
+		data: []string{"title"},
+		decode: func(el *Element, d astData) (Node, error) {
+			return &Page{Element: el, Title: d.string("title")}, nil
+		},
+	},
+	"ref": {decode: func(el *Element, _ astData) (Node, error) { return &Ref{Element: el}, nil }},
+	"session": {
+		data: []string{"env", "prompt", "shell", "steps", "timeout"},
+		decode: func(el *Element, d astData) (Node, error) {
+			s := &Session{
+				Element: el,
+				Env:     d.strings("env"),
+				Shell:   d.string("shell"),
+				Prompt:  d.string("prompt"),
+				Timeout: d.duration("timeout"),
+			}
+
+			for _, sd := range d.list("steps") {
+				s.Steps = append(s.Steps, &SessionStep{
+					Element:      NewEl("step", el),
+					Exec:         sd.string("exec"),
+					ExpectedExit: sd.int("expected_exit"),
+					Ran:          sd.bool("ran"),
+					Exit:         sd.int("exit"),
+					Stdout:       []byte(sd.string("stdout")),
+					Stderr:       []byte(sd.string("stderr")),
+				})
+			}
+
+			return s, nil
+		},
+	},
+	"table": {decode: func(el *Element, _ astData) (Node, error) { return &Table{Element: el}, nil }},
+	"td":    {decode: func(el *Element, _ astData) (Node, error) { return &TD{Element: el}, nil }},
+	"th":    {decode: func(el *Element, _ astData) (Node, error) { return &TH{Element: el}, nil }},
+	"thead": {decode: func(el *Element, _ astData) (Node, error) { return &THead{Element: el}, nil }},
+	"toc": {
+		data: []string{"depth", "markdown", "root"},
+		decode: func(el *Element, d astData) (Node, error) {
+			return &ToC{
+				Element: el,
+				Depth:   d.int("depth"),
+				Root:    d.bool("root"),
+				mdNodes: d.string("markdown"),
+			}, nil
+		},
+	},
+	"tr": {decode: func(el *Element, _ astData) (Node, error) { return &TR{Element: el}, nil }},
+	"ul": {decode: func(el *Element, _ astData) (Node, error) { return &UL{Element: el}, nil }},
+	"var": {
+		data: []string{"key", "value"},
+		decode: func(el *Element, d astData) (Node, error) {
+			return &Var{Element: el, Key: d.string("key"), Value: d["value"]}, nil
+		},
+	},
+	"youtube": {decode: func(el *Element, _ astData) (Node, error) { return &YouTube{Element: el}, nil }},
+}
+
+// AST returns the document as an ASTDocument, of the
+// current ASTVersion.
+func (doc *Document) AST() (*ASTDocument, error) {
+	if doc == nil {
+		return nil, ErrIsNil("document")
+	}
+
+	doc.RLock()
+	defer doc.RUnlock()
+
+	ad := &ASTDocument{
+		Version:     ASTVersion,
+		Filename:    doc.Filename,
+		Title:       doc.Title,
+		FrontMatter: doc.FrontMatter,
+		Nodes:       []ASTNode{},
+	}
+
+	enc := astEncoder{root: doc.Root}
+	ad.Nodes = enc.nodes(doc.Nodes)
+
+	return ad, nil
+}
+
+// UnmarshalJSON decodes an ASTDocument, and returns an error
+// if it is of a version newer than ASTVersion, or has none.
+func (ad *ASTDocument) UnmarshalJSON(b []byte) error {
+	if ad == nil {
+		return ErrIsNil("ast document")
+	}
+
+	type doc ASTDocument
+
+	var x doc
+	if err := json.Unmarshal(b, &x); err != nil {
+		return err
+	}
+
+	if x.Version < 1 {
+		return fmt.Errorf("ast document has no version")
+	}
+
+	if x.Version > ASTVersion {
+		return fmt.Errorf("ast document version %d is newer than the supported version %d", x.Version, ASTVersion)
+	}
+
+	*ad = ASTDocument(x)
+
+	return nil
+}
+
+// Document reconstructs the Document, and its nodes. Elements
+// of a type that is not in the schema become plain Elements,
+// and raw nodes become Text.
+//
+// The document has no Parser; it can be rendered, with
+// String, or MD, but not executed again.
+func (ad *ASTDocument) Document() (*Document, error) {
+	if ad == nil {
+		return nil, ErrIsNil("ast document")
+	}
+
+	doc := &Document{
+		Filename:    ad.Filename,
+		FrontMatter: ad.FrontMatter,
+		Title:       ad.Title,
+	}
+
+	nodes, err := decodeASTNodes(ad.Nodes, nil, ad.Filename)
+	if err != nil {
+		return nil, err
+	}
+
+	doc.Nodes = nodes
+
+	return doc, nil
+}
+
+// UnmarshalAST decodes the JSON of an ASTDocument, such as
+// the output of `hype encode -format=ast`, and reconstructs
+// its Document.
+func UnmarshalAST(b []byte) (*Document, error) {
+	var ad ASTDocument
+	if err := json.Unmarshal(b, &ad); err != nil {
+		return nil, err
+	}
+
+	return ad.Document()
+}
+
+// astEncoder converts nodes to ASTNodes.
+type astEncoder struct {
+	root string // root of the document, positions are relative to
+}
+
+func (enc astEncoder) nodes(nodes Nodes) []ASTNode {
+	var list []ASTNode
+
+	for _, n := range nodes {
+		if n == nil {
+			continue
+		}
+
+		// nested lists of nodes are flattened
+		if nn, ok := n.(Nodes); ok {
+			list = append(list, enc.nodes(nn)...)
+			continue
+		}
+
+		list = append(list, enc.node(n))
+	}
+
+	return list
+}
+
+func (enc astEncoder) node(n Node) ASTNode {
+	switch t := n.(type) {
+	case Text:
+		return ASTNode{Kind: ASTText, Text: string(t)}
+	case Snippet:
+		return ASTNode{Kind: ASTText, Text: t.Content}
+	case Comment:
+		return ASTNode{Kind: ASTComment, Text: string(t)}
+	case *Metadata:
+		an := enc.element(t.Element, "metadata")
+		an.Data = map[string]any{"values": t.Map.Map()}
+		an.Nodes = enc.nodes(t.Element.Children())
+		return an
+	}
+
+	name, el, data := astTypeOf(n)
+	if len(name) == 0 || el == nil {
+		return ASTNode{Kind: ASTRaw, Text: Nodes{n}.String()}
+	}
+
+	an := enc.element(el, name)
+	an.Data = data
+	an.Nodes = enc.nodes(n.Children())
+
+	return an
+}
+
+func (enc astEncoder) element(el *Element, name string) ASTNode {
+	el.RLock()
+	defer el.RUnlock()
+
+	an := ASTNode{
+		Kind: ASTElement,
+		Type: name,
+		Tag:  el.Atom().String(),
+	}
+
+	if el.Attributes != nil && el.Attributes.Len() > 0 {
+		an.Attrs = el.Attributes.Map()
+	}
+
+	if el.Pos.IsValid() {
+		pos := el.Pos
+		if len(enc.root) > 0 && filepath.IsAbs(pos.File) == filepath.IsAbs(enc.root) {
+			if rel, err := filepath.Rel(enc.root, pos.File); err == nil && !strings.HasPrefix(rel, "..") {
+				pos.File = filepath.ToSlash(rel)
+			}
+		}
+		an.Pos = &pos
+	}
+
+	return an
+}
+
+// astTypeOf returns the name of the type of the node, in the
+// schema, its element, and its data. The name is empty for
+// node types that are not in the schema.
+func astTypeOf(n Node) (string, *Element, map[string]any) {
+	switch t := n.(type) {
+	case *Element:
+		return "element", t, nil
+	case *Body:
+		return "body", t.Element, nil
+	case *Cmd:
+		data := map[string]any{
+			"args":          t.Args,
+			"expected_exit": t.ExpectedExit,
+			"timeout":       t.Timeout.String(),
+		}
+		if len(t.Env) > 0 {
+			data["env"] = t.Env
+		}
+		return "cmd", t.Element, data
+	case *CmdResult:
+		if t.Result == nil {
+			return "cmd_result", t.Element, nil
+		}
+		return "cmd_result", t.Element, map[string]any{
+			"args":     t.Args,
+			"duration": t.Duration.String(),
+			"exit":     t.Exit,
+			"stderr":   string(t.Stderr),
+			"stdout":   string(t.Stdout),
+		}
+	case *SourceCode:
+		data := map[string]any{"lang": t.Lang, "src": t.Src}
+		if !t.Snippet.IsZero() {
+			// the content of the snippet is
+			// the text of the nodes of the code
+			data["snippet"] = map[string]any{
+				"end":   t.Snippet.End,
+				"file":  t.Snippet.File,
+				"lang":  t.Snippet.Lang,
+				"name":  t.Snippet.Name,
+				"start": t.Snippet.Start,
+			}
+		}
+		return "code", t.Element, data
+	case *Diagram:
+		return "diagram", t.Element, map[string]any{"lang": t.Lang, "source": t.Source, "svg": t.SVG}
+	case *FencedCode:
+		return "fenced_code", t.Element, nil
+	case *Figcaption:
+		return "figcaption", t.Element, nil
+	case *Figure:
+		return "figure", t.Element, map[string]any{
+			"label":      t.label,
+			"number":     t.Pos,
+			"section_id": t.SectionID,
+			"style":      t.style,
+		}
+	case *Heading:
+		return "heading", t.Element, nil
+	case *Image:
+		return "image", t.Element, nil
+	case *Include:
+		return "include", t.Element, nil
+	case *InlineCode:
+		return "inline_code", t.Element, nil
+	case *LI:
+		return "li", t.Element, map[string]any{"list": t.Type}
+	case *Link:
+		return "link", t.Element, nil
+	case *Mermaid:
+		return "mermaid", t.Element, map[string]any{
+			"render":   t.Render,
+			"rendered": t.Rendered,
+			"source":   t.Source,
+			"svg":      t.SVG,
+		}
+	case *Now:
+		return "now", t.Element, nil
+	case *OL:
+		return "ol", t.Element, nil
+	case *Paragraph:
+		return "p", t.Element, nil
+	case *Page:
+		return "page", t.Element, map[string]any{"title": t.Title}
+	case *Ref:
+		return "ref", t.Element, nil
+	case *Session:
+		steps := make([]map[string]any, 0, len(t.Steps))
+		for _, st := range t.Steps {
+			steps = append(steps, map[string]any{
+				"exec":          st.Exec,
+				"exit":          st.Exit,
+				"expected_exit": st.ExpectedExit,
+				"ran":           st.Ran,
+				"stderr":        string(st.Stderr),
+				"stdout":        string(st.Stdout),
+			})
+		}
+		data := map[string]any{
+			"prompt":  t.Prompt,
+			"shell":   t.Shell,
+			"steps":   steps,
+			"timeout": t.Timeout.String(),
+		}
+		if len(t.Env) > 0 {
+			data["env"] = t.Env
+		}
+		return "session", t.Element, data
+	case *Table:
+		return "table", t.Element, nil
+	case *TD:
+		return "td", t.Element, nil
+	case *TH:
+		return "th", t.Element, nil
+	case *THead:
+		return "thead", t.Element, nil
+	case *ToC:
+		return "toc", t.Element, map[string]any{"depth": t.Depth, "markdown": t.mdNodes, "root": t.Root}
+	case *TR:
+		return "tr", t.Element, nil
+	case *UL:
+		return "ul", t.Element, nil
+	case *Var:
+		return "var", t.Element, map[string]any{"key": t.Key, "value": t.Value}
+	case *YouTube:
+		return "youtube", t.Element, nil
+	}
+
+	return "", nil, nil
+}
+
+func decodeASTNodes(list []ASTNode, parent Node, filename string) (Nodes, error) {
+	nodes := make(Nodes, 0, len(list))
+
+	for _, an := range list {
+		n, err := decodeASTNode(an, parent, filename)
+		if err != nil {
+			return nil, err
+		}
+
+		nodes = append(nodes, n)
+	}
+
+	return nodes, nil
+}
+
+func decodeASTNode(an ASTNode, parent Node, filename string) (Node, error) {
+	switch an.Kind {
+	case ASTText, ASTRaw:
+		return Text(an.Text), nil
+	case ASTComment:
+		return Comment(an.Text), nil
+	case ASTElement:
+	default:
+		return nil, fmt.Errorf("unknown ast node kind %q", an.Kind)
+	}
+
+	hn := &html.Node{
+		Type:     html.ElementNode,
+		Data:     an.Tag,
+		DataAtom: atom.Lookup([]byte(an.Tag)),
+	}
+
+	if len(an.Tag) == 0 {
+		hn.Type = html.DocumentNode
+	}
+
+	el := &Element{
+		Attributes: &Attributes{},
+		HTMLNode:   hn,
+		Parent:     parent,
+		Filename:   filename,
+	}
+
+	if an.Pos != nil {
+		el.Pos = *an.Pos
+	}
+
+	keys := make([]string, 0, len(an.Attrs))
+	for k := range an.Attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if err := el.Set(k, an.Attrs[k]); err != nil {
+			return nil, err
+		}
+	}
+
+	kids, err := decodeASTNodes(an.Nodes, el, filename)
+	if err != nil {
+		return nil, err
+	}
+
+	el.Nodes = kids
+
+	t, ok := astTypes[an.Type]
+	if !ok {
+		t = astTypes["element"]
+	}
+
+	n, err := t.decode(el, astData(an.Data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", an.Type, err)
+	}
+
+	return n, nil
+}
+
+// astData is the data of an ASTNode, as decoded from JSON.
+type astData map[string]any
+
+func (d astData) string(k string) string {
+	s, _ := d[k].(string)
+	return s
+}
+
+func (d astData) int(k string) int {
+	switch v := d[k].(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	}
+
+	return 0
+}
+
+func (d astData) bool(k string) bool {
+	b, _ := d[k].(bool)
+	return b
+}
+
+func (d astData) duration(k string) time.Duration {
+	dur, _ := time.ParseDuration(d.string(k))
+	return dur
+}
+
+func (d astData) strings(k string) []string {
+	switch v := d[k].(type) {
+	case []string:
+		return v
+	case []any:
+		ss := make([]string, 0, len(v))
+		for _, x := range v {
+			if s, ok := x.(string); ok {
+				ss = append(ss, s)
+			}
+		}
+		return ss
+	}
+
+	return nil
+}
+
+func (d astData) stringMap(k string) map[string]string {
+	m := map[string]string{}
+
+	switch v := d[k].(type) {
+	case map[string]string:
+		return v
+	case map[string]any:
+		for mk, mv := range v {
+			if s, ok := mv.(string); ok {
+				m[mk] = s
+			}
+		}
+	}
+
+	return m
+}
+
+func (d astData) object(k string) astData {
+	switch v := d[k].(type) {
+	case map[string]any:
+		return astData(v)
+	}
+
+	return nil
+}
+
+func (d astData) list(k string) []astData {
+	var list []astData
+
+	switch v := d[k].(type) {
+	case []map[string]any:
+		for _, x := range v {
+			list = append(list, astData(x))
+		}
+	case []any:
+		for _, x := range v {
+			if m, ok := x.(map[string]any); ok {
+				list = append(list, astData(m))
+			}
+		}
+	}
+
+	return list
+}
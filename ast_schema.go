@@ -0,0 +1,176 @@
+package hype
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ASTSchemaID is the $id of the JSON Schema of the
+// current ASTVersion.
+var ASTSchemaID = fmt.Sprintf("https://github.com/gopherguides/hype/schema/ast.v%d.json", ASTVersion)
+
+// ASTSchema returns the JSON Schema of ASTDocument, of the
+// current ASTVersion. It is generated from the json, desc,
+// and enum tags of the Go types, and checked in to schema/,
+// by `go generate`.
+func ASTSchema() ([]byte, error) {
+	gen := &schemaGen{defs: map[string]any{}}
+
+	doc := gen.object(reflect.TypeOf(ASTDocument{}))
+
+	// the data keys of each type are not in the Go
+	// types, so they are listed in the description
+	node, ok := gen.defs["ASTNode"].(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("missing ASTNode definition")
+	}
+
+	props := node["properties"].(map[string]any)
+	data := props["data"].(map[string]any)
+	data["description"] = fmt.Sprintf("%s\n\n%s", data["description"], astTypesDoc())
+
+	dprops := doc["properties"].(map[string]any)
+	dprops["version"] = map[string]any{
+		"description": dprops["version"].(map[string]any)["description"],
+		"const":       ASTVersion,
+	}
+
+	schema := map[string]any{
+		"$schema":     "https://json-schema.org/draft/2020-12/schema",
+		"$id":         ASTSchemaID,
+		"title":       "Hype AST",
+		"description": fmt.Sprintf("Version %d of the JSON AST of a hype document, as written by `hype encode -format=ast`.", ASTVersion),
+		"$defs":       gen.defs,
+	}
+
+	for k, v := range doc {
+		schema[k] = v
+	}
+
+	b, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append(b, '\n'), nil
+}
+
+// astTypesDoc returns the types of the schema, and the
+// keys of their data, one per line.
+func astTypesDoc() string {
+	types := ASTTypes()
+
+	names := make([]string, 0, len(types))
+	for name := range types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	lines := make([]string, 0, len(names))
+	for _, name := range names {
+		keys := "(none)"
+		if len(types[name]) > 0 {
+			keys = strings.Join(types[name], ", ")
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s", name, keys))
+	}
+
+	return "Types, and their data keys:\n" + strings.Join(lines, "\n")
+}
+
+// schemaGen generates JSON Schema from Go types.
+type schemaGen struct {
+	defs map[string]any
+}
+
+func (gen *schemaGen) schema(t reflect.Type) map[string]any {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return gen.schema(t.Elem())
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": gen.schema(t.Elem())}
+	case reflect.Map:
+		m := map[string]any{"type": "object"}
+		if t.Elem().Kind() != reflect.Interface {
+			m["additionalProperties"] = gen.schema(t.Elem())
+		}
+		return m
+	case reflect.Struct:
+		name := t.Name()
+		if _, ok := gen.defs[name]; !ok {
+			// reserve the name first, for recursive types
+			gen.defs[name] = nil
+			gen.defs[name] = gen.object(t)
+		}
+		return map[string]any{"$ref": "#/$defs/" + name}
+	}
+
+	// any value
+	return map[string]any{}
+}
+
+func (gen *schemaGen) object(t reflect.Type) map[string]any {
+	props := map[string]any{}
+	var required []string
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(tag, ",")
+		if len(name) == 0 {
+			name = f.Name
+		}
+
+		prop := gen.schema(f.Type)
+
+		if desc := f.Tag.Get("desc"); len(desc) > 0 {
+			if _, ok := prop["$ref"]; ok {
+				prop = map[string]any{"allOf": []any{prop}}
+			}
+			prop["description"] = desc
+		}
+
+		if enum := f.Tag.Get("enum"); len(enum) > 0 {
+			prop["enum"] = strings.Split(enum, ",")
+		}
+
+		props[name] = prop
+
+		if !strings.Contains(opts, "omitempty") {
+			required = append(required, name)
+		}
+	}
+
+	// fields may be added within a version, so
+	// additional properties are allowed
+	m := map[string]any{
+		"type":       "object",
+		"properties": props,
+	}
+
+	if len(required) > 0 {
+		m["required"] = required
+	}
+
+	return m
+}
@@ -0,0 +1,32 @@
+//go:build ignore
+// +build ignore
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/gopherguides/hype"
+)
+
+// writes the JSON Schema of the current
+// version of the AST to schema/
+func main() {
+	b, err := hype.ASTSchema()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fp := filepath.Join("schema", fmt.Sprintf("ast.v%d.json", hype.ASTVersion))
+
+	if err := os.MkdirAll(filepath.Dir(fp), 0755); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := os.WriteFile(fp, b, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
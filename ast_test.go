@@ -0,0 +1,211 @@
+package hype
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Document_AST_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	r := require.New(t)
+
+	root := "testdata/auto"
+
+	err := fs.WalkDir(os.DirFS(root), ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if filepath.Base(path) != "hype.md" {
+			return nil
+		}
+
+		dir := filepath.Dir(path)
+
+		t.Run(dir, func(t *testing.T) {
+			r := require.New(t)
+
+			p := testParser(t, filepath.Join(root, dir))
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			doc, err := p.ParseExecuteFile(ctx, "hype.md")
+			if err != nil {
+				// documents that fail to execute are
+				// covered by Test_Testdata_Auto_Modules
+				t.Skip(err)
+			}
+
+			ad, err := doc.AST()
+			r.NoError(err)
+
+			b, err := json.Marshal(ad)
+			r.NoError(err)
+
+			got, err := UnmarshalAST(b)
+			r.NoError(err)
+
+			r.Equal(doc.Title, got.Title)
+			r.Equal(doc.String(), got.String())
+			r.Equal(doc.MD(), got.MD())
+
+			// encoding the decoded document is stable
+			ad2, err := got.AST()
+			r.NoError(err)
+
+			b2, err := json.Marshal(ad2)
+			r.NoError(err)
+
+			r.JSONEq(string(b), string(b2))
+		})
+
+		return filepath.SkipDir
+	})
+
+	r.NoError(err)
+}
+
+func Test_Document_AST(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	p := testParser(t, "testdata/markdown/roundtrip")
+
+	doc, err := p.Parse(strings.NewReader("# Hello\n\n<custom a=\"b\"></custom>\n\n<!-- note -->\n"))
+	r.NoError(err)
+
+	ad, err := doc.AST()
+	r.NoError(err)
+
+	r.Equal(ASTVersion, ad.Version)
+	r.Equal("Hello", ad.Title)
+
+	page, ok := astFind(ad.Nodes, "page")
+	r.True(ok)
+	r.Equal("page", page.Tag)
+	r.Equal("Hello", page.Data["title"])
+
+	var types []string
+	for _, n := range page.Nodes {
+		if n.Kind == ASTElement {
+			types = append(types, n.Type)
+		}
+	}
+	r.Equal([]string{"heading", "element"}, types)
+
+	h, ok := astFind(page.Nodes, "heading")
+	r.True(ok)
+	r.Equal("h1", h.Tag)
+	r.NotNil(h.Pos)
+	r.Equal("Hello", h.Nodes[0].Text)
+}
+
+func Test_ASTDocument_UnmarshalJSON_Version(t *testing.T) {
+	t.Parallel()
+
+	table := []struct {
+		name string
+		in   string
+		exp  int
+		err  bool
+	}{
+		{name: "current", in: `{"version":2,"nodes":[]}`, exp: ASTVersion},
+		{name: "earlier", in: `{"version":1,"nodes":[]}`, exp: 1},
+		{name: "missing", in: `{"nodes":[]}`, err: true},
+		{name: "newer", in: `{"version":3,"nodes":[]}`, err: true},
+	}
+
+	for _, tt := range table {
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+
+			var ad ASTDocument
+			err := json.Unmarshal([]byte(tt.in), &ad)
+
+			if tt.err {
+				r.Error(err)
+				return
+			}
+
+			r.NoError(err)
+			r.Equal(tt.exp, ad.Version)
+		})
+	}
+}
+
+func Test_UnmarshalAST_Types(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	in := `{
+	"version": 2,
+	"title": "Hello",
+	"nodes": [
+		{"kind": "element", "type": "page", "tag": "page", "data": {"title": "Hello"}, "nodes": [
+			{"kind": "element", "type": "heading", "tag": "h1", "nodes": [{"kind": "text", "text": "Hello"}]},
+			{"kind": "element", "type": "custom", "tag": "custom", "attrs": {"a": "b"}},
+			{"kind": "raw", "text": "<b>raw</b>"},
+			{"kind": "comment", "text": "note"}
+		]}
+	]
+}`
+
+	doc, err := UnmarshalAST([]byte(in))
+	r.NoError(err)
+
+	pages := ByType[*Page](doc.Nodes)
+	r.Len(pages, 1)
+	r.Equal("Hello", pages[0].Title)
+
+	hs := ByType[*Heading](doc.Nodes)
+	r.Len(hs, 1)
+	r.Equal(1, hs[0].Level())
+
+	r.Equal(`<page><h1>Hello</h1><custom a="b"></custom><b>raw</b><!-- note --></page>`, doc.String())
+
+	_, err = UnmarshalAST([]byte(`{"version":2,"nodes":[{"kind":"bogus"}]}`))
+	r.Error(err)
+}
+
+func Test_ASTSchema(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	b, err := ASTSchema()
+	r.NoError(err)
+
+	var schema map[string]any
+	r.NoError(json.Unmarshal(b, &schema))
+	r.Equal(ASTSchemaID, schema["$id"])
+
+	// the checked in schema is generated by `go generate`
+	exp, err := os.ReadFile(filepath.Join("schema", fmt.Sprintf("ast.v%d.json", ASTVersion)))
+	r.NoError(err)
+
+	r.Equal(string(exp), string(b))
+}
+
+func astFind(nodes []ASTNode, typ string) (ASTNode, bool) {
+	for _, n := range nodes {
+		if n.Type == typ {
+			return n, true
+		}
+
+		if fn, ok := astFind(n.Nodes, typ); ok {
+			return fn, true
+		}
+	}
+
+	return ASTNode{}, false
+}
@@ -99,6 +99,7 @@ const (
 	Desc                      Atom = "desc"
 	Details                   Atom = "details"
 	Dfn                       Atom = "dfn"
+	Diagram                   Atom = "diagram"
 	Dialog                    Atom = "dialog"
 	Dir                       Atom = "dir"
 	Dirname                   Atom = "dirname"
@@ -351,6 +352,7 @@ const (
 	Section                   Atom = "section"
 	Select                    Atom = "select"
 	Selected                  Atom = "selected"
+	Session                   Atom = "session"
 	Shape                     Atom = "shape"
 	Size                      Atom = "size"
 	Sizes                     Atom = "sizes"
@@ -71,6 +71,7 @@ let atoms =
   "Desc": "desc",
   "Details": "details",
   "Dfn": "dfn",
+  "Diagram": "diagram",
   "Dialog": "dialog",
   "Dir": "dir",
   "Dirname": "dirname",
@@ -331,6 +332,7 @@ let atoms =
   "Section": "section",
   "Select": "select",
   "Selected": "selected",
+  "Session": "session",
   "Shape": "shape",
   "Size": "size",
   "Sizes": "sizes",
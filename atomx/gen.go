@@ -23,6 +23,7 @@ import (
 var hype = []string{
 	"cmd",
 	"code",
+	"diagram",
 	"file",
 	"filegroup",
 	"go",
@@ -30,6 +31,8 @@ var hype = []string{
 	"metadata",
 	"page",
 	"ref",
+	"session",
+	"step",
 	"unknown",
 }
 
@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"github.com/gopherguides/hype"
+	"github.com/gopherguides/hype/images"
 )
 
 type Article struct {
@@ -30,6 +31,24 @@ type Article struct {
 	ReadingTime    int               `json:"reading_time"`
 	File           string            `json:"file"`
 	Dir            string            `json:"dir"`
+	Deps           *hype.DepGraph    `json:"-"` // files the article depends on, relative to Dir
+	Images         []images.Variant  `json:"-"` // images to write with the article, relative to Dir
+}
+
+// DependsOn reports whether the article needs to be rebuilt
+// when the given file, relative to the content directory, changes.
+// If the article's dependencies are not known, true is returned.
+func (a Article) DependsOn(file string) bool {
+	if a.Deps == nil {
+		return true
+	}
+
+	rel, err := filepath.Rel(a.Dir, file)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return false
+	}
+
+	return a.Deps.DependsOn(filepath.Base(a.File), rel)
 }
 
 func (a Article) IsPublished() bool {
@@ -49,6 +68,8 @@ func (a Article) ISODate() string {
 }
 
 type ArticleParser struct {
+	Images *images.Pipeline // optional; sizes, and copies, the images of articles
+
 	fsys        fs.FS
 	highlighter *Highlighter
 	baseURL     string
@@ -89,11 +110,18 @@ func (ap *ArticleParser) ParseArticle(ctx context.Context, dir string) (Article,
 	a.File = filepath.Join(dir, mdFile)
 
 	p := hype.NewParser(subFS)
+	p.MermaidRender = hype.MermaidSVG
+
 	doc, err := p.ParseFile(mdFile)
 	if err != nil {
 		return a, fmt.Errorf("failed to parse %s: %w", a.File, err)
 	}
 
+	a.Deps, err = doc.Deps()
+	if err != nil {
+		return a, fmt.Errorf("failed to find dependencies of %s: %w", a.File, err)
+	}
+
 	if err := doc.Execute(ctx); err != nil {
 		return a, fmt.Errorf("failed to execute %s: %w", a.File, err)
 	}
@@ -117,16 +145,20 @@ func (ap *ArticleParser) ParseArticle(ctx context.Context, dir string) (Article,
 	if !ok {
 		return a, fmt.Errorf("missing published date in %s", a.File)
 	}
-	pb, ok := pbVal.(string)
-	if !ok {
-		return a, fmt.Errorf("published date is not a string in %s", a.File)
-	}
 
-	t, err := time.ParseInLocation("01/02/2006", pb, time.Local)
-	if err != nil {
-		return a, fmt.Errorf("invalid published date format in %s: %w", a.File, err)
+	switch pb := pbVal.(type) {
+	case time.Time:
+		// a date from front matter
+		a.Published = pb
+	case string:
+		t, err := time.ParseInLocation("01/02/2006", pb, time.Local)
+		if err != nil {
+			return a, fmt.Errorf("invalid published date format in %s: %w", a.File, err)
+		}
+		a.Published = t
+	default:
+		return a, fmt.Errorf("published date is not a string or date in %s", a.File)
 	}
-	a.Published = t
 
 	if v, ok := p.Vars.Get("author"); ok {
 		if s, ok := v.(string); ok {
@@ -153,18 +185,37 @@ func (ap *ArticleParser) ParseArticle(ctx context.Context, dir string) (Article,
 	}
 
 	if v, ok := p.Vars.Get("tags"); ok {
-		if s, ok := v.(string); ok {
-			for _, tag := range strings.Split(s, ",") {
-				tag = strings.TrimSpace(tag)
-				if tag != "" {
-					a.Tags = append(a.Tags, tag)
+		var tags []string
+
+		switch x := v.(type) {
+		case string:
+			tags = strings.Split(x, ",")
+		case []any:
+			// a list from front matter
+			for _, t := range x {
+				if s, ok := t.(string); ok {
+					tags = append(tags, s)
 				}
 			}
 		}
+
+		for _, tag := range tags {
+			tag = strings.TrimSpace(tag)
+			if tag != "" {
+				a.Tags = append(a.Tags, tag)
+			}
+		}
 	}
 
 	a.Title = doc.Title
 
+	if ap.Images != nil {
+		a.Images, err = ap.Images.Process(doc)
+		if err != nil {
+			return a, fmt.Errorf("failed to process images of %s: %w", a.File, err)
+		}
+	}
+
 	body, err := doc.Body()
 	if err != nil {
 		return a, fmt.Errorf("failed to get body from %s: %w", a.File, err)
@@ -181,7 +232,7 @@ func (ap *ArticleParser) ParseArticle(ctx context.Context, dir string) (Article,
 	if err == nil {
 		for _, page := range pages {
 			if v, ok := p.Vars.Get("overview"); ok {
-				if s, ok := v.(string); ok && s == "true" {
+				if v == true || v == "true" {
 					a.Overview = template.HTML(page.Children().String())
 					break
 				}
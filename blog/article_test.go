@@ -1,8 +1,10 @@
 package blog
 
 import (
+	"context"
 	"strings"
 	"testing"
+	"testing/fstest"
 	"time"
 
 	"github.com/stretchr/testify/require"
@@ -126,3 +128,37 @@ func TestStripDetailsBlocks(t *testing.T) {
 		r.Equal("abc", stripDetailsBlocks(input))
 	})
 }
+
+func TestArticleParser_FrontMatter(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	fsys := fstest.MapFS{
+		"content/first/module.md": &fstest.MapFile{
+			Data: []byte(`---
+slug: first-post
+published: 2024-03-15
+author: Jane Smith
+tags: [go, testing]
+overview: true
+---
+
+# First Post
+
+Some content.
+`),
+		},
+	}
+
+	ap := NewArticleParser(fsys, nil, "")
+
+	a, err := ap.ParseArticle(context.Background(), "content/first")
+	r.NoError(err)
+
+	r.Equal("First Post", a.Title)
+	r.Equal("first-post", a.Slug)
+	r.Equal("Jane Smith", a.Author)
+	r.Equal([]string{"go", "testing"}, a.Tags)
+	r.Equal("March 15, 2024", a.FormattedDate())
+	r.NotEmpty(a.Overview)
+}
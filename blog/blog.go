@@ -5,17 +5,22 @@ import (
 	"fmt"
 	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
 	"sort"
 	"strings"
+
+	"github.com/gopherguides/hype/images"
 )
 
 type Blog struct {
 	Config      Config
 	Articles    []Article
 	Highlighter *Highlighter
+	Images      *images.Pipeline
 	fsys        fs.FS
 	root        string
+	parsed      map[string]Article // previously parsed articles, by directory
 }
 
 func New(root string) (*Blog, error) {
@@ -31,12 +36,24 @@ func New(root string) (*Blog, error) {
 	return &Blog{
 		Config:      cfg,
 		Highlighter: h,
-		fsys:        fsys,
-		root:        root,
+		Images: &images.Pipeline{
+			Widths:  cfg.Images.Widths,
+			Quality: cfg.Images.Quality,
+			Format:  cfg.Images.Format,
+		},
+		fsys: fsys,
+		root: root,
 	}, nil
 }
 
 func (b *Blog) Discover(ctx context.Context) error {
+	return b.discover(ctx, func(string) bool { return true })
+}
+
+// discover finds and parses the articles in the content directory.
+// Articles for which stale returns false are reused from the
+// previous parse, if there is one.
+func (b *Blog) discover(ctx context.Context, stale func(dir string) bool) error {
 	contentDir := b.Config.ContentDir
 
 	contentFS, err := fs.Sub(b.fsys, contentDir)
@@ -50,6 +67,10 @@ func (b *Blog) Discover(ctx context.Context) error {
 	}
 
 	parser := NewArticleParser(contentFS, b.Highlighter, b.Config.BaseURL)
+	parser.Images = b.Images
+
+	parsed := map[string]Article{}
+	b.Articles = nil
 
 	for _, entry := range entries {
 		if !entry.IsDir() {
@@ -61,12 +82,17 @@ func (b *Blog) Discover(ctx context.Context) error {
 			continue
 		}
 
-		article, err := parser.ParseArticle(ctx, name)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "warning: skipping %s: %v\n", name, err)
-			continue
+		article, ok := b.parsed[name]
+		if !ok || stale(name) {
+			article, err = parser.ParseArticle(ctx, name)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "warning: skipping %s: %v\n", name, err)
+				continue
+			}
 		}
 
+		parsed[name] = article
+
 		if !article.IsPublished() {
 			continue
 		}
@@ -74,6 +100,8 @@ func (b *Blog) Discover(ctx context.Context) error {
 		b.Articles = append(b.Articles, article)
 	}
 
+	b.parsed = parsed
+
 	sort.Slice(b.Articles, func(i, j int) bool {
 		return b.Articles[i].Published.After(b.Articles[j].Published)
 	})
@@ -86,6 +114,59 @@ func (b *Blog) Build(ctx context.Context) error {
 		return err
 	}
 
+	return b.render()
+}
+
+// Rebuild rebuilds the site after the given files have changed.
+// Only articles that depend on one of the changed files are
+// parsed and executed again; all other articles are reused from
+// the previous build. Files outside of the content directory,
+// such as templates and static files, only cause the site to be
+// rendered again. If the site has not been built yet, Rebuild
+// is the same as Build.
+func (b *Blog) Rebuild(ctx context.Context, changed []string) error {
+	if b.parsed == nil {
+		return b.Build(ctx)
+	}
+
+	contentDir := filepath.Join(b.root, b.Config.ContentDir)
+	absContent, err := filepath.Abs(contentDir)
+	if err != nil {
+		return err
+	}
+
+	var files []string
+	for _, f := range changed {
+		abs, err := filepath.Abs(f)
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(absContent, abs)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+
+		files = append(files, rel)
+	}
+
+	stale := func(dir string) bool {
+		for _, f := range files {
+			if b.parsed[dir].DependsOn(f) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if err := b.discover(ctx, stale); err != nil {
+		return err
+	}
+
+	return b.render()
+}
+
+func (b *Blog) render() error {
 	outDir := filepath.Join(b.root, b.Config.OutputDir)
 
 	if err := validateOutputDir(b.root, outDir); err != nil {
@@ -113,6 +194,10 @@ func (b *Blog) Build(ctx context.Context) error {
 		if err := renderer.RenderArticle(outDir, article); err != nil {
 			return fmt.Errorf("failed to render %s: %w", article.Slug, err)
 		}
+
+		if err := b.writeImages(outDir, article); err != nil {
+			return fmt.Errorf("failed to write images of %s: %w", article.Slug, err)
+		}
 	}
 
 	if err := renderer.RenderRSS(outDir); err != nil {
@@ -149,6 +234,21 @@ func (b *Blog) Build(ctx context.Context) error {
 	return nil
 }
 
+// writeImages writes the images of the article, and their
+// resized copies, next to the article's index.html.
+func (b *Blog) writeImages(outDir string, article Article) error {
+	if b.Images == nil || len(article.Images) == 0 {
+		return nil
+	}
+
+	fsys, err := fs.Sub(b.fsys, path.Join(b.Config.ContentDir, article.Dir))
+	if err != nil {
+		return err
+	}
+
+	return b.Images.Write(fsys, filepath.Join(outDir, article.Slug), article.Images)
+}
+
 func copyDir(src, dst string) error {
 	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
@@ -1,6 +1,14 @@
 package blog
 
 import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"sort"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -35,3 +43,83 @@ func TestValidateOutputDir(t *testing.T) {
 		})
 	}
 }
+
+func TestBlog_Rebuild(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	root := t.TempDir()
+	r.NoError(os.WriteFile(filepath.Join(root, "config.yaml"), []byte("title: Test\n"), 0644))
+
+	write := func(slug string, title string) string {
+		dir := filepath.Join(root, "content", slug)
+		r.NoError(os.MkdirAll(dir, 0755))
+
+		fp := filepath.Join(dir, "module.md")
+		body := fmt.Sprintf("<metadata>\npublished: 01/02/2020\n</metadata>\n\n# %s\n\nHello.\n", title)
+		r.NoError(os.WriteFile(fp, []byte(body), 0644))
+		return fp
+	}
+
+	first := write("first", "First")
+	write("second", "Second")
+
+	b, err := New(root)
+	r.NoError(err)
+
+	ctx := context.Background()
+	r.NoError(b.Rebuild(ctx, nil))
+
+	titles := func() []string {
+		var res []string
+		for _, a := range b.Articles {
+			res = append(res, a.Title)
+		}
+		sort.Strings(res)
+		return res
+	}
+
+	r.Equal([]string{"First", "Second"}, titles())
+
+	write("first", "First Updated")
+	write("second", "Second Updated")
+
+	// only the article depending on the changed file is parsed again
+	r.NoError(b.Rebuild(ctx, []string{first}))
+	r.Equal([]string{"First Updated", "Second"}, titles())
+
+	r.NoError(b.Build(ctx))
+	r.Equal([]string{"First Updated", "Second Updated"}, titles())
+}
+
+func TestBlog_Build_Images(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	root := t.TempDir()
+	r.NoError(os.WriteFile(filepath.Join(root, "config.yaml"), []byte("title: Test\nimages:\n  widths: [100]\n"), 0644))
+
+	dir := filepath.Join(root, "content", "gophers")
+	r.NoError(os.MkdirAll(filepath.Join(dir, "assets"), 0755))
+
+	bb := &bytes.Buffer{}
+	r.NoError(png.Encode(bb, image.NewGray(image.Rect(0, 0, 300, 150))))
+	r.NoError(os.WriteFile(filepath.Join(dir, "assets", "gopher.png"), bb.Bytes(), 0644))
+
+	body := "<metadata>\npublished: 01/02/2020\n</metadata>\n\n# Gophers\n\n<img src=\"assets/gopher.png\" alt=\"gopher\">\n"
+	r.NoError(os.WriteFile(filepath.Join(dir, "module.md"), []byte(body), 0644))
+
+	b, err := New(root)
+	r.NoError(err)
+	r.NoError(b.Build(context.Background()))
+
+	r.Len(b.Articles, 1)
+	html := string(b.Articles[0].Body)
+	r.Contains(html, `width="300"`)
+	r.Contains(html, `height="150"`)
+	r.Contains(html, `srcset="assets/gopher-100w.png 100w, assets/gopher.png 300w"`)
+
+	out := filepath.Join(root, "public", "gophers", "assets")
+	r.FileExists(filepath.Join(out, "gopher.png"))
+	r.FileExists(filepath.Join(out, "gopher-100w.png"))
+}
@@ -20,6 +20,7 @@ type Config struct {
 	ContentDir  string    `yaml:"contentDir"`
 	OutputDir   string    `yaml:"outputDir"`
 	ListPages   []string  `yaml:"listPages"`
+	Images      Images    `yaml:"images"`
 }
 
 type Author struct {
@@ -33,6 +34,15 @@ type Highlight struct {
 	LineNumbers bool   `yaml:"lineNumbers"`
 }
 
+// Images configures the copies made of the images of articles.
+// The widths, quality, and format attributes of an <img> tag
+// take precedence.
+type Images struct {
+	Widths  []int  `yaml:"widths"`  // widths of the resized copies, for srcset
+	Quality int    `yaml:"quality"` // JPEG quality
+	Format  string `yaml:"format"`  // format to convert images to: jpeg, png, or gif
+}
+
 type SEOConfig struct {
 	OGImage     string `yaml:"ogImage"`
 	TwitterCard string `yaml:"twitterCard"`
@@ -71,6 +71,52 @@ func (h *Highlighter) CSS() string {
 	return buf.String()
 }
 
+// Span is a run of highlighted code with a single style.
+type Span struct {
+	Text   string
+	Color  string // hex color, for example "#a6e22e"; empty for the default color
+	Bold   bool
+	Italic bool
+}
+
+// Spans returns the highlighted code as styled runs of text,
+// for formats, such as PDF, that can not use HTML and CSS.
+// If the code can not be tokenized, a single unstyled span
+// containing all of the code is returned.
+func (h *Highlighter) Spans(code, language string) []Span {
+	lexer := getLexer(language)
+	lexer = chroma.Coalesce(lexer)
+
+	style := styles.Get(h.style)
+	if style == nil {
+		style = styles.Fallback
+	}
+
+	iterator, err := lexer.Tokenise(nil, code)
+	if err != nil {
+		return []Span{{Text: code}}
+	}
+
+	var spans []Span
+	for _, tok := range iterator.Tokens() {
+		entry := style.Get(tok.Type)
+
+		sp := Span{
+			Text:   tok.Value,
+			Bold:   entry.Bold == chroma.Yes,
+			Italic: entry.Italic == chroma.Yes,
+		}
+
+		if entry.Colour.IsSet() {
+			sp.Color = entry.Colour.String()
+		}
+
+		spans = append(spans, sp)
+	}
+
+	return spans
+}
+
 func getLexer(language string) chroma.Lexer {
 	lang := strings.ToLower(strings.TrimPrefix(language, "."))
 
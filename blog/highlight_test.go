@@ -46,6 +46,31 @@ func TestHighlighter_Highlight(t *testing.T) {
 	})
 }
 
+func TestHighlighter_Spans(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	h := NewHighlighter("github", false)
+
+	code := "package main\n\nfunc main() {}\n"
+	spans := h.Spans(code, "go")
+	r.NotEmpty(spans)
+
+	var text string
+	var colored bool
+	for _, sp := range spans {
+		text += sp.Text
+		if sp.Color != "" {
+			colored = true
+		}
+	}
+
+	r.Equal(code, text)
+	r.True(colored)
+	r.Equal("package", spans[0].Text)
+	r.Equal("#cf222e", spans[0].Color)
+}
+
 func TestHighlighter_CSS(t *testing.T) {
 	t.Parallel()
 	r := require.New(t)
@@ -0,0 +1,180 @@
+package hype
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/gopherguides/hype/atomx"
+)
+
+// RefHref returns the href of a link, in the document from,
+// to the figure fig in the document to. The documents are
+// the same for a figure in an include of the document.
+type RefHref func(from, to *Document, fig *Figure) (string, error)
+
+// RelativeRefHref returns a RefHref that links to the file of
+// the other document, relative to the document with the ref.
+// If ext is not empty, such as ".html", it replaces the
+// extension of the file.
+//
+//	../02-testing/hype.html#listing-2-3
+func RelativeRefHref(ext string) RefHref {
+	return func(from, to *Document, fig *Figure) (string, error) {
+		if from == nil || to == nil {
+			return "", ErrIsNil("document")
+		}
+
+		if from == to {
+			return fig.Link(), nil
+		}
+
+		name := to.Filename
+		if len(ext) > 0 {
+			name = strings.TrimSuffix(name, filepath.Ext(name)) + ext
+		}
+
+		rel, err := filepath.Rel(from.Root, filepath.Join(to.Root, name))
+		if err != nil {
+			return "", err
+		}
+
+		return filepath.ToSlash(rel) + fig.Link(), nil
+	}
+}
+
+// ResolveRefs resolves the <ref> tags of the documents that refer
+// to a figure in another document of the book, or in an include,
+// by the id the figure was written with. The figure is numbered
+// by the section of its own document, such as "Listing 2.3", and
+// fn creates the href of the link to it.
+//
+// The documents should already be executed. Refs resolved by a
+// previous call are resolved again, so that a book can be linked
+// for each format it is exported to. A ref to an id that is not
+// in the book, or that is in more than one other document, is
+// an error.
+func (docs Documents) ResolveRefs(fn RefHref) error {
+	if docs == nil {
+		return ErrIsNil("documents")
+	}
+
+	if fn == nil {
+		return ErrIsNil("RefHref")
+	}
+
+	figs := docs.figures()
+
+	for _, doc := range docs {
+		if doc == nil {
+			return ErrIsNil("document")
+		}
+
+		for _, r := range ByType[*Ref](doc.Nodes) {
+			// resolved within its own document
+			if r.Figure != nil && !r.external {
+				continue
+			}
+
+			id, err := r.ValidAttr("id")
+			if err != nil {
+				return err
+			}
+
+			bf, err := figs.resolve(doc, id)
+			if err != nil {
+				return r.WrapErr(err)
+			}
+
+			href, err := fn(doc, bf.doc, bf.fig)
+			if err != nil {
+				return r.WrapErr(err)
+			}
+
+			link := &Link{
+				Element: NewEl(atomx.A, r),
+			}
+
+			if err := link.Set("href", href); err != nil {
+				return err
+			}
+
+			link.Nodes = Nodes{Text(bf.fig.Name())}
+
+			r.Figure = bf.fig
+			r.external = true
+			r.Nodes = Nodes{link}
+		}
+	}
+
+	return nil
+}
+
+// refHref returns the Parser.RefHref of the documents,
+// or, if none is set, a RelativeRefHref to their source.
+func (docs Documents) refHref() RefHref {
+	for _, doc := range docs {
+		if doc == nil || doc.Parser == nil {
+			continue
+		}
+
+		if fn := doc.Parser.RefHref; fn != nil {
+			return fn
+		}
+	}
+
+	return RelativeRefHref("")
+}
+
+// bookFigure is a figure, and the document of the book it is in.
+type bookFigure struct {
+	doc *Document
+	fig *Figure
+}
+
+// bookFigures are the figures of a book, by label.
+type bookFigures map[string][]bookFigure
+
+func (docs Documents) figures() bookFigures {
+	figs := bookFigures{}
+
+	for _, doc := range docs {
+		if doc == nil {
+			continue
+		}
+
+		for _, fig := range ByType[*Figure](doc.Nodes) {
+			if l := fig.Label(); len(l) > 0 {
+				figs[l] = append(figs[l], bookFigure{doc: doc, fig: fig})
+			}
+		}
+	}
+
+	return figs
+}
+
+// resolve returns the figure with the label id, for a ref in doc.
+// A figure in doc is preferred to those in other documents.
+func (figs bookFigures) resolve(doc *Document, id string) (bookFigure, error) {
+	found := figs[id]
+
+	for _, bf := range found {
+		if bf.doc == doc {
+			return bf, nil
+		}
+	}
+
+	switch len(found) {
+	case 0:
+		return bookFigure{}, fmt.Errorf("ref target not found: figure id %q", id)
+	case 1:
+		return found[0], nil
+	}
+
+	names := make([]string, 0, len(found))
+	for _, bf := range found {
+		names = append(names, filepath.Join(bf.doc.Root, bf.doc.Filename))
+	}
+
+	return bookFigure{}, fmt.Errorf("ambiguous ref: figure id %q is in %s", id, strings.Join(names, ", "))
+}
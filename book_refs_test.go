@@ -0,0 +1,115 @@
+package hype
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Documents_ResolveRefs(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	p := NewParser(os.DirFS("testdata/book-refs/valid"))
+
+	docs, err := p.ParseExecuteFolder(context.Background(), ".")
+	r.NoError(err)
+	r.Len(docs, 2)
+
+	intro, ch2 := docs[0].String(), docs[1].String()
+
+	r.Contains(intro, `<a href="../02-testing/hype.md#listing-2-1">Listing 2.1</a>`)
+	r.Contains(ch2, `<a href="../01-intro/hype.md#listing-1-1">Listing 1.1</a>`)
+	r.Contains(ch2, `<a href="#listing-2-1">Listing 2.1</a>`)
+
+	// refs are resolved again for other formats
+	r.NoError(docs.ResolveRefs(RelativeRefHref(".html")))
+
+	r.Contains(docs[0].String(), `<a href="../02-testing/hype.html#listing-2-1">Listing 2.1</a>`)
+	r.Contains(docs[1].MD(), `[Listing 1.1](../01-intro/hype.html#listing-1-1)`)
+
+	r.Error(docs.ResolveRefs(nil))
+}
+
+func Test_Documents_Execute_RefHref(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	p := NewParser(os.DirFS("testdata/book-refs/valid"))
+	p.RefHref = RelativeRefHref(".html")
+
+	docs, err := p.ParseExecuteFolder(context.Background(), ".")
+	r.NoError(err)
+	r.Len(docs, 2)
+
+	r.Contains(docs[0].String(), `<a href="../02-testing/hype.html#listing-2-1">Listing 2.1</a>`)
+	r.Contains(docs[1].String(), `<a href="../01-intro/hype.html#listing-1-1">Listing 1.1</a>`)
+	r.NotContains(docs[1].String(), `hype.md`)
+}
+
+func Test_Documents_ResolveRefs_Dangling(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	p := NewParser(os.DirFS("testdata/book-refs/dangling"))
+
+	_, err := p.ParseExecuteFolder(context.Background(), ".")
+	r.Error(err)
+	r.Contains(err.Error(), `ref target not found: figure id "missing"`)
+}
+
+func Test_bookFigures_resolve(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	a := &Document{Root: "01-a", Filename: "hype.md"}
+	b := &Document{Root: "02-b", Filename: "hype.md"}
+
+	fa := &Figure{label: "x"}
+	fb := &Figure{label: "x"}
+
+	figs := bookFigures{
+		"x": {{doc: a, fig: fa}, {doc: b, fig: fb}},
+	}
+
+	bf, err := figs.resolve(a, "x")
+	r.NoError(err)
+	r.Equal(fa, bf.fig)
+
+	_, err = figs.resolve(&Document{}, "x")
+	r.Error(err)
+	r.Contains(err.Error(), "ambiguous ref")
+
+	_, err = figs.resolve(a, "y")
+	r.Error(err)
+	r.Contains(err.Error(), "ref target not found")
+}
+
+func Test_ValidateBook(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	ctx := context.Background()
+
+	p := NewParser(os.DirFS("testdata/book-refs/valid"))
+	docs, err := p.ParseFolder(".")
+	r.NoError(err)
+
+	res := ValidateBook(ctx, docs, ValidateOptions{Exec: true})
+	r.False(res.HasErrors(), res.Issues)
+
+	// the same chapter, on its own, refers to a missing figure
+	res = Validate(ctx, docs[0], ValidateOptions{})
+	r.True(res.HasErrors())
+
+	p = NewParser(os.DirFS("testdata/book-refs/dangling"))
+	docs, err = p.ParseFolder(".")
+	r.NoError(err)
+
+	res = ValidateBook(ctx, docs, ValidateOptions{})
+	r.Len(res.Errors(), 1)
+	r.Contains(res.Errors()[0].Message, `ref target not found: figure id "missing"`)
+	r.Equal(3, res.Errors()[0].Pos.Line)
+}
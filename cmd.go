@@ -22,7 +22,8 @@ type Cmd struct {
 	ExpectedExit int
 	Timeout      time.Duration
 
-	res *CmdResult
+	res    *CmdResult
+	expect *expectation
 }
 
 func (c *Cmd) MarshalJSON() ([]byte, error) {
@@ -118,7 +119,11 @@ func (c *Cmd) Execute(ctx context.Context, doc *Document) error {
 		}
 	}
 
-	res, err := cmd.Run(ctx, args...)
+	res, err := c.run(ctx, doc, cmd, args)
+	if err != nil && res == nil {
+		return c.newError(err)
+	}
+
 	if err != nil {
 		switch c.ExpectedExit {
 		case -1:
@@ -145,6 +150,93 @@ func (c *Cmd) Execute(ctx context.Context, doc *Document) error {
 	return nil
 }
 
+// run executes the command with the runner requested by the
+// `runner` attribute, or the parser's default runner.
+// If the document's parser has an ExecCache configured,
+// a previously cached result for the same command and inputs
+// is returned instead. The `no-cache` attribute disables the
+// cache for the tag, as does a document without a root for
+// a command without a `src`.
+func (c *Cmd) run(ctx context.Context, doc *Document, cmd *clam.Cmd, args []string) (*clam.Result, error) {
+	p := doc.Parser
+	if p == nil {
+		p = &Parser{}
+	}
+
+	name, _ := c.Get("runner")
+	if len(name) == 0 {
+		name = p.DefaultRunner
+	}
+
+	if len(name) == 0 {
+		name = HostRunnerName
+	}
+
+	runner, err := p.Runner(name)
+	if err != nil {
+		return nil, err
+	}
+
+	// a command without a `src` runs in, and is keyed on,
+	// the root of the document, rather than the working
+	// directory of the process, which may be far larger
+	if len(cmd.Dir) == 0 {
+		cmd.Dir = doc.Root
+	}
+
+	ec := p.ExecCache
+
+	if _, ok := c.Get("no-cache"); ok || ec == nil || ec.Disabled {
+		return runner.Run(ctx, cmd, args...)
+	}
+
+	dir := cmd.Dir
+	if len(dir) == 0 {
+		return runner.Run(ctx, cmd, args...)
+	}
+
+	key, err := ec.Key(name, args, c.Env, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	res, ok, err := ec.Get(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if ok {
+		if res.Err == nil {
+			return res, nil
+		}
+
+		return res, clam.RunError{
+			Err:    res.Err,
+			Args:   res.Args,
+			Dir:    res.Dir,
+			Exit:   res.Exit,
+			Output: append(res.Stdout, res.Stderr...),
+		}
+	}
+
+	res, err = runner.Run(ctx, cmd, args...)
+	if res == nil {
+		return res, err
+	}
+
+	// only cache results that completed; timeouts
+	// and cancellations are not deterministic
+	if ctx.Err() != nil {
+		return res, err
+	}
+
+	if perr := ec.Put(key, res); perr != nil {
+		return res, perr
+	}
+
+	return res, err
+}
+
 func (c *Cmd) newError(err error) error {
 	if c == nil {
 		return err
@@ -157,12 +249,14 @@ func (c *Cmd) newError(err error) error {
 				Err: err,
 			},
 			Filename: c.Filename,
+			Pos:      c.Pos,
 		}
 	}
 
 	return CmdError{
 		RunError: re,
 		Filename: c.Filename,
+		Pos:      c.Pos,
 	}
 }
 
@@ -268,6 +362,11 @@ func NewCmdNodes(p *Parser, el *Element) (Nodes, error) {
 		return nil, err
 	}
 
+	cmd.expect, err = newExpectation(p, el)
+	if err != nil {
+		return nil, err
+	}
+
 	nodes = append(nodes, cmd)
 	return nodes, nil
 }
@@ -695,6 +695,10 @@ func (cmd *Blog) watchLoop(ctx context.Context, watcher *fsnotify.Watcher, pwd s
 	var changedFiles []string
 	debounceDelay := 500 * time.Millisecond
 
+	// the blog is kept between rebuilds so that only the
+	// articles affected by a change are parsed again
+	var b *blog.Blog
+
 	rebuild := func() {
 		mu.Lock()
 		files := changedFiles
@@ -711,12 +715,23 @@ func (cmd *Blog) watchLoop(ctx context.Context, watcher *fsnotify.Watcher, pwd s
 		}
 		fmt.Fprintf(cmd.Stdout(), "Rebuilding site...\n")
 
-		b, err := blog.New(pwd)
-		if err != nil {
-			fmt.Fprintf(cmd.Stderr(), "Rebuild error: %v\n", err)
-			return
+		for _, f := range files {
+			switch filepath.Base(f) {
+			case "config.yaml", "config.yml":
+				b = nil
+			}
 		}
-		if err := b.Build(ctx); err != nil {
+
+		if b == nil {
+			nb, err := blog.New(pwd)
+			if err != nil {
+				fmt.Fprintf(cmd.Stderr(), "Rebuild error: %v\n", err)
+				return
+			}
+			b = nb
+		}
+
+		if err := b.Rebuild(ctx, files); err != nil {
 			fmt.Fprintf(cmd.Stderr(), "Rebuild error: %v\n", err)
 			return
 		}
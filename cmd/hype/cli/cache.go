@@ -0,0 +1,115 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/gopherguides/hype"
+	"github.com/markbates/cleo"
+)
+
+// Cache manages the on-disk cache of <cmd> and <go> results.
+type Cache struct {
+	cleo.Cmd
+
+	Dir string // cache directory; default: hype.DefaultExecCacheDir()
+
+	flags *flag.FlagSet
+	mu    sync.RWMutex
+}
+
+func (cmd *Cache) Flags(stderr io.Writer) (*flag.FlagSet, error) {
+	usage := `
+Usage: hype cache <command> [options]
+
+Commands:
+    clean    Remove all cached command results
+    dir      Print the cache directory
+
+Examples:
+    hype cache clean
+    hype cache dir
+    hype cache -dir ./.hype-cache clean
+`
+
+	if cmd == nil {
+		return nil, fmt.Errorf("cache is nil")
+	}
+
+	cmd.mu.Lock()
+	defer cmd.mu.Unlock()
+
+	if cmd.flags != nil {
+		return cmd.flags, nil
+	}
+
+	cmd.flags = flag.NewFlagSet("cache", flag.ContinueOnError)
+	cmd.flags.SetOutput(stderr)
+	cmd.flags.StringVar(&cmd.Dir, "dir", "", "cache directory; defaults to the user cache directory")
+
+	cmd.flags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage of %s:\n", os.Args[0])
+		cmd.flags.PrintDefaults()
+		fmt.Fprintln(stderr, usage)
+	}
+
+	return cmd.flags, nil
+}
+
+func (cmd *Cache) Main(ctx context.Context, pwd string, args []string) error {
+	if cmd == nil {
+		return fmt.Errorf("cache is nil")
+	}
+
+	if err := (&cmd.Cmd).Init(); err != nil {
+		return err
+	}
+
+	flags, err := cmd.Flags(cmd.Stderr())
+	if err != nil {
+		return err
+	}
+
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return nil
+		}
+		return err
+	}
+
+	remaining := flags.Args()
+	if len(remaining) == 0 {
+		flags.Usage()
+		return nil
+	}
+
+	ec := hype.NewExecCache(cmd.Dir)
+
+	switch remaining[0] {
+	case "clean":
+		if err := ec.Clean(); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.Stdout(), "removed %s\n", ec.Dir)
+		return nil
+	case "dir":
+		fmt.Fprintln(cmd.Stdout(), ec.Dir)
+		return nil
+	default:
+		return fmt.Errorf("unknown subcommand: %s", remaining[0])
+	}
+}
+
+// newExecCache returns the execution cache to use
+// for a command, or nil if caching is disabled.
+func newExecCache(disabled bool, dir string) *hype.ExecCache {
+	if disabled {
+		return nil
+	}
+
+	return hype.NewExecCache(dir)
+}
@@ -0,0 +1,38 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Cache_Main(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	dir := filepath.Join(t.TempDir(), "cache")
+	r.NoError(os.MkdirAll(filepath.Join(dir, "ab"), 0755))
+	r.NoError(os.WriteFile(filepath.Join(dir, "ab", "abc.json"), []byte("{}"), 0644))
+
+	bb := &bytes.Buffer{}
+	cmd := &Cache{}
+	cmd.Out = bb
+
+	ctx := context.Background()
+
+	r.NoError(cmd.Main(ctx, ".", []string{"-dir", dir, "dir"}))
+	r.Equal(dir, strings.TrimSpace(bb.String()))
+
+	r.NoError(cmd.Main(ctx, ".", []string{"-dir", dir, "clean"}))
+
+	_, err := os.Stat(dir)
+	r.True(os.IsNotExist(err))
+
+	err = cmd.Main(ctx, ".", []string{"-dir", dir, "unknown"})
+	r.Error(err)
+}
@@ -5,7 +5,9 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"slices"
 	"sync"
 	"time"
 
@@ -24,11 +26,30 @@ type App struct {
 
 	Parser *hype.Parser
 
+	// Project is the project configuration, read from the
+	// closest hype.yaml; nil if there is none
+	Project *ProjectConfig
+
+	// Profile is the profile of the project configuration in
+	// use, from the -profile flag, or $HYPE_PROFILE
+	Profile string
+
 	once    sync.Once
 	initErr error
 }
 
 func (cmd *App) Main(ctx context.Context, pwd string, args []string) error {
+	profile, args, err := profileFromArgs(args)
+	if err != nil {
+		return err
+	}
+
+	if len(profile) == 0 {
+		profile = os.Getenv(ProfileEnv)
+	}
+
+	cmd.Profile = profile
+
 	if len(args) == 0 {
 		return cleo.ErrNoCommand
 	}
@@ -80,12 +101,43 @@ func (cmd *App) execute(ctx context.Context, pwd string, args []string) error {
 		return cleo.ErrNoCommands
 	}
 
-	err := c.Main(ctx, pwd, args[1:])
-	if err == nil {
-		return nil
+	args, err := cmd.configArgs(c, args[1:])
+	if err != nil {
+		return err
 	}
 
-	return err
+	return c.Main(ctx, pwd, args)
+}
+
+// configArgs returns the args of a command, with the flags
+// of its settings in the project configuration, its profile,
+// and the environment, before them.
+func (cmd *App) configArgs(c cleo.Commander, args []string) ([]string, error) {
+	var name string
+	for k, v := range cmd.Commands {
+		if v == c {
+			name = k
+			break
+		}
+	}
+
+	fc, ok := c.(Flagger)
+	if !ok || !slices.Contains(configured, name) {
+		return args, nil
+	}
+
+	stderr := io.Writer(os.Stderr)
+	if se, ok := c.(interface{ Stderr() io.Writer }); ok {
+		stderr = se.Stderr()
+	}
+
+	flags, err := fc.Flags(stderr)
+	if err != nil {
+		return nil, err
+	}
+
+	args, _, err = cmd.Project.Args(name, cmd.Profile, flags, args, os.Getenv)
+	return args, err
 }
 
 func (cmd *App) init(pwd string, args []string) error {
@@ -98,6 +150,18 @@ func (cmd *App) init(pwd string, args []string) error {
 			cmd.initErr = err
 		}
 
+		if err := cmd.loadConfig(pwd); err != nil {
+			cmd.initErr = err
+			return
+		}
+
+		if cmd.Parser != nil && cmd.initErr == nil {
+			if err := useTagPlugins(context.Background(), cmd.Parser, cmd.Project.PluginPaths()...); err != nil {
+				cmd.initErr = err
+				return
+			}
+		}
+
 		for _, c := range cmd.SubCommands() {
 			if pc, ok := c.(ParserCommander); ok {
 				if err := pc.SetParser(cmd.Parser); err != nil {
@@ -111,6 +175,35 @@ func (cmd *App) init(pwd string, args []string) error {
 	return cmd.initErr
 }
 
+// loadConfig reads the closest hype.yaml to pwd, if there
+// is one, and gives it to the config command.
+func (cmd *App) loadConfig(pwd string) error {
+	fp, err := FindConfig(pwd)
+	if err != nil {
+		return err
+	}
+
+	if len(fp) > 0 {
+		cmd.Project, err = LoadConfig(fp)
+		if err != nil {
+			return err
+		}
+	}
+
+	cc, ok := cmd.Commands["config"].(*Config)
+	if !ok {
+		return nil
+	}
+
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	cc.Project = cmd.Project
+	cc.Profile = cmd.Profile
+
+	return nil
+}
+
 type VersionInfo struct {
 	Version string
 	Commit  string
@@ -144,6 +237,14 @@ func New(root string, info VersionInfo) *App {
 		Parser: p,
 	}
 
+	enc := &Encode{
+		Cmd: cleo.Cmd{
+			Name: "encode",
+			Desc: "encode the document as JSON (json, or the versioned ast schema)",
+		},
+		Parser: p,
+	}
+
 	pv := &Preview{
 		Cmd: cleo.Cmd{
 			Name:    "preview",
@@ -190,6 +291,33 @@ func New(root string, info VersionInfo) *App {
 		Parser: p,
 	}
 
+	ca := &Cache{
+		Cmd: cleo.Cmd{
+			Name: "cache",
+			Desc: "manage the cache of <cmd> and <go> results",
+		},
+	}
+
+	cfg := &Config{
+		Cmd: cleo.Cmd{
+			Name: "config",
+			Desc: "show the settings of the commands from hype.yaml, profiles, and the environment",
+		},
+		Commands: map[string]Flagger{
+			"export":   e,
+			"preview":  pv,
+			"slides":   sl,
+			"validate": val,
+		},
+	}
+
+	ls := &LSP{
+		Cmd: cleo.Cmd{
+			Name: "lsp",
+			Desc: "language server for editors (diagnostics, completion, definitions, hover)",
+		},
+	}
+
 	app := &App{
 		Cmd: cleo.Cmd{
 			Name: "hype",
@@ -199,9 +327,13 @@ func New(root string, info VersionInfo) *App {
 				"preview":  pv,
 				"slides":   sl,
 				"export":   e,
+				"encode":   enc,
 				"blog":     bl,
 				"version":  ver,
 				"validate": val,
+				"cache":    ca,
+				"config":   cfg,
+				"lsp":      ls,
 			},
 		},
 		Parser: p,
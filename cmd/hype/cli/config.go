@@ -0,0 +1,540 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/markbates/cleo"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFile is the name of the project configuration file. It
+// is found in the working directory, or the closest parent of it.
+const ConfigFile = "hype.yaml"
+
+// ProfileEnv is the environment variable that selects
+// a profile, when the -profile flag is not given.
+const ProfileEnv = "HYPE_PROFILE"
+
+// configured are the commands that read their settings
+// from the project configuration.
+var configured = []string{"export", "preview", "slides", "validate"}
+
+// Flagger is a command whose flags can be set by
+// the project configuration.
+type Flagger interface {
+	Flags(stderr io.Writer) (*flag.FlagSet, error)
+}
+
+// ConfigSections are the settings of the commands, by the
+// names of their flags, such as format for -format.
+type ConfigSections struct {
+	Export   map[string]any `yaml:"export,omitempty"`
+	Preview  map[string]any `yaml:"preview,omitempty"`
+	Slides   map[string]any `yaml:"slides,omitempty"`
+	Validate map[string]any `yaml:"validate,omitempty"`
+}
+
+func (cs ConfigSections) section(command string) map[string]any {
+	switch command {
+	case "export":
+		return cs.Export
+	case "preview":
+		return cs.Preview
+	case "slides":
+		return cs.Slides
+	case "validate":
+		return cs.Validate
+	}
+
+	return nil
+}
+
+// ProjectConfig is the project configuration, read from a
+// hype.yaml file:
+//
+//	export:
+//	  format: html
+//	  theme: solarized-dark
+//	  check-links: true
+//	preview:
+//	  port: 4000
+//	plugins:
+//	  - tools/hype-plugin-quiz
+//	profiles:
+//	  book:
+//	    export:
+//	      f: book
+//	      format: pdf
+//
+// A profile, chosen with -profile, or $HYPE_PROFILE, overrides
+// the settings of the commands. Environment variables named
+// HYPE_<COMMAND>_<FLAG>, such as HYPE_EXPORT_CHECK_LINKS,
+// override the profile, and flags override them all.
+type ProjectConfig struct {
+	ConfigSections `yaml:",inline"`
+
+	// Plugins are tag plugin executables, relative to the
+	// folder of the file, loaded as well as those in
+	// $HYPE_PLUGIN_PATH
+	Plugins []string `yaml:"plugins,omitempty"`
+
+	// Profiles are named settings that override the settings
+	// of the commands
+	Profiles map[string]ConfigSections `yaml:"profiles,omitempty"`
+
+	// Path is the file the configuration was read from
+	Path string `yaml:"-"`
+}
+
+// ConfigSetting is the effective value of a flag of a
+// command, and where it was set.
+type ConfigSetting struct {
+	Name   string
+	Value  string
+	Source string // default, hype.yaml, profile <name>, env <NAME>, or flag
+}
+
+// FindConfig returns the path to the closest hype.yaml, in
+// dir, or a parent of it. The path is empty if there is none.
+func FindConfig(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		fp := filepath.Join(dir, ConfigFile)
+
+		fi, err := os.Stat(fp)
+		if err == nil && !fi.IsDir() {
+			return fp, nil
+		}
+
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+
+		dir = parent
+	}
+}
+
+// LoadConfig reads the project configuration at path.
+// Unknown sections are an error.
+func LoadConfig(path string) (*ProjectConfig, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &ProjectConfig{
+		Path: path,
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return cfg, nil
+}
+
+// PluginPaths returns the paths to the tag plugins
+// of the configuration.
+func (cfg *ProjectConfig) PluginPaths() []string {
+	if cfg == nil {
+		return nil
+	}
+
+	paths := make([]string, 0, len(cfg.Plugins))
+	for _, pl := range cfg.Plugins {
+		if !filepath.IsAbs(pl) {
+			pl = filepath.Join(filepath.Dir(cfg.Path), pl)
+		}
+		paths = append(paths, pl)
+	}
+
+	return paths
+}
+
+// Args returns the flags, for the command, of the settings
+// in the configuration, its profile, and the environment, that
+// are not set in args, followed by args. The settings are the
+// effective value, and source, of every flag of the command.
+//
+// The configuration may be nil, to only read the environment.
+func (cfg *ProjectConfig) Args(command string, profile string, flags *flag.FlagSet, args []string, getenv func(string) string) ([]string, []ConfigSetting, error) {
+	if flags == nil {
+		return nil, nil, fmt.Errorf("flags are nil")
+	}
+
+	if getenv == nil {
+		getenv = os.Getenv
+	}
+
+	type layer struct {
+		source string
+		values map[string]any
+	}
+
+	var layers []layer
+
+	if cfg != nil {
+		layers = append(layers, layer{source: filepath.Base(cfg.Path), values: cfg.section(command)})
+	}
+
+	if len(profile) > 0 {
+		var prof ConfigSections
+		ok := false
+		if cfg != nil {
+			prof, ok = cfg.Profiles[profile]
+		}
+
+		if !ok {
+			return nil, nil, fmt.Errorf("unknown profile %q", profile)
+		}
+
+		layers = append(layers, layer{source: "profile " + profile, values: prof.section(command)})
+	}
+
+	// the values of the flags, by flag.Value, so
+	// aliases, such as -w, and -watch, are one flag
+	type setting struct {
+		name   string
+		values []string
+		source string
+	}
+
+	settings := map[flag.Value]*setting{}
+	var order []flag.Value
+
+	set := func(f *flag.Flag, values []string, source string) {
+		s, ok := settings[f.Value]
+		if !ok {
+			s = &setting{}
+			settings[f.Value] = s
+			order = append(order, f.Value)
+		}
+
+		s.name = f.Name
+		s.values = values
+		s.source = source
+	}
+
+	for _, l := range layers {
+		keys := make([]string, 0, len(l.values))
+		for k := range l.values {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			f := flags.Lookup(k)
+			if f == nil {
+				return nil, nil, fmt.Errorf("%s: %s: unknown flag -%s", l.source, command, k)
+			}
+
+			values, err := configValues(f, l.values[k])
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s: %s: -%s: %w", l.source, command, k, err)
+			}
+
+			set(f, values, l.source)
+		}
+	}
+
+	flags.VisitAll(func(f *flag.Flag) {
+		name := configEnvName(command, f.Name)
+		if v := getenv(name); len(v) > 0 {
+			set(f, []string{v}, "env "+name)
+		}
+	})
+
+	given := flagsInArgs(flags, args)
+
+	var res []string
+	for _, v := range order {
+		s := settings[v]
+		if given[v] {
+			continue
+		}
+
+		for _, val := range s.values {
+			res = append(res, fmt.Sprintf("-%s=%s", s.name, val))
+		}
+	}
+
+	var effective []ConfigSetting
+	flags.VisitAll(func(f *flag.Flag) {
+		cs := ConfigSetting{
+			Name:   f.Name,
+			Value:  f.DefValue,
+			Source: "default",
+		}
+
+		if s, ok := settings[f.Value]; ok {
+			cs.Value = strings.Join(s.values, ",")
+			cs.Source = s.source
+		}
+
+		if given[f.Value] {
+			cs.Value = ""
+			cs.Source = "flag"
+		}
+
+		effective = append(effective, cs)
+	})
+
+	return append(res, args...), effective, nil
+}
+
+func (cfg *ProjectConfig) section(command string) map[string]any {
+	if cfg == nil {
+		return nil
+	}
+
+	return cfg.ConfigSections.section(command)
+}
+
+// configEnvName returns the name of the environment variable
+// that sets the flag of a command, such as HYPE_EXPORT_CHECK_LINKS.
+func configEnvName(command string, name string) string {
+	name = strings.ReplaceAll(name, "-", "_")
+	return strings.ToUpper("HYPE_" + command + "_" + name)
+}
+
+// configValues returns the value of a setting, as the values
+// of its flag. A list sets a repeatable flag once for each
+// item, and other flags to the comma separated items.
+func configValues(f *flag.Flag, v any) ([]string, error) {
+	switch t := v.(type) {
+	case nil:
+		return nil, fmt.Errorf("missing value")
+	case []any:
+		values := make([]string, 0, len(t))
+		for _, x := range t {
+			values = append(values, fmt.Sprint(x))
+		}
+
+		if _, ok := f.Value.(*stringSlice); ok {
+			return values, nil
+		}
+
+		return []string{strings.Join(values, ",")}, nil
+	case map[string]any:
+		return nil, fmt.Errorf("expected a value, or a list, not a map")
+	case bool:
+		return []string{strconv.FormatBool(t)}, nil
+	}
+
+	return []string{fmt.Sprint(v)}, nil
+}
+
+// flagsInArgs returns the flags, by flag.Value, that are
+// set in args, without parsing args into flags.
+func flagsInArgs(flags *flag.FlagSet, args []string) map[flag.Value]bool {
+	given := map[flag.Value]bool{}
+
+	scratch := flag.NewFlagSet(flags.Name(), flag.ContinueOnError)
+	scratch.SetOutput(io.Discard)
+
+	flags.VisitAll(func(f *flag.Flag) {
+		bf, ok := f.Value.(interface{ IsBoolFlag() bool })
+		scratch.Var(&argValue{bool: ok && bf.IsBoolFlag()}, f.Name, f.Usage)
+	})
+
+	// errors, such as unknown flags, are
+	// reported when the command parses args
+	_ = scratch.Parse(args)
+
+	scratch.Visit(func(sf *flag.Flag) {
+		if f := flags.Lookup(sf.Name); f != nil {
+			given[f.Value] = true
+		}
+	})
+
+	return given
+}
+
+// argValue is a flag.Value that accepts any value.
+type argValue struct {
+	bool bool
+}
+
+func (a *argValue) String() string   { return "" }
+func (a *argValue) Set(string) error { return nil }
+func (a *argValue) IsBoolFlag() bool { return a.bool }
+
+// profileFromArgs returns the value of the -profile flag, in
+// args, before their first --, and args without it.
+func profileFromArgs(args []string) (string, []string, error) {
+	var profile string
+
+	res := make([]string, 0, len(args))
+
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+
+		if a == "--" {
+			res = append(res, args[i:]...)
+			break
+		}
+
+		name, val, hasVal := strings.Cut(strings.TrimLeft(a, "-"), "=")
+		if !strings.HasPrefix(a, "-") || name != "profile" {
+			res = append(res, a)
+			continue
+		}
+
+		if !hasVal {
+			if i+1 >= len(args) {
+				return "", nil, fmt.Errorf("flag needs an argument: -profile")
+			}
+			i++
+			val = args[i]
+		}
+
+		profile = val
+	}
+
+	return profile, res, nil
+}
+
+// Config prints the project configuration.
+type Config struct {
+	cleo.Cmd
+
+	// Project is the project configuration;
+	// nil if there is no hype.yaml
+	Project *ProjectConfig
+
+	// Profile is the profile in use
+	Profile string
+
+	// Commands are the commands configured by the project
+	// configuration, by name
+	Commands map[string]Flagger
+
+	mu sync.RWMutex
+}
+
+func (cmd *Config) Main(ctx context.Context, pwd string, args []string) error {
+	usage := `Usage: hype config <command>
+
+Commands:
+    show    Print the effective settings of the commands, and where they are set
+
+Examples:
+    hype config show
+    hype config show -profile book
+`
+
+	if cmd == nil {
+		return fmt.Errorf("config is nil")
+	}
+
+	if err := (&cmd.Cmd).Init(); err != nil {
+		return err
+	}
+
+	if len(args) == 0 {
+		fmt.Fprint(cmd.Stderr(), usage)
+		return nil
+	}
+
+	switch args[0] {
+	case "show":
+		return cmd.show(cmd.Stdout())
+	case "help", "-h", "--help":
+		fmt.Fprint(cmd.Stderr(), usage)
+		return nil
+	}
+
+	return fmt.Errorf("unknown subcommand: %s", args[0])
+}
+
+// show prints the effective settings of each command,
+// as YAML, with where each is set.
+func (cmd *Config) show(w io.Writer) error {
+	cmd.mu.RLock()
+	cfg := cmd.Project
+	profile := cmd.Profile
+	commands := cmd.Commands
+	cmd.mu.RUnlock()
+
+	if cfg != nil {
+		fmt.Fprintf(w, "# %s\n", cfg.Path)
+	} else {
+		fmt.Fprintf(w, "# no %s found\n", ConfigFile)
+	}
+
+	if len(profile) > 0 {
+		fmt.Fprintf(w, "# profile: %s\n", profile)
+	}
+
+	for _, name := range configured {
+		c, ok := commands[name]
+		if !ok {
+			continue
+		}
+
+		flags, err := c.Flags(io.Discard)
+		if err != nil {
+			return err
+		}
+
+		_, settings, err := cfg.Args(name, profile, flags, nil, os.Getenv)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(w, "%s:\n", name)
+
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		for _, s := range settings {
+			fmt.Fprintf(tw, "  %s: %s\t# %s\n", s.Name, configQuote(s.Value), s.Source)
+		}
+
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if paths := cfg.PluginPaths(); len(paths) > 0 {
+		fmt.Fprintln(w, "plugins:")
+		for _, p := range paths {
+			fmt.Fprintf(w, "  - %s\n", p)
+		}
+	}
+
+	return nil
+}
+
+// configQuote quotes values that YAML would
+// not read back as the same string.
+func configQuote(s string) string {
+	if len(s) == 0 || strings.ContainsAny(s, ":#,[]{}&*!|>'\"%@`") || strings.TrimSpace(s) != s {
+		return strconv.Quote(s)
+	}
+
+	return s
+}
@@ -0,0 +1,280 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/markbates/iox"
+	"github.com/stretchr/testify/require"
+)
+
+const testProjectConfig = `export:
+  format: html
+  theme: solarized-dark
+  check-links: true
+  link-exclude: [https://localhost:*, https://internal.example.com/*]
+preview:
+  port: 4000
+  w: [src, images]
+profiles:
+  book:
+    export:
+      f: book
+      format: pdf
+`
+
+func writeProjectConfig(t testing.TB, dir string, body string) string {
+	t.Helper()
+
+	fp := filepath.Join(dir, ConfigFile)
+	require.NoError(t, os.WriteFile(fp, []byte(body), 0644))
+
+	return fp
+}
+
+func Test_FindConfig(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	root := t.TempDir()
+	sub := filepath.Join(root, "a", "b")
+	r.NoError(os.MkdirAll(sub, 0755))
+
+	fp, err := FindConfig(sub)
+	r.NoError(err)
+	r.Empty(fp)
+
+	exp := writeProjectConfig(t, root, testProjectConfig)
+
+	fp, err = FindConfig(sub)
+	r.NoError(err)
+	r.Equal(exp, fp)
+}
+
+func Test_LoadConfig(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	dir := t.TempDir()
+
+	cfg, err := LoadConfig(writeProjectConfig(t, dir, testProjectConfig))
+	r.NoError(err)
+	r.Equal("html", cfg.Export["format"])
+	r.Equal(4000, cfg.Preview["port"])
+	r.Contains(cfg.Profiles, "book")
+
+	_, err = LoadConfig(writeProjectConfig(t, dir, "exprot:\n  format: html\n"))
+	r.Error(err)
+
+	cfg, err = LoadConfig(writeProjectConfig(t, dir, ""))
+	r.NoError(err)
+	r.Nil(cfg.Export)
+}
+
+func Test_ProjectConfig_Args(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := LoadConfig(writeProjectConfig(t, t.TempDir(), testProjectConfig))
+	require.NoError(t, err)
+
+	env := func(m map[string]string) func(string) string {
+		return func(k string) string { return m[k] }
+	}
+
+	table := []struct {
+		name    string
+		command string
+		profile string
+		env     map[string]string
+		args    []string
+		exp     []string
+	}{
+		{
+			name:    "config",
+			command: "export",
+			args:    []string{"-v"},
+			exp:     []string{"-check-links=true", "-format=html", "-link-exclude=https://localhost:*,https://internal.example.com/*", "-theme=solarized-dark", "-v"},
+		},
+		{
+			name:    "profile",
+			command: "export",
+			profile: "book",
+			exp:     []string{"-check-links=true", "-format=pdf", "-link-exclude=https://localhost:*,https://internal.example.com/*", "-theme=solarized-dark", "-f=book"},
+		},
+		{
+			name:    "env",
+			command: "export",
+			profile: "book",
+			env:     map[string]string{"HYPE_EXPORT_FORMAT": "epub", "HYPE_EXPORT_NO_CSS": "true"},
+			exp:     []string{"-check-links=true", "-format=epub", "-link-exclude=https://localhost:*,https://internal.example.com/*", "-theme=solarized-dark", "-f=book", "-no-css=true"},
+		},
+		{
+			name:    "flags",
+			command: "export",
+			env:     map[string]string{"HYPE_EXPORT_THEME": "github"},
+			args:    []string{"-format", "markdown", "--theme=dracula", "-check-links=false"},
+			exp:     []string{"-link-exclude=https://localhost:*,https://internal.example.com/*", "-format", "markdown", "--theme=dracula", "-check-links=false"},
+		},
+		{
+			name:    "repeatable",
+			command: "preview",
+			exp:     []string{"-port=4000", "-w=src", "-w=images"},
+		},
+		{
+			name:    "repeatable alias",
+			command: "preview",
+			args:    []string{"-watch", "docs"},
+			exp:     []string{"-port=4000", "-watch", "docs"},
+		},
+		{
+			name:    "none",
+			command: "validate",
+			args:    []string{"-exec"},
+			exp:     []string{"-exec"},
+		},
+	}
+
+	for _, tt := range table {
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+
+			var c Flagger
+			switch tt.command {
+			case "export":
+				c = &Export{}
+			case "preview":
+				c = &Preview{}
+			case "validate":
+				c = &Validate{}
+			}
+
+			flags, err := c.Flags(io.Discard)
+			r.NoError(err)
+
+			act, _, err := cfg.Args(tt.command, tt.profile, flags, tt.args, env(tt.env))
+			r.NoError(err)
+			r.Equal(tt.exp, act)
+
+			r.NoError(flags.Parse(act))
+		})
+	}
+}
+
+func Test_ProjectConfig_Args_Errors(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	cfg, err := LoadConfig(writeProjectConfig(t, t.TempDir(), "export:\n  colour: red\n"))
+	r.NoError(err)
+
+	flags, err := (&Export{}).Flags(io.Discard)
+	r.NoError(err)
+
+	_, _, err = cfg.Args("export", "", flags, nil, nil)
+	r.Error(err)
+	r.Contains(err.Error(), "unknown flag -colour")
+
+	_, _, err = cfg.Args("slides", "book", flag.NewFlagSet("slides", flag.ContinueOnError), nil, nil)
+	r.Error(err)
+	r.Contains(err.Error(), `unknown profile "book"`)
+
+	var none *ProjectConfig
+	args, _, err := none.Args("slides", "", flag.NewFlagSet("slides", flag.ContinueOnError), []string{"a.md"}, nil)
+	r.NoError(err)
+	r.Equal([]string{"a.md"}, args)
+}
+
+func Test_profileFromArgs(t *testing.T) {
+	t.Parallel()
+
+	table := []struct {
+		in      []string
+		profile string
+		args    []string
+		err     bool
+	}{
+		{in: []string{"export", "-f", "a.md"}, args: []string{"export", "-f", "a.md"}},
+		{in: []string{"--profile=book", "export"}, profile: "book", args: []string{"export"}},
+		{in: []string{"export", "-profile", "book", "-f", "a.md"}, profile: "book", args: []string{"export", "-f", "a.md"}},
+		{in: []string{"export", "--", "-profile=book"}, args: []string{"export", "--", "-profile=book"}},
+		{in: []string{"export", "-profile"}, err: true},
+	}
+
+	for _, tt := range table {
+		profile, args, err := profileFromArgs(tt.in)
+		if tt.err {
+			require.Error(t, err)
+			continue
+		}
+
+		require.NoError(t, err)
+		require.Equal(t, tt.profile, profile)
+		require.Equal(t, tt.args, args)
+	}
+}
+
+func Test_Config_Show(t *testing.T) {
+	r := require.New(t)
+
+	dir := t.TempDir()
+	fp := writeProjectConfig(t, dir, testProjectConfig)
+
+	t.Setenv("HYPE_PREVIEW_THEME", "dracula")
+
+	app := New(dir, VersionInfo{})
+
+	bb := iox.Buffer{}
+	cc := app.Commands["config"].(*Config)
+	cc.IO.Out = &bb.Out
+	cc.IO.Err = &bb.Err
+
+	err := app.Main(context.Background(), dir, []string{"config", "show", "-profile", "book"})
+	r.NoError(err)
+
+	act := bb.Out.String()
+
+	r.Contains(act, "# "+fp+"\n# profile: book\n")
+	r.Contains(act, "export:\n")
+	r.Regexp(`  format: pdf +# profile book\n`, act)
+	r.Regexp(`  theme: solarized-dark +# hype.yaml\n`, act)
+	r.Regexp(`  timeout: 30s +# default\n`, act)
+	r.Regexp(`  link-exclude: "https://localhost:\*,https://internal.example.com/\*" +# hype.yaml\n`, act)
+	r.Regexp(`  theme: dracula +# env HYPE_PREVIEW_THEME\n`, act)
+	r.Contains(act, "slides:\n")
+	r.Contains(act, "validate:\n")
+}
+
+func Test_App_configArgs(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	dir := t.TempDir()
+
+	cfg, err := LoadConfig(writeProjectConfig(t, dir, testProjectConfig))
+	r.NoError(err)
+
+	app := New(dir, VersionInfo{})
+	app.Project = cfg
+	app.Profile = "book"
+
+	e := app.Commands["export"]
+
+	args, err := app.configArgs(e, []string{"-f", "hype.md"})
+	r.NoError(err)
+	r.Contains(args, "-format=pdf")
+	r.NotContains(args, "-f=book")
+	r.Equal([]string{"-f", "hype.md"}, args[len(args)-2:])
+
+	// commands without settings are unchanged
+	args, err = app.configArgs(app.Commands["version"], []string{"-x"})
+	r.NoError(err)
+	r.Equal([]string{"-x"}, args)
+
+	var buf bytes.Buffer
+	r.NoError(app.Commands["config"].(*Config).show(&buf))
+}
@@ -21,6 +21,8 @@ type Encode struct {
 	Timeout   time.Duration // default: 5s
 	Parser    *hype.Parser  // If nil, a default parser is used.
 	ParseOnly bool          // if true, only parse the file and exit
+	Format    string        // json (default), or ast
+	Schema    bool          // if true, print the JSON Schema of the ast format and exit
 
 	flags *flag.FlagSet
 
@@ -56,6 +58,8 @@ func (cmd *Encode) Flags() (*flag.FlagSet, error) {
 	cmd.flags.BoolVar(&cmd.ParseOnly, "p", cmd.ParseOnly, "if true, only parse the file and exit")
 	cmd.flags.DurationVar(&cmd.Timeout, "timeout", DefaultTimeout, "timeout for execution")
 	cmd.flags.StringVar(&cmd.File, "f", cmd.File, "optional file name to preview")
+	cmd.flags.StringVar(&cmd.Format, "format", "json", "output format: json, or ast (the versioned schema)")
+	cmd.flags.BoolVar(&cmd.Schema, "schema", cmd.Schema, "print the JSON Schema of the ast format and exit")
 
 	return cmd.flags, nil
 }
@@ -90,6 +94,22 @@ func (cmd *Encode) Main(ctx context.Context, pwd string, args []string) error {
 
 	args = flags.Args()
 
+	if cmd.Schema {
+		b, err := hype.ASTSchema()
+		if err != nil {
+			return err
+		}
+
+		_, err = cmd.Stdout().Write(b)
+		return err
+	}
+
+	switch cmd.Format {
+	case "", "json", "ast":
+	default:
+		return fmt.Errorf("unknown format %q: expected json, or ast", cmd.Format)
+	}
+
 	if len(args) > 0 {
 		fn := args[0]
 
@@ -107,6 +127,16 @@ func (cmd *Encode) Main(ctx context.Context, pwd string, args []string) error {
 
 		enc := json.NewEncoder(cmd.Stdout())
 		enc.SetIndent("", "  ")
+
+		if cmd.Format == "ast" {
+			ad, err := doc.AST()
+			if err != nil {
+				return err
+			}
+
+			return enc.Encode(ad)
+		}
+
 		return enc.Encode(doc)
 	}
 
@@ -2,6 +2,7 @@ package cli
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io/fs"
 	"os"
@@ -107,3 +108,68 @@ func Test_Encode_JSON(t *testing.T) {
 	}
 
 }
+
+func Test_Encode_AST(t *testing.T) {
+	t.Parallel()
+
+	r := require.New(t)
+
+	root := "testdata/encode/json"
+	cab := os.DirFS(root)
+
+	p, err := NewParser(cab, root, root)
+	r.NoError(err)
+
+	cmd := &Encode{
+		Parser: p,
+	}
+
+	bb := iox.Buffer{}
+	cmd.IO.Out = &bb.Out
+	cmd.IO.Err = &bb.Err
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err = cmd.Main(ctx, root, []string{"-p", "-format", "ast", "hype.md"})
+	r.NoError(err)
+
+	var ad hype.ASTDocument
+	r.NoError(json.Unmarshal(bb.Out.Bytes(), &ad))
+	r.Equal(hype.ASTVersion, ad.Version)
+
+	doc, err := ad.Document()
+	r.NoError(err)
+	r.Contains(doc.String(), `<echo id="123">Hello!</echo>`)
+}
+
+func Test_Encode_Schema(t *testing.T) {
+	t.Parallel()
+
+	r := require.New(t)
+
+	cmd := &Encode{}
+
+	bb := iox.Buffer{}
+	cmd.IO.Out = &bb.Out
+	cmd.IO.Err = &bb.Err
+
+	err := cmd.Main(context.Background(), "testdata/encode/json", []string{"-schema"})
+	r.NoError(err)
+
+	exp, err := hype.ASTSchema()
+	r.NoError(err)
+
+	r.Equal(string(exp), bb.Out.String())
+}
+
+func Test_Encode_Unknown_Format(t *testing.T) {
+	t.Parallel()
+
+	r := require.New(t)
+
+	cmd := &Encode{}
+
+	err := cmd.Main(context.Background(), "testdata/encode/json", []string{"-format", "xml", "hype.md"})
+	r.Error(err)
+}
@@ -17,7 +17,11 @@ import (
 	"sync"
 	"time"
 
+	"github.com/gobuffalo/flect"
 	"github.com/gopherguides/hype"
+	"github.com/gopherguides/hype/epub"
+	"github.com/gopherguides/hype/images"
+	"github.com/gopherguides/hype/pdf"
 	"github.com/gopherguides/hype/themes"
 	"github.com/markbates/cleo"
 	"github.com/markbates/plugins"
@@ -41,11 +45,22 @@ type Export struct {
 	NoCSS      bool   // output raw HTML without styling
 	ListThemes bool   // list available themes and exit
 
+	PageSize string // page size for PDF export; default: pdf.DefaultPageSize
+
+	Mermaid string // how mermaid diagrams are rendered in HTML, and EPUB: ascii, or svg; default: svg
+
+	Images      bool   // size, and copy, local images next to the HTML output
+	ImageWidths string // comma-separated widths of resized copies of images, for srcset
+
 	CheckLinks  bool          // enable link reachability checking
 	LinkTimeout time.Duration // per-link check timeout
 	LinkExclude string        // comma-separated URL patterns to exclude
 	LinkRate    float64       // requests per second per host
 
+	NoCache  bool   // disable the execution cache
+	CacheDir string // execution cache directory; default: hype.DefaultExecCacheDir()
+	Runner   string // run every <cmd> with this runner only; default: all runners available
+
 	flags *flag.FlagSet
 
 	mu sync.RWMutex
@@ -117,6 +132,14 @@ Examples:
 	hype export -f hype.md -check-links
 	hype export -f hype.md -check-links -link-timeout=20s -link-rate=1
 	hype export -f hype.md -check-links -link-exclude="https://localhost:*,https://internal.example.com/*"
+	hype export -f hype.md -no-cache
+	hype export -f hype.md -runner=restricted
+	hype export -f hype.md -format deps -o hype.d
+	hype export -f hype.md -format json-deps
+	hype export -f book -format epub -o book.epub
+	hype export -f book -format pdf -page-size A4 -o book.pdf
+	hype export -f hype.md -format html -images -image-widths=480,960 -o site/index.html
+	hype export -f hype.md -format html -mermaid ascii
 `
 
 	if err := cmd.validate(); err != nil {
@@ -135,16 +158,23 @@ Examples:
 	cmd.flags.DurationVar(&cmd.Timeout, "timeout", DefaultTimeout, "timeout for execution, defaults to 30 seconds (30s)")
 	cmd.flags.StringVar(&cmd.File, "f", "hype.md", "optional file name to preview, if not provided, defaults to hype.md")
 	cmd.flags.BoolVar(&cmd.Verbose, "v", false, "enable verbose output for debugging")
-	cmd.flags.StringVar(&cmd.Format, "format", "markdown", "content type to export to: markdown, html, json-meta, json-toc")
+	cmd.flags.StringVar(&cmd.Format, "format", "markdown", "content type to export to: markdown, html, json-meta, json-toc, deps, json-deps, epub, pdf")
 	cmd.flags.Var(&cmd.OutPath, "o", "path to the output file; if not provided, output is written to stdout")
 	cmd.flags.StringVar(&cmd.Theme, "theme", themes.DefaultTheme, "theme for HTML export (e.g., github, solarized-dark)")
 	cmd.flags.StringVar(&cmd.CustomCSS, "css", "", "path to custom CSS file for HTML export")
 	cmd.flags.BoolVar(&cmd.NoCSS, "no-css", false, "output raw HTML without styling")
 	cmd.flags.BoolVar(&cmd.ListThemes, "themes", false, "list available themes and exit")
+	cmd.flags.StringVar(&cmd.PageSize, "page-size", pdf.DefaultPageSize, "page size for PDF export (e.g., Letter, A4)")
+	cmd.flags.StringVar(&cmd.Mermaid, "mermaid", hype.MermaidSVG, "how mermaid diagrams are rendered in HTML, and EPUB (ascii, svg); markdown, and PDF, use ascii; a render attribute on a diagram takes precedence")
+	cmd.flags.BoolVar(&cmd.Images, "images", false, "for HTML export, write local images, and resized copies of them, next to the -o file")
+	cmd.flags.StringVar(&cmd.ImageWidths, "image-widths", "", "comma-separated widths of resized copies of images, for srcset; <img widths> takes precedence")
 	cmd.flags.BoolVar(&cmd.CheckLinks, "check-links", false, "enable URL reachability checking for links")
 	cmd.flags.DurationVar(&cmd.LinkTimeout, "link-timeout", 10*time.Second, "per-link check timeout")
 	cmd.flags.StringVar(&cmd.LinkExclude, "link-exclude", "", "comma-separated URL patterns to exclude from link checking")
 	cmd.flags.Float64Var(&cmd.LinkRate, "link-rate", 2, "max requests per second per host for link checking")
+	cmd.flags.BoolVar(&cmd.NoCache, "no-cache", false, "disable the cache of <cmd> and <go> results")
+	cmd.flags.StringVar(&cmd.CacheDir, "cache-dir", "", "directory for the cache of <cmd> and <go> results; defaults to the user cache directory")
+	cmd.flags.StringVar(&cmd.Runner, "runner", "", "run every <cmd> with the named runner (host, restricted); tags requesting any other runner fail")
 
 	cmd.flags.Usage = func() {
 		fmt.Fprintf(stderr, "Usage of %s:\n", os.Args[0])
@@ -305,13 +335,39 @@ func (cmd *Export) execute(ctx context.Context, pwd string) error {
 		p.LinkValidator = nil
 	}
 
+	p.ExecCache = newExecCache(cmd.NoCache, cmd.CacheDir)
+
+	if err := useRunner(p, cmd.Runner); err != nil {
+		return err
+	}
+
 	p.Root = filepath.Join(filepath.Dir(mp), fileDir)
 
+	if err := cmd.useMermaid(p); err != nil {
+		return err
+	}
+
+	cmd.useRefHref(p)
+
+	switch cmd.Format {
+	case "epub":
+		return cmd.writeEPUB(ctx, p, fileName)
+	case "pdf":
+		return cmd.writePDF(ctx, p, fileName)
+	}
+
 	doc, err := p.ParseFile(fileName)
 	if err != nil {
 		return err
 	}
 
+	switch cmd.Format {
+	case "deps", "json-deps":
+		// dependencies are known after parsing,
+		// so there is no need to execute the document
+		return cmd.writeDeps(doc, fileDir)
+	}
+
 	if err := doc.Execute(ctx); err != nil {
 		return err
 	}
@@ -320,6 +376,12 @@ func (cmd *Export) execute(ctx context.Context, pwd string) error {
 	case "markdown":
 		fmt.Fprintln(cmd.Stdout(), doc.MD())
 	case "html":
+		if cmd.Images {
+			if err := cmd.writeImages(doc); err != nil {
+				return err
+			}
+		}
+
 		if cmd.NoCSS {
 			fmt.Fprintln(cmd.Stdout(), doc.String())
 			return nil
@@ -345,6 +407,169 @@ func (cmd *Export) execute(ctx context.Context, pwd string) error {
 	return nil
 }
 
+// useMermaid sets how the parser renders mermaid diagrams,
+// for the format. Only HTML, and EPUB, can show SVG.
+func (cmd *Export) useMermaid(p *hype.Parser) error {
+	render := cmd.Mermaid
+	if len(render) == 0 {
+		render = hype.MermaidSVG
+	}
+
+	if render != hype.MermaidASCII && render != hype.MermaidSVG {
+		return fmt.Errorf("invalid -mermaid %q: use %s, or %s", render, hype.MermaidASCII, hype.MermaidSVG)
+	}
+
+	switch cmd.Format {
+	case "html", "epub":
+		p.MermaidRender = render
+	}
+
+	return nil
+}
+
+// useRefHref sets the hrefs of refs to figures in other
+// documents of a book, for the format. HTML links to the
+// exported page of the other document, markdown to its
+// source, and EPUB, and PDF, link the book themselves.
+func (cmd *Export) useRefHref(p *hype.Parser) {
+	switch cmd.Format {
+	case "html":
+		p.RefHref = hype.RelativeRefHref(".html")
+	}
+}
+
+// writeDeps writes the dependency graph of the document,
+// with paths relative to the working directory.
+func (cmd *Export) writeDeps(doc *hype.Document, dir string) error {
+	g, err := doc.Deps()
+	if err != nil {
+		return err
+	}
+
+	if dir != "." && dir != "" {
+		dg := hype.NewDepGraph()
+		dg.Merge(dir, g)
+		g = dg
+	}
+
+	if cmd.Format == "deps" {
+		return g.WriteMakefile(cmd.Stdout())
+	}
+
+	b, err := json.MarshalIndent(g, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(cmd.Stdout(), string(b))
+
+	return nil
+}
+
+// writeImages sizes the local images of the document, and
+// writes them, and any resized copies, next to the output file.
+func (cmd *Export) writeImages(doc *hype.Document) error {
+	if !cmd.OutPath.Exists() {
+		return fmt.Errorf("-images requires an output file, -o")
+	}
+
+	widths, err := images.ParseWidths(cmd.ImageWidths)
+	if err != nil {
+		return err
+	}
+
+	ip := &images.Pipeline{
+		Widths: widths,
+	}
+
+	vs, err := ip.Process(doc)
+	if err != nil {
+		return err
+	}
+
+	return ip.Write(doc.FS, filepath.Dir(cmd.OutPath.Value()), vs)
+}
+
+// parseBook parses and executes the document, or if name is a
+// folder, the book in the folder. Each chapter of a book is a
+// folder containing a hype.md file. The returned title is the
+// name of the folder, if name is a folder.
+func (cmd *Export) parseBook(ctx context.Context, p *hype.Parser, name string) (hype.Documents, string, error) {
+	info, err := fs.Stat(p.FS, name)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if !info.IsDir() {
+		doc, err := p.ParseExecuteFile(ctx, name)
+		if err != nil {
+			return nil, "", err
+		}
+
+		return hype.Documents{doc}, "", nil
+	}
+
+	p, err = p.Sub(name)
+	if err != nil {
+		return nil, "", err
+	}
+
+	docs, err := p.ParseExecuteFolder(ctx, name)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return docs, flect.Titleize(filepath.Base(name)), nil
+}
+
+// writeEPUB writes the document, or book, as an EPUB 3 publication.
+func (cmd *Export) writeEPUB(ctx context.Context, p *hype.Parser, name string) error {
+	docs, title, err := cmd.parseBook(ctx, p, name)
+	if err != nil {
+		return err
+	}
+
+	book, err := epub.New(title, docs)
+	if err != nil {
+		return err
+	}
+
+	if !cmd.NoCSS {
+		css, err := cmd.css()
+		if err != nil {
+			return err
+		}
+		book.CSS = css
+	}
+
+	return book.Write(cmd.Stdout())
+}
+
+// writePDF writes the document, or book, as a PDF file.
+func (cmd *Export) writePDF(ctx context.Context, p *hype.Parser, name string) error {
+	docs, title, err := cmd.parseBook(ctx, p, name)
+	if err != nil {
+		return err
+	}
+
+	book, err := pdf.New(title, docs)
+	if err != nil {
+		return err
+	}
+
+	book.PageSize = cmd.PageSize
+
+	if err := book.Write(cmd.Stdout()); err != nil {
+		return err
+	}
+
+	for _, w := range book.Warnings {
+		fmt.Fprintf(cmd.Stderr(), "warning: %s\n", w)
+	}
+
+	return nil
+}
+
 func (cmd *Export) printThemes() error {
 	themeList := themes.ListThemes()
 	fmt.Fprintln(os.Stdout, "Available themes:")
@@ -358,20 +583,20 @@ func (cmd *Export) printThemes() error {
 	return nil
 }
 
-func (cmd *Export) renderStyledHTML(doc *hype.Document) error {
-	var css string
-	var err error
-
+// css returns the custom CSS file, if there is one,
+// or the CSS of the theme.
+func (cmd *Export) css() (string, error) {
 	if cmd.CustomCSS != "" {
-		css, err = themes.LoadCustomCSS(cmd.CustomCSS)
-		if err != nil {
-			return err
-		}
-	} else {
-		css, err = themes.GetCSS(cmd.Theme)
-		if err != nil {
-			return err
-		}
+		return themes.LoadCustomCSS(cmd.CustomCSS)
+	}
+
+	return themes.GetCSS(cmd.Theme)
+}
+
+func (cmd *Export) renderStyledHTML(doc *hype.Document) error {
+	css, err := cmd.css()
+	if err != nil {
+		return err
 	}
 
 	title := cmd.extractTitle(doc)
@@ -1,7 +1,9 @@
 package cli
 
 import (
+	"archive/zip"
 	"context"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
@@ -259,3 +261,191 @@ func Test_Export_HTML_TitleWithEntities(t *testing.T) {
 	r.Contains(output, "<title>Fish &amp; Chips</title>")
 	r.NotContains(output, "&amp;amp;")
 }
+
+func Test_Export_Deps(t *testing.T) {
+	r := require.New(t)
+
+	pwd, err := filepath.Abs("testdata/export/subdir")
+	r.NoError(err)
+
+	t.Setenv("MARKED_PATH", filepath.Join(pwd, "dummy.md"))
+
+	outFile := filepath.Join(t.TempDir(), "hype.d")
+
+	cmd := &Export{}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	err = cmd.Main(ctx, pwd, []string{"-f", ".hype/module.md", "-format", "deps", "-o", outFile})
+	r.NoError(err)
+
+	act, err := os.ReadFile(outFile)
+	r.NoError(err)
+
+	exp := ".hype/module.md: .hype/included.md\n\n.hype/included.md:\n"
+	r.Equal(exp, string(act))
+}
+
+func Test_Export_JSONDeps(t *testing.T) {
+	r := require.New(t)
+
+	pwd, err := filepath.Abs("testdata/export/subdir")
+	r.NoError(err)
+
+	t.Setenv("MARKED_PATH", filepath.Join(pwd, "dummy.md"))
+
+	outFile := filepath.Join(t.TempDir(), "deps.json")
+
+	cmd := &Export{}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	err = cmd.Main(ctx, pwd, []string{"-f", ".hype/module.md", "-format", "json-deps", "-o", outFile})
+	r.NoError(err)
+
+	act, err := os.ReadFile(outFile)
+	r.NoError(err)
+	r.JSONEq(`{".hype/included.md": [], ".hype/module.md": [".hype/included.md"]}`, string(act))
+}
+
+func Test_Export_EPUB(t *testing.T) {
+	r := require.New(t)
+
+	pwd, err := filepath.Abs("testdata/export/epub")
+	r.NoError(err)
+
+	t.Setenv("MARKED_PATH", filepath.Join(pwd, "dummy.md"))
+
+	outFile := filepath.Join(t.TempDir(), "book.epub")
+
+	cmd := &Export{}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	err = cmd.Main(ctx, pwd, []string{"-f", "book", "-format", "epub", "-o", outFile})
+	r.NoError(err)
+
+	zr, err := zip.OpenReader(outFile)
+	r.NoError(err)
+	defer zr.Close()
+
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+
+	r.Equal("mimetype", names[0])
+	r.Contains(names, "OEBPS/chapter-01.xhtml")
+	r.Contains(names, "OEBPS/chapter-02.xhtml")
+	r.Contains(names, "OEBPS/images/02/gopher.svg")
+	r.Contains(names, "OEBPS/style.css")
+
+	opf, err := fs.ReadFile(zr, "OEBPS/content.opf")
+	r.NoError(err)
+	r.Contains(string(opf), "<dc:title>The Gopher Book</dc:title>")
+}
+
+func Test_Export_PDF(t *testing.T) {
+	r := require.New(t)
+
+	pwd, err := filepath.Abs("testdata/export/epub")
+	r.NoError(err)
+
+	t.Setenv("MARKED_PATH", filepath.Join(pwd, "dummy.md"))
+
+	outFile := filepath.Join(t.TempDir(), "book.pdf")
+
+	cmd := &Export{}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	err = cmd.Main(ctx, pwd, []string{"-f", "book", "-format", "pdf", "-page-size", "A4", "-o", outFile})
+	r.NoError(err)
+
+	act, err := os.ReadFile(outFile)
+	r.NoError(err)
+
+	r.True(strings.HasPrefix(string(act), "%PDF-"))
+	r.Contains(string(act), "/MediaBox [0 0 595.28 841.89]")
+	r.Contains(string(act), "/Title (Getting Started)")
+}
+
+func Test_Export_HTML_Images(t *testing.T) {
+	r := require.New(t)
+
+	pwd, err := filepath.Abs("testdata/export/images")
+	r.NoError(err)
+
+	t.Setenv("MARKED_PATH", filepath.Join(pwd, "dummy.md"))
+
+	dir := t.TempDir()
+	outFile := filepath.Join(dir, "index.html")
+
+	cmd := &Export{}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	err = cmd.Main(ctx, pwd, []string{"-f", "test.md", "-format", "html", "-no-css", "-images", "-image-widths", "16", "-o", outFile})
+	r.NoError(err)
+
+	act, err := os.ReadFile(outFile)
+	r.NoError(err)
+
+	output := string(act)
+	r.Contains(output, `width="64"`)
+	r.Contains(output, `srcset="assets/gopher-32w.png 32w, assets/gopher.png 64w"`)
+	r.NotContains(output, "widths=")
+
+	r.FileExists(filepath.Join(dir, "assets", "gopher.png"))
+	r.FileExists(filepath.Join(dir, "assets", "gopher-32w.png"))
+
+	// without an output file, there is nowhere to write the images
+	cmd = &Export{}
+	err = cmd.Main(ctx, pwd, []string{"-f", "test.md", "-format", "html", "-images"})
+	r.Error(err)
+}
+
+func Test_Export_HTML_Mermaid(t *testing.T) {
+	r := require.New(t)
+
+	pwd, err := filepath.Abs("testdata/export/mermaid")
+	r.NoError(err)
+
+	t.Setenv("MARKED_PATH", filepath.Join(pwd, "dummy.md"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	export := func(args ...string) string {
+		outFile := filepath.Join(t.TempDir(), "index.html")
+
+		cmd := &Export{}
+		err := cmd.Main(ctx, pwd, append([]string{"-f", "test.md", "-no-css", "-o", outFile}, args...))
+		r.NoError(err)
+
+		act, err := os.ReadFile(outFile)
+		r.NoError(err)
+
+		return string(act)
+	}
+
+	act := export("-format", "html")
+	r.Contains(act, `<svg xmlns="http://www.w3.org/2000/svg"`)
+	r.Contains(act, `dy="0.35em">Start</tspan>`)
+
+	act = export("-format", "html", "-mermaid", "ascii")
+	r.NotContains(act, "<svg")
+	r.Contains(act, "Start")
+
+	act = export("-format", "markdown")
+	r.NotContains(act, "<svg")
+
+	cmd := &Export{}
+	err = cmd.Main(ctx, pwd, []string{"-f", "test.md", "-format", "html", "-mermaid", "png"})
+	r.Error(err)
+}
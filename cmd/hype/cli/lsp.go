@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gopherguides/hype"
+	"github.com/gopherguides/hype/lsp"
+	"github.com/markbates/cleo"
+)
+
+// LSP runs a Language Server Protocol server for
+// hype documents, speaking JSON-RPC over stdio.
+type LSP struct {
+	cleo.Cmd
+
+	Timeout time.Duration // how long a <cmd> may run for a hover preview
+
+	NoCache  bool   // disable the execution cache
+	CacheDir string // execution cache directory; default: hype.DefaultExecCacheDir()
+	Runner   string // run every <cmd> with this runner only; default: all runners available
+
+	flags *flag.FlagSet
+	mu    sync.RWMutex
+}
+
+func (cmd *LSP) Flags(stderr io.Writer) (*flag.FlagSet, error) {
+	usage := `
+Usage: hype lsp [options]
+
+Starts a language server on stdin and stdout. Configure your
+editor to run it for Markdown files.
+
+Examples:
+	hype lsp
+	hype lsp -timeout=5s
+	hype lsp -runner=restricted
+`
+
+	if cmd == nil {
+		return nil, fmt.Errorf("lsp is nil")
+	}
+
+	cmd.mu.Lock()
+	defer cmd.mu.Unlock()
+
+	if cmd.flags != nil {
+		return cmd.flags, nil
+	}
+
+	cmd.flags = flag.NewFlagSet("lsp", flag.ContinueOnError)
+	cmd.flags.SetOutput(stderr)
+	cmd.flags.DurationVar(&cmd.Timeout, "timeout", lsp.DefaultHoverTimeout, "timeout for running a <cmd> to preview its output on hover")
+	cmd.flags.BoolVar(&cmd.NoCache, "no-cache", false, "disable the cache of <cmd> and <go> results")
+	cmd.flags.StringVar(&cmd.CacheDir, "cache-dir", "", "directory for the cache of <cmd> and <go> results; defaults to the user cache directory")
+	cmd.flags.StringVar(&cmd.Runner, "runner", "", "run every <cmd> with the named runner (host, restricted); tags requesting any other runner fail")
+
+	cmd.flags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage of %s:\n", os.Args[0])
+		cmd.flags.PrintDefaults()
+		fmt.Fprintln(stderr, usage)
+	}
+
+	return cmd.flags, nil
+}
+
+func (cmd *LSP) Main(ctx context.Context, pwd string, args []string) error {
+	if cmd == nil {
+		return fmt.Errorf("lsp is nil")
+	}
+
+	if err := (&cmd.Cmd).Init(); err != nil {
+		return err
+	}
+
+	flags, err := cmd.Flags(cmd.Stderr())
+	if err != nil {
+		return err
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	// fail before the editor connects
+	if err := useRunner(hype.NewParser(nil), cmd.Runner); err != nil {
+		return err
+	}
+
+	s := &lsp.Server{
+		HoverTimeout: cmd.Timeout,
+		NewParser: func(dir string) *hype.Parser {
+			p := hype.NewParser(os.DirFS(dir))
+			p.ExecCache = newExecCache(cmd.NoCache, cmd.CacheDir)
+
+			// the runner was checked above
+			_ = useRunner(p, cmd.Runner)
+
+			return p
+		},
+	}
+
+	return s.Serve(ctx, cmd.Stdin(), cmd.Stdout())
+}
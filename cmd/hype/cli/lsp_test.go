@@ -0,0 +1,42 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_LSP_Main(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	in := &bytes.Buffer{}
+	for _, msg := range []string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"shutdown"}`,
+		`{"jsonrpc":"2.0","method":"exit"}`,
+	} {
+		fmt.Fprintf(in, "Content-Length: %d\r\n\r\n%s", len(msg), msg)
+	}
+
+	out := &bytes.Buffer{}
+
+	cmd := &LSP{}
+	cmd.In = in
+	cmd.Out = out
+
+	r.NoError(cmd.Main(context.Background(), ".", []string{"-no-cache"}))
+
+	act := out.String()
+	r.Contains(act, `"id":1,"result":{"capabilities"`)
+	r.Contains(act, `"id":2,"result":null`)
+
+	cmd = &LSP{}
+	err := cmd.Main(context.Background(), ".", []string{"-runner", "nope"})
+	r.Error(err)
+	r.True(strings.Contains(err.Error(), "unknown runner"))
+}
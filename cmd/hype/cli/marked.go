@@ -204,6 +204,10 @@ func (cmd *Marked) execute(ctx context.Context, pwd string) error {
 	p.Root = filepath.Dir(mp)
 	p.Filename = filepath.Base(mp)
 
+	// Marked shows HTML, so refs to the other
+	// documents of a book link to their pages
+	p.RefHref = hype.RelativeRefHref(".html")
+
 	if len(cmd.File) > 0 {
 		f, err := cmd.FS.Open(cmd.File)
 		if err != nil {
@@ -103,6 +103,8 @@ func (cmd *Preview) Flags(stderr io.Writer) (*flag.FlagSet, error) {
 Usage: hype preview [options]
 
 Starts a live preview server with file watching and auto-reload.
+If the file is a directory, every hype.md in it is previewed,
+each at its own path, with navigation between them.
 
 Available themes: ` + strings.Join(themes.ListThemes(), ", ") + `
 
@@ -114,6 +116,7 @@ Examples:
     hype preview -f hype.md -e md,html,go,png,jpg
     hype preview -f hype.md -i "**/*.md" -i "**/*.go"
     hype preview -f hype.md -x "**/vendor/**" -x "**/tmp/**"
+    hype preview -f ./book
     hype preview -themes
 `
 
@@ -131,7 +134,7 @@ Examples:
 	cmd.flags = flag.NewFlagSet("preview", flag.ContinueOnError)
 	cmd.flags.SetOutput(stderr)
 
-	cmd.flags.StringVar(&cmd.File, "f", "hype.md", "markdown file to preview")
+	cmd.flags.StringVar(&cmd.File, "f", "hype.md", "markdown file to preview, or a directory to preview every hype.md in")
 	cmd.flags.IntVar(&cmd.Port, "port", 3000, "port for the preview server")
 	cmd.flags.Var(&cmd.WatchDirs, "w", "directories to watch (repeatable)")
 	cmd.flags.Var(&cmd.WatchDirs, "watch", "directories to watch (repeatable)")
@@ -0,0 +1,33 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/gopherguides/hype"
+)
+
+// useRunner limits the parser to the named runner, making
+// it the default for every <cmd>. Documents that request a
+// different runner will fail to execute and validate.
+// If name is empty, the parser is left unchanged.
+func useRunner(p *hype.Parser, name string) error {
+	if p == nil {
+		return fmt.Errorf("parser is nil")
+	}
+
+	if len(name) == 0 {
+		return nil
+	}
+
+	r, ok := hype.DefaultRunners()[name]
+	if !ok {
+		return fmt.Errorf("unknown runner: %q", name)
+	}
+
+	p.Runners = map[string]hype.Runner{
+		name: r,
+	}
+	p.DefaultRunner = name
+
+	return nil
+}
@@ -0,0 +1,26 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/gopherguides/hype"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_useRunner(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	p := hype.NewParser(nil)
+	r.NoError(useRunner(p, ""))
+	r.Len(p.Runners, 2)
+
+	r.NoError(useRunner(p, hype.RestrictedRunnerName))
+	r.Len(p.Runners, 1)
+	r.Equal(hype.RestrictedRunnerName, p.DefaultRunner)
+
+	_, err := p.Runner(hype.HostRunnerName)
+	r.Error(err)
+
+	r.Error(useRunner(p, "docker"))
+}
@@ -1,6 +1,7 @@
 package cli
 
 import (
+	"bytes"
 	"context"
 	"flag"
 	"fmt"
@@ -10,9 +11,11 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"sync"
 
 	"github.com/gopherguides/hype"
+	"github.com/gopherguides/hype/preview"
 	"github.com/gopherguides/hype/slides"
 	"github.com/markbates/cleo"
 	"github.com/markbates/fsx"
@@ -38,7 +41,17 @@ type Slides struct {
 	// Port to listen on. Defaults to 3000.
 	Port int
 
-	mu sync.RWMutex
+	// ExportPath is where the slides are exported to, as a
+	// single HTML file, instead of being served. If it is not
+	// an .html file, it is a directory to write index.html to.
+	ExportPath string
+
+	// ThemeDir is the directory of a theme, with a
+	// slides.html template, to use instead of the default.
+	ThemeDir string
+
+	flags *flag.FlagSet
+	mu    sync.RWMutex
 }
 
 // snippet: main
@@ -48,8 +61,12 @@ func (a *Slides) Main(ctx context.Context, pwd string, args []string) error {
 		return fmt.Errorf("nil app")
 	}
 
-	flags := a.flags()
-	err := flags.Parse(args)
+	flags, err := a.Flags(a.Stderr())
+	if err != nil {
+		return err
+	}
+
+	err = flags.Parse(args)
 	if err != nil {
 		return err
 	}
@@ -68,6 +85,23 @@ func (a *Slides) Main(ctx context.Context, pwd string, args []string) error {
 	if err != nil {
 		return err
 	}
+
+	if len(a.ThemeDir) > 0 {
+		dir := a.ThemeDir
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(pwd, dir)
+		}
+		a.App.Theme = os.DirFS(dir)
+	}
+
+	if len(a.ExportPath) > 0 {
+		return a.export(ctx, pwd)
+	}
+
+	if a.App.Sync == nil {
+		a.App.Sync = preview.NewLiveReload()
+	}
+
 	return WithinDir(a.App.PWD, func() error {
 		srv, err := a.server()
 		if err != nil {
@@ -113,6 +147,42 @@ func (a *Slides) Main(ctx context.Context, pwd string, args []string) error {
 
 // snippet: main
 
+// export writes the slides, as a single HTML file,
+// to the ExportPath.
+func (a *Slides) export(ctx context.Context, pwd string) error {
+	fp := a.ExportPath
+	if !filepath.IsAbs(fp) {
+		fp = filepath.Join(pwd, fp)
+	}
+
+	switch strings.ToLower(filepath.Ext(fp)) {
+	case ".html", ".htm":
+	default:
+		fp = filepath.Join(fp, "index.html")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(fp), 0755); err != nil {
+		return err
+	}
+
+	bb := &bytes.Buffer{}
+
+	err := WithinDir(a.App.PWD, func() error {
+		return a.App.Export(ctx, bb)
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(fp, bb.Bytes(), 0644); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(a.Stdout(), "exported slides to %s\n", fp)
+
+	return nil
+}
+
 func (a *Slides) SetIO(oi iox.IO) {
 	if a == nil {
 		return
@@ -133,7 +203,11 @@ func (a *Slides) Print(w io.Writer) error {
 		w = os.Stdout
 	}
 
-	flags := a.flags()
+	flags, err := a.Flags(w)
+	if err != nil {
+		return err
+	}
+
 	flags.SetOutput(w)
 	flags.Usage()
 
@@ -152,14 +226,25 @@ func (a *Slides) Getenv(key string) (s string) {
 	return a.Env.Getenv(key)
 }
 
-func (a *Slides) flags() *flag.FlagSet {
+func (a *Slides) Flags(stderr io.Writer) (*flag.FlagSet, error) {
+	if a == nil {
+		return nil, fmt.Errorf("nil app")
+	}
 
-	flags := flag.NewFlagSet("server", flag.ContinueOnError)
+	a.mu.Lock()
+	defer a.mu.Unlock()
 
-	flags.SetOutput(a.Stderr())
-	flags.IntVar(&a.Port, "port", 3000, "port to listen on")
+	if a.flags != nil {
+		return a.flags, nil
+	}
 
-	return flags
+	a.flags = flag.NewFlagSet("server", flag.ContinueOnError)
+	a.flags.SetOutput(stderr)
+	a.flags.IntVar(&a.Port, "port", 3000, "port to listen on")
+	a.flags.StringVar(&a.ExportPath, "export", "", "export the slides, with every asset embedded, to an .html file, or to index.html in a directory, instead of serving them")
+	a.flags.StringVar(&a.ThemeDir, "theme", "", "directory of a theme, with a slides.html template")
+
+	return a.flags, nil
 }
 
 func (a *Slides) server() (*http.Server, error) {
@@ -176,6 +261,9 @@ func (a *Slides) server() (*http.Server, error) {
 	mux.Handle("/", a)
 
 	cab := &fsx.ArrayFS{}
+	if a.App.Theme != nil {
+		cab.Append(a.App.Theme)
+	}
 	cab.Append(slides.AssetsFS)
 	cab.Append(a.Parser.FS)
 
@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/markbates/iox"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Slides_Export(t *testing.T) {
+	r := require.New(t)
+
+	dir := t.TempDir()
+	r.NoError(os.WriteFile(filepath.Join(dir, "hype.md"), []byte("<page>\n\n# Hello\n\n</page>\n"), 0644))
+
+	theme := filepath.Join(dir, "theme")
+	r.NoError(os.MkdirAll(theme, 0755))
+	r.NoError(os.WriteFile(filepath.Join(theme, "slides.html"), []byte(`<html><head><title>{{ .title }}</title></head><body class="gopher">{{ .body }}</body></html>`), 0644))
+
+	table := []struct {
+		name string
+		args []string
+		exp  string
+	}{
+		{name: "directory", args: []string{"-export", "out"}, exp: filepath.Join(dir, "out", "index.html")},
+		{name: "file", args: []string{"-export", "talk.html", "-theme", "theme"}, exp: filepath.Join(dir, "talk.html")},
+	}
+
+	for _, tt := range table {
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+
+			bb := iox.Buffer{}
+
+			cmd := &Slides{}
+			cmd.IO = bb.IO()
+
+			r.NoError(cmd.Main(context.Background(), dir, tt.args))
+			r.Contains(bb.Out.String(), tt.exp)
+
+			b, err := os.ReadFile(tt.exp)
+			r.NoError(err)
+
+			act := string(b)
+			r.Contains(act, "<title>Hello</title>")
+			r.Contains(act, `<page id="page-0">`)
+
+			if len(cmd.ThemeDir) > 0 {
+				r.Contains(act, `<body class="gopher">`)
+				return
+			}
+
+			r.Contains(act, "@media print")
+		})
+	}
+}
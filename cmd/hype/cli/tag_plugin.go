@@ -0,0 +1,58 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/gopherguides/hype"
+)
+
+// useTagPlugins registers the tags of the plugin executables
+// at paths, such as those in hype.yaml, and of the
+// hype-plugin-<name> executables in the directories of
+// $HYPE_PLUGIN_PATH, with the parser. Nothing is searched
+// for, or run, unless it is configured.
+func useTagPlugins(ctx context.Context, p *hype.Parser, paths ...string) error {
+	if p == nil {
+		return fmt.Errorf("parser is nil")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	var plugs []*hype.TagPlugin
+	for _, fp := range paths {
+		pl, err := hype.NewTagPlugin(ctx, fp)
+		if err != nil {
+			return err
+		}
+		plugs = append(plugs, pl)
+	}
+
+	dirs := filepath.SplitList(os.Getenv(hype.TagPluginPathEnv))
+
+	found, err := hype.FindTagPlugins(ctx, dirs...)
+	if err != nil {
+		return err
+	}
+
+	// a plugin in hype.yaml takes the place of
+	// one of the same name in $HYPE_PLUGIN_PATH
+	for _, pl := range found {
+		if !slices.ContainsFunc(plugs, func(x *hype.TagPlugin) bool { return x.Name == pl.Name }) {
+			plugs = append(plugs, pl)
+		}
+	}
+
+	for _, pl := range plugs {
+		if err := p.UsePlugin(pl); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,46 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/gopherguides/hype"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_useTagPlugins(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugin scripts need a POSIX shell")
+	}
+
+	r := require.New(t)
+
+	dir := t.TempDir()
+
+	script := "#!/bin/sh\ncat > /dev/null\necho '{\"tags\":[\"exercise\"]}'\n"
+	r.NoError(os.WriteFile(filepath.Join(dir, hype.TagPluginPrefix+"course"), []byte(script), 0755))
+
+	// plugins on $PATH are not run
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv(hype.TagPluginPathEnv, "")
+
+	p := hype.NewParser(nil)
+	r.NoError(useTagPlugins(context.Background(), p))
+
+	_, ok := p.NodeParsers[hype.Atom("exercise")]
+	r.False(ok)
+
+	// unless they are in $HYPE_PLUGIN_PATH
+	t.Setenv(hype.TagPluginPathEnv, dir)
+
+	p = hype.NewParser(nil)
+	r.NoError(useTagPlugins(context.Background(), p))
+
+	_, ok = p.NodeParsers[hype.Atom("exercise")]
+	r.True(ok)
+
+	r.Error(useTagPlugins(context.Background(), nil))
+}
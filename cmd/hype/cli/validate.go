@@ -25,8 +25,13 @@ type Validate struct {
 	Parser  *hype.Parser
 	Verbose bool
 	Exec    bool
+	Update  bool // rewrite the golden files of <cmd expect="..."> tags; implies Exec
 	Format  string
 
+	NoCache  bool   // disable the execution cache
+	CacheDir string // execution cache directory; default: hype.DefaultExecCacheDir()
+	Runner   string // run every <cmd> with this runner only; default: all runners available
+
 	flags *flag.FlagSet
 	mu    sync.RWMutex
 }
@@ -52,6 +57,10 @@ Examples:
 	hype validate -f document.md --exec
 	hype validate -f document.md -v
 	hype validate -f document.md --format=json
+	hype validate -f document.md --exec --no-cache
+	hype validate -f document.md --exec -runner=restricted
+	hype validate -f document.md --update
+	hype validate -f book
 `
 
 	if err := cmd.validate(); err != nil {
@@ -67,11 +76,15 @@ Examples:
 
 	cmd.flags = flag.NewFlagSet("validate", flag.ContinueOnError)
 	cmd.flags.SetOutput(stderr)
-	cmd.flags.StringVar(&cmd.File, "f", "hype.md", "file to validate")
+	cmd.flags.StringVar(&cmd.File, "f", "hype.md", "file to validate, or a folder to validate as a book")
 	cmd.flags.DurationVar(&cmd.Timeout, "timeout", DefaultTimeout, "timeout for execution, defaults to 30 seconds (30s)")
 	cmd.flags.BoolVar(&cmd.Verbose, "v", false, "enable verbose output")
 	cmd.flags.BoolVar(&cmd.Exec, "exec", false, "also validate code execution")
+	cmd.flags.BoolVar(&cmd.Update, "update", false, "rewrite the golden files of <cmd expect=\"...\"> tags with the output of the commands; implies -exec")
 	cmd.flags.StringVar(&cmd.Format, "format", "text", "output format: text, json")
+	cmd.flags.BoolVar(&cmd.NoCache, "no-cache", false, "disable the cache of <cmd> and <go> results")
+	cmd.flags.StringVar(&cmd.CacheDir, "cache-dir", "", "directory for the cache of <cmd> and <go> results; defaults to the user cache directory")
+	cmd.flags.StringVar(&cmd.Runner, "runner", "", "run every <cmd> with the named runner (host, restricted); tags requesting any other runner fail")
 
 	cmd.flags.Usage = func() {
 		fmt.Fprintf(stderr, "Usage of %s:\n", os.Args[0])
@@ -133,9 +146,21 @@ func (cmd *Validate) execute(ctx context.Context, pwd string) error {
 	}
 
 	slog.Debug("validate", "pwd", pwd, "file", cmd.File)
+
+	// a folder is a book, with a
+	// chapter in each sub-folder
+	book := false
+	if info, err := fs.Stat(cmd.FS, cmd.File); err == nil && info.IsDir() {
+		book = true
+	}
+
 	fileDir := filepath.Dir(cmd.File)
 	fileName := filepath.Base(cmd.File)
 
+	if book {
+		fileDir, fileName = cmd.File, "."
+	}
+
 	parserFS := cmd.FS
 	if fileDir != "." && fileDir != "" {
 		subFS, err := fs.Sub(cmd.FS, fileDir)
@@ -153,13 +178,33 @@ func (cmd *Validate) execute(ctx context.Context, pwd string) error {
 	}
 
 	p.Root = filepath.Join(pwd, fileDir)
+	p.ExecCache = newExecCache(cmd.NoCache, cmd.CacheDir)
 
-	doc, err := p.ParseFile(fileName)
-	if err != nil {
-		return fmt.Errorf("parse error: %w", err)
+	if err := useRunner(p, cmd.Runner); err != nil {
+		return err
+	}
+
+	opts := hype.ValidateOptions{
+		Exec:   cmd.Exec || cmd.Update,
+		Update: cmd.Update,
 	}
 
-	result := hype.Validate(ctx, doc, hype.ValidateOptions{Exec: cmd.Exec})
+	var result *hype.ValidationResult
+	if book {
+		docs, err := p.ParseFolder(fileName)
+		if err != nil {
+			return fmt.Errorf("parse error: %w", err)
+		}
+
+		result = hype.ValidateBook(ctx, docs, opts)
+	} else {
+		doc, err := p.ParseFile(fileName)
+		if err != nil {
+			return fmt.Errorf("parse error: %w", err)
+		}
+
+		result = hype.Validate(ctx, doc, opts)
+	}
 
 	out := cmd.Stdout()
 
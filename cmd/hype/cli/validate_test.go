@@ -1,7 +1,9 @@
 package cli
 
 import (
+	"bytes"
 	"context"
+	"os"
 	"path/filepath"
 	"testing"
 	"time"
@@ -52,3 +54,55 @@ func Test_Validate_Main_JSONFormat(t *testing.T) {
 	r.Error(err)
 	r.Contains(err.Error(), "validation failed")
 }
+
+func Test_Validate_Main_Book(t *testing.T) {
+	r := require.New(t)
+
+	pwd, err := filepath.Abs("testdata/validate")
+	r.NoError(err)
+
+	cmd := &Validate{}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	// refs resolve to figures in other chapters
+	err = cmd.Main(ctx, pwd, []string{"-f", "book"})
+	r.NoError(err)
+
+	// but not when a chapter is validated on its own
+	cmd = &Validate{}
+	err = cmd.Main(ctx, pwd, []string{"-f", "book/01-intro/hype.md"})
+	r.Error(err)
+}
+
+func Test_Validate_Main_Update(t *testing.T) {
+	r := require.New(t)
+
+	pwd := t.TempDir()
+	r.NoError(os.CopyFS(pwd, os.DirFS("testdata/validate/expect")))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	// the golden file has drifted, but is only
+	// compared when the commands are executed
+	cmd := &Validate{}
+	err := cmd.Main(ctx, pwd, []string{"-f", "module.md"})
+	r.NoError(err)
+
+	bb := &bytes.Buffer{}
+	cmd = &Validate{}
+	cmd.Out = bb
+	err = cmd.Main(ctx, pwd, []string{"-f", "module.md", "-exec", "-no-cache"})
+	r.Error(err)
+	r.Contains(bb.String(), "ERROR expect: output does not match golden/hello.txt")
+	r.Contains(bb.String(), "-goodbye\n+hello")
+
+	cmd = &Validate{}
+	err = cmd.Main(ctx, pwd, []string{"-f", "module.md", "-update", "-no-cache"})
+	r.NoError(err)
+
+	b, err := os.ReadFile(filepath.Join(pwd, "golden", "hello.txt"))
+	r.NoError(err)
+	r.Equal("hello\n", string(b))
+}
@@ -12,6 +12,7 @@ import (
 type CmdError struct {
 	clam.RunError
 	Filename string
+	Pos      Pos // position of the <cmd> tag, if known
 }
 
 func (ce CmdError) MarshalJSON() ([]byte, error) {
@@ -25,10 +26,18 @@ func (ce CmdError) MarshalJSON() ([]byte, error) {
 		"type":     toType(ce),
 	}
 
+	if ce.Pos.IsValid() {
+		mm["pos"] = ce.Pos
+	}
+
 	return json.MarshalIndent(mm, "", "  ")
 }
 
 func (ce CmdError) Error() string {
+	if ce.Pos.IsValid() {
+		return diagnostic(ce.Pos, nil, ce.message())
+	}
+
 	sb := &strings.Builder{}
 
 	var lines []string
@@ -57,6 +66,25 @@ func (ce CmdError) Error() string {
 	return strings.TrimSpace(s)
 }
 
+// Position returns the position of the <cmd> tag.
+func (ce CmdError) Position() Pos {
+	return ce.Pos
+}
+
+func (ce CmdError) message() string {
+	lines := []string{fmt.Sprintf("cmd error: %s", ce.Err)}
+
+	if len(ce.Args) > 0 {
+		lines = append(lines, fmt.Sprintf("cmd: $ %s", strings.Join(ce.Args, " ")))
+	}
+
+	if ce.Exit != 0 {
+		lines = append(lines, fmt.Sprintf("exit: %d", ce.Exit))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
 func (ce CmdError) As(target any) bool {
 	ex, ok := target.(*CmdError)
 	if !ok {
@@ -82,4 +82,9 @@ func Test_CmdError_Error(t *testing.T) {
 	exp = strings.TrimSpace(exp)
 
 	r.Equal(exp, act)
+
+	ce.Pos = Pos{File: "/tmp/hype.md", Line: 7, Col: 1}
+
+	exp = "/tmp/hype.md:7:1: cmd error: EOF\ncmd: $ echo hello\nexit: 1"
+	r.Equal(exp, ce.Error())
 }
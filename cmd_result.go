@@ -198,19 +198,7 @@ func NewCmdResult(p *Parser, c *Cmd, res *clam.Result) (*CmdResult, error) {
 }
 
 func resultBody(res *clam.Result, ats *Attributes, body string) (string, error) {
-	body = strings.TrimSpace(body)
-
-	if len(res.Dir) > 0 {
-		body = strings.ReplaceAll(body, res.Dir, ".")
-	}
-
-	if pwd, err := os.Getwd(); err == nil {
-		fp := fmt.Sprintf("%s%s", pwd, string(filepath.Separator))
-		body = strings.ReplaceAll(body, fp, "")
-	}
-
-	var err error
-	body, err = applyReplacements(ats, body)
+	body, err := cleanBody(res, ats, body)
 	if err != nil {
 		return "", err
 	}
@@ -239,6 +227,42 @@ func resultBody(res *clam.Result, ats *Attributes, body string) (string, error)
 	return strings.Join(lines, "\n"), nil
 }
 
+// cmdOutput returns the stdout, and stderr, of the command,
+// as they are shown in the document, without the command
+// line, escaping, or truncation. It is the output compared
+// with the `expect` attribute of the command.
+func cmdOutput(res *clam.Result, ats *Attributes) (string, error) {
+	var lines []string
+
+	if s := strings.TrimSpace(string(res.Stdout)); len(s) > 0 {
+		lines = append(lines, s)
+	}
+
+	if s := strings.TrimSpace(string(res.Stderr)); len(s) > 0 {
+		lines = append(lines, s)
+	}
+
+	return cleanBody(res, ats, strings.Join(lines, "\n\n"))
+}
+
+// cleanBody removes the directories of the command, and of
+// the process, from the body, and applies the replace-N
+// attributes.
+func cleanBody(res *clam.Result, ats *Attributes, body string) (string, error) {
+	body = strings.TrimSpace(body)
+
+	if len(res.Dir) > 0 {
+		body = strings.ReplaceAll(body, res.Dir, ".")
+	}
+
+	if pwd, err := os.Getwd(); err == nil {
+		fp := fmt.Sprintf("%s%s", pwd, string(filepath.Separator))
+		body = strings.ReplaceAll(body, fp, "")
+	}
+
+	return applyReplacements(ats, body)
+}
+
 // applyReplacements applies regex-based replacements to the output body.
 // It looks for numbered attribute pairs: replace-N="pattern" and replace-N-with="replacement"
 // Replacements are applied in numeric order (1, 2, 3, ...).
@@ -20,6 +20,13 @@ func NewCodeNodes(p *Parser, el *Element) (Nodes, error) {
 		return NewMermaidNodes(p, el)
 	}
 
+	// fenced code blocks of diagram languages, such as "```dot"
+	if len(lang) > 0 {
+		if _, err := p.DiagramRenderer(lang); err == nil {
+			return NewDiagramNodes(p, el)
+		}
+	}
+
 	// No attributes: check if single-line (inline) or multi-line (fenced)
 	// Per CommonMark spec, fenced code blocks are block-level elements
 	if ats.Len() == 0 {
@@ -0,0 +1,413 @@
+package hype
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DepGraph is a graph of the files a document depends on.
+// Each file points at the files it directly references,
+// for example, a document points at the markdown files it
+// includes, and an included file points at the source files,
+// images, and command directories it references.
+//
+// All paths are slash separated and relative to the
+// root of the document's file system.
+type DepGraph struct {
+	edges map[string]map[string]struct{}
+	mu    sync.RWMutex
+}
+
+// NewDepGraph returns an empty DepGraph.
+func NewDepGraph() *DepGraph {
+	return &DepGraph{
+		edges: map[string]map[string]struct{}{},
+	}
+}
+
+// Add records that from depends on each of the given files.
+func (g *DepGraph) Add(from string, to ...string) {
+	if g == nil {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.edges == nil {
+		g.edges = map[string]map[string]struct{}{}
+	}
+
+	from = cleanDepPath(from)
+
+	if _, ok := g.edges[from]; !ok {
+		g.edges[from] = map[string]struct{}{}
+	}
+
+	for _, t := range to {
+		t = cleanDepPath(t)
+		if t == from {
+			continue
+		}
+
+		g.edges[from][t] = struct{}{}
+
+		if _, ok := g.edges[t]; !ok {
+			g.edges[t] = map[string]struct{}{}
+		}
+	}
+}
+
+// Files returns every file in the graph, sorted.
+func (g *DepGraph) Files() []string {
+	if g == nil {
+		return nil
+	}
+
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	files := make([]string, 0, len(g.edges))
+	for k := range g.edges {
+		files = append(files, k)
+	}
+
+	sort.Strings(files)
+
+	return files
+}
+
+// Deps returns the files that name directly depends on, sorted.
+func (g *DepGraph) Deps(name string) []string {
+	if g == nil {
+		return nil
+	}
+
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	return sortedKeys(g.edges[cleanDepPath(name)])
+}
+
+// AllDeps returns every file that name directly,
+// or transitively, depends on, sorted.
+func (g *DepGraph) AllDeps(name string) []string {
+	if g == nil {
+		return nil
+	}
+
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	seen := map[string]struct{}{}
+
+	var walk func(n string)
+	walk = func(n string) {
+		for d := range g.edges[n] {
+			if _, ok := seen[d]; ok {
+				continue
+			}
+			seen[d] = struct{}{}
+			walk(d)
+		}
+	}
+
+	walk(cleanDepPath(name))
+
+	return sortedKeys(seen)
+}
+
+// Affected returns every file in the graph that needs to
+// be rebuilt when the given files change. This includes
+// the changed files themselves, if they are in the graph,
+// and every file that directly, or transitively, depends
+// on them.
+func (g *DepGraph) Affected(changed ...string) []string {
+	if g == nil {
+		return nil
+	}
+
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	rev := map[string][]string{}
+	for from, tos := range g.edges {
+		for to := range tos {
+			rev[to] = append(rev[to], from)
+		}
+	}
+
+	seen := map[string]struct{}{}
+
+	var walk func(n string)
+	walk = func(n string) {
+		if _, ok := seen[n]; ok {
+			return
+		}
+		seen[n] = struct{}{}
+		for _, from := range rev[n] {
+			walk(from)
+		}
+	}
+
+	for _, c := range changed {
+		c = cleanDepPath(c)
+		if _, ok := g.edges[c]; !ok {
+			continue
+		}
+		walk(c)
+	}
+
+	return sortedKeys(seen)
+}
+
+// DependsOn returns true if name is, or directly or
+// transitively depends on, any of the given files.
+func (g *DepGraph) DependsOn(name string, files ...string) bool {
+	name = cleanDepPath(name)
+
+	for _, a := range g.Affected(files...) {
+		if a == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Merge adds all of the edges of other to the graph.
+// The paths in other are prefixed with dir.
+func (g *DepGraph) Merge(dir string, other *DepGraph) {
+	if g == nil || other == nil {
+		return
+	}
+
+	for _, from := range other.Files() {
+		deps := other.Deps(from)
+		for i, d := range deps {
+			deps[i] = path.Join(filepath.ToSlash(dir), d)
+		}
+		g.Add(path.Join(filepath.ToSlash(dir), from), deps...)
+	}
+}
+
+// MarshalJSON returns the graph as a JSON object mapping
+// each file to the files it directly depends on.
+func (g *DepGraph) MarshalJSON() ([]byte, error) {
+	if g == nil {
+		return nil, ErrIsNil("dep graph")
+	}
+
+	m := map[string][]string{}
+	for _, f := range g.Files() {
+		m[f] = g.Deps(f)
+	}
+
+	return json.MarshalIndent(m, "", "  ")
+}
+
+// WriteMakefile writes the graph as Makefile rules,
+// one rule for each file with dependencies, followed by
+// an empty rule for each leaf file so that make does not
+// fail when a file is removed.
+//
+//	hype.md: includes.md
+//	includes.md: src/main.go
+//	src/main.go:
+func (g *DepGraph) WriteMakefile(w io.Writer) error {
+	if g == nil {
+		return ErrIsNil("dep graph")
+	}
+
+	files := g.Files()
+
+	var leaves []string
+	for _, f := range files {
+		deps := g.Deps(f)
+		if len(deps) == 0 {
+			leaves = append(leaves, f)
+			continue
+		}
+
+		for i, d := range deps {
+			deps[i] = makeEscape(d)
+		}
+
+		if _, err := fmt.Fprintf(w, "%s: %s\n", makeEscape(f), strings.Join(deps, " ")); err != nil {
+			return err
+		}
+	}
+
+	if len(leaves) == 0 {
+		return nil
+	}
+
+	if _, err := fmt.Fprintln(w); err != nil {
+		return err
+	}
+
+	for _, f := range leaves {
+		if _, err := fmt.Fprintf(w, "%s:\n", makeEscape(f)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Deps returns the dependency graph of the document.
+// The graph is built from the <include>, <code>, <diagram>,
+// <img>, and <cmd> tags found in the parsed document.
+// A <cmd> depends on every file in its `src` directory,
+// or in the document's directory if `src` is not set.
+//
+// Deps does not require the document to be executed.
+func (doc *Document) Deps() (*DepGraph, error) {
+	if doc == nil {
+		return nil, ErrIsNil("document")
+	}
+
+	g := NewDepGraph()
+
+	name := doc.Filename
+	if len(name) == 0 {
+		name = "."
+	}
+
+	g.Add(name)
+
+	if err := doc.deps(g, name, doc.Children()); err != nil {
+		return nil, err
+	}
+
+	return g, nil
+}
+
+func (doc *Document) deps(g *DepGraph, from string, nodes Nodes) error {
+	for _, n := range nodes {
+		switch t := n.(type) {
+		case *Include:
+			src, ok := t.Get("src")
+			if !ok {
+				break
+			}
+
+			g.Add(from, src)
+
+			if err := doc.deps(g, src, t.Children()); err != nil {
+				return err
+			}
+
+			continue
+		case *SourceCode:
+			src, ok := t.Get("src")
+			if !ok {
+				break
+			}
+
+			src, _, _ = strings.Cut(src, "#")
+			g.Add(from, src)
+		case *Diagram:
+			src, ok := t.Get("src")
+			if !ok {
+				break
+			}
+
+			g.Add(from, src)
+		case *Image:
+			src, ok := t.Get("src")
+			if !ok || isRemoteSrc(src) {
+				break
+			}
+
+			g.Add(from, src)
+		case *Cmd, *Session:
+			dir, ok := t.(AttrNode).Attrs().Get("src")
+			if !ok || filepath.IsAbs(dir) {
+				dir = "."
+			}
+
+			files, err := depFiles(doc.FS, dir)
+			if err != nil {
+				return err
+			}
+
+			g.Add(from, files...)
+		}
+
+		if err := doc.deps(g, from, n.Children()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// depFiles returns all of the regular files under dir,
+// skipping hidden files and directories.
+func depFiles(cab fs.FS, dir string) ([]string, error) {
+	if cab == nil {
+		return nil, nil
+	}
+
+	dir = cleanDepPath(dir)
+
+	var files []string
+	err := fs.WalkDir(cab, dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if p != dir && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		if d.Type().IsRegular() {
+			files = append(files, p)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+func isRemoteSrc(src string) bool {
+	return strings.HasPrefix(src, "http") || strings.HasPrefix(src, "data:") || strings.HasPrefix(src, "//")
+}
+
+func cleanDepPath(p string) string {
+	return path.Clean(filepath.ToSlash(p))
+}
+
+func makeEscape(s string) string {
+	s = strings.ReplaceAll(s, "$", "$$")
+	s = strings.ReplaceAll(s, " ", `\ `)
+	s = strings.ReplaceAll(s, "#", `\#`)
+	return s
+}
+
+func sortedKeys(m map[string]struct{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
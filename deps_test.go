@@ -0,0 +1,98 @@
+package hype
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Document_Deps(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	p := testParser(t, "testdata/deps")
+
+	doc, err := p.ParseFile("hype.md")
+	r.NoError(err)
+
+	g, err := doc.Deps()
+	r.NoError(err)
+
+	r.Equal([]string{"chapter/chapter.md"}, g.Deps("hype.md"))
+
+	r.Equal([]string{
+		"chapter/assets/gopher.png",
+		"chapter/nested.md",
+		"chapter/src/go.mod",
+		"chapter/src/main.go",
+	}, g.Deps("chapter/chapter.md"))
+
+	r.Equal([]string{"chapter/src/go.mod"}, g.Deps("chapter/nested.md"))
+
+	r.Equal([]string{
+		"chapter/assets/gopher.png",
+		"chapter/chapter.md",
+		"chapter/nested.md",
+		"chapter/src/go.mod",
+		"chapter/src/main.go",
+	}, g.AllDeps("hype.md"))
+
+	r.True(g.DependsOn("hype.md", "chapter/src/go.mod"))
+	r.False(g.DependsOn("hype.md", "README.md"))
+
+	r.Equal([]string{
+		"chapter/chapter.md",
+		"chapter/nested.md",
+		"chapter/src/go.mod",
+		"hype.md",
+	}, g.Affected("chapter/src/go.mod"))
+}
+
+func Test_DepGraph_WriteMakefile(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	g := NewDepGraph()
+	g.Add("hype.md", "a.md", "my file.md")
+	g.Add("a.md", "src/main.go")
+
+	bb := &bytes.Buffer{}
+	r.NoError(g.WriteMakefile(bb))
+
+	exp := `a.md: src/main.go
+hype.md: a.md my\ file.md
+
+my\ file.md:
+src/main.go:
+`
+
+	r.Equal(exp, bb.String())
+}
+
+func Test_DepGraph_JSON(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	g := NewDepGraph()
+	g.Add("hype.md", "a.md")
+
+	b, err := json.Marshal(g)
+	r.NoError(err)
+	r.JSONEq(`{"a.md":[],"hype.md":["a.md"]}`, string(b))
+}
+
+func Test_DepGraph_Merge(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	ch := NewDepGraph()
+	ch.Add("hype.md", "src/main.go")
+
+	g := NewDepGraph()
+	g.Merge("ch01", ch)
+
+	r.Equal([]string{"ch01/hype.md", "ch01/src/main.go"}, g.Files())
+	r.Equal([]string{"ch01/src/main.go"}, g.Deps("ch01/hype.md"))
+}
@@ -0,0 +1,248 @@
+package hype
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// Diagram is a diagram, written in a diagram language, such as
+// Graphviz DOT, that is rendered to SVG by the DiagramRenderer
+// configured for its language in Parser.DiagramRenderers.
+//
+// The source is the body of the tag, or the file in its src
+// attribute, whose extension is the language if lang is not
+// set:
+//
+//	<diagram lang="dot">digraph { a -> b }</diagram>
+//	<diagram src="arch.dot"></diagram>
+//
+// Fenced code blocks in a language with a renderer, such as
+// "```dot", are diagrams too.
+type Diagram struct {
+	*Element
+
+	// Lang is the language of the diagram, such as "dot"
+	Lang string
+
+	// Source is the source of the diagram
+	Source string
+
+	// SVG is the rendered diagram, set by Execute
+	SVG string
+
+	renderer DiagramRenderer
+	figure   bool // the diagram is in a <figure>, that numbers it
+}
+
+func (d *Diagram) MarshalJSON() ([]byte, error) {
+	if d == nil {
+		return nil, ErrIsNil("diagram")
+	}
+
+	d.RLock()
+	defer d.RUnlock()
+
+	mm, err := d.JSONMap()
+	if err != nil {
+		return nil, err
+	}
+
+	mm["type"] = toType(d)
+	mm["lang"] = d.Lang
+
+	if len(d.Source) > 0 {
+		mm["source"] = d.Source
+	}
+
+	if len(d.SVG) > 0 {
+		mm["svg"] = d.SVG
+	}
+
+	return json.MarshalIndent(mm, "", "  ")
+}
+
+// MD returns the source of the diagram, in a fenced code
+// block, as SVG can not be written in Markdown.
+func (d *Diagram) MD() string {
+	if d == nil {
+		return ""
+	}
+
+	d.RLock()
+	defer d.RUnlock()
+
+	return fmt.Sprintf("```%s\n%s\n```", d.Lang, strings.TrimSpace(d.Source))
+}
+
+// String returns the SVG of the diagram, in a <figure>, unless
+// the diagram is already in one, such as a numbered
+// <figure id="..." type="diagram">. Before the diagram is
+// executed, its source is returned, in a code block.
+func (d *Diagram) String() string {
+	if d == nil {
+		return ""
+	}
+
+	d.RLock()
+	defer d.RUnlock()
+
+	if len(d.SVG) == 0 {
+		return fmt.Sprintf("<pre><code class=\"language-%s\">%s</code></pre>", d.Lang, html.EscapeString(d.Source))
+	}
+
+	if d.figure {
+		return d.SVG
+	}
+
+	return fmt.Sprintf("<figure class=\"diagram diagram-%s\">%s</figure>", d.Lang, d.SVG)
+}
+
+// Execute renders the diagram to SVG. Rendered diagrams are
+// cached by the hash of their language, renderer, and source,
+// by the document's parser, and, if it has one, and the renderer
+// is a DiagramKeyer, in the parser's ExecCache.
+func (d *Diagram) Execute(ctx context.Context, doc *Document) error {
+	if d == nil {
+		return ErrIsNil("diagram")
+	}
+
+	if d.Element == nil {
+		return ErrIsNil("element")
+	}
+
+	if doc == nil {
+		return ErrIsNil("document")
+	}
+
+	d.Lock()
+	defer d.Unlock()
+
+	key := d.key()
+
+	p := doc.Parser
+	if p == nil {
+		p = &Parser{}
+	}
+
+	if svg, ok := p.diagrams.Get(key); ok {
+		d.SVG = svg
+		return nil
+	}
+
+	// the diagrams of a renderer without a key
+	// may not be shared with other parsers
+	var ec *ExecCache
+	if _, ok := d.renderer.(DiagramKeyer); ok {
+		ec = p.ExecCache
+	}
+
+	svg, ok, err := ec.GetDiagram(key)
+	if err != nil {
+		return d.WrapErr(err)
+	}
+
+	if !ok {
+		svg, err = d.renderer.RenderDiagram(ctx, strings.TrimSpace(d.Source))
+		if err != nil {
+			return d.WrapErr(fmt.Errorf("failed to render %s diagram: %w", d.Lang, err))
+		}
+
+		if err := ec.PutDiagram(key, svg); err != nil {
+			return d.WrapErr(err)
+		}
+	}
+
+	d.SVG = svg
+
+	return p.diagrams.Set(key, svg)
+}
+
+// key returns the hash of the language, renderer,
+// and source, of the diagram.
+func (d *Diagram) key() string {
+	h := sha256.New()
+	fmt.Fprintf(h, "lang: %s\n", d.Lang)
+
+	if dk, ok := d.renderer.(DiagramKeyer); ok {
+		fmt.Fprintf(h, "renderer: %s\n", dk.DiagramKey())
+	}
+
+	fmt.Fprintf(h, "source: %s\n", strings.TrimSpace(d.Source))
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// NewDiagram creates a new Diagram from the given element,
+// with the renderer for its language, from the parser.
+func NewDiagram(p *Parser, el *Element) (*Diagram, error) {
+	if p == nil {
+		return nil, ErrIsNil("parser")
+	}
+
+	if el == nil {
+		return nil, ErrIsNil("element")
+	}
+
+	d := &Diagram{
+		Element: el,
+		Lang:    Language(el.Attrs(), ""),
+	}
+
+	if src, ok := el.Get("src"); ok {
+		b, err := fs.ReadFile(p.FS, src)
+		if err != nil {
+			return nil, d.WrapErr(fmt.Errorf("failed to read file %q: %w", src, err))
+		}
+
+		d.Source = string(b)
+
+		if len(d.Lang) == 0 {
+			d.Lang = strings.TrimPrefix(path.Ext(src), ".")
+		}
+	} else {
+		d.Source = html.UnescapeString(el.Children().String())
+	}
+
+	if l, ok := el.Get("lang"); ok {
+		d.Lang = l
+	}
+
+	d.Lang = strings.ToLower(strings.TrimSpace(d.Lang))
+
+	if len(d.Lang) == 0 {
+		return nil, d.WrapErr(fmt.Errorf("diagram has no lang attribute"))
+	}
+
+	if len(strings.TrimSpace(d.Source)) == 0 {
+		return nil, d.WrapErr(fmt.Errorf("%s diagram source is empty", d.Lang))
+	}
+
+	dr, err := p.DiagramRenderer(d.Lang)
+	if err != nil {
+		return nil, d.WrapErr(err)
+	}
+
+	d.renderer = dr
+	d.figure = inFigure(el.Parent)
+
+	return d, nil
+}
+
+// NewDiagramNodes implements the ParseElementFn type,
+// for <diagram> tags, and fenced code blocks of the
+// languages of Parser.DiagramRenderers.
+func NewDiagramNodes(p *Parser, el *Element) (Nodes, error) {
+	d, err := NewDiagram(p, el)
+	if err != nil {
+		return nil, err
+	}
+
+	return Nodes{d}, nil
+}
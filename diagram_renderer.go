@@ -0,0 +1,156 @@
+package hype
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/gopherguides/hype/dot"
+)
+
+// DiagramRenderer renders the source of a diagram, written in
+// a diagram language, such as Graphviz DOT, to an SVG document.
+//
+// It is used by <diagram lang="..."> tags, and by fenced code
+// blocks of the languages in Parser.DiagramRenderers.
+type DiagramRenderer interface {
+	RenderDiagram(ctx context.Context, src string) (string, error)
+}
+
+// DiagramKeyer is implemented by a DiagramRenderer whose
+// diagrams may be stored in an ExecCache. DiagramKey returns
+// the name of the renderer, and its version, or a hash of its
+// options, so that the diagrams of two renderers, or of two
+// versions of one, are not mistaken for each other. Diagrams
+// of a renderer that is not a DiagramKeyer are only reused
+// by the parser that rendered them.
+type DiagramKeyer interface {
+	DiagramKey() string
+}
+
+// DiagramRendererFn is a function that implements
+// the DiagramRenderer interface.
+type DiagramRendererFn func(ctx context.Context, src string) (string, error)
+
+func (fn DiagramRendererFn) RenderDiagram(ctx context.Context, src string) (string, error) {
+	return fn(ctx, src)
+}
+
+// DefaultDiagramRenderers returns the built-in diagram
+// renderers, keyed by language: DotRenderer for "dot",
+// and "graphviz".
+func DefaultDiagramRenderers() map[string]DiagramRenderer {
+	return map[string]DiagramRenderer{
+		"dot":      DotRenderer{},
+		"graphviz": DotRenderer{},
+	}
+}
+
+// DotRenderer renders Graphviz DOT graphs, in Go,
+// with the dot package. Graphviz is not required.
+type DotRenderer struct{}
+
+func (DotRenderer) RenderDiagram(ctx context.Context, src string) (string, error) {
+	return dot.SVG(src)
+}
+
+// DiagramKey implements DiagramKeyer.
+func (DotRenderer) DiagramKey() string {
+	return fmt.Sprintf("dot %d", dot.Version)
+}
+
+// DiagramCommand returns a DiagramRenderer that runs the
+// command name, with args, with the source of the diagram
+// on stdin, and reads the SVG from stdout. It allows diagram
+// languages with a command line tool, such as D2, or PlantUML,
+// to be registered:
+//
+//	p.DiagramRenderers["d2"] = hype.DiagramCommand("d2", "-", "-")
+//	p.DiagramRenderers["plantuml"] = hype.DiagramCommand("plantuml", "-tsvg", "-pipe")
+func DiagramCommand(name string, args ...string) DiagramRenderer {
+	return diagramCommand{
+		name: name,
+		args: args,
+	}
+}
+
+// diagramCommand is the DiagramRenderer of DiagramCommand.
+type diagramCommand struct {
+	name string
+	args []string
+}
+
+func (dc diagramCommand) RenderDiagram(ctx context.Context, src string) (string, error) {
+	c := exec.CommandContext(ctx, dc.name, dc.args...)
+	c.Stdin = strings.NewReader(src)
+
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	c.Stdout = stdout
+	c.Stderr = stderr
+
+	if err := c.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); len(msg) > 0 {
+			return "", fmt.Errorf("%s: %w: %s", dc.name, err, msg)
+		}
+		return "", fmt.Errorf("%s: %w", dc.name, err)
+	}
+
+	return stdout.String(), nil
+}
+
+// DiagramKey implements DiagramKeyer. The command has no
+// version, so the size, and modification time, of its
+// executable stand in for one, and change when it is upgraded.
+func (dc diagramCommand) DiagramKey() string {
+	key := fmt.Sprintf("command %q %q", dc.name, dc.args)
+
+	fp, err := exec.LookPath(dc.name)
+	if err != nil {
+		return key
+	}
+
+	info, err := os.Stat(fp)
+	if err != nil {
+		return key
+	}
+
+	return fmt.Sprintf("%s %s %d %d", key, fp, info.Size(), info.ModTime().UnixNano())
+}
+
+// ErrDiagramRendererNotFound is returned when a diagram is
+// written in a language with no DiagramRenderer configured.
+type ErrDiagramRendererNotFound string
+
+func (e ErrDiagramRendererNotFound) Error() string {
+	return fmt.Sprintf("no diagram renderer configured for %q", string(e))
+}
+
+// DiagramRenderer returns the renderer for the diagram
+// language lang.
+//
+// If Parser.DiagramRenderers is nil, only DotRenderer
+// is available.
+func (p *Parser) DiagramRenderer(lang string) (DiagramRenderer, error) {
+	if p == nil {
+		return nil, ErrIsNil("parser")
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	renderers := p.DiagramRenderers
+	if renderers == nil {
+		renderers = DefaultDiagramRenderers()
+	}
+
+	dr, ok := renderers[lang]
+	if !ok || dr == nil {
+		return nil, ErrDiagramRendererNotFound(lang)
+	}
+
+	return dr, nil
+}
@@ -0,0 +1,236 @@
+package hype
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Diagram(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	p := testParser(t, "testdata/diagram/dot")
+
+	doc, err := p.ParseFile("hype.md")
+	r.NoError(err)
+
+	ds := ByType[*Diagram](doc.Nodes)
+	r.Len(ds, 3)
+	for _, d := range ds {
+		r.Equal("dot", d.Lang)
+	}
+
+	r.NoError(doc.Execute(context.Background()))
+
+	html := doc.String()
+	r.Equal(2, strings.Count(html, `<figure class="diagram diagram-dot"><svg`))
+	r.Contains(html, `dy="0.35em">execute</tspan>`)
+	r.Contains(html, `dy="0.35em">markdown</tspan>`)
+	r.NotContains(html, "<pre>")
+	r.NotContains(html, "<diagram")
+
+	// a diagram in a figure is numbered by it
+	r.Contains(html, "Diagram 1.1")
+
+	// markdown falls back to the source
+	md := doc.MD()
+	r.Contains(md, "```dot\ndigraph { parse -> execute -> export }\n```")
+	r.Contains(md, "```dot\ndigraph arch {")
+	r.NotContains(md, "<svg")
+
+	deps, err := doc.Deps()
+	r.NoError(err)
+	r.Contains(deps.Deps("hype.md"), "arch.dot")
+}
+
+func Test_Diagram_Renderers(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	var calls atomic.Int32
+
+	src := "x -> y"
+
+	cab := fstest.MapFS{
+		"hype.md":  &fstest.MapFile{Data: []byte(fmt.Sprintf("# D2\n\n```d2\n%s\n```\n", src))},
+		"again.md": &fstest.MapFile{Data: []byte(fmt.Sprintf("# Again\n\n<diagram lang=\"d2\">%s</diagram>\n", src))},
+	}
+
+	p := NewParser(cab)
+	p.DiagramRenderers["d2"] = DiagramRendererFn(func(ctx context.Context, src string) (string, error) {
+		calls.Add(1)
+		return "<svg>" + src + "</svg>", nil
+	})
+
+	for _, name := range []string{"hype.md", "again.md"} {
+		doc, err := p.ParseFile(name)
+		r.NoError(err)
+
+		r.NoError(doc.Execute(context.Background()))
+		r.Contains(doc.String(), fmt.Sprintf(`<figure class="diagram diagram-d2"><svg>%s</svg></figure>`, src))
+	}
+
+	// the same source is rendered once by a parser
+	r.Equal(int32(1), calls.Load())
+
+	// and again by another
+	p2 := NewParser(cab)
+	p2.DiagramRenderers["d2"] = p.DiagramRenderers["d2"]
+
+	doc, err := p2.ParseFile("hype.md")
+	r.NoError(err)
+	r.NoError(doc.Execute(context.Background()))
+	r.Equal(int32(2), calls.Load())
+
+	// a fenced block of a language without a renderer is code
+	p = NewParser(fstest.MapFS{
+		"hype.md": &fstest.MapFile{Data: []byte("# D2\n\n```d2\nx -> y\n```\n")},
+	})
+
+	doc, err = p.ParseFile("hype.md")
+	r.NoError(err)
+	r.Empty(ByType[*Diagram](doc.Nodes))
+}
+
+// keyedRenderer is a DiagramRenderer, and DiagramKeyer,
+// that counts its calls.
+type keyedRenderer struct {
+	key   string
+	calls *atomic.Int32
+}
+
+func (kr keyedRenderer) RenderDiagram(ctx context.Context, src string) (string, error) {
+	kr.calls.Add(1)
+	return "<svg>" + kr.key + "</svg>", nil
+}
+
+func (kr keyedRenderer) DiagramKey() string {
+	return kr.key
+}
+
+func Test_Diagram_Cache(t *testing.T) {
+	t.Parallel()
+
+	ec := NewExecCache(t.TempDir())
+
+	execute := func(t *testing.T, dr DiagramRenderer) string {
+		t.Helper()
+		r := require.New(t)
+
+		d := &Diagram{
+			Element:  NewEl("diagram", nil),
+			Lang:     "cached",
+			Source:   "a -> b",
+			renderer: dr,
+		}
+
+		// a new parser, as a new build has
+		p := NewParser(fstest.MapFS{})
+		p.ExecCache = ec
+
+		r.NoError(d.Execute(context.Background(), &Document{Parser: p}))
+
+		return d.SVG
+	}
+
+	var calls atomic.Int32
+
+	v1 := keyedRenderer{key: "v1", calls: &calls}
+	require.Equal(t, "<svg>v1</svg>", execute(t, v1))
+	require.Equal(t, "<svg>v1</svg>", execute(t, v1))
+	require.Equal(t, int32(1), calls.Load())
+
+	// another version of the renderer
+	require.Equal(t, "<svg>v2</svg>", execute(t, keyedRenderer{key: "v2", calls: &calls}))
+	require.Equal(t, int32(2), calls.Load())
+
+	// a renderer without a key is not stored
+	var fnCalls atomic.Int32
+	fn := DiagramRendererFn(func(ctx context.Context, src string) (string, error) {
+		fnCalls.Add(1)
+		return "<svg>fn</svg>", nil
+	})
+
+	execute(t, fn)
+	execute(t, fn)
+	require.Equal(t, int32(2), fnCalls.Load())
+}
+
+func Test_DiagramCommand_Key(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	a := DiagramCommand("sh", "-c", "cat").(DiagramKeyer).DiagramKey()
+	b := DiagramCommand("sh", "-c", "cat -u").(DiagramKeyer).DiagramKey()
+	r.NotEqual(a, b)
+	r.Equal(a, DiagramCommand("sh", "-c", "cat").(DiagramKeyer).DiagramKey())
+}
+
+func Test_Diagram_Errors(t *testing.T) {
+	t.Parallel()
+
+	table := []struct {
+		name string
+		in   string
+		err  string
+	}{
+		{name: "no lang", in: `<diagram>a -> b</diagram>`, err: "diagram has no lang attribute"},
+		{name: "unknown lang", in: `<diagram lang="pikchr">box</diagram>`, err: `no diagram renderer configured for "pikchr"`},
+		{name: "empty", in: `<diagram lang="dot"></diagram>`, err: "dot diagram source is empty"},
+		{name: "missing src", in: `<diagram src="missing.dot"></diagram>`, err: `failed to read file "missing.dot"`},
+	}
+
+	for _, tt := range table {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			r := require.New(t)
+
+			p := NewParser(fstest.MapFS{})
+
+			_, err := p.Parse(strings.NewReader(tt.in))
+			r.Error(err)
+			r.Contains(err.Error(), tt.err)
+		})
+	}
+
+	t.Run("invalid source", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		p := NewParser(fstest.MapFS{})
+
+		doc, err := p.Parse(strings.NewReader(`<diagram lang="dot">digraph { a -> }</diagram>`))
+		r.NoError(err)
+
+		err = doc.Execute(context.Background())
+		r.Error(err)
+		r.Contains(err.Error(), "failed to render dot diagram")
+	})
+}
+
+func Test_DiagramCommand(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+
+	ctx := context.Background()
+
+	svg, err := DiagramCommand("sh", "-c", "cat").RenderDiagram(ctx, "<svg></svg>")
+	r.NoError(err)
+	r.Equal("<svg></svg>", svg)
+
+	_, err = DiagramCommand("sh", "-c", "echo oops >&2; exit 1").RenderDiagram(ctx, "x")
+	r.Error(err)
+	r.Contains(err.Error(), "oops")
+}
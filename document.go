@@ -17,14 +17,18 @@ type Document struct {
 	fs.FS
 	sync.RWMutex
 
-	ID        string
-	Nodes     Nodes
-	Parser    *Parser // Parser used to create the document
-	Root      string
-	SectionID int
-	Snippets  Snippets
-	Title     string
-	Filename  string
+	FrontMatter map[string]any // typed values of the front matter of the document, if any
+	ID          string
+	Nodes       Nodes
+	Parser      *Parser // Parser used to create the document
+	Root        string
+	SectionID   int
+	Snippets    Snippets
+	Title       string
+	Filename    string
+
+	book        bool   // executed as part of a book, see Documents.Execute
+	frontMatter string // the front matter as written, for Markdown output
 }
 
 func (doc *Document) MarshalJSON() ([]byte, error) {
@@ -60,25 +64,27 @@ func (doc *Document) MarshalJSON() ([]byte, error) {
 	}
 
 	x := struct {
-		Filename  string  `json:"filename,omitempty"`
-		ID        string  `json:"id,omitempty"`
-		Nodes     Nodes   `json:"nodes,omitempty"`
-		Parser    *Parser `json:"parser,omitempty"`
-		Root      string  `json:"root,omitempty"`
-		SectionID int     `json:"section_id,omitempty"`
-		Snippets  any     `json:"snippets,omitempty"`
-		Title     string  `json:"title,omitempty"`
-		Type      string  `json:"type"`
+		Filename    string         `json:"filename,omitempty"`
+		FrontMatter map[string]any `json:"front_matter,omitempty"`
+		ID          string         `json:"id,omitempty"`
+		Nodes       Nodes          `json:"nodes,omitempty"`
+		Parser      *Parser        `json:"parser,omitempty"`
+		Root        string         `json:"root,omitempty"`
+		SectionID   int            `json:"section_id,omitempty"`
+		Snippets    any            `json:"snippets,omitempty"`
+		Title       string         `json:"title,omitempty"`
+		Type        string         `json:"type"`
 	}{
-		Filename:  doc.Filename,
-		ID:        doc.ID,
-		Nodes:     doc.Nodes,
-		Parser:    doc.Parser,
-		Root:      doc.Root,
-		SectionID: doc.SectionID,
-		Snippets:  snips,
-		Title:     doc.Title,
-		Type:      toType(doc),
+		Filename:    doc.Filename,
+		FrontMatter: doc.FrontMatter,
+		ID:          doc.ID,
+		Nodes:       doc.Nodes,
+		Parser:      doc.Parser,
+		Root:        doc.Root,
+		SectionID:   doc.SectionID,
+		Snippets:    snips,
+		Title:       doc.Title,
+		Type:        toType(doc),
 	}
 
 	return json.MarshalIndent(x, "", "  ")
@@ -205,6 +211,12 @@ func (doc *Document) processRefs(err error) error {
 
 type Documents []*Document
 
+// Execute executes the documents of a book. Refs to figures in
+// other documents of the book are resolved once every document
+// has been executed, with the Parser.RefHref of the documents,
+// so the links fit the format the book is exported to. Without
+// one, they link to the source file of the other document.
+// See Documents.ResolveRefs.
 func (docs Documents) Execute(ctx context.Context) error {
 	if docs == nil {
 		return ErrIsNil("documents")
@@ -214,12 +226,21 @@ func (docs Documents) Execute(ctx context.Context) error {
 
 	for _, doc := range docs {
 		doc := doc
+
+		doc.Lock()
+		doc.book = true
+		doc.Unlock()
+
 		wg.Go(func() error {
 			return doc.Execute(ctx)
 		})
 	}
 
-	return wg.Wait()
+	if err := wg.Wait(); err != nil {
+		return err
+	}
+
+	return docs.ResolveRefs(docs.refHref())
 }
 
 func (doc *Document) MD() string {
@@ -239,7 +260,15 @@ func (doc *Document) MD() string {
 		bodies = append(bodies, page.MD())
 	}
 
-	return strings.Join(bodies, "\n---\n")
+	md := strings.Join(bodies, "\n---\n")
+
+	// front matter is kept as written, so it
+	// can be read by other Markdown tools
+	if len(doc.frontMatter) > 0 {
+		md = doc.frontMatter + "\n" + md
+	}
+
+	return md
 }
 
 func (doc *Document) ensureExecuteError(err error) error {
@@ -266,5 +295,19 @@ func (doc *Document) ensureExecuteError(err error) error {
 		Err:      err,
 		Filename: doc.Filename,
 		Root:     doc.Root,
+		Pos:      errPos(err, nil),
 	}
 }
+
+// inBook returns true if the document is being
+// executed as part of a book.
+func (doc *Document) inBook() bool {
+	if doc == nil {
+		return false
+	}
+
+	doc.RLock()
+	defer doc.RUnlock()
+
+	return doc.book
+}
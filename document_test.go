@@ -33,7 +33,7 @@ func Test_Document_Execute(t *testing.T) {
 		act := doc.String()
 		act = strings.TrimSpace(act)
 
-		exp := "<html><head></head><body><page>\n<h1>Command</h1>\n\n<cmd exec=\"echo 'Hello World'\"><pre><code class=\"language-shell\" language=\"shell\">$ echo Hello World\n\nHello World</code></pre></cmd>\n</page>\n</body></html>"
+		exp := "<html><head></head><body><page>\n<h1>Command</h1>\n<cmd exec=\"echo 'Hello World'\"><pre><code class=\"language-shell\" language=\"shell\">$ echo Hello World\n\nHello World</code></pre></cmd>\n</page>\n</body></html>"
 
 		r.Equal(exp, act)
 	})
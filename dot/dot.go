@@ -0,0 +1,191 @@
+// Package dot renders Graphviz DOT graphs to SVG, in Go,
+// without Graphviz.
+//
+// Graphs, and digraphs, with subgraphs, default node, and
+// edge, attributes, and edge chains, such as a -> {b c},
+// are supported. Graphs are laid out in ranks, as Graphviz
+// dot does, by the mermaid package. The rankdir attribute
+// of the graph, the label, and shape, of nodes, and the
+// label, style, dir, arrowhead, and arrowtail, of edges,
+// are drawn. Other attributes are ignored.
+//
+//	svg, err := dot.SVG("digraph { a -> b }")
+package dot
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gopherguides/hype/mermaid"
+)
+
+// Version is the version of the SVG drawn by SVG. It changes
+// when the drawing of a graph changes, so that drawings cached
+// by an earlier version are not used.
+const Version = 1
+
+// SVG renders the DOT graph src as an SVG document.
+func SVG(src string) (string, error) {
+	if len(strings.TrimSpace(src)) == 0 {
+		return "", fmt.Errorf("dot graph is empty")
+	}
+
+	g, err := parse(src)
+	if err != nil {
+		return "", err
+	}
+
+	return g.mermaid().SVG()
+}
+
+// mermaid returns the graph, as a mermaid.Graph.
+func (g *graph) mermaid() mermaid.Graph {
+	mg := mermaid.Graph{
+		Kind: "dot",
+		Dir:  strings.ToUpper(g.attrs["rankdir"]),
+	}
+
+	switch mg.Dir {
+	case "TB", "BT", "LR", "RL":
+	default:
+		mg.Dir = "TB"
+	}
+
+	for _, n := range g.nodes {
+		shape, ok := shapes[n.attrs["shape"]]
+		if !ok {
+			shape = "rect"
+		}
+
+		text, ok := n.attrs["label"]
+		if !ok {
+			text = `\N`
+		}
+
+		if s := n.attrs["shape"]; s == "record" || s == "Mrecord" {
+			text = record(text)
+		}
+
+		mg.Nodes = append(mg.Nodes, mermaid.Node{
+			ID:    n.id,
+			Label: g.label(text, n.id),
+			Shape: shape,
+		})
+	}
+
+	for _, e := range g.edges {
+		me := mermaid.Edge{
+			From: e.from,
+			To:   e.to,
+		}
+
+		if text, ok := e.attrs["label"]; ok {
+			me.Label = g.label(text, "")
+		}
+
+		for _, s := range strings.Split(e.attrs["style"], ",") {
+			switch strings.TrimSpace(s) {
+			case "dashed", "dotted":
+				me.Style = "dotted"
+			case "bold":
+				me.Style = "thick"
+			case "invis":
+				me.Style = "invisible"
+			}
+		}
+
+		dir := e.attrs["dir"]
+		if len(dir) == 0 {
+			dir = "none"
+			if g.directed {
+				dir = "forward"
+			}
+		}
+
+		if dir == "forward" || dir == "both" {
+			me.End = arrow(e.attrs["arrowhead"])
+		}
+
+		if dir == "back" || dir == "both" {
+			me.Start = arrow(e.attrs["arrowtail"])
+		}
+
+		mg.Edges = append(mg.Edges, me)
+	}
+
+	return mg
+}
+
+// shapes are the mermaid.Node shapes of the shapes of
+// DOT nodes. Shapes that are not listed are rectangles.
+var shapes = map[string]string{
+	"":              "ellipse",
+	"ellipse":       "ellipse",
+	"oval":          "ellipse",
+	"egg":           "ellipse",
+	"circle":        "circle",
+	"doublecircle":  "circle",
+	"point":         "circle",
+	"Mcircle":       "circle",
+	"diamond":       "diamond",
+	"Mdiamond":      "diamond",
+	"hexagon":       "hexagon",
+	"octagon":       "hexagon",
+	"doubleoctagon": "hexagon",
+	"tripleoctagon": "hexagon",
+	"septagon":      "hexagon",
+	"cylinder":      "cylinder",
+	"Mrecord":       "round",
+}
+
+// arrow returns the marker of the DOT arrow shape.
+func arrow(shape string) string {
+	switch strings.TrimLeft(shape, "lr") {
+	case "", "normal", "inv", "tee", "box", "crow", "curve":
+		return "arrow"
+	case "empty", "onormal", "invempty", "oinv":
+		return "triangle"
+	case "open", "vee":
+		return "open"
+	case "dot", "odot", "obox":
+		return "circle"
+	case "diamond":
+		return "diamond"
+	case "odiamond", "ediamond":
+		return "odiamond"
+	}
+
+	return "none"
+}
+
+// label returns the text of a label, with the escapes
+// \N (the node id), \G (the graph name), and the line
+// breaks \n, \l, and \r, replaced.
+func (g *graph) label(text string, id string) string {
+	text = strings.NewReplacer(
+		`\N`, id,
+		`\G`, g.name,
+		`\n`, "\n",
+		`\l`, "\n",
+		`\r`, "\n",
+		`\\`, `\`,
+	).Replace(text)
+
+	text = strings.TrimSuffix(text, "\n")
+
+	lines := strings.Split(text, "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimSpace(l)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// record returns the label of a record shape, with
+// its fields, such as "{<f0> a|<f1> b}", on lines.
+func record(text string) string {
+	text = tagRx.ReplaceAllString(text, "")
+	text = strings.NewReplacer("{", "", "}", "", "|", `\n`).Replace(text)
+
+	return text
+}
@@ -0,0 +1,70 @@
+package dot
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_SVG(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	svg, err := SVG(`digraph {
+	rankdir=LR
+	start [shape=Mdiamond]
+	a [label="\N\nnext"]
+	start -> a [label=go]
+	a -> end [arrowhead=empty]
+}`)
+	r.NoError(err)
+
+	r.Contains(svg, `class="mermaid dot"`)
+	r.Contains(svg, `<polygon`)
+	r.Contains(svg, `<ellipse`)
+	r.Contains(svg, `dy="0.35em">a</tspan><tspan`)
+	r.Contains(svg, `dy="0.35em">next</tspan>`)
+	r.Contains(svg, `dy="0.35em">go</tspan>`)
+	r.Contains(svg, `-triangle)`)
+
+	svg, err = SVG(`graph { a -- b }`)
+	r.NoError(err)
+	r.NotContains(svg, "marker-end")
+
+	_, err = SVG(" ")
+	r.Error(err)
+}
+
+func Test_graph_mermaid(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	g, err := parse(`digraph G {
+	rankdir=bt
+	r [shape=record, label="{<f0> left|<f1> right}"]
+	r -> x [dir=both, arrowtail=odiamond, style="dashed"]
+	x -> y [dir=none, style=bold]
+	y -> z [style=invis]
+	label="\G"
+}`)
+	r.NoError(err)
+
+	mg := g.mermaid()
+	r.Equal("BT", mg.Dir)
+
+	r.Equal("left\nright", mg.Nodes[0].Label)
+	r.Equal("rect", mg.Nodes[0].Shape)
+	r.Equal("ellipse", mg.Nodes[1].Shape)
+
+	r.Equal("odiamond", mg.Edges[0].Start)
+	r.Equal("arrow", mg.Edges[0].End)
+	r.Equal("dotted", mg.Edges[0].Style)
+
+	r.Empty(mg.Edges[1].Start)
+	r.Empty(mg.Edges[1].End)
+	r.Equal("thick", mg.Edges[1].Style)
+
+	r.Equal("invisible", mg.Edges[2].Style)
+
+	r.Equal("G", g.label(`\G`, ""))
+}
@@ -0,0 +1,223 @@
+package dot
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// token is a token of a DOT document: an ID, an
+// edge operator ("->", or "--"), or punctuation.
+type token struct {
+	kind string // id, or the operator, or punctuation
+	text string
+	kw   bool // the ID is an unquoted keyword, such as "graph"
+	line int
+}
+
+var numRx = regexp.MustCompile(`^-?(\.[0-9]+|[0-9]+(\.[0-9]*)?)`)
+
+var keywords = map[string]bool{
+	"digraph":  true,
+	"edge":     true,
+	"graph":    true,
+	"node":     true,
+	"strict":   true,
+	"subgraph": true,
+}
+
+// lex returns the tokens of src.
+func lex(src string) ([]token, error) {
+	var toks []token
+
+	line := 1
+	i := 0
+
+	for i < len(src) {
+		c := src[i]
+
+		switch {
+		case c == '\n':
+			line++
+			i++
+			continue
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+			continue
+		case strings.HasPrefix(src[i:], "//"):
+			i = skipLine(src, i)
+			continue
+		case c == '#' && (i == 0 || src[i-1] == '\n'):
+			// preprocessor output lines
+			i = skipLine(src, i)
+			continue
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated comment", line)
+			}
+			line += strings.Count(src[i:i+2+end], "\n")
+			i += end + 4
+			continue
+		case strings.HasPrefix(src[i:], "->") || strings.HasPrefix(src[i:], "--"):
+			toks = append(toks, token{kind: src[i : i+2], text: src[i : i+2], line: line})
+			i += 2
+			continue
+		case strings.ContainsRune("{}[];,=:", rune(c)):
+			toks = append(toks, token{kind: string(c), text: string(c), line: line})
+			i++
+			continue
+		case c == '"':
+			text, n, err := quoted(src[i:])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+
+			// "a" + "b" is the string "ab"
+			if k := len(toks) - 1; k >= 0 && toks[k].kind == "+" {
+				prev := toks[k-1]
+				toks = toks[:k-1]
+				text = prev.text + text
+			}
+
+			toks = append(toks, token{kind: "id", text: text, line: line})
+			line += strings.Count(src[i:i+n], "\n")
+			i += n
+			continue
+		case c == '+':
+			if len(toks) == 0 || toks[len(toks)-1].kind != "id" {
+				return nil, fmt.Errorf("line %d: unexpected '+'", line)
+			}
+			toks = append(toks, token{kind: "+", line: line})
+			i++
+			continue
+		case c == '<':
+			text, n, err := htmlString(src[i:])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			toks = append(toks, token{kind: "id", text: text, line: line})
+			line += strings.Count(src[i:i+n], "\n")
+			i += n
+			continue
+		}
+
+		if m := numRx.FindString(src[i:]); len(m) > 0 {
+			toks = append(toks, token{kind: "id", text: m, line: line})
+			i += len(m)
+			continue
+		}
+
+		r, _ := utf8.DecodeRuneInString(src[i:])
+		if !isIDRune(r, true) {
+			return nil, fmt.Errorf("line %d: unexpected %q", line, r)
+		}
+
+		j := i
+		for j < len(src) {
+			r, n := utf8.DecodeRuneInString(src[j:])
+			if !isIDRune(r, false) {
+				break
+			}
+			j += n
+		}
+
+		text := src[i:j]
+		toks = append(toks, token{
+			kind: "id",
+			text: text,
+			kw:   keywords[strings.ToLower(text)],
+			line: line,
+		})
+		i = j
+	}
+
+	if len(toks) > 0 && toks[len(toks)-1].kind == "+" {
+		return nil, fmt.Errorf("line %d: unexpected '+'", line)
+	}
+
+	return toks, nil
+}
+
+func skipLine(src string, i int) int {
+	if n := strings.IndexByte(src[i:], '\n'); n >= 0 {
+		return i + n
+	}
+
+	return len(src)
+}
+
+func isIDRune(r rune, first bool) bool {
+	if r == '_' || r >= 0x80 && r != utf8.RuneError {
+		return true
+	}
+
+	if r > unicode.MaxASCII {
+		return false
+	}
+
+	if unicode.IsLetter(r) {
+		return true
+	}
+
+	return !first && unicode.IsDigit(r)
+}
+
+// quoted returns the text of the double quoted string at the
+// start of s, and its length. Only \" is an escape; others,
+// such as \n, are the escapes of labels.
+func quoted(s string) (string, int, error) {
+	bb := &strings.Builder{}
+
+	for i := 1; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s) && s[i+1] == '"':
+			bb.WriteByte('"')
+			i++
+		case s[i] == '\\' && i+1 < len(s) && s[i+1] == '\n':
+			// a line continuation
+			i++
+		case s[i] == '"':
+			return bb.String(), i + 1, nil
+		default:
+			bb.WriteByte(s[i])
+		}
+	}
+
+	return "", 0, fmt.Errorf("unterminated string")
+}
+
+var tagRx = regexp.MustCompile(`<[^>]*>`)
+
+// htmlString returns the text of the HTML string at the start
+// of s, such as <<b>bold</b>>, without its tags, and its length.
+// Line breaks, <br/>, are kept as \n.
+func htmlString(s string) (string, int, error) {
+	var depth int
+
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '<':
+			depth++
+		case '>':
+			depth--
+			if depth > 0 {
+				continue
+			}
+
+			text := s[1:i]
+			text = brRx.ReplaceAllString(text, `\n`)
+			text = tagRx.ReplaceAllString(text, "")
+			text = strings.Join(strings.Fields(text), " ")
+			text = strings.NewReplacer("&lt;", "<", "&gt;", ">", "&quot;", `"`, "&amp;", "&").Replace(text)
+
+			return text, i + 1, nil
+		}
+	}
+
+	return "", 0, fmt.Errorf("unterminated HTML string")
+}
+
+var brRx = regexp.MustCompile(`(?i)\s*<br\s*/?>\s*`)
@@ -0,0 +1,354 @@
+package dot
+
+import (
+	"fmt"
+	"maps"
+	"strings"
+)
+
+type attrs map[string]string
+
+// graph is a parsed DOT graph.
+type graph struct {
+	name     string
+	directed bool
+	attrs    attrs
+	nodes    []*node
+	ids      map[string]*node
+	edges    []*edge
+}
+
+type node struct {
+	id    string
+	attrs attrs
+}
+
+type edge struct {
+	from, to string
+	attrs    attrs
+}
+
+// scope holds the default attributes of the nodes, and
+// edges, of a graph, or subgraph, set by "node [...]",
+// and "edge [...]" statements.
+type scope struct {
+	node attrs
+	edge attrs
+}
+
+type parser struct {
+	toks []token
+	i    int
+	g    *graph
+}
+
+// parse parses the DOT document src.
+func parse(src string) (*graph, error) {
+	toks, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{
+		toks: toks,
+		g: &graph{
+			attrs: attrs{},
+			ids:   map[string]*node{},
+		},
+	}
+
+	if err := p.graph(); err != nil {
+		return nil, err
+	}
+
+	return p.g, nil
+}
+
+func (p *parser) peek() token {
+	if p.i < len(p.toks) {
+		return p.toks[p.i]
+	}
+
+	line := 1
+	if n := len(p.toks); n > 0 {
+		line = p.toks[n-1].line
+	}
+
+	return token{kind: "eof", line: line}
+}
+
+func (p *parser) next() token {
+	t := p.peek()
+	if p.i < len(p.toks) {
+		p.i++
+	}
+
+	return t
+}
+
+// keyword reports whether the next token is the keyword kw.
+func (p *parser) keyword(kw string) bool {
+	t := p.peek()
+	return t.kind == "id" && t.kw && strings.EqualFold(t.text, kw)
+}
+
+func (p *parser) accept(kind string) bool {
+	if p.peek().kind == kind {
+		p.i++
+		return true
+	}
+
+	return false
+}
+
+func (p *parser) expect(kind string) (token, error) {
+	t := p.next()
+	if t.kind != kind {
+		return t, p.errorf(t, "expected %q", kind)
+	}
+
+	return t, nil
+}
+
+func (p *parser) errorf(t token, format string, args ...any) error {
+	found := t.text
+	if t.kind == "eof" {
+		found = "end of graph"
+	}
+
+	return fmt.Errorf("line %d: %s, found %q", t.line, fmt.Sprintf(format, args...), found)
+}
+
+// graph parses: [strict] (graph | digraph) [ID] '{' stmt_list '}'
+func (p *parser) graph() error {
+	if p.keyword("strict") {
+		p.next()
+	}
+
+	switch {
+	case p.keyword("digraph"):
+		p.g.directed = true
+	case p.keyword("graph"):
+	default:
+		return p.errorf(p.peek(), "expected graph, or digraph")
+	}
+	p.next()
+
+	if t := p.peek(); t.kind == "id" && !t.kw {
+		p.g.name = p.next().text
+	}
+
+	if _, err := p.expect("{"); err != nil {
+		return err
+	}
+
+	sc := scope{node: attrs{}, edge: attrs{}}
+	if _, err := p.stmts(sc, true); err != nil {
+		return err
+	}
+
+	if t := p.next(); t.kind != "eof" {
+		return p.errorf(t, "expected the end of the graph")
+	}
+
+	return nil
+}
+
+// stmts parses statements up to, and including, the closing
+// brace, and returns the ids of the nodes they use, in order.
+func (p *parser) stmts(sc scope, top bool) ([]string, error) {
+	var used []string
+
+	for {
+		t := p.peek()
+
+		switch {
+		case t.kind == "}":
+			p.next()
+			return used, nil
+		case t.kind == "eof":
+			return nil, p.errorf(t, "expected %q", "}")
+		case t.kind == ";" || t.kind == ",":
+			p.next()
+		case p.keyword("graph"):
+			p.next()
+			a, err := p.attrList()
+			if err != nil {
+				return nil, err
+			}
+			if top {
+				maps.Copy(p.g.attrs, a)
+			}
+		case p.keyword("node"):
+			p.next()
+			a, err := p.attrList()
+			if err != nil {
+				return nil, err
+			}
+			maps.Copy(sc.node, a)
+		case p.keyword("edge"):
+			p.next()
+			a, err := p.attrList()
+			if err != nil {
+				return nil, err
+			}
+			maps.Copy(sc.edge, a)
+		case t.kind == "id" && !t.kw && p.i+1 < len(p.toks) && p.toks[p.i+1].kind == "=":
+			// ID '=' ID sets an attribute of the graph
+			p.i += 2
+			v, err := p.expect("id")
+			if err != nil {
+				return nil, err
+			}
+			if top {
+				p.g.attrs[t.text] = v.text
+			}
+		default:
+			ids, err := p.nodeOrEdge(sc)
+			if err != nil {
+				return nil, err
+			}
+			used = append(used, ids...)
+		}
+	}
+}
+
+// nodeOrEdge parses a node statement, or an edge statement,
+// with its operands, and returns the ids of the nodes used.
+func (p *parser) nodeOrEdge(sc scope) ([]string, error) {
+	first, single, err := p.operand(sc)
+	if err != nil {
+		return nil, err
+	}
+
+	operands := [][]string{first}
+
+	for {
+		t := p.peek()
+		if t.kind != "->" && t.kind != "--" {
+			break
+		}
+
+		if p.g.directed != (t.kind == "->") {
+			return nil, p.errorf(t, "wrong edge operator")
+		}
+		p.next()
+
+		ids, _, err := p.operand(sc)
+		if err != nil {
+			return nil, err
+		}
+
+		operands = append(operands, ids)
+	}
+
+	a, err := p.attrList()
+	if err != nil {
+		return nil, err
+	}
+
+	var used []string
+	for _, ids := range operands {
+		used = append(used, ids...)
+	}
+
+	if len(operands) == 1 {
+		if single {
+			maps.Copy(p.g.ids[first[0]].attrs, a)
+		}
+		return used, nil
+	}
+
+	for i := 1; i < len(operands); i++ {
+		for _, from := range operands[i-1] {
+			for _, to := range operands[i] {
+				ea := maps.Clone(sc.edge)
+				maps.Copy(ea, a)
+				p.g.edges = append(p.g.edges, &edge{from: from, to: to, attrs: ea})
+			}
+		}
+	}
+
+	return used, nil
+}
+
+// operand parses a node id, with an optional port,
+// or a subgraph, and returns the ids of its nodes.
+// single is true for a node id.
+func (p *parser) operand(sc scope) (ids []string, single bool, err error) {
+	if p.keyword("subgraph") || p.peek().kind == "{" {
+		ids, err := p.subgraph(sc)
+		return ids, false, err
+	}
+
+	t := p.next()
+	if t.kind != "id" || t.kw {
+		return nil, false, p.errorf(t, "expected a node")
+	}
+
+	// ports, such as a:p1, or a:p1:ne, do not change the layout
+	for p.accept(":") {
+		if _, err := p.expect("id"); err != nil {
+			return nil, false, err
+		}
+	}
+
+	if _, ok := p.g.ids[t.text]; !ok {
+		n := &node{id: t.text, attrs: maps.Clone(sc.node)}
+		p.g.ids[t.text] = n
+		p.g.nodes = append(p.g.nodes, n)
+	}
+
+	return []string{t.text}, true, nil
+}
+
+// subgraph parses: [subgraph [ID]] '{' stmt_list '}'
+// The defaults of its nodes, and edges, start as
+// those of the enclosing scope.
+func (p *parser) subgraph(sc scope) ([]string, error) {
+	if p.keyword("subgraph") {
+		p.next()
+		if t := p.peek(); t.kind == "id" && !t.kw {
+			p.next()
+		}
+	}
+
+	if _, err := p.expect("{"); err != nil {
+		return nil, err
+	}
+
+	inner := scope{node: maps.Clone(sc.node), edge: maps.Clone(sc.edge)}
+
+	return p.stmts(inner, false)
+}
+
+// attrList parses zero, or more: '[' [ID '=' ID [(';' | ',')]]... ']'
+func (p *parser) attrList() (attrs, error) {
+	a := attrs{}
+
+	for p.accept("[") {
+		for !p.accept("]") {
+			k, err := p.expect("id")
+			if err != nil {
+				return nil, err
+			}
+
+			if _, err := p.expect("="); err != nil {
+				return nil, err
+			}
+
+			v, err := p.expect("id")
+			if err != nil {
+				return nil, err
+			}
+
+			a[k.text] = v.text
+
+			if !p.accept(",") {
+				p.accept(";")
+			}
+		}
+	}
+
+	return a, nil
+}
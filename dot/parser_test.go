@@ -0,0 +1,103 @@
+package dot
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parse(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	g, err := parse(`
+/* a comment */
+strict digraph "G" {
+	rankdir=LR; // another comment
+	node [shape=box]
+	a [label="A \"quoted\"" + " label"]
+	a -> b -> {c d} [label=<<b>bold</b>>]
+	subgraph cluster_0 {
+		node [shape=circle]
+		e:port:ne -- f
+	}
+	b -> e
+}`)
+	r.Error(err)
+	r.Contains(err.Error(), "line 10: wrong edge operator")
+
+	g, err = parse(`
+/* a comment */
+strict digraph "G" {
+	rankdir=LR; // another comment
+	node [shape=box]
+	a [label="A \"quoted\"" + " label"]
+	a -> b -> {c d} [label=<<b>bold</b>>]
+	subgraph cluster_0 {
+		node [shape=circle]
+		e:port:ne -> f
+	}
+	b -> e [style=dashed, dir=back]
+}`)
+	r.NoError(err)
+
+	r.Equal("G", g.name)
+	r.True(g.directed)
+	r.Equal("LR", g.attrs["rankdir"])
+
+	var ids []string
+	for _, n := range g.nodes {
+		ids = append(ids, n.id)
+	}
+	r.Equal([]string{"a", "b", "c", "d", "e", "f"}, ids)
+
+	r.Equal(`A "quoted" label`, g.ids["a"].attrs["label"])
+	r.Equal("box", g.ids["a"].attrs["shape"])
+	r.Equal("circle", g.ids["e"].attrs["shape"])
+
+	type link struct{ from, to, label string }
+
+	var edges []link
+	for _, e := range g.edges {
+		edges = append(edges, link{e.from, e.to, e.attrs["label"]})
+	}
+
+	r.Equal([]link{
+		{"a", "b", "bold"},
+		{"b", "c", "bold"},
+		{"b", "d", "bold"},
+		{"e", "f", ""},
+		{"b", "e", ""},
+	}, edges)
+
+	r.Equal("back", g.edges[4].attrs["dir"])
+}
+
+func Test_parse_Errors(t *testing.T) {
+	t.Parallel()
+
+	table := []struct {
+		name string
+		in   string
+		err  string
+	}{
+		{name: "no graph", in: `a -> b`, err: "expected graph, or digraph"},
+		{name: "unclosed", in: "digraph {\na -> b", err: `line 2: expected "}"`},
+		{name: "string", in: `graph { a [label="x] }`, err: "unterminated string"},
+		{name: "comment", in: `graph { /* a }`, err: "unterminated comment"},
+		{name: "attribute", in: `graph { a [label] }`, err: `expected "="`},
+		{name: "trailing", in: `graph { } x`, err: "expected the end of the graph"},
+	}
+
+	for _, tt := range table {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			r := require.New(t)
+
+			_, err := parse(tt.in)
+			r.Error(err)
+			r.Contains(err.Error(), tt.err)
+		})
+	}
+}
@@ -9,7 +9,6 @@ import (
 	"strings"
 	"sync"
 
-	"github.com/gopherguides/hype/atomx"
 	"golang.org/x/net/html"
 	"golang.org/x/net/html/atom"
 )
@@ -24,6 +23,7 @@ type Element struct {
 	Nodes    Nodes
 	Parent   Node
 	Filename string // only set when Parser.ParseFile() is used
+	Pos      Pos    // position of the element in its source file, if known
 }
 
 // FileName returns the filename of the element.
@@ -33,6 +33,15 @@ func (el *Element) FileName() string {
 	return el.Filename
 }
 
+// Position returns the position of the element in its source file.
+func (el *Element) Position() Pos {
+	if el == nil {
+		return Pos{}
+	}
+
+	return el.Pos
+}
+
 func (el *Element) JSONMap() (map[string]any, error) {
 	if el == nil {
 		return nil, ErrIsNil("element")
@@ -289,31 +298,9 @@ func (el *Element) MD() string {
 		return ""
 	}
 
-	switch el.Atom() {
-	case atomx.Strong, atomx.B:
-		return fmt.Sprintf("**%s**", el.Children().MD())
-	case atomx.Em, atomx.I:
-		return fmt.Sprintf("_%s_", el.Children().MD())
-	case atomx.Pre:
-		return el.Children().MD()
-	case atomx.Hr:
-		return "\n\n---\n\n"
-	case atomx.Br:
-		return "\n\n"
-	case atomx.Blockquote:
-		b := el.Children().MD()
-		b = strings.TrimSpace(b)
-		return fmt.Sprintf("> %s", b)
-	case atomx.Details:
-		return el.String()
-	default:
-		// fmt.Printf("TODO: Element.MD(): %q\n", el.Atom())
+	if mdIsInline(el) {
+		return mdInlineElement(el)
 	}
 
-	bb := &bytes.Buffer{}
-	bb.WriteString(el.StartTag())
-	bb.WriteString(el.Children().MD())
-	bb.WriteString(el.EndTag())
-
-	return bb.String()
+	return mdElement(el)
 }
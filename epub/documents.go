@@ -0,0 +1,191 @@
+package epub
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// packageDocument returns the OPF package document
+// describing the metadata, manifest, and spine of the book.
+func (b *Book) packageDocument(md Metadata) []byte {
+	bb := &bytes.Buffer{}
+
+	bb.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	bb.WriteString(`<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="book-id" xml:lang="` + escape(md.Language) + `">` + "\n")
+
+	bb.WriteString(`  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">` + "\n")
+	fmt.Fprintf(bb, "    <dc:identifier id=\"book-id\">%s</dc:identifier>\n", escape(md.Identifier))
+	fmt.Fprintf(bb, "    <dc:title>%s</dc:title>\n", escape(md.Title))
+	fmt.Fprintf(bb, "    <dc:language>%s</dc:language>\n", escape(md.Language))
+
+	for _, a := range md.Authors {
+		fmt.Fprintf(bb, "    <dc:creator>%s</dc:creator>\n", escape(a))
+	}
+
+	if len(md.Publisher) > 0 {
+		fmt.Fprintf(bb, "    <dc:publisher>%s</dc:publisher>\n", escape(md.Publisher))
+	}
+
+	if len(md.Description) > 0 {
+		fmt.Fprintf(bb, "    <dc:description>%s</dc:description>\n", escape(md.Description))
+	}
+
+	if len(md.Date) > 0 {
+		fmt.Fprintf(bb, "    <dc:date>%s</dc:date>\n", escape(md.Date))
+	}
+
+	fmt.Fprintf(bb, "    <meta property=\"dcterms:modified\">%s</meta>\n", md.Modified.Format(time.RFC3339))
+	bb.WriteString("  </metadata>\n")
+
+	bb.WriteString("  <manifest>\n")
+	bb.WriteString(`    <item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>` + "\n")
+
+	if len(b.CSS) > 0 {
+		bb.WriteString(`    <item id="css" href="style.css" media-type="text/css"/>` + "\n")
+	}
+
+	for i, ch := range b.Chapters {
+		var props []string
+		if ch.svg {
+			props = append(props, "svg")
+		}
+		if ch.remote {
+			props = append(props, "remote-resources")
+		}
+
+		fmt.Fprintf(bb, "    <item id=\"chapter-%02d\" href=\"%s\" media-type=\"application/xhtml+xml\"", i+1, chapterPath(i))
+		if len(props) > 0 {
+			fmt.Fprintf(bb, " properties=\"%s\"", strings.Join(props, " "))
+		}
+		bb.WriteString("/>\n")
+	}
+
+	for i, r := range b.Resources {
+		fmt.Fprintf(bb, "    <item id=\"resource-%d\" href=\"%s\" media-type=\"%s\"/>\n", i+1, escape(r.Path), escape(r.MediaType))
+	}
+
+	bb.WriteString("  </manifest>\n")
+
+	bb.WriteString("  <spine>\n")
+	for i := range b.Chapters {
+		fmt.Fprintf(bb, "    <itemref idref=\"chapter-%02d\"/>\n", i+1)
+	}
+	bb.WriteString("  </spine>\n")
+
+	bb.WriteString("</package>\n")
+
+	return bb.Bytes()
+}
+
+// navDocument returns the EPUB navigation document.
+// Each chapter is an entry in the table of contents,
+// with the headings of the chapter nested below it.
+// The first heading of a chapter is skipped if it is
+// the chapter title.
+func (b *Book) navDocument(md Metadata) []byte {
+	bb := &bytes.Buffer{}
+
+	writeHead(bb, md, md.Title, false)
+
+	bb.WriteString("<nav epub:type=\"toc\" id=\"toc\">\n")
+	fmt.Fprintf(bb, "<h1>%s</h1>\n", escape(md.Title))
+	bb.WriteString("<ol>\n")
+
+	for i, ch := range b.Chapters {
+		href := chapterPath(i)
+
+		hs := ch.Headings
+		if len(hs) > 0 && hs[0].Level == 1 && hs[0].Text == ch.Title {
+			hs = hs[1:]
+		}
+
+		fmt.Fprintf(bb, "<li><a href=\"%s\">%s</a>", href, escape(ch.Title))
+		writeNav(bb, href, navTree(hs))
+		bb.WriteString("</li>\n")
+	}
+
+	bb.WriteString("</ol>\n")
+	bb.WriteString("</nav>\n")
+
+	writeFoot(bb)
+
+	return bb.Bytes()
+}
+
+// chapterDocument returns the XHTML content document of ch.
+func (b *Book) chapterDocument(md Metadata, ch Chapter) []byte {
+	bb := &bytes.Buffer{}
+
+	writeHead(bb, md, ch.Title, len(b.CSS) > 0)
+
+	bb.WriteString(ch.Body)
+	writeFoot(bb)
+
+	return bb.Bytes()
+}
+
+func writeHead(bb *bytes.Buffer, md Metadata, title string, css bool) {
+	lang := escape(md.Language)
+
+	bb.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	bb.WriteString("<!DOCTYPE html>\n")
+	fmt.Fprintf(bb, "<html xmlns=\"http://www.w3.org/1999/xhtml\" xmlns:epub=\"http://www.idpf.org/2007/ops\" xml:lang=\"%s\" lang=\"%s\">\n", lang, lang)
+	bb.WriteString("<head>\n")
+	bb.WriteString("<meta charset=\"UTF-8\"/>\n")
+	fmt.Fprintf(bb, "<title>%s</title>\n", escape(title))
+	if css {
+		bb.WriteString("<link rel=\"stylesheet\" type=\"text/css\" href=\"style.css\"/>\n")
+	}
+	bb.WriteString("</head>\n")
+	bb.WriteString("<body>\n")
+}
+
+func writeFoot(bb *bytes.Buffer) {
+	bb.WriteString("\n</body>\n</html>\n")
+}
+
+type navPoint struct {
+	Heading
+	Children []*navPoint
+}
+
+// navTree nests the headings by level.
+func navTree(hs []Heading) []*navPoint {
+	var roots []*navPoint
+	var stack []*navPoint
+
+	for _, h := range hs {
+		np := &navPoint{Heading: h}
+
+		for len(stack) > 0 && stack[len(stack)-1].Level >= h.Level {
+			stack = stack[:len(stack)-1]
+		}
+
+		if len(stack) == 0 {
+			roots = append(roots, np)
+		} else {
+			parent := stack[len(stack)-1]
+			parent.Children = append(parent.Children, np)
+		}
+
+		stack = append(stack, np)
+	}
+
+	return roots
+}
+
+func writeNav(bb *bytes.Buffer, href string, points []*navPoint) {
+	if len(points) == 0 {
+		return
+	}
+
+	bb.WriteString("<ol>")
+	for _, np := range points {
+		fmt.Fprintf(bb, "<li><a href=\"%s#%s\">%s</a>", href, escape(np.ID), escape(np.Text))
+		writeNav(bb, href, np.Children)
+		bb.WriteString("</li>")
+	}
+	bb.WriteString("</ol>")
+}
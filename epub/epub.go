@@ -0,0 +1,352 @@
+// Package epub writes hype documents as EPUB 3 books.
+//
+// Each document becomes one XHTML chapter, in order. The navigation
+// document is generated from the headings of the chapters, local
+// images are embedded in the book, and the book metadata is read
+// from the <metadata> tags of the documents.
+package epub
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/fs"
+	"mime"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/gopherguides/hype"
+	"golang.org/x/net/html"
+)
+
+// Metadata describes the book in the package document.
+type Metadata struct {
+	Identifier  string    // defaults to a UUID derived from the title
+	Title       string    // required
+	Language    string    // defaults to "en"
+	Authors     []string  // optional
+	Publisher   string    // optional
+	Description string    // optional
+	Date        string    // publication date; optional
+	Modified    time.Time // defaults to the time the book is written
+}
+
+// Heading is a heading found in a chapter.
+type Heading struct {
+	Level int
+	Text  string
+	ID    string
+}
+
+// Chapter is a single XHTML content document of the book.
+type Chapter struct {
+	Title    string
+	Body     string    // XHTML contents of the <body> element
+	Headings []Heading // headings in the chapter, in document order
+
+	svg    bool // the body contains inline SVG
+	remote bool // the body references remote resources
+}
+
+// Resource is a file embedded in the book, such as an image.
+type Resource struct {
+	Path      string // path relative to the package document
+	MediaType string
+	Data      []byte
+}
+
+// Book is an EPUB 3 publication.
+type Book struct {
+	Metadata
+
+	CSS       string // optional style sheet for every chapter
+	Chapters  []Chapter
+	Resources []Resource
+}
+
+// New returns a book with one chapter for each document.
+// The documents should already be executed. Refs to figures
+// in other documents link to the chapter of the figure.
+//
+// The metadata of the book is read from the <metadata> tags
+// of the documents; the first value found for a key wins.
+// The following keys are used:
+//
+//	title, author, language, publisher, description, date, identifier
+//
+// If there is no title in the metadata, title is used, or,
+// if that is empty, the title of the first document.
+func New(title string, docs hype.Documents) (*Book, error) {
+	if len(docs) == 0 {
+		return nil, fmt.Errorf("epub: no documents")
+	}
+
+	b := &Book{}
+
+	data := map[string]string{}
+	for _, doc := range docs {
+		for k, v := range hype.ExtractMeta(doc).Metadata {
+			if _, ok := data[k]; !ok {
+				data[k] = v
+			}
+		}
+	}
+
+	b.Title = data["title"]
+	if len(b.Title) == 0 {
+		b.Title = title
+	}
+	if len(b.Title) == 0 {
+		b.Title = docs[0].Title
+	}
+
+	b.Language = data["language"]
+	b.Publisher = data["publisher"]
+	b.Description = data["description"]
+	b.Date = data["date"]
+	b.Identifier = data["identifier"]
+
+	if a, ok := data["author"]; ok {
+		for _, s := range strings.Split(a, ",") {
+			if s = strings.TrimSpace(s); len(s) > 0 {
+				b.Authors = append(b.Authors, s)
+			}
+		}
+	}
+
+	// each chapter is a file of its own
+	err := docs.ResolveRefs(func(from, to *hype.Document, fig *hype.Figure) (string, error) {
+		if from == to {
+			return fig.Link(), nil
+		}
+
+		for i, doc := range docs {
+			if doc == to {
+				return chapterPath(i) + fig.Link(), nil
+			}
+		}
+
+		return "", fmt.Errorf("document not in book: %s", to.Filename)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("epub: %w", err)
+	}
+
+	for i, doc := range docs {
+		if err := b.addDocument(i+1, doc); err != nil {
+			return nil, err
+		}
+	}
+
+	return b, nil
+}
+
+func (b *Book) addDocument(n int, doc *hype.Document) error {
+	if doc == nil {
+		return hype.ErrIsNil("document")
+	}
+
+	root, err := html.Parse(strings.NewReader(doc.String()))
+	if err != nil {
+		return fmt.Errorf("epub: %s: %w", doc.Filename, err)
+	}
+
+	c := &converter{
+		book:    b,
+		fsys:    doc.FS,
+		prefix:  fmt.Sprintf("images/%02d/", n),
+		images:  map[string]string{},
+		ids:     map[string]int{},
+		chapter: &Chapter{Title: doc.Title},
+	}
+
+	body := findElement(root, "body")
+	if body == nil {
+		return fmt.Errorf("epub: %s: missing body", doc.Filename)
+	}
+
+	bb := &bytes.Buffer{}
+	for ch := body.FirstChild; ch != nil; ch = ch.NextSibling {
+		if err := c.write(bb, ch); err != nil {
+			return fmt.Errorf("epub: %s: %w", doc.Filename, err)
+		}
+	}
+
+	ch := c.chapter
+	ch.Body = bb.String()
+
+	if len(ch.Title) == 0 && len(ch.Headings) > 0 {
+		ch.Title = ch.Headings[0].Text
+	}
+
+	if len(ch.Title) == 0 {
+		ch.Title = fmt.Sprintf("Chapter %d", n)
+	}
+
+	b.Chapters = append(b.Chapters, *ch)
+
+	return nil
+}
+
+// Write writes the book to w as an EPUB container.
+func (b *Book) Write(w io.Writer) error {
+	if b == nil {
+		return hype.ErrIsNil("book")
+	}
+
+	if len(b.Title) == 0 {
+		return fmt.Errorf("epub: missing title")
+	}
+
+	if len(b.Chapters) == 0 {
+		return fmt.Errorf("epub: no chapters")
+	}
+
+	md := b.Metadata
+	if md.Modified.IsZero() {
+		md.Modified = time.Now()
+	}
+	md.Modified = md.Modified.UTC().Truncate(time.Second)
+
+	if len(md.Language) == 0 {
+		md.Language = "en"
+	}
+
+	if len(md.Identifier) == 0 {
+		md.Identifier = "urn:uuid:" + uuid.NewV5(uuid.NamespaceURL, md.Title).String()
+	}
+
+	zw := zip.NewWriter(w)
+
+	if err := writeMimetype(zw); err != nil {
+		return err
+	}
+
+	if err := writeZip(zw, "META-INF/container.xml", zip.Deflate, md.Modified, []byte(containerXML)); err != nil {
+		return err
+	}
+
+	files := []Resource{
+		{Path: "content.opf", Data: b.packageDocument(md)},
+		{Path: "nav.xhtml", Data: b.navDocument(md)},
+	}
+
+	if len(b.CSS) > 0 {
+		files = append(files, Resource{Path: "style.css", Data: []byte(b.CSS)})
+	}
+
+	for i, ch := range b.Chapters {
+		files = append(files, Resource{
+			Path: chapterPath(i),
+			Data: b.chapterDocument(md, ch),
+		})
+	}
+
+	files = append(files, b.Resources...)
+
+	for _, r := range files {
+		if err := writeZip(zw, path.Join("OEBPS", r.Path), zip.Deflate, md.Modified, r.Data); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+// writeMimetype writes the mimetype file. The OCF requires it
+// to be the first file, stored, with no extra field, so that
+// its contents are at offset 38. It is written raw, with no
+// modified time, as the extended timestamp field, and the data
+// descriptor, that zip adds to other files are not allowed.
+func writeMimetype(zw *zip.Writer) error {
+	data := []byte(mimetype)
+
+	f, err := zw.CreateRaw(&zip.FileHeader{
+		Name:               "mimetype",
+		Method:             zip.Store,
+		CRC32:              crc32.ChecksumIEEE(data),
+		CompressedSize64:   uint64(len(data)),
+		UncompressedSize64: uint64(len(data)),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(data)
+	return err
+}
+
+func writeZip(zw *zip.Writer, name string, method uint16, mod time.Time, data []byte) error {
+	f, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   method,
+		Modified: mod,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(data)
+	return err
+}
+
+// addImage embeds the image at src, relative to the document, in the book,
+// and returns the path to use in the chapter.
+func (c *converter) addImage(src string) (string, error) {
+	if p, ok := c.images[src]; ok {
+		return p, nil
+	}
+
+	if c.fsys == nil {
+		return "", fmt.Errorf("no file system to read %q", src)
+	}
+
+	name := path.Clean(strings.TrimPrefix(src, "/"))
+
+	data, err := fs.ReadFile(c.fsys, name)
+	if err != nil {
+		return "", err
+	}
+
+	p := c.prefix + path.Base(name)
+	for _, r := range c.book.Resources {
+		if r.Path == p {
+			p = fmt.Sprintf("%s%d-%s", c.prefix, len(c.images), path.Base(name))
+			break
+		}
+	}
+
+	mt := mime.TypeByExtension(path.Ext(name))
+	mt, _, _ = strings.Cut(mt, ";")
+	if len(mt) == 0 {
+		mt = "application/octet-stream"
+	}
+
+	c.book.Resources = append(c.book.Resources, Resource{
+		Path:      p,
+		MediaType: mt,
+		Data:      data,
+	})
+
+	c.images[src] = p
+
+	return p, nil
+}
+
+func chapterPath(i int) string {
+	return fmt.Sprintf("chapter-%02d.xhtml", i+1)
+}
+
+const mimetype = "application/epub+zip"
+
+const containerXML = `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles>
+    <rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+  </rootfiles>
+</container>
+`
@@ -0,0 +1,179 @@
+package epub
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/xml"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gopherguides/hype"
+	"github.com/stretchr/testify/require"
+)
+
+func testBook(t testing.TB) *Book {
+	t.Helper()
+	r := require.New(t)
+
+	p := hype.NewParser(os.DirFS("testdata/book"))
+	p.Root = "testdata/book"
+
+	docs, err := p.ParseExecuteFolder(context.Background(), "book")
+	r.NoError(err)
+	r.Len(docs, 2)
+
+	b, err := New("Book", docs)
+	r.NoError(err)
+
+	return b
+}
+
+func readZip(t testing.TB, b *Book) map[string]string {
+	t.Helper()
+	r := require.New(t)
+
+	bb := &bytes.Buffer{}
+	r.NoError(b.Write(bb))
+
+	zr, err := zip.NewReader(bytes.NewReader(bb.Bytes()), int64(bb.Len()))
+	r.NoError(err)
+
+	r.Equal("mimetype", zr.File[0].Name)
+	r.Equal(zip.Store, zr.File[0].Method)
+
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		r.NoError(err)
+
+		data, err := io.ReadAll(rc)
+		r.NoError(err)
+		rc.Close()
+
+		files[f.Name] = string(data)
+	}
+
+	return files
+}
+
+func Test_Book_Write_Mimetype(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	b := testBook(t)
+	b.Modified = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	bb := &bytes.Buffer{}
+	r.NoError(b.Write(bb))
+
+	raw := bb.Bytes()
+	r.Equal("PK\x03\x04", string(raw[:4]))
+
+	// no data descriptor, stored, and no extra field
+	r.Equal(uint16(0), binary.LittleEndian.Uint16(raw[6:8])&0x8)
+	r.Equal(zip.Store, binary.LittleEndian.Uint16(raw[8:10]))
+	r.Equal(uint16(8), binary.LittleEndian.Uint16(raw[26:28]))
+	r.Equal(uint16(0), binary.LittleEndian.Uint16(raw[28:30]))
+
+	exp := "mimetypeapplication/epub+zip"
+	r.Equal(exp, string(raw[30:30+len(exp)]))
+}
+
+func Test_New(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	b := testBook(t)
+
+	r.Equal("The Gopher Book", b.Title)
+	r.Equal("en-US", b.Language)
+	r.Equal([]string{"Ada Gopher", "Rob Gopher"}, b.Authors)
+	r.Equal("Gopher Guides", b.Publisher)
+
+	r.Len(b.Chapters, 2)
+	r.Equal("Introduction", b.Chapters[0].Title)
+	r.Equal("Usage", b.Chapters[1].Title)
+
+	exp := []Heading{
+		{Level: 1, Text: "Introduction", ID: "introduction"},
+		{Level: 2, Text: "Getting Started", ID: "getting-started"},
+		{Level: 3, Text: "Requirements", ID: "requirements"},
+	}
+	r.Equal(exp, b.Chapters[0].Headings)
+
+	r.Len(b.Resources, 1)
+	r.Equal("images/02/gopher.svg", b.Resources[0].Path)
+	r.Equal("image/svg+xml", b.Resources[0].MediaType)
+}
+
+func Test_Book_Write(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	b := testBook(t)
+	b.Modified = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	files := readZip(t, b)
+
+	r.Equal("application/epub+zip", files["mimetype"])
+	r.Contains(files["META-INF/container.xml"], `full-path="OEBPS/content.opf"`)
+
+	opf := files["OEBPS/content.opf"]
+	r.Contains(opf, `<dc:identifier id="book-id">urn:uuid:`)
+	r.Contains(opf, `<dc:creator>Rob Gopher</dc:creator>`)
+	r.Contains(opf, `<meta property="dcterms:modified">2024-01-02T03:04:05Z</meta>`)
+	r.Contains(opf, `<item id="resource-1" href="images/02/gopher.svg" media-type="image/svg+xml"/>`)
+	r.Contains(opf, `<itemref idref="chapter-02"/>`)
+
+	nav := files["OEBPS/nav.xhtml"]
+	r.Contains(nav, `<li><a href="chapter-01.xhtml">Introduction</a><ol><li><a href="chapter-01.xhtml#getting-started">Getting Started</a><ol><li><a href="chapter-01.xhtml#requirements">Requirements</a></li></ol></li></ol></li>`)
+
+	// refs to figures in other chapters link to their chapter
+	r.Contains(files["OEBPS/chapter-01.xhtml"], `<a href="chapter-02.xhtml#figure-2-1">Figure 2.1</a>`)
+
+	ch := files["OEBPS/chapter-02.xhtml"]
+	r.Contains(ch, `<section class="page">`)
+	r.Contains(ch, `<figure id="figure-2-1">`)
+	r.Contains(ch, `<div class="cmd"><pre><code class="language-shell">`)
+	r.Contains(ch, `<img alt="the gopher" src="images/02/gopher.svg"/>`)
+
+	r.Contains(files, "OEBPS/images/02/gopher.svg")
+
+	// every document in the book must be well-formed XML
+	for name, data := range files {
+		if name == "mimetype" || name == "OEBPS/images/02/gopher.svg" {
+			continue
+		}
+
+		dec := xml.NewDecoder(bytes.NewReader([]byte(data)))
+		dec.Strict = true
+		for {
+			_, err := dec.Token()
+			if err == io.EOF {
+				break
+			}
+			r.NoError(err, name)
+		}
+	}
+}
+
+func Test_Book_Write_Errors(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	var b *Book
+	r.Error(b.Write(io.Discard))
+
+	b = &Book{}
+	r.Error(b.Write(io.Discard))
+
+	b.Title = "Empty"
+	r.Error(b.Write(io.Discard))
+
+	_, err := New("", nil)
+	r.Error(err)
+}
@@ -0,0 +1,327 @@
+package epub
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// converter writes the HTML of a hype document as XHTML
+// suitable for an EPUB content document.
+//
+// Elements that are not part of HTML, such as <page> and <cmd>,
+// are written as <section>, <div>, or <span> elements with the
+// original tag name as their class. Headings are given an ID,
+// if they do not already have one, so that they can be linked
+// to from the navigation document.
+type converter struct {
+	book    *Book
+	chapter *Chapter
+	fsys    fs.FS
+	prefix  string            // path prefix for images of the chapter
+	images  map[string]string // image src => path in the book
+	ids     map[string]int    // generated heading IDs
+}
+
+var voidElements = map[atom.Atom]bool{
+	atom.Area:   true,
+	atom.Base:   true,
+	atom.Br:     true,
+	atom.Col:    true,
+	atom.Embed:  true,
+	atom.Hr:     true,
+	atom.Img:    true,
+	atom.Input:  true,
+	atom.Link:   true,
+	atom.Meta:   true,
+	atom.Source: true,
+	atom.Track:  true,
+	atom.Wbr:    true,
+}
+
+var headingLevels = map[atom.Atom]int{
+	atom.H1: 1,
+	atom.H2: 2,
+	atom.H3: 3,
+	atom.H4: 4,
+	atom.H5: 5,
+	atom.H6: 6,
+}
+
+// attributes hype adds to HTML elements that are not valid HTML.
+var droppedAttrs = map[string]bool{
+	"language": true,
+}
+
+func (c *converter) write(bb *bytes.Buffer, n *html.Node) error {
+	switch n.Type {
+	case html.TextNode:
+		bb.WriteString(escape(n.Data))
+		return nil
+	case html.ElementNode:
+	default:
+		return nil
+	}
+
+	if n.DataAtom == atom.Head {
+		return nil
+	}
+
+	name := n.Data
+	attrs := n.Attr
+
+	switch {
+	case n.Namespace == "svg":
+		c.chapter.svg = true
+	case n.Namespace != "":
+	case n.DataAtom == 0:
+		name, attrs = c.rename(n)
+	default:
+		var err error
+		attrs, err = c.attrs(n)
+		if err != nil {
+			return err
+		}
+	}
+
+	bb.WriteString("<" + name)
+
+	if n.Namespace == "svg" && (n.Parent == nil || n.Parent.Namespace != "svg") {
+		bb.WriteString(` xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink"`)
+	}
+
+	for _, a := range attrs {
+		key := a.Key
+		if len(a.Namespace) > 0 {
+			key = a.Namespace + ":" + key
+		}
+		fmt.Fprintf(bb, " %s=\"%s\"", key, escape(a.Val))
+	}
+
+	if voidElements[n.DataAtom] && n.Namespace == "" {
+		bb.WriteString("/>")
+		return nil
+	}
+
+	bb.WriteString(">")
+
+	for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+		if err := c.write(bb, ch); err != nil {
+			return err
+		}
+	}
+
+	bb.WriteString("</" + name + ">")
+
+	return nil
+}
+
+// rename returns the HTML element, and attributes, to use
+// for an element that is not part of HTML.
+func (c *converter) rename(n *html.Node) (string, []html.Attribute) {
+	class := n.Data
+
+	var attrs []html.Attribute
+	for _, a := range n.Attr {
+		switch a.Key {
+		case "id":
+			attrs = append(attrs, a)
+		case "class":
+			class += " " + a.Val
+		}
+	}
+
+	attrs = append(attrs, html.Attribute{Key: "class", Val: class})
+
+	if n.Data == "page" {
+		return "section", attrs
+	}
+
+	if hasBlock(n) {
+		return "div", attrs
+	}
+
+	return "span", attrs
+}
+
+// attrs returns the attributes to write for an HTML element.
+// Image sources are embedded in the book, and headings
+// are recorded for the navigation document.
+func (c *converter) attrs(n *html.Node) ([]html.Attribute, error) {
+	attrs := make([]html.Attribute, 0, len(n.Attr))
+	for _, a := range n.Attr {
+		if droppedAttrs[a.Key] {
+			continue
+		}
+		attrs = append(attrs, a)
+	}
+
+	switch n.DataAtom {
+	case atom.Img:
+		hasAlt := false
+		for i, a := range attrs {
+			switch a.Key {
+			case "alt":
+				hasAlt = true
+			case "src":
+				if strings.HasPrefix(a.Val, "data:") {
+					continue
+				}
+
+				if isRemote(a.Val) {
+					c.chapter.remote = true
+					continue
+				}
+
+				p, err := c.addImage(a.Val)
+				if err != nil {
+					return nil, err
+				}
+				attrs[i].Val = p
+			}
+		}
+
+		if !hasAlt {
+			attrs = append(attrs, html.Attribute{Key: "alt", Val: ""})
+		}
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		h := Heading{
+			Level: headingLevels[n.DataAtom],
+			Text:  strings.Join(strings.Fields(textContent(n)), " "),
+		}
+
+		for _, a := range attrs {
+			if a.Key == "id" {
+				h.ID = a.Val
+			}
+		}
+
+		if len(h.ID) == 0 {
+			h.ID = anchorID(n)
+		}
+
+		if len(h.ID) == 0 {
+			h.ID = c.headingID(h.Text)
+			attrs = append(attrs, html.Attribute{Key: "id", Val: h.ID})
+		}
+
+		c.chapter.Headings = append(c.chapter.Headings, h)
+	}
+
+	return attrs, nil
+}
+
+var idRx = regexp.MustCompile(`[^a-z0-9]+`)
+
+// headingID returns a unique ID for a heading with the given text.
+func (c *converter) headingID(text string) string {
+	id := strings.Trim(idRx.ReplaceAllString(strings.ToLower(text), "-"), "-")
+	if len(id) == 0 {
+		id = "heading"
+	}
+
+	c.ids[id]++
+	if n := c.ids[id]; n > 1 {
+		id = fmt.Sprintf("%s-%d", id, n)
+	}
+
+	return id
+}
+
+// anchorID returns the ID of the first <a id="..."> in n,
+// which hype adds to numbered headings.
+func anchorID(n *html.Node) string {
+	for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+		if ch.Type != html.ElementNode {
+			continue
+		}
+
+		if ch.DataAtom == atom.A {
+			for _, a := range ch.Attr {
+				if a.Key == "id" && len(a.Val) > 0 {
+					return a.Val
+				}
+			}
+		}
+
+		if id := anchorID(ch); len(id) > 0 {
+			return id
+		}
+	}
+
+	return ""
+}
+
+// hasBlock returns true if n contains any block content,
+// in which case it can not be written as a <span>.
+func hasBlock(n *html.Node) bool {
+	for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+		if ch.Type != html.ElementNode {
+			continue
+		}
+
+		switch ch.DataAtom {
+		case atom.A, atom.Abbr, atom.B, atom.Br, atom.Code, atom.Del, atom.Em,
+			atom.I, atom.Img, atom.Input, atom.Kbd, atom.Mark, atom.Q, atom.S,
+			atom.Samp, atom.Small, atom.Span, atom.Strong, atom.Sub, atom.Sup,
+			atom.Time, atom.U, atom.Var:
+			continue
+		case 0:
+			if hasBlock(ch) {
+				return true
+			}
+			continue
+		}
+
+		return true
+	}
+
+	return false
+}
+
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+
+	sb := &strings.Builder{}
+	for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+		sb.WriteString(textContent(ch))
+	}
+
+	return sb.String()
+}
+
+func findElement(n *html.Node, name string) *html.Node {
+	if n.Type == html.ElementNode && n.Data == name {
+		return n
+	}
+
+	for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+		if f := findElement(ch, name); f != nil {
+			return f
+		}
+	}
+
+	return nil
+}
+
+func isRemote(src string) bool {
+	return strings.HasPrefix(src, "http") || strings.HasPrefix(src, "//")
+}
+
+var escaper = strings.NewReplacer(
+	"&", "&amp;",
+	"<", "&lt;",
+	">", "&gt;",
+	`"`, "&quot;",
+)
+
+func escape(s string) string {
+	return escaper.Replace(s)
+}
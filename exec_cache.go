@@ -0,0 +1,401 @@
+package hype
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/markbates/clam"
+)
+
+// ExecCache is an on-disk, content-addressed cache of
+// command results. It allows a *Cmd (and the commands
+// generated by the <go> tag) to be rebuilt from a previous
+// run without executing the command again.
+//
+// Cache keys are derived from the command arguments,
+// environment, the contents of the working directory,
+// and the Go version.
+//
+// The hashes of directories are memoized, so an ExecCache is
+// meant to be used for one build, and keys reflect the files
+// as they were when the build started. Invalidate forgets the
+// hashes of directories whose files have changed.
+//
+// The SVG of rendered diagrams is stored too, under the
+// hash of their source, by GetDiagram, and PutDiagram.
+type ExecCache struct {
+	Dir      string // directory to store cached results in; default: DefaultExecCacheDir()
+	Disabled bool   // disable reading and writing of the cache
+
+	dirs map[string]string // memoized directory hashes
+	mu   sync.Mutex
+}
+
+// DefaultExecCacheDir returns the default location for
+// the execution cache. This is `$XDG_CACHE_HOME/hype/exec`
+// (or the platform equivalent). If the HYPE_CACHE_DIR
+// environment variable is set, it is used instead.
+func DefaultExecCacheDir() string {
+	if dir := os.Getenv("HYPE_CACHE_DIR"); len(dir) > 0 {
+		return dir
+	}
+
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+
+	return filepath.Join(dir, "hype", "exec")
+}
+
+// NewExecCache returns a new ExecCache that stores its
+// results in the given directory. If dir is empty,
+// DefaultExecCacheDir() is used.
+func NewExecCache(dir string) *ExecCache {
+	if len(dir) == 0 {
+		dir = DefaultExecCacheDir()
+	}
+
+	return &ExecCache{
+		Dir: dir,
+	}
+}
+
+// Key returns the cache key for running the given
+// arguments, with the given runner and environment,
+// in the given working directory. The directory is required.
+func (ec *ExecCache) Key(runner string, args []string, env []string, dir string) (string, error) {
+	if ec == nil {
+		return "", ErrIsNil("exec cache")
+	}
+
+	if len(dir) == 0 {
+		return "", fmt.Errorf("exec cache: working directory is not set")
+	}
+
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+
+	dh, err := ec.hashDir(dir)
+	if err != nil {
+		return "", err
+	}
+
+	env = append([]string{}, env...)
+	sort.Strings(env)
+
+	h := sha256.New()
+	fmt.Fprintf(h, "go: %s\n", GoVersion())
+	fmt.Fprintf(h, "runner: %s\n", runner)
+	fmt.Fprintf(h, "dir: %s\n", dir)
+	fmt.Fprintf(h, "files: %s\n", dh)
+
+	for _, a := range args {
+		fmt.Fprintf(h, "arg: %q\n", a)
+	}
+
+	for _, e := range env {
+		fmt.Fprintf(h, "env: %q\n", e)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Invalidate forgets the memoized hashes of the directories
+// that hold, or are inside, any of the given files, or
+// directories, so they are hashed again. For example, when a
+// watcher sees the files of a DepGraph change.
+func (ec *ExecCache) Invalidate(paths ...string) {
+	if ec == nil {
+		return
+	}
+
+	ec.mu.Lock()
+	defer ec.mu.Unlock()
+
+	for _, p := range paths {
+		p, err := filepath.Abs(p)
+		if err != nil {
+			continue
+		}
+
+		for dir := range ec.dirs {
+			if isWithin(dir, p) || isWithin(p, dir) {
+				delete(ec.dirs, dir)
+			}
+		}
+	}
+}
+
+// Get returns the cached result for the given key.
+// If the cache is disabled, or the key is not found,
+// false is returned.
+func (ec *ExecCache) Get(key string) (*clam.Result, bool, error) {
+	if ec == nil || ec.Disabled {
+		return nil, false, nil
+	}
+
+	b, err := os.ReadFile(ec.path(key))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+
+	var ce cachedResult
+	if err := json.Unmarshal(b, &ce); err != nil {
+		// a corrupt entry is treated as a miss
+		return nil, false, nil
+	}
+
+	res := &clam.Result{
+		Args:     ce.Args,
+		Dir:      ce.Dir,
+		Exit:     ce.Exit,
+		Stderr:   []byte(ce.Stderr),
+		Stdout:   []byte(ce.Stdout),
+		Duration: ce.Duration,
+	}
+
+	if len(ce.Err) > 0 {
+		res.Err = errors.New(ce.Err)
+	}
+
+	return res, true, nil
+}
+
+// Put stores the result in the cache under the given key.
+func (ec *ExecCache) Put(key string, res *clam.Result) error {
+	if ec == nil || ec.Disabled {
+		return nil
+	}
+
+	if res == nil {
+		return ErrIsNil("result")
+	}
+
+	ce := cachedResult{
+		Args:     res.Args,
+		Dir:      res.Dir,
+		Exit:     res.Exit,
+		Stderr:   string(res.Stderr),
+		Stdout:   string(res.Stdout),
+		Duration: res.Duration,
+	}
+
+	if res.Err != nil {
+		ce.Err = res.Err.Error()
+	}
+
+	b, err := json.MarshalIndent(ce, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeCacheFile(ec.path(key), b)
+}
+
+// GetDiagram returns the cached SVG of the diagram with the
+// given key. If the cache is disabled, or the key is not
+// found, false is returned.
+func (ec *ExecCache) GetDiagram(key string) (string, bool, error) {
+	if ec == nil || ec.Disabled {
+		return "", false, nil
+	}
+
+	b, err := os.ReadFile(ec.diagramPath(key))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return "", false, nil
+		}
+		return "", false, err
+	}
+
+	return string(b), true, nil
+}
+
+// PutDiagram stores the SVG of a diagram in the cache
+// under the given key.
+func (ec *ExecCache) PutDiagram(key string, svg string) error {
+	if ec == nil || ec.Disabled {
+		return nil
+	}
+
+	return writeCacheFile(ec.diagramPath(key), []byte(svg))
+}
+
+// writeCacheFile writes b to fp, creating its directory.
+func writeCacheFile(fp string, b []byte) error {
+	if err := os.MkdirAll(filepath.Dir(fp), 0755); err != nil {
+		return err
+	}
+
+	// write to a temp file first so concurrent
+	// readers never see a partial entry
+	f, err := os.CreateTemp(filepath.Dir(fp), ".tmp-*")
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+
+	return os.Rename(f.Name(), fp)
+}
+
+// Clean removes all entries from the cache.
+func (ec *ExecCache) Clean() error {
+	if ec == nil {
+		return ErrIsNil("exec cache")
+	}
+
+	if len(ec.Dir) == 0 {
+		return fmt.Errorf("exec cache directory is not set")
+	}
+
+	ec.mu.Lock()
+	ec.dirs = nil
+	ec.mu.Unlock()
+
+	return os.RemoveAll(ec.Dir)
+}
+
+// diagramPath returns the path of the diagram with the key,
+// in the diagrams directory of the cache.
+func (ec *ExecCache) diagramPath(key string) string {
+	dir := ec.Dir
+	if len(dir) == 0 {
+		dir = DefaultExecCacheDir()
+	}
+
+	dir = filepath.Join(dir, "diagrams")
+
+	if len(key) < 2 {
+		return filepath.Join(dir, key+".svg")
+	}
+
+	return filepath.Join(dir, key[:2], key+".svg")
+}
+
+func (ec *ExecCache) path(key string) string {
+	dir := ec.Dir
+	if len(dir) == 0 {
+		dir = DefaultExecCacheDir()
+	}
+
+	if len(key) < 2 {
+		return filepath.Join(dir, key+".json")
+	}
+
+	return filepath.Join(dir, key[:2], key+".json")
+}
+
+// hashDir returns a hash of the names and contents of
+// all of the files under dir. Hidden files and directories,
+// such as .git, are skipped. Hashes are memoized until
+// they are invalidated.
+func (ec *ExecCache) hashDir(dir string) (string, error) {
+	ec.mu.Lock()
+	s, ok := ec.dirs[dir]
+	ec.mu.Unlock()
+
+	if ok {
+		return s, nil
+	}
+
+	h := sha256.New()
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		name := d.Name()
+		if path != dir && strings.HasPrefix(name, ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if d.IsDir() || !d.Type().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(h, "%s\n", filepath.ToSlash(rel))
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		if _, err := io.Copy(h, f); err != nil {
+			return err
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	s = hex.EncodeToString(h.Sum(nil))
+
+	ec.mu.Lock()
+	if ec.dirs == nil {
+		ec.dirs = map[string]string{}
+	}
+	ec.dirs[dir] = s
+	ec.mu.Unlock()
+
+	return s, nil
+}
+
+// isWithin returns true if path is dir, or is inside of dir.
+func isWithin(dir string, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
+
+type cachedResult struct {
+	Args     []string      `json:"args,omitempty"`
+	Dir      string        `json:"dir,omitempty"`
+	Err      string        `json:"err,omitempty"`
+	Exit     int           `json:"exit"`
+	Stderr   string        `json:"stderr,omitempty"`
+	Stdout   string        `json:"stdout,omitempty"`
+	Duration time.Duration `json:"duration,omitempty"`
+}
@@ -0,0 +1,206 @@
+package hype
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/markbates/clam"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ExecCache_Key(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	dir := t.TempDir()
+	r.NoError(os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main"), 0644))
+
+	ec := NewExecCache(t.TempDir())
+
+	k1, err := ec.Key(HostRunnerName, []string{"go", "run", "."}, nil, dir)
+	r.NoError(err)
+
+	k2, err := ec.Key(HostRunnerName, []string{"go", "run", "."}, nil, dir)
+	r.NoError(err)
+	r.Equal(k1, k2)
+
+	k3, err := ec.Key(HostRunnerName, []string{"go", "test", "."}, nil, dir)
+	r.NoError(err)
+	r.NotEqual(k1, k3)
+
+	k4, err := ec.Key(HostRunnerName, []string{"go", "run", "."}, []string{"GOOS=linux"}, dir)
+	r.NoError(err)
+	r.NotEqual(k1, k4)
+
+	k6, err := ec.Key(RestrictedRunnerName, []string{"go", "run", "."}, nil, dir)
+	r.NoError(err)
+	r.NotEqual(k1, k6)
+
+	// the hash of the directory is memoized for the
+	// build, until the changed file invalidates it
+	fp := filepath.Join(dir, "main.go")
+	r.NoError(os.WriteFile(fp, []byte("package main\n\nfunc main() {}"), 0644))
+
+	k5, err := ec.Key(HostRunnerName, []string{"go", "run", "."}, nil, dir)
+	r.NoError(err)
+	r.Equal(k1, k5)
+
+	ec.Invalidate(fp)
+
+	k5, err = ec.Key(HostRunnerName, []string{"go", "run", "."}, nil, dir)
+	r.NoError(err)
+	r.NotEqual(k1, k5)
+}
+
+func Test_ExecCache_GetPut(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	ec := NewExecCache(t.TempDir())
+
+	_, ok, err := ec.Get("abc123")
+	r.NoError(err)
+	r.False(ok)
+
+	res := &clam.Result{
+		Args:     []string{"echo", "hello"},
+		Dir:      "/tmp",
+		Err:      fmt.Errorf("boom"),
+		Exit:     1,
+		Stdout:   []byte("hello"),
+		Stderr:   []byte("oops"),
+		Duration: time.Second,
+	}
+
+	r.NoError(ec.Put("abc123", res))
+
+	act, ok, err := ec.Get("abc123")
+	r.NoError(err)
+	r.True(ok)
+	r.Equal(res.Args, act.Args)
+	r.Equal(res.Dir, act.Dir)
+	r.Equal(res.Exit, act.Exit)
+	r.Equal(res.Stdout, act.Stdout)
+	r.Equal(res.Stderr, act.Stderr)
+	r.Equal(res.Duration, act.Duration)
+	r.EqualError(act.Err, "boom")
+
+	r.NoError(ec.Clean())
+
+	_, ok, err = ec.Get("abc123")
+	r.NoError(err)
+	r.False(ok)
+}
+
+func Test_ExecCache_Disabled(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	ec := NewExecCache(t.TempDir())
+	ec.Disabled = true
+
+	r.NoError(ec.Put("abc123", &clam.Result{}))
+
+	_, ok, err := ec.Get("abc123")
+	r.NoError(err)
+	r.False(ok)
+}
+
+func Test_Cmd_Execute_ExecCache(t *testing.T) {
+	t.Parallel()
+
+	ec := NewExecCache(t.TempDir())
+	src := t.TempDir()
+
+	run := func(t *testing.T, exec string, ats ...string) string {
+		t.Helper()
+		r := require.New(t)
+
+		el := NewEl("cmd", nil)
+		r.NoError(el.Set("exec", exec))
+		r.NoError(el.Set("src", src))
+		r.NoError(el.Set("hide-cmd", ""))
+
+		for _, a := range ats {
+			r.NoError(el.Set(a, ""))
+		}
+
+		c, err := NewCmd(el)
+		r.NoError(err)
+
+		p := NewParser(nil)
+		p.ExecCache = ec
+
+		doc := &Document{
+			Parser: p,
+		}
+
+		r.NoError(c.Execute(context.Background(), doc))
+
+		return string(c.Result().Stdout)
+	}
+
+	exec := "sh -c 'od -An -N8 -tx8 /dev/urandom'"
+
+	first := run(t, exec)
+	require.NotEmpty(t, first)
+
+	// served from the cache
+	require.Equal(t, first, run(t, exec))
+
+	// no-cache always executes
+	require.NotEqual(t, first, run(t, exec, "no-cache"))
+}
+
+func Test_Cmd_Execute_ExecCache_Root(t *testing.T) {
+	t.Parallel()
+
+	ec := NewExecCache(t.TempDir())
+	root := t.TempDir()
+
+	run := func(t *testing.T) string {
+		t.Helper()
+		r := require.New(t)
+
+		el := NewEl("cmd", nil)
+		r.NoError(el.Set("exec", "sh -c 'pwd; od -An -N8 -tx8 /dev/urandom'"))
+		r.NoError(el.Set("hide-cmd", ""))
+
+		c, err := NewCmd(el)
+		r.NoError(err)
+
+		p := NewParser(nil)
+		p.ExecCache = ec
+
+		doc := &Document{
+			Parser: p,
+			Root:   root,
+		}
+
+		r.NoError(c.Execute(context.Background(), doc))
+
+		return string(c.Result().Stdout)
+	}
+
+	first := run(t)
+	require.NotEmpty(t, first)
+
+	// a command without a src runs in, and is
+	// keyed on, the root of the document
+	pwd, err := filepath.EvalSymlinks(root)
+	require.NoError(t, err)
+	require.Contains(t, first, pwd)
+	require.Equal(t, first, run(t))
+
+	fp := filepath.Join(root, "hype.md")
+	require.NoError(t, os.WriteFile(fp, []byte("# Hello"), 0644))
+	ec.Invalidate(fp)
+	require.NotEqual(t, first, run(t))
+
+	_, err = ec.Key(HostRunnerName, []string{"ls"}, nil, "")
+	require.Error(t, err)
+}
@@ -55,6 +55,7 @@ func (list Nodes) Execute(wg WaitGrouper, ctx context.Context, d *Document) (err
 						Err:      err,
 						Filename: name,
 						Root:     d.Root,
+						Pos:      errPos(err, cn),
 					}
 				}
 				return nil
@@ -69,6 +70,7 @@ func (list Nodes) Execute(wg WaitGrouper, ctx context.Context, d *Document) (err
 				Err:      err,
 				Filename: name,
 				Root:     d.Root,
+				Pos:      errPos(err, n),
 			}
 		}
 
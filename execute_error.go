@@ -14,6 +14,7 @@ type ExecuteError struct {
 	Document *Document
 	Filename string
 	Root     string
+	Pos      Pos // position of the node the error occurred at, if known
 }
 
 func (pe ExecuteError) MarshalJSON() ([]byte, error) {
@@ -26,10 +27,23 @@ func (pe ExecuteError) MarshalJSON() ([]byte, error) {
 		"type":     toType(pe),
 	}
 
+	if pe.Pos.IsValid() {
+		mm["pos"] = pe.Pos
+	}
+
 	return json.MarshalIndent(mm, "", "  ")
 }
 
 func (pe ExecuteError) Error() string {
+	if pe.Pos.IsValid() {
+		src := docSource(pe.Document, pe.Pos)
+		if src == nil && filepath.Join(pe.Root, pe.Filename) == pe.Pos.File {
+			src = pe.Contents
+		}
+
+		return diagnostic(pe.Pos, src, pe.message())
+	}
+
 	sb := &strings.Builder{}
 
 	var lines []string
@@ -54,6 +68,21 @@ func (pe ExecuteError) Error() string {
 	return strings.TrimSpace(s)
 }
 
+// Position returns the position the error occurred at.
+func (pe ExecuteError) Position() Pos {
+	return pe.Pos
+}
+
+func (pe ExecuteError) message() string {
+	msg := fmt.Sprintf("execute error: %s", errMessage(pe.Err, pe.Pos))
+
+	if pe.Document != nil && len(pe.Document.Title) > 0 {
+		msg += fmt.Sprintf("\ndocument: %s", pe.Document.Title)
+	}
+
+	return msg
+}
+
 func (pe ExecuteError) String() string {
 	return pe.Error()
 }
@@ -153,6 +153,16 @@ func Test_ExecuteError_String(t *testing.T) {
 			in:   ExecuteError{Err: io.EOF},
 			exp:  "execute error: EOF",
 		},
+		{
+			name: "positioned",
+			in: ExecuteError{
+				Contents: []byte("# Title\n\n<foo></foo>\n"),
+				Err:      io.EOF,
+				Filename: "hype.md",
+				Pos:      Pos{File: "hype.md", Line: 3, Col: 1},
+			},
+			exp: "hype.md:3:1: execute error: EOF\n    3 | <foo></foo>\n      | ^",
+		},
 	}
 
 	for _, tc := range tcs {
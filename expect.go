@@ -0,0 +1,507 @@
+package hype
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// expectation is the output a <cmd> is expected to print. It
+// is read from the golden file in the `expect` attribute, or,
+// if the attribute is empty, is the body of the tag:
+//
+//	<cmd exec="go run ." expect="golden/run.txt"></cmd>
+//
+//	<cmd exec="echo hello" expect>
+//	hello
+//	</cmd>
+type expectation struct {
+	file string // golden file, relative to the document; empty for an inline expectation
+	root string // directory of the document, for writing the golden file
+	cab  fs.FS  // file system of the document, for reading the golden file
+	text string // the inline expectation
+}
+
+// read returns the expected output. A golden file
+// that does not exist is an error that wraps
+// fs.ErrNotExist.
+func (e *expectation) read() (string, error) {
+	if len(e.file) == 0 {
+		return e.text, nil
+	}
+
+	b, err := fs.ReadFile(e.cab, e.file)
+	if err != nil {
+		return "", fmt.Errorf("failed to read golden file %q: %w", e.file, err)
+	}
+
+	return string(b), nil
+}
+
+// write rewrites the golden file with the output.
+func (e *expectation) write(output string) error {
+	if len(e.file) == 0 {
+		return fmt.Errorf("inline expectations can not be updated; update the body of the tag")
+	}
+
+	fp := filepath.Join(e.root, filepath.FromSlash(e.file))
+	if err := os.MkdirAll(filepath.Dir(fp), 0755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(fp, []byte(normalizeOutput(output)+"\n"), 0644)
+}
+
+// newExpectation returns the expectation of the <cmd> el,
+// if it has an `expect` attribute.
+func newExpectation(p *Parser, el *Element) (*expectation, error) {
+	file, ok := el.Get("expect")
+	if !ok {
+		return nil, nil
+	}
+
+	e := &expectation{
+		file: strings.TrimSpace(file),
+	}
+
+	if p != nil {
+		e.root = p.Root
+		e.cab = p.FS
+	}
+
+	if len(e.file) > 0 {
+		return e, nil
+	}
+
+	e.text = normalizeOutput(html.UnescapeString(el.Children().String()))
+	if len(e.text) == 0 {
+		return nil, el.WrapErr(fmt.Errorf("expect needs a golden file, or the expected output in the body of the tag"))
+	}
+
+	return e, nil
+}
+
+// ExpectError is returned when the output of a <cmd>
+// does not match the output it is expected to print.
+type ExpectError struct {
+	File string // golden file; empty for an inline expectation
+	Diff string // unified diff of the expected, and actual, output
+}
+
+func (e ExpectError) Error() string {
+	name := e.File
+	if len(name) == 0 {
+		name = "the expected output"
+	}
+
+	return fmt.Sprintf("output does not match %s:\n%s", name, strings.TrimRight(e.Diff, "\n"))
+}
+
+// Verify compares the output of the executed command with the
+// output it is expected to print, from the `expect` attribute,
+// and returns an ExpectError, with a diff, if they differ.
+// Commands without an expectation, or that have not been
+// executed, are not verified.
+//
+// If update is true, a golden file that does not match, or
+// does not exist, is rewritten with the output instead.
+//
+// In the expected output, these placeholders match values
+// that change between runs:
+//
+//	[..]        any text, up to the end of the line
+//	...         on a line of its own, any number of lines
+//	[DURATION]  a duration, such as 1.2s, or 350ms
+//	[NUMBER]    a number, such as 42, or -1.5
+//	[PATH]      a file path, such as /tmp/go-build123/b001
+//	[re:EXPR]   the regular expression EXPR
+func (c *Cmd) Verify(update bool) error {
+	if c == nil {
+		return ErrIsNil("cmd")
+	}
+
+	c.RLock()
+	e := c.expect
+	res := c.res
+	c.RUnlock()
+
+	if e == nil || res == nil || res.Result == nil {
+		return nil
+	}
+
+	actual, err := cmdOutput(res.Result, c.Attrs())
+	if err != nil {
+		return err
+	}
+
+	want, err := e.read()
+	if err != nil && (!update || !errors.Is(err, fs.ErrNotExist)) {
+		return err
+	}
+
+	if err == nil {
+		ok, err := matchOutput(want, actual)
+		if err != nil {
+			return err
+		}
+
+		if ok {
+			return nil
+		}
+	}
+
+	if update {
+		if err := e.write(actual); err != nil {
+			return err
+		}
+		return nil
+	}
+
+	name := e.file
+	if len(name) == 0 {
+		name = "expected"
+	}
+
+	return ExpectError{
+		File: e.file,
+		Diff: diffOutput(name, want, actual),
+	}
+}
+
+// normalizeOutput returns s with Windows line endings,
+// trailing white space, and leading, and trailing,
+// blank lines removed.
+func normalizeOutput(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimRight(l, " \t\r")
+	}
+
+	return strings.Trim(strings.Join(lines, "\n"), "\n")
+}
+
+// placeholders are the patterns of the placeholders
+// of expected output, other than [re:EXPR].
+var placeholders = map[string]string{
+	"[..]":       `.*`,
+	"[DURATION]": `(?:\d+(?:\.\d+)?(?:ns|µs|us|ms|s|m|h))+`,
+	"[NUMBER]":   `-?\d+(?:\.\d+)?`,
+	"[PATH]":     `(?:[A-Za-z]:)?[^\s:]*[/\\][^\s:]*`,
+}
+
+var placeholderRx = regexp.MustCompile(`\[(?:\.\.|DURATION|NUMBER|PATH|re:)`)
+
+// linePattern returns the regular expression that
+// matches a line of expected output.
+func linePattern(line string) (string, error) {
+	bb := &strings.Builder{}
+
+	for len(line) > 0 {
+		loc := placeholderRx.FindStringIndex(line)
+		if loc == nil {
+			bb.WriteString(regexp.QuoteMeta(line))
+			break
+		}
+
+		bb.WriteString(regexp.QuoteMeta(line[:loc[0]]))
+		line = line[loc[0]:]
+
+		if !strings.HasPrefix(line, "[re:") {
+			end := strings.IndexByte(line, ']') + 1
+			bb.WriteString(placeholders[line[:end]])
+			line = line[end:]
+			continue
+		}
+
+		expr, n, err := regexPlaceholder(line)
+		if err != nil {
+			return "", err
+		}
+
+		fmt.Fprintf(bb, "(?:%s)", expr)
+		line = line[n:]
+	}
+
+	return bb.String(), nil
+}
+
+// regexPlaceholder returns the expression of the [re:EXPR]
+// placeholder at the start of s, and the length of the
+// placeholder. Brackets in EXPR must be balanced, or escaped.
+func regexPlaceholder(s string) (string, int, error) {
+	depth := 0
+
+	for i := len("[re:"); i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '[':
+			depth++
+		case ']':
+			if depth > 0 {
+				depth--
+				continue
+			}
+
+			expr := s[len("[re:"):i]
+			if _, err := regexp.Compile(expr); err != nil {
+				return "", 0, fmt.Errorf("invalid placeholder %s: %w", s[:i+1], err)
+			}
+
+			return expr, i + 1, nil
+		}
+	}
+
+	return "", 0, fmt.Errorf("unterminated placeholder %q", s)
+}
+
+// lineMatcher reports whether a line of expected output,
+// with placeholders, matches a line of actual output.
+type lineMatcher func(want, got string) bool
+
+func newLineMatcher() (lineMatcher, func() error) {
+	cache := map[string]*regexp.Regexp{}
+
+	var first error
+
+	match := func(want, got string) bool {
+		if want == got {
+			return true
+		}
+
+		rx, ok := cache[want]
+		if !ok {
+			pat, err := linePattern(want)
+			if err == nil {
+				rx, err = regexp.Compile("^" + pat + "$")
+			}
+
+			if err != nil && first == nil {
+				first = err
+			}
+
+			cache[want] = rx
+		}
+
+		return rx != nil && rx.MatchString(got)
+	}
+
+	return match, func() error { return first }
+}
+
+// matchOutput reports whether the actual output
+// matches the expected output, with placeholders.
+func matchOutput(want, got string) (bool, error) {
+	want, got = normalizeOutput(want), normalizeOutput(got)
+
+	match, errFn := newLineMatcher()
+	ops := alignOutput(strings.Split(want, "\n"), strings.Split(got, "\n"), match)
+
+	if err := errFn(); err != nil {
+		return false, err
+	}
+
+	for _, op := range ops {
+		if op.kind == '-' || op.kind == '+' {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// diffOp is a line of a diff: ' ' for a line of both,
+// '-' for an expected line, and '+' for an actual line.
+type diffOp struct {
+	kind byte
+	line string
+}
+
+// maxAlign limits the size of the outputs that are
+// aligned line by line, as alignment is quadratic.
+const maxAlign = 4_000_000
+
+// alignOutput returns the fewest lines to remove from want, and
+// add from got, to turn want into got. A line of want that is
+// "..." matches any number of lines of got.
+func alignOutput(want, got []string, match lineMatcher) []diffOp {
+	n, m := len(want), len(got)
+
+	if n*m > maxAlign {
+		// too large to align; every line differs,
+		// unless the outputs are the same
+		if strings.Join(want, "\n") == strings.Join(got, "\n") {
+			ops := make([]diffOp, 0, n)
+			for _, l := range want {
+				ops = append(ops, diffOp{' ', l})
+			}
+			return ops
+		}
+
+		var ops []diffOp
+		for _, l := range want {
+			ops = append(ops, diffOp{'-', l})
+		}
+		for _, l := range got {
+			ops = append(ops, diffOp{'+', l})
+		}
+		return ops
+	}
+
+	// cost[i][j] is the number of edits to align want[i:] with got[j:]
+	cost := make([][]int, n+1)
+	for i := range cost {
+		cost[i] = make([]int, m+1)
+	}
+
+	for i := n; i >= 0; i-- {
+		for j := m; j >= 0; j-- {
+			switch {
+			case i == n:
+				cost[i][j] = m - j
+				continue
+			case want[i] == "...":
+				// skip it, or let it take a line
+				cost[i][j] = cost[i+1][j]
+				if j < m {
+					cost[i][j] = min(cost[i][j], cost[i][j+1])
+				}
+				continue
+			}
+
+			c := cost[i+1][j] + 1
+			if j < m {
+				c = min(c, cost[i][j+1]+1)
+				if match(want[i], got[j]) {
+					c = min(c, cost[i+1][j+1])
+				}
+			}
+			cost[i][j] = c
+		}
+	}
+
+	var ops []diffOp
+
+	i, j := 0, 0
+	for i < n || j < m {
+		switch {
+		case i < n && want[i] == "...":
+			if j < m && cost[i][j] == cost[i][j+1] {
+				ops = append(ops, diffOp{' ', got[j]})
+				j++
+				continue
+			}
+			i++
+		case i < n && j < m && match(want[i], got[j]) && cost[i][j] == cost[i+1][j+1]:
+			ops = append(ops, diffOp{' ', got[j]})
+			i++
+			j++
+		case i < n && cost[i][j] == cost[i+1][j]+1:
+			ops = append(ops, diffOp{'-', want[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', got[j]})
+			j++
+		}
+	}
+
+	return ops
+}
+
+// diffOutput returns a unified diff, of the expected output,
+// from the golden file name, and the actual output. Lines
+// that match placeholders are not changes.
+func diffOutput(name, want, got string) string {
+	want, got = normalizeOutput(want), normalizeOutput(got)
+
+	match, _ := newLineMatcher()
+	ops := alignOutput(splitLines(want), splitLines(got), match)
+
+	const context = 3
+
+	bb := &strings.Builder{}
+	fmt.Fprintf(bb, "--- %s\n+++ actual\n", name)
+
+	// line numbers, in want and got, of each op
+	type pos struct{ a, b int }
+	at := make([]pos, len(ops)+1)
+	for k, op := range ops {
+		at[k+1] = at[k]
+		if op.kind != '+' {
+			at[k+1].a++
+		}
+		if op.kind != '-' {
+			at[k+1].b++
+		}
+	}
+
+	for k := 0; k < len(ops); {
+		if ops[k].kind == ' ' {
+			k++
+			continue
+		}
+
+		// a hunk, from context lines before the change,
+		// to context lines after the last change near it
+		start := max(k-context, 0)
+		end := k
+		for end < len(ops) {
+			if ops[end].kind != ' ' {
+				end++
+				continue
+			}
+
+			next := end
+			for next < len(ops) && ops[next].kind == ' ' {
+				next++
+			}
+
+			if next == len(ops) || next-end > 2*context {
+				end = min(end+context, len(ops))
+				break
+			}
+
+			end = next
+		}
+
+		fmt.Fprintf(bb, "@@ -%s +%s @@\n",
+			hunkRange(at[start].a, at[end].a-at[start].a),
+			hunkRange(at[start].b, at[end].b-at[start].b))
+
+		for _, op := range ops[start:end] {
+			fmt.Fprintf(bb, "%c%s\n", op.kind, op.line)
+		}
+
+		k = end
+	}
+
+	return bb.String()
+}
+
+func splitLines(s string) []string {
+	if len(s) == 0 {
+		return nil
+	}
+
+	return strings.Split(s, "\n")
+}
+
+// hunkRange formats the start, and length, of
+// the lines of a hunk, as unified diffs do.
+func hunkRange(start, n int) string {
+	switch n {
+	case 0:
+		return fmt.Sprintf("%d,0", start)
+	case 1:
+		return fmt.Sprintf("%d", start+1)
+	}
+
+	return fmt.Sprintf("%d,%d", start+1, n)
+}
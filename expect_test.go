@@ -0,0 +1,104 @@
+package hype
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_matchOutput(t *testing.T) {
+	t.Parallel()
+
+	table := []struct {
+		name string
+		want string
+		got  string
+		ok   bool
+	}{
+		{name: "equal", want: "a\nb", got: "a\nb", ok: true},
+		{name: "line endings", want: "a\r\nb  \n\n", got: "\na\nb", ok: true},
+		{name: "different", want: "a\nb", got: "a\nc"},
+		{name: "missing line", want: "a\nb", got: "a"},
+		{name: "any", want: "go: [..] done", got: "go: downloading x done", ok: true},
+		{name: "any lines", want: "start\n...\nend", got: "start\n1\n2\nend", ok: true},
+		{name: "no lines", want: "start\n...\nend", got: "start\nend", ok: true},
+		{name: "duration", want: "ok pkg [DURATION]", got: "ok pkg 1m2.5s", ok: true},
+		{name: "not duration", want: "ok pkg [DURATION]", got: "ok pkg fast"},
+		{name: "number", want: "[NUMBER] files", got: "-12.5 files", ok: true},
+		{name: "path", want: "wrote [PATH]", got: `wrote C:\tmp\out.txt`, ok: true},
+		{name: "regex", want: "id: [re:[a-f0-9]{8}]", got: "id: 0a1b2c3d", ok: true},
+		{name: "regex mismatch", want: "id: [re:[a-f0-9]{8}]", got: "id: xyz"},
+		{name: "literal", want: "a.b (c) [x]", got: "a.b (c) [x]", ok: true},
+	}
+
+	for _, tt := range table {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			r := require.New(t)
+
+			ok, err := matchOutput(tt.want, tt.got)
+			r.NoError(err)
+			r.Equal(tt.ok, ok)
+		})
+	}
+}
+
+func Test_matchOutput_Errors(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	_, err := matchOutput("[re:(]", "x")
+	r.Error(err)
+	r.Contains(err.Error(), "invalid placeholder")
+
+	_, err = matchOutput("[re:abc", "x")
+	r.Error(err)
+	r.Contains(err.Error(), "unterminated placeholder")
+}
+
+func Test_diffOutput(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	want := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12"
+	got := "1\n2\n3\n4\nfive\n6\n7\n8\n9\n10\n11\n12\n13"
+
+	exp := `--- golden.txt
++++ actual
+@@ -2,7 +2,7 @@
+ 2
+ 3
+ 4
+-5
++five
+ 6
+ 7
+ 8
+@@ -10,3 +10,4 @@
+ 10
+ 11
+ 12
++13
+`
+	r.Equal(exp, diffOutput("golden.txt", want, got))
+
+	// placeholders are not differences
+	exp = `--- golden.txt
++++ actual
+@@ -1,3 +1,4 @@
+ took 1s
+ a
+-c
++b
++d
+`
+	r.Equal(exp, diffOutput("golden.txt", "took [DURATION]\na\nc", "took 1s\na\nb\nd"))
+
+	exp = `--- golden.txt
++++ actual
+@@ -0,0 +1 @@
++hello
+`
+	r.Equal(exp, diffOutput("golden.txt", "", "hello"))
+}
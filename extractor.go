@@ -0,0 +1,96 @@
+package hype
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// Extractor finds a named symbol, such as a function, method,
+// or type, in the source code of a file.
+//
+// It is used by <code src="..." symbol="..."> tags to include
+// a piece of a file without snippet markers in the source.
+// The returned Snippet must have its Start and End set to the
+// first, and last, line of the symbol in the file.
+type Extractor interface {
+	Extract(filename string, src []byte, symbol string) (Snippet, error)
+}
+
+// ExtractorFn is a function that implements the Extractor interface.
+type ExtractorFn func(filename string, src []byte, symbol string) (Snippet, error)
+
+func (fn ExtractorFn) Extract(filename string, src []byte, symbol string) (Snippet, error) {
+	return fn(filename, src, symbol)
+}
+
+// DefaultExtractors returns the built-in extractors,
+// keyed by file extension: GoExtractor for ".go" files.
+func DefaultExtractors() map[string]Extractor {
+	return map[string]Extractor{
+		".go": GoExtractor{},
+	}
+}
+
+// ErrExtractorNotFound is returned when a <code symbol> tag
+// refers to a file with no Extractor configured for its extension.
+type ErrExtractorNotFound string
+
+func (e ErrExtractorNotFound) Error() string {
+	return fmt.Sprintf("no symbol extractor configured for %q files", string(e))
+}
+
+// SymbolError is returned by an Extractor when a symbol
+// can not be found in a file. Line and Col, if set, are
+// the position of the closest match, such as the type
+// of a missing method. If there is no match, the document
+// sets File, Line, and Col to the position of the <code>
+// tag that asked for the symbol.
+type SymbolError struct {
+	File   string
+	Symbol string
+	Line   int
+	Col    int
+	Msg    string // optional; default: symbol %q not found
+}
+
+func (e SymbolError) Error() string {
+	msg := e.Msg
+	if len(msg) == 0 {
+		msg = fmt.Sprintf("symbol %q not found", e.Symbol)
+	}
+
+	if e.Line > 0 {
+		return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Line, e.Col, msg)
+	}
+
+	return fmt.Sprintf("%s: %s", e.File, msg)
+}
+
+// Extractor returns the extractor for the file extension
+// of filename.
+//
+// If Parser.Extractors is nil, only the GoExtractor is available.
+func (p *Parser) Extractor(filename string) (Extractor, error) {
+	if p == nil {
+		return nil, ErrIsNil("parser")
+	}
+
+	ext := filepath.Ext(filename)
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.Extractors == nil {
+		if ext == ".go" {
+			return GoExtractor{}, nil
+		}
+		return nil, ErrExtractorNotFound(ext)
+	}
+
+	x, ok := p.Extractors[ext]
+	if !ok || x == nil {
+		return nil, ErrExtractorNotFound(ext)
+	}
+
+	return x, nil
+}
@@ -0,0 +1,81 @@
+package hype
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Parser_Extractor(t *testing.T) {
+	t.Parallel()
+
+	table := []struct {
+		name    string
+		p       *Parser
+		file    string
+		err     bool
+		expType any
+	}{
+		{name: "go", p: NewParser(nil), file: "main.go", expType: GoExtractor{}},
+		{name: "unknown", p: NewParser(nil), file: "main.rs", err: true},
+		{name: "nil extractors", p: &Parser{}, file: "main.go", expType: GoExtractor{}},
+		{name: "nil extractors unknown", p: &Parser{}, file: "main.rs", err: true},
+		{name: "go removed", p: &Parser{
+			Extractors: map[string]Extractor{".rs": ExtractorFn(nil)},
+		}, file: "main.go", err: true},
+	}
+
+	for _, tt := range table {
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+
+			x, err := tt.p.Extractor(tt.file)
+			if tt.err {
+				r.Error(err)
+				r.True(errors.As(err, new(ErrExtractorNotFound)))
+				return
+			}
+
+			r.NoError(err)
+			r.IsType(tt.expType, x)
+		})
+	}
+}
+
+func Test_SourceCode_Symbol_Extractor(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	cab := fstest.MapFS{
+		"hype.md":    &fstest.MapFile{Data: []byte("# Hello\n\n<code src=\"src/lib.rs\" symbol=\"greet\"></code>\n")},
+		"src/lib.rs": &fstest.MapFile{Data: []byte("fn greet() -> &'static str {\n    \"hi\"\n}\n")},
+	}
+
+	p := NewParser(cab)
+	p.Extractors[".rs"] = ExtractorFn(func(filename string, src []byte, symbol string) (Snippet, error) {
+		s := string(src)
+		if !strings.Contains(s, "fn "+symbol) {
+			return Snippet{}, SymbolError{File: filename, Symbol: symbol}
+		}
+
+		return Snippet{Content: strings.TrimSpace(s), File: filename, Name: symbol, Start: 1, End: 3}, nil
+	})
+
+	doc, err := p.ParseExecuteFile(context.Background(), "hype.md")
+	r.NoError(err)
+
+	act := doc.String()
+	r.Contains(act, `language="rs"`)
+	r.Contains(act, "fn greet() -&gt; &amp;&#39;static str {")
+
+	cab["hype.md"] = &fstest.MapFile{Data: []byte("# Hello\n\n<code src=\"src/lib.rs\" symbol=\"wave\"></code>\n")}
+
+	_, err = p.ParseExecuteFile(context.Background(), "hype.md")
+	r.Error(err)
+	r.True(errors.As(err, new(SymbolError)))
+	r.Contains(err.Error(), `hype.md:3:1: symbol "wave" not found in src/lib.rs`)
+}
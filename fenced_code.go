@@ -44,6 +44,7 @@ func (code *FencedCode) MD() string {
 
 	body := code.Children().MD()
 	body = html.UnescapeString(body)
+	body = strings.TrimSuffix(body, "\n")
 
 	// Choose fence that doesn't appear in content
 	// Per CommonMark spec, tildes and backticks ignore each other
@@ -57,7 +58,9 @@ func (code *FencedCode) MD() string {
 		}
 	}
 
-	fmt.Fprintf(bb, "%s%s\n", fence, code.Lang())
+	// code blocks without a language are
+	// rendered without an info string
+	fmt.Fprintf(bb, "%s%s\n", fence, Language(code.Attrs(), ""))
 	fmt.Fprintln(bb, body)
 	fmt.Fprint(bb, fence)
 
@@ -2,6 +2,7 @@ package hype
 
 import (
 	"encoding/json"
+	"errors"
 	"strings"
 )
 
@@ -32,12 +33,7 @@ func (fc *Figcaption) MD() string {
 		return ""
 	}
 
-	bb := &strings.Builder{}
-	bb.WriteString("> *")
-	bb.WriteString(fc.Nodes.MD())
-	bb.WriteString("*")
-
-	return bb.String()
+	return mdBlock(fc)
 }
 
 func NewFigcaption(el *Element) (*Figcaption, error) {
@@ -58,5 +54,25 @@ func NewFigcaptionNodes(p *Parser, el *Element) (Nodes, error) {
 		return nil, err
 	}
 
+	if p == nil {
+		return Nodes{f}, nil
+	}
+
+	// a <figcaption> on a single line is an HTML block,
+	// so its Markdown has not been rendered yet
+	body := f.Children().String()
+	if len(strings.TrimSpace(body)) == 0 {
+		return Nodes{f}, nil
+	}
+
+	nodes, err := p.ParseFragment(strings.NewReader(body))
+	if err != nil {
+		if !errors.Is(err, ErrNilFigure) {
+			return nil, f.WrapErr(err)
+		}
+	}
+
+	f.Nodes = nodes
+
 	return Nodes{f}, nil
 }
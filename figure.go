@@ -15,6 +15,7 @@ type Figure struct {
 	Pos       int
 	SectionID int
 
+	label string // the id the figure was written with
 	style string
 }
 
@@ -44,14 +45,7 @@ func (f *Figure) MD() string {
 		return ""
 	}
 
-	bb := &strings.Builder{}
-	// bb.WriteString(f.StartTag())
-	fmt.Fprintf(bb, "<a id=%q></a>\n", strings.TrimPrefix(f.Link(), "#"))
-	fmt.Fprintln(bb)
-	bb.WriteString(f.Nodes.MD())
-	// bb.WriteString(f.EndTag())
-
-	return bb.String()
+	return mdFigure(f)
 }
 
 // Style returns type of the figure.
@@ -64,6 +58,17 @@ func (f *Figure) Style() string {
 	return f.style
 }
 
+// Label returns the id the figure was written with, before
+// it was restriped. Refs in other documents of a book refer
+// to the figure by its label.
+func (f *Figure) Label() string {
+	if f == nil {
+		return ""
+	}
+
+	return f.label
+}
+
 func (f *Figure) Name() string {
 	if f == nil {
 		return ""
@@ -96,10 +101,13 @@ func NewFigure(p *Parser, el *Element) (*Figure, error) {
 		Element: el,
 	}
 
-	if _, err := f.ValidAttr("id"); err != nil {
+	id, err := f.ValidAttr("id")
+	if err != nil {
 		return nil, err
 	}
 
+	f.label = id
+
 	f.style = "figure"
 	style, ok := f.Get("type")
 	style = strings.TrimSpace(style)
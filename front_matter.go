@@ -0,0 +1,269 @@
+package hype
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/gopherguides/hype/atomx"
+	"github.com/markbates/syncx"
+	"gopkg.in/yaml.v3"
+)
+
+// FrontMatter returns a pre-parser for the front matter at the
+// start of a document: YAML between "---" lines, or TOML between
+// "+++" lines.
+//
+//	---
+//	title: Getting Started
+//	published: 2024-01-02
+//	tags: [go, testing]
+//	---
+//
+// Values keep their types, such as lists, dates, and nested maps.
+// Each key is set in Parser.Vars, unless it is already set, for
+// use by GoTemplates and <var>. The front matter is kept in
+// Document.FrontMatter, and merged into the document's metadata.
+func FrontMatter() PreParseFn {
+	fn := func(p *Parser, r io.Reader) (io.Reader, error) {
+		if p == nil {
+			return nil, ErrIsNil("parser")
+		}
+
+		if r == nil {
+			return nil, ErrIsNil("reader")
+		}
+
+		b, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+
+		// front matter is only at the start of
+		// a file, not of a fragment of one
+		if !bytes.Equal(b, p.Contents) {
+			return bytes.NewReader(b), nil
+		}
+
+		fm, ok := splitFrontMatter(b)
+		if !ok {
+			return bytes.NewReader(b), nil
+		}
+
+		data, err := fm.decode()
+		if err != nil {
+			pos := Pos{
+				File: filepath.Join(p.Root, p.Filename),
+				Line: 1 + fm.errLine(err),
+				Col:  1,
+			}
+
+			return nil, ParseError{
+				Err:      fmt.Errorf("invalid %s front matter: %w", fm.format, err),
+				Filename: p.Filename,
+				Root:     p.Root,
+				Contents: p.Contents,
+				Pos:      pos,
+			}
+		}
+
+		for k, v := range data {
+			if _, ok := p.Vars.Get(k); ok {
+				continue
+			}
+
+			if err := p.Vars.Set(k, v); err != nil {
+				return nil, err
+			}
+		}
+
+		fm.data = data
+
+		p.mu.Lock()
+		p.frontMatter = fm
+		p.mu.Unlock()
+
+		return bytes.NewReader(b[fm.end:]), nil
+	}
+
+	return fn
+}
+
+// frontMatter is the front matter found at the start of a file.
+type frontMatter struct {
+	format string // "yaml" or "toml"
+	raw    string // the front matter, with its delimiters
+	body   string // the front matter, without its delimiters
+	end    int    // offset of the content after the front matter
+	data   map[string]any
+}
+
+// splitFrontMatter returns the front matter at the start of src, if any.
+func splitFrontMatter(src []byte) (*frontMatter, bool) {
+	line, rest, ok := bytes.Cut(src, []byte("\n"))
+	if !ok {
+		return nil, false
+	}
+
+	fm := &frontMatter{}
+
+	closers := []string{}
+	switch string(bytes.TrimRight(line, " \t\r")) {
+	case "---":
+		fm.format = "yaml"
+		closers = append(closers, "---", "...")
+	case "+++":
+		fm.format = "toml"
+		closers = append(closers, "+++")
+	default:
+		return nil, false
+	}
+
+	off := len(line) + 1
+	start := off
+
+	for len(rest) > 0 {
+		line, rest, _ = bytes.Cut(rest, []byte("\n"))
+
+		for _, c := range closers {
+			if string(bytes.TrimRight(line, " \t\r")) != c {
+				continue
+			}
+
+			fm.body = string(src[start:off])
+			fm.end = min(off+len(line)+1, len(src))
+			fm.raw = string(src[:fm.end])
+
+			return fm, true
+		}
+
+		off += len(line) + 1
+	}
+
+	return nil, false
+}
+
+func (fm *frontMatter) decode() (map[string]any, error) {
+	data := map[string]any{}
+
+	switch fm.format {
+	case "toml":
+		if _, err := toml.Decode(fm.body, &data); err != nil {
+			return nil, err
+		}
+	default:
+		if err := yaml.Unmarshal([]byte(fm.body), &data); err != nil {
+			return nil, err
+		}
+	}
+
+	return data, nil
+}
+
+var yamlLineRx = regexp.MustCompile(`line (\d+):`)
+
+// errLine returns the line, in the body of the front
+// matter, of an error decoding it, or 0 if it is not known.
+func (fm *frontMatter) errLine(err error) int {
+	var te toml.ParseError
+	if errors.As(err, &te) {
+		return te.Position.Line
+	}
+
+	if m := yamlLineRx.FindStringSubmatch(err.Error()); len(m) > 1 {
+		n, _ := strconv.Atoi(m[1])
+		return n
+	}
+
+	return 0
+}
+
+// mergeFrontMatter adds the front matter of the document to the
+// metadata of its first page, replacing any keys already set by
+// a <metadata> tag. If the page has no metadata, it is added
+// to the <head> of the document.
+func (doc *Document) mergeFrontMatter() error {
+	if doc == nil || len(doc.FrontMatter) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(doc.FrontMatter))
+	for k := range doc.FrontMatter {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var md *Metadata
+	if mds := firstPageMetadata(doc); len(mds) > 0 {
+		md = mds[0]
+	}
+
+	if md == nil {
+		heads := ByAtom(doc.Nodes, atomx.Head)
+		if len(heads) == 0 {
+			return nil
+		}
+
+		head, ok := heads[0].(*Element)
+		if !ok {
+			return fmt.Errorf("head is not an element: %T", heads[0])
+		}
+
+		md = &Metadata{
+			Element: NewEl(atomx.Metadata, head),
+			Map:     syncx.Map[string, string]{},
+		}
+
+		head.Nodes = append(head.Nodes, md)
+	}
+
+	for _, k := range keys {
+		if err := md.Set(k, valueString(doc.FrontMatter[k])); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// valueString returns the value of a var, or front matter
+// key, as text: lists are joined with ", ", dates without
+// a time are formatted as "2006-01-02", and maps as JSON.
+func valueString(v any) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	case time.Time:
+		if x.Hour() == 0 && x.Minute() == 0 && x.Second() == 0 && x.Nanosecond() == 0 {
+			return x.Format("2006-01-02")
+		}
+		return x.Format(time.RFC3339)
+	case []any:
+		s := make([]string, 0, len(x))
+		for _, e := range x {
+			s = append(s, valueString(e))
+		}
+		return strings.Join(s, ", ")
+	case []string:
+		return strings.Join(x, ", ")
+	case map[string]any:
+		b, err := json.Marshal(x)
+		if err != nil {
+			return fmt.Sprint(x)
+		}
+		return string(b)
+	}
+
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,188 @@
+package hype
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_FrontMatter(t *testing.T) {
+	t.Parallel()
+
+	table := []struct {
+		format string
+		line   int // line of the heading
+	}{
+		{format: "yaml", line: 9},
+		{format: "toml", line: 10},
+	}
+
+	for _, tt := range table {
+		t.Run(tt.format, func(t *testing.T) {
+			t.Parallel()
+			r := require.New(t)
+
+			p := testParser(t, "testdata/front-matter/"+tt.format)
+
+			doc, err := p.ParseFile("hype.md")
+			r.NoError(err)
+
+			r.NoError(doc.Execute(context.Background()))
+
+			// typed values
+			fm := doc.FrontMatter
+			r.Equal("Front Matter", fm["title"])
+			r.Equal([]any{"go", "testing"}, fm["tags"])
+			r.Equal(map[string]any{"name": "Jane"}, fm["author"])
+
+			pub, ok := fm["published"].(time.Time)
+			r.True(ok, "%T", fm["published"])
+			r.Equal("2024-01-02", pub.Format("2006-01-02"))
+
+			v, ok := p.Vars.Get("tags")
+			r.True(ok)
+			r.Equal(fm["tags"], v)
+
+			// GoTemplates and <var>
+			act := doc.String()
+			r.Contains(act, "<h1>Front Matter</h1>")
+			r.Contains(act, "By Jane, tagged")
+			r.NotContains(act, "title:")
+			r.NotContains(act, "title =")
+
+			// metadata
+			meta := ExtractMeta(doc)
+			r.Equal("2024-01-02", meta.Metadata["published"])
+			r.Equal("go, testing", meta.Metadata["tags"])
+			r.Equal(`{"name":"Jane"}`, meta.Metadata["author"])
+			r.Contains(act, `<meta title="Front Matter">`)
+
+			// positions are in the original file
+			h, ok := FirstByType[*Heading](doc.Nodes)
+			r.True(ok)
+			r.Equal(tt.line, h.Position().Line)
+		})
+	}
+}
+
+func Test_FrontMatter_MD(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	p := testParser(t, "testdata/front-matter/yaml")
+
+	doc, err := p.ParseFile("hype.md")
+	r.NoError(err)
+	r.NoError(doc.Execute(context.Background()))
+
+	act := doc.MD()
+	r.True(strings.HasPrefix(act, "---\ntitle: Front Matter\n"), act)
+	r.Contains(act, "  name: Jane\n---\n")
+	r.Contains(act, "# Front Matter")
+	r.NotContains(act, "<metadata>")
+}
+
+func Test_FrontMatter_Metadata(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	src := `---
+author: Jane
+---
+
+<metadata>
+author: John
+slug: my-post
+</metadata>
+
+# Hello
+`
+
+	p := testParser(t, "testdata")
+
+	doc, err := p.Parse(strings.NewReader(src))
+	r.NoError(err)
+
+	mds := ByType[*Metadata](doc.Nodes)
+	r.Len(mds, 1)
+
+	author, _ := mds[0].Get("author")
+	r.Equal("Jane", author)
+
+	slug, _ := mds[0].Get("slug")
+	r.Equal("my-post", slug)
+}
+
+func Test_FrontMatter_Vars(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	src := "---\ntitle: From Front Matter\n---\n\n# {{ .title }}\n"
+
+	p := testParser(t, "testdata")
+	r.NoError(p.Vars.Set("title", "From Vars"))
+
+	doc, err := p.Parse(strings.NewReader(src))
+	r.NoError(err)
+
+	r.Contains(doc.String(), "<h1>From Vars</h1>")
+	r.Equal("From Front Matter", doc.FrontMatter["title"])
+}
+
+func Test_FrontMatter_Not(t *testing.T) {
+	t.Parallel()
+
+	table := []struct {
+		name string
+		src  string
+	}{
+		{name: "page break", src: "# Title\n\n---\n\nText\n\n---\n"},
+		{name: "unclosed", src: "---\n\n# Title\n"},
+	}
+
+	for _, tt := range table {
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+
+			p := testParser(t, "testdata")
+
+			doc, err := p.Parse(strings.NewReader(tt.src))
+			r.NoError(err)
+			r.Nil(doc.FrontMatter)
+			r.Contains(doc.String(), "<h1>Title</h1>")
+		})
+	}
+}
+
+func Test_FrontMatter_Error(t *testing.T) {
+	t.Parallel()
+
+	table := []struct {
+		name string
+		src  string
+		line int
+	}{
+		{name: "yaml", src: "---\ntitle: ok\nfoo: : bar\n---\n\n# Title\n", line: 3},
+		{name: "toml", src: "+++\ntitle = \"ok\"\ntags = \n+++\n\n# Title\n", line: 3},
+	}
+
+	for _, tt := range table {
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+
+			p := testParser(t, "testdata")
+			p.Filename = "hype.md"
+
+			_, err := p.Parse(strings.NewReader(tt.src))
+			r.Error(err)
+			r.Contains(err.Error(), "invalid "+tt.name+" front matter")
+
+			pos, ok := ErrPos(err)
+			r.True(ok)
+			r.Equal(tt.line, pos.Line)
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package hype
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+
+	"github.com/markbates/sweets"
+)
+
+// GoExtractor extracts symbols from Go source files using go/ast.
+//
+// A symbol is either the name of a top level function, type,
+// const, or var, such as "NewServer", or a method named
+// by its receiver type, such as "Server.Run".
+//
+// The doc comment of the symbol is included. A const or var
+// declared in a group is extracted with the whole group, while
+// a type declared in a group is extracted on its own.
+type GoExtractor struct{}
+
+func (GoExtractor) Extract(filename string, src []byte, symbol string) (Snippet, error) {
+	fset := token.NewFileSet()
+
+	file, err := parser.ParseFile(fset, filename, src, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return Snippet{}, err
+	}
+
+	recv, name, isMethod := strings.Cut(symbol, ".")
+	if !isMethod {
+		name = recv
+		recv = ""
+	}
+
+	if len(name) == 0 || len(recv) == 0 && isMethod {
+		return Snippet{}, SymbolError{
+			File:   filename,
+			Symbol: symbol,
+			Msg:    fmt.Sprintf("invalid symbol %q", symbol),
+		}
+	}
+
+	var node ast.Node
+	var doc *ast.CommentGroup
+	var typ *ast.TypeSpec
+
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Name.Name != name || receiverName(d) != recv {
+				continue
+			}
+			node, doc = d, d.Doc
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					if isMethod {
+						if s.Name.Name == recv {
+							typ = s
+						}
+						continue
+					}
+
+					if s.Name.Name != name {
+						continue
+					}
+
+					node, doc = d, d.Doc
+					if d.Lparen.IsValid() {
+						node, doc = s, s.Doc
+					}
+				case *ast.ValueSpec:
+					if isMethod {
+						continue
+					}
+
+					for _, id := range s.Names {
+						if id.Name == name {
+							node, doc = d, d.Doc
+						}
+					}
+				}
+			}
+		}
+
+		if node != nil {
+			break
+		}
+	}
+
+	if node == nil {
+		se := SymbolError{
+			File:   filename,
+			Symbol: symbol,
+		}
+
+		if typ != nil {
+			pos := fset.Position(typ.Pos())
+			se.Line = pos.Line
+			se.Col = pos.Column
+			se.Msg = fmt.Sprintf("type %s has no method %q", recv, name)
+		}
+
+		return Snippet{}, se
+	}
+
+	begin := node.Pos()
+	if doc != nil {
+		begin = doc.Pos()
+	}
+
+	start := fset.Position(begin)
+	end := fset.Position(node.End())
+
+	// start at the beginning of the line so that
+	// indented symbols, in a group, can be dedented
+	from := start.Offset - (start.Column - 1)
+
+	content := string(src[from:end.Offset])
+
+	// a type in a group is missing its keyword
+	if ts, ok := node.(*ast.TypeSpec); ok {
+		at := fset.Position(ts.Pos()).Offset - from
+		content = content[:at] + "type " + content[at:]
+		content = sweets.TrimLeftSpace(content)
+	}
+
+	snip := Snippet{
+		Content: content,
+		File:    filename,
+		Lang:    "go",
+		Name:    symbol,
+		Start:   start.Line,
+		End:     end.Line,
+	}
+
+	return snip, nil
+}
+
+// receiverName returns the name of the receiver type of fn,
+// without any pointer or type parameters, or "" for functions.
+func receiverName(fn *ast.FuncDecl) string {
+	if fn.Recv == nil || len(fn.Recv.List) == 0 {
+		return ""
+	}
+
+	expr := fn.Recv.List[0].Type
+	for {
+		switch x := expr.(type) {
+		case *ast.StarExpr:
+			expr = x.X
+		case *ast.IndexExpr:
+			expr = x.X
+		case *ast.IndexListExpr:
+			expr = x.X
+		case *ast.ParenExpr:
+			expr = x.X
+		case *ast.Ident:
+			return x.Name
+		default:
+			return ""
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package hype
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_GoExtractor_Extract(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	fn := "testdata/to_md/source_code/symbol/src/server.go"
+	src, err := os.ReadFile(fn)
+	r.NoError(err)
+
+	table := []struct {
+		symbol string
+		start  int
+		end    int
+		exp    string
+	}{
+		{symbol: "main", start: 37, end: 37, exp: "func main() {}"},
+		{symbol: "DefaultName", start: 5, end: 8, exp: "const (\n\tDefaultAddr = \":3000\"\n\tDefaultName = \"gopher\"\n)"},
+		{symbol: "Server", start: 19, end: 23, exp: "// Server serves gophers.\ntype Server struct {\n\tOptions\n\thandlers []Handler\n}"},
+		{symbol: "Options", start: 11, end: 14, exp: "// Options configure a Server.\ntype Options struct {\n\tAddr string\n}"},
+		{symbol: "Handler", start: 16, end: 16, exp: "type Handler func(ctx context.Context) error"},
+		{symbol: "Server.Run", start: 25, end: 35, exp: "// Run runs the server until the context is done.\nfunc (s *Server) Run(ctx context.Context) error {"},
+	}
+
+	for _, tt := range table {
+		t.Run(tt.symbol, func(t *testing.T) {
+			r := require.New(t)
+
+			snip, err := GoExtractor{}.Extract(fn, src, tt.symbol)
+			r.NoError(err)
+
+			r.Equal("go", snip.Lang)
+			r.Equal(tt.symbol, snip.Name)
+			r.Equal(fn, snip.File)
+			r.Equal(tt.start, snip.Start)
+			r.Equal(tt.end, snip.End)
+			r.Contains(snip.Content, tt.exp)
+		})
+	}
+}
+
+func Test_GoExtractor_Extract_Errors(t *testing.T) {
+	t.Parallel()
+
+	fn := "testdata/to_md/source_code/symbol/src/server.go"
+	src, err := os.ReadFile(fn)
+	require.NoError(t, err)
+
+	table := []struct {
+		name   string
+		src    []byte
+		symbol string
+		exp    string
+	}{
+		{name: "missing", src: src, symbol: "NewServer", exp: fn + `: symbol "NewServer" not found`},
+		{name: "missing method", src: src, symbol: "Server.Stop", exp: fn + `:20:6: type Server has no method "Stop"`},
+		{name: "method on missing type", src: src, symbol: "Client.Run", exp: fn + `: symbol "Client.Run" not found`},
+		{name: "method is not a func", src: src, symbol: "Run", exp: fn + `: symbol "Run" not found`},
+		{name: "invalid", src: src, symbol: ".Run", exp: fn + `: invalid symbol ".Run"`},
+		{name: "syntax error", src: []byte("package main\n\nfunc {"), symbol: "main", exp: fn + ":3:6"},
+	}
+
+	for _, tt := range table {
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+
+			_, err := GoExtractor{}.Extract(fn, tt.src, tt.symbol)
+			r.Error(err)
+			r.Contains(err.Error(), tt.exp)
+
+			if tt.name != "syntax error" {
+				r.True(errors.As(err, new(SymbolError)))
+			}
+		})
+	}
+}
@@ -33,9 +33,11 @@ func (h *Heading) MarshalJSON() ([]byte, error) {
 }
 
 func (h *Heading) MD() string {
-	x := strings.Repeat("#", h.level)
+	if h == nil || h.Element == nil {
+		return ""
+	}
 
-	return fmt.Sprintf("%s %s", x, h.Children().MD())
+	return mdHeading(h)
 }
 
 func (h *Heading) Level() int {
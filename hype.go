@@ -16,6 +16,7 @@ func DefaultElements() map[Atom]ParseElementFn {
 		atomx.Body:       NewBodyNodes,
 		atomx.Cmd:        NewCmdNodes,
 		atomx.Code:       NewCodeNodes,
+		atomx.Diagram:    NewDiagramNodes,
 		atomx.Figcaption: NewFigcaptionNodes,
 		atomx.Figure:     NewFigureNodes,
 		atomx.Go:         NewGolangNodes,
@@ -28,7 +29,9 @@ func DefaultElements() map[Atom]ParseElementFn {
 		atomx.Ol:         NewOLNodes,
 		atomx.P:          NewParagraphNodes,
 		atomx.Page:       NewPageNodes,
+		atomx.Pre:        NewPreNodes,
 		atomx.Ref:        NewRefNodes,
+		atomx.Session:    NewSessionNodes,
 		atomx.Table:      NewTableNodes,
 		atomx.Td:         NewTDNodes,
 		atomx.Th:         NewTHNodes,
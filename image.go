@@ -31,7 +31,7 @@ func (i *Image) MD() string {
 		return ""
 	}
 
-	return i.Element.String()
+	return mdImage(i)
 }
 
 func (i *Image) Execute(ctx context.Context, doc *Document) error {
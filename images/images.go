@@ -0,0 +1,526 @@
+// Package images generates resized, and converted, copies of the
+// local images of hype documents, for responsive HTML.
+//
+// Attributes of the <img> tag control the copies made of it:
+//
+//	<img src="assets/gopher.png" widths="480,960" quality="75" format="jpeg">
+//
+// The tag is given the intrinsic width and height of the image,
+// to prevent layout shift, and a srcset, and sizes, listing a copy
+// for each width that is smaller than the image; images are never
+// scaled up. Everything is done in Go, so JPEG, PNG, and GIF images
+// can be read and written. WebP images can be read, and are written
+// by an Encoder set on the Pipeline, as Go has no WebP encoder.
+// SVG images are copied. Other images can not be resized, or
+// converted, so asking for it is an error.
+package images
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gopherguides/hype"
+	_ "golang.org/x/image/webp"
+)
+
+// DefaultQuality is the JPEG quality used if none is set.
+const DefaultQuality = 80
+
+// Variant is a file generated for an image.
+type Variant struct {
+	Src     string // path of the image, in the file system of the document
+	Path    string // path of the variant, relative to the document
+	Width   int
+	Height  int
+	Format  string // "jpeg", "png", "gif", or the format of an Encoder; empty for images that are copied as is
+	Quality int    // JPEG, or Encoder, quality
+}
+
+// Encoder writes images in a format Go can read,
+// but not write, such as WebP.
+type Encoder interface {
+	Encode(w io.Writer, img image.Image, quality int) error
+}
+
+// EncoderFn is a function that implements the Encoder interface.
+type EncoderFn func(w io.Writer, img image.Image, quality int) error
+
+func (fn EncoderFn) Encode(w io.Writer, img image.Image, quality int) error {
+	return fn(w, img, quality)
+}
+
+// Pipeline generates the variants of the images of documents.
+// The attributes of an <img> tag take precedence over the
+// defaults of the pipeline.
+type Pipeline struct {
+	Widths  []int  // widths of the copies of images without a widths attribute
+	Quality int    // JPEG quality; default: DefaultQuality
+	Format  string // format to convert images to; default: the format of the image
+
+	// Encoders write the formats Go can not, such as "webp",
+	// by name. Without one, WebP images are only copied.
+	Encoders map[string]Encoder
+
+	mu    sync.Mutex
+	cache map[cacheKey]cached // encoded variants, so unchanged images are not encoded again
+}
+
+type cacheKey struct {
+	dir string
+	Variant
+}
+
+type cached struct {
+	mod  time.Time
+	data []byte
+}
+
+// Process sets the width, height, srcset, and sizes attributes of
+// the local images of doc, and returns the variants to write for
+// them, with Write. The widths, quality, and format attributes
+// are removed from the tags.
+func (p *Pipeline) Process(doc *hype.Document) ([]Variant, error) {
+	if p == nil {
+		return nil, hype.ErrIsNil("pipeline")
+	}
+
+	if doc == nil {
+		return nil, hype.ErrIsNil("document")
+	}
+
+	var res []Variant
+	seen := map[Variant]bool{}
+
+	for _, img := range hype.ByType[*hype.Image](doc.Nodes) {
+		vs, err := p.image(doc.FS, img)
+		if err != nil {
+			return nil, img.WrapErr(err)
+		}
+
+		for _, v := range vs {
+			if seen[v] {
+				continue
+			}
+			seen[v] = true
+			res = append(res, v)
+		}
+	}
+
+	return res, nil
+}
+
+func (p *Pipeline) image(fsys fs.FS, img *hype.Image) ([]Variant, error) {
+	src, err := img.ValidAttr("src")
+	if err != nil {
+		return nil, err
+	}
+
+	name, ok := localPath(src)
+	if !ok {
+		return nil, nil
+	}
+
+	widths, err := p.widths(img)
+	if err != nil {
+		return nil, err
+	}
+
+	quality, err := p.quality(img)
+	if err != nil {
+		return nil, err
+	}
+
+	format, _ := img.Get("format")
+
+	// the tag asks for its image to be resized, or converted,
+	// rather than the defaults of the pipeline
+	_, asked := img.Get("widths")
+	asked = asked || len(format) > 0
+
+	if len(format) == 0 {
+		format = p.Format
+	}
+
+	for _, k := range []string{"widths", "quality", "format"} {
+		img.Attrs().Delete(k)
+	}
+
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	cfg, srcFormat, err := image.DecodeConfig(f)
+	if errors.Is(err, image.ErrFormat) {
+		// not an image Go can read; SVG scales on its own,
+		// and other images are copied if the tag asks nothing of them
+		if isVector(name) || !asked {
+			return []Variant{{Src: name, Path: name}}, nil
+		}
+
+		return nil, fmt.Errorf("%s: unsupported image format; use jpeg, png, gif, or webp", name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	format, err = normalize(format, srcFormat)
+	if err != nil {
+		return nil, err
+	}
+
+	if format != "jpeg" && p.Encoders[format] == nil {
+		quality = 0
+	}
+
+	base := strings.TrimSuffix(name, path.Ext(name))
+
+	ext := path.Ext(name)
+	if format != srcFormat {
+		ext = extensions[format]
+	}
+
+	orig := Variant{
+		Src:     name,
+		Path:    base + ext,
+		Width:   cfg.Width,
+		Height:  cfg.Height,
+		Format:  format,
+		Quality: quality,
+	}
+
+	vs := []Variant{orig}
+
+	var srcset []string
+	for _, w := range widths {
+		if w >= cfg.Width {
+			continue
+		}
+
+		h := max(1, int(math.Round(float64(cfg.Height)*float64(w)/float64(cfg.Width))))
+
+		v := orig
+		v.Path = fmt.Sprintf("%s-%dw%s", base, w, ext)
+		v.Width = w
+		v.Height = h
+
+		vs = append(vs, v)
+		srcset = append(srcset, fmt.Sprintf("%s %dw", v.Path, w))
+	}
+
+	// an image that is copied as is needs no encoder
+	if len(vs) > 1 || format != srcFormat {
+		if err := p.writable(format); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	if err := img.Set("src", orig.Path); err != nil {
+		return nil, err
+	}
+
+	_, hasW := img.Get("width")
+	_, hasH := img.Get("height")
+	if !hasW && !hasH {
+		if err := img.Set("width", strconv.Itoa(cfg.Width)); err != nil {
+			return nil, err
+		}
+		if err := img.Set("height", strconv.Itoa(cfg.Height)); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(srcset) == 0 {
+		return vs, nil
+	}
+
+	srcset = append(srcset, fmt.Sprintf("%s %dw", orig.Path, cfg.Width))
+
+	if err := img.Set("srcset", strings.Join(srcset, ", ")); err != nil {
+		return nil, err
+	}
+
+	if _, ok := img.Get("sizes"); !ok {
+		sizes := fmt.Sprintf("(max-width: %dpx) 100vw, %dpx", cfg.Width, cfg.Width)
+		if err := img.Set("sizes", sizes); err != nil {
+			return nil, err
+		}
+	}
+
+	return vs, nil
+}
+
+// widths returns the widths of the copies of img, in order.
+func (p *Pipeline) widths(img *hype.Image) ([]int, error) {
+	s, ok := img.Get("widths")
+	if !ok {
+		ws := append([]int(nil), p.Widths...)
+		sort.Ints(ws)
+		return ws, nil
+	}
+
+	return ParseWidths(s)
+}
+
+func (p *Pipeline) quality(img *hype.Image) (int, error) {
+	s, ok := img.Get("quality")
+	if !ok {
+		if p.Quality > 0 {
+			return p.Quality, nil
+		}
+		return DefaultQuality, nil
+	}
+
+	q, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || q < 1 || q > 100 {
+		return 0, fmt.Errorf("invalid quality %q: must be 1-100", s)
+	}
+
+	return q, nil
+}
+
+// ParseWidths parses a comma separated list of widths,
+// such as "480,960", and returns them in order.
+func ParseWidths(s string) ([]int, error) {
+	var ws []int
+
+	for _, f := range strings.Split(s, ",") {
+		f = strings.TrimSpace(f)
+		if len(f) == 0 {
+			continue
+		}
+
+		w, err := strconv.Atoi(f)
+		if err != nil || w < 1 {
+			return nil, fmt.Errorf("invalid width %q", f)
+		}
+
+		ws = append(ws, w)
+	}
+
+	sort.Ints(ws)
+
+	return ws, nil
+}
+
+var extensions = map[string]string{
+	"gif":  ".gif",
+	"jpeg": ".jpg",
+	"png":  ".png",
+	"webp": ".webp",
+}
+
+// encoders are the formats Go can write.
+var encoders = map[string]bool{
+	"gif":  true,
+	"jpeg": true,
+	"png":  true,
+}
+
+// normalize returns the format to write an image, read
+// as src, in, or an error if it is not an image format.
+func normalize(format, src string) (string, error) {
+	format = strings.ToLower(strings.TrimSpace(format))
+
+	switch format {
+	case "":
+		format = src
+	case "jpg":
+		format = "jpeg"
+	}
+
+	if _, ok := extensions[format]; !ok {
+		return "", fmt.Errorf("can not write %s images; use jpeg, png, gif, or webp", format)
+	}
+
+	return format, nil
+}
+
+// writable returns an error if images can not be
+// written as format, by Go, or an Encoder.
+func (p *Pipeline) writable(format string) error {
+	if encoders[format] || p.Encoders[format] != nil {
+		return nil
+	}
+
+	return fmt.Errorf("can not write %s images: no encoder is set for it; use jpeg, png, or gif", format)
+}
+
+// isVector returns true if name is a vector image, which
+// needs no resizing, such as SVG.
+func isVector(name string) bool {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".svg", ".svgz":
+		return true
+	}
+
+	return false
+}
+
+// localPath returns the path, in the file system of the
+// document, of src, if it is an image of the document.
+func localPath(src string) (string, bool) {
+	if strings.Contains(src, "://") || strings.HasPrefix(src, "//") || strings.HasPrefix(src, "data:") {
+		return "", false
+	}
+
+	if strings.HasPrefix(src, "/") {
+		return "", false
+	}
+
+	name := path.Clean(src)
+	if name == ".." || strings.HasPrefix(name, "../") {
+		return "", false
+	}
+
+	return name, true
+}
+
+// Write writes the variants, of the images in fsys, to dir.
+// Files that are unchanged are not written again.
+func (p *Pipeline) Write(fsys fs.FS, dir string, vs []Variant) error {
+	if p == nil {
+		return hype.ErrIsNil("pipeline")
+	}
+
+	for _, v := range vs {
+		data, err := p.render(fsys, dir, v)
+		if err != nil {
+			return fmt.Errorf("%s: %w", v.Path, err)
+		}
+
+		dst := filepath.Join(dir, filepath.FromSlash(v.Path))
+
+		// the variant may be the image itself
+		if b, err := os.ReadFile(dst); err == nil && bytes.Equal(b, data) {
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return err
+		}
+
+		if err := os.WriteFile(dst, data, 0644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// render returns the contents of the variant.
+func (p *Pipeline) render(fsys fs.FS, dir string, v Variant) ([]byte, error) {
+	info, err := fs.Stat(fsys, v.Src)
+	if err != nil {
+		return nil, err
+	}
+
+	key := cacheKey{dir: dir, Variant: v}
+
+	p.mu.Lock()
+	c, ok := p.cache[key]
+	p.mu.Unlock()
+
+	if ok && c.mod.Equal(info.ModTime()) {
+		return c.data, nil
+	}
+
+	data, err := fs.ReadFile(fsys, v.Src)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(v.Format) > 0 {
+		data, err = convert(data, v, p.Encoders[v.Format])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	p.mu.Lock()
+	if p.cache == nil {
+		p.cache = map[cacheKey]cached{}
+	}
+	p.cache[key] = cached{mod: info.ModTime(), data: data}
+	p.mu.Unlock()
+
+	return data, nil
+}
+
+// convert resizes, and encodes, the image in data as v,
+// with enc, if it is set, or Go. An image that is already
+// the size, and format, of v is returned as is.
+func convert(data []byte, v Variant, enc Encoder) ([]byte, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Width == v.Width && format == v.Format {
+		return data, nil
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	if v.Width < cfg.Width {
+		img = resize(img, v.Width, v.Height)
+	}
+
+	bb := &bytes.Buffer{}
+
+	switch {
+	case enc != nil:
+		err = enc.Encode(bb, img, v.Quality)
+	case v.Format == "jpeg":
+		err = jpeg.Encode(bb, flatten(img), &jpeg.Options{Quality: v.Quality})
+	case v.Format == "png":
+		err = png.Encode(bb, img)
+	case v.Format == "gif":
+		err = gif.Encode(bb, img, nil)
+	default:
+		err = fmt.Errorf("can not write %s images", v.Format)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return bb.Bytes(), nil
+}
+
+// flatten draws images with transparency on white,
+// as JPEG images can not be transparent.
+func flatten(img image.Image) image.Image {
+	if o, ok := img.(interface{ Opaque() bool }); ok && o.Opaque() {
+		return img
+	}
+
+	b := img.Bounds()
+	dst := image.NewRGBA(b)
+	draw.Draw(dst, b, image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(dst, b, img, b.Min, draw.Over)
+
+	return dst
+}
@@ -0,0 +1,239 @@
+package images
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/gopherguides/hype"
+	"github.com/stretchr/testify/require"
+)
+
+// testPNG returns a w x h PNG image, with a transparent pixel.
+func testPNG(t testing.TB, w, h int) []byte {
+	t.Helper()
+
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	img.Set(0, 0, color.NRGBA{})
+
+	bb := &bytes.Buffer{}
+	require.NoError(t, png.Encode(bb, img))
+
+	return bb.Bytes()
+}
+
+// testWebP is a 1 x 1, lossless, WebP image.
+const testWebP = "UklGRhoAAABXRUJQVlA4TA0AAAAvAAAAEAcQERGIiP4HAA=="
+
+func testDoc(t testing.TB, md string) *hype.Document {
+	t.Helper()
+	r := require.New(t)
+
+	webp, err := base64.StdEncoding.DecodeString(testWebP)
+	r.NoError(err)
+
+	fsys := fstest.MapFS{
+		"hype.md":           {Data: []byte(md)},
+		"assets/gopher.png": {Data: testPNG(t, 200, 100)},
+		"assets/logo.svg":   {Data: []byte(`<svg xmlns="http://www.w3.org/2000/svg"></svg>`)},
+		"assets/dot.webp":   {Data: webp},
+		"assets/icon.bmp":   {Data: []byte("BM not really")},
+	}
+
+	p := hype.NewParser(fsys)
+
+	doc, err := p.ParseFile("hype.md")
+	r.NoError(err)
+
+	return doc
+}
+
+func Test_Pipeline_Process(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	doc := testDoc(t, `# Images
+
+<img src="./assets/gopher.png" alt="gopher" widths="480, 50,100" quality="70" format="jpg">
+
+![logo](assets/logo.svg)
+
+![remote](https://example.com/x.png)
+`)
+
+	p := &Pipeline{}
+
+	vs, err := p.Process(doc)
+	r.NoError(err)
+
+	exp := []Variant{
+		{Src: "assets/gopher.png", Path: "assets/gopher.jpg", Width: 200, Height: 100, Format: "jpeg", Quality: 70},
+		{Src: "assets/gopher.png", Path: "assets/gopher-50w.jpg", Width: 50, Height: 25, Format: "jpeg", Quality: 70},
+		{Src: "assets/gopher.png", Path: "assets/gopher-100w.jpg", Width: 100, Height: 50, Format: "jpeg", Quality: 70},
+		{Src: "assets/logo.svg", Path: "assets/logo.svg"},
+	}
+	r.Equal(exp, vs)
+
+	imgs := hype.ByType[*hype.Image](doc.Nodes)
+	r.Len(imgs, 3)
+
+	act := imgs[0].Attrs().Map()
+	r.Equal(map[string]string{
+		"src":    "assets/gopher.jpg",
+		"alt":    "gopher",
+		"width":  "200",
+		"height": "100",
+		"srcset": "assets/gopher-50w.jpg 50w, assets/gopher-100w.jpg 100w, assets/gopher.jpg 200w",
+		"sizes":  "(max-width: 200px) 100vw, 200px",
+	}, act)
+
+	_, ok := imgs[1].Get("width")
+	r.False(ok)
+
+	src, _ := imgs[2].Get("src")
+	r.Equal("https://example.com/x.png", src)
+}
+
+func Test_Pipeline_Process_Defaults(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	doc := testDoc(t, `<img src="assets/gopher.png" width="50">`)
+
+	p := &Pipeline{Widths: []int{120}}
+
+	vs, err := p.Process(doc)
+	r.NoError(err)
+	r.Len(vs, 2)
+	r.Equal("assets/gopher-120w.png", vs[1].Path)
+	r.Equal("png", vs[1].Format)
+	r.Zero(vs[1].Quality)
+
+	img := hype.ByType[*hype.Image](doc.Nodes)[0]
+
+	// an explicit size is kept
+	w, _ := img.Get("width")
+	r.Equal("50", w)
+	_, ok := img.Get("height")
+	r.False(ok)
+}
+
+func Test_Pipeline_Process_Errors(t *testing.T) {
+	t.Parallel()
+
+	table := []struct {
+		name string
+		md   string
+		err  string
+	}{
+		{name: "format", md: `<img src="assets/gopher.png" format="webp">`, err: "can not write webp images"},
+		{name: "quality", md: `<img src="assets/gopher.png" quality="0">`, err: "invalid quality"},
+		{name: "widths", md: `<img src="assets/gopher.png" widths="a">`, err: "invalid width"},
+		{name: "missing", md: `<img src="assets/missing.png">`, err: "missing.png"},
+		{name: "unsupported", md: `<img src="assets/icon.bmp" widths="50">`, err: "assets/icon.bmp: unsupported image format"},
+	}
+
+	for _, tt := range table {
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+
+			_, err := (&Pipeline{}).Process(testDoc(t, tt.md))
+			r.Error(err)
+			r.Contains(err.Error(), tt.err)
+		})
+	}
+}
+
+func Test_Pipeline_Write(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	doc := testDoc(t, `<img src="assets/gopher.png" widths="50"> <img src="assets/logo.svg">`)
+
+	p := &Pipeline{}
+
+	vs, err := p.Process(doc)
+	r.NoError(err)
+
+	dir := t.TempDir()
+	r.NoError(p.Write(doc.FS, dir, vs))
+
+	// the image is copied as is
+	b, err := os.ReadFile(filepath.Join(dir, "assets", "gopher.png"))
+	r.NoError(err)
+	r.Equal(testPNG(t, 200, 100), b)
+
+	b, err = os.ReadFile(filepath.Join(dir, "assets", "gopher-50w.png"))
+	r.NoError(err)
+
+	cfg, err := png.DecodeConfig(bytes.NewReader(b))
+	r.NoError(err)
+	r.Equal(50, cfg.Width)
+	r.Equal(25, cfg.Height)
+
+	r.FileExists(filepath.Join(dir, "assets", "logo.svg"))
+
+	// writing again uses the cache
+	r.Len(p.cache, 3)
+	r.NoError(p.Write(doc.FS, dir, vs))
+	r.Len(p.cache, 3)
+}
+
+func Test_Pipeline_WebP(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	doc := testDoc(t, `<img src="assets/dot.webp" format="png"> <img src="assets/gopher.png" widths="50" format="webp"> <img src="assets/icon.bmp">`)
+
+	p := &Pipeline{
+		Encoders: map[string]Encoder{
+			"webp": EncoderFn(func(w io.Writer, img image.Image, quality int) error {
+				_, err := fmt.Fprintf(w, "webp %d %d", img.Bounds().Dx(), quality)
+				return err
+			}),
+		},
+	}
+
+	vs, err := p.Process(doc)
+	r.NoError(err)
+
+	exp := []Variant{
+		{Src: "assets/dot.webp", Path: "assets/dot.png", Width: 1, Height: 1, Format: "png"},
+		{Src: "assets/gopher.png", Path: "assets/gopher.webp", Width: 200, Height: 100, Format: "webp", Quality: DefaultQuality},
+		{Src: "assets/gopher.png", Path: "assets/gopher-50w.webp", Width: 50, Height: 25, Format: "webp", Quality: DefaultQuality},
+		{Src: "assets/icon.bmp", Path: "assets/icon.bmp"},
+	}
+	r.Equal(exp, vs)
+
+	dir := t.TempDir()
+	r.NoError(p.Write(doc.FS, dir, vs))
+
+	// webp is read by Go
+	b, err := os.ReadFile(filepath.Join(dir, "assets", "dot.png"))
+	r.NoError(err)
+
+	cfg, err := png.DecodeConfig(bytes.NewReader(b))
+	r.NoError(err)
+	r.Equal(1, cfg.Width)
+
+	// and written by the encoder
+	b, err = os.ReadFile(filepath.Join(dir, "assets", "gopher-50w.webp"))
+	r.NoError(err)
+	r.Equal("webp 50 80", string(b))
+
+	// an image that is not resized, or converted, is copied
+	r.FileExists(filepath.Join(dir, "assets", "icon.bmp"))
+}
@@ -0,0 +1,98 @@
+package images
+
+import (
+	"image"
+	"image/draw"
+	"math"
+)
+
+// resize scales img down to w x h pixels. Each pixel is the
+// average of the pixels of img it covers, weighted by how
+// much of each it covers, which keeps fine detail from
+// turning into noise, as it can with nearest neighbor,
+// or bilinear, scaling.
+func resize(img image.Image, w, h int) *image.RGBA {
+	b := img.Bounds()
+
+	src, ok := img.(*image.RGBA)
+	if !ok || b.Min != (image.Point{}) {
+		src = image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+		draw.Draw(src, src.Bounds(), img, b.Min, draw.Src)
+	}
+
+	sw, sh := b.Dx(), b.Dy()
+
+	// scale the rows, then the columns, of the
+	// premultiplied colors, so alpha is averaged
+	// along with the colors it scales
+	xs := spans(sw, w)
+	ys := spans(sh, h)
+
+	tmp := make([]float64, w*sh*4)
+	for y := 0; y < sh; y++ {
+		row := src.Pix[y*src.Stride:]
+		for x, s := range xs {
+			var px [4]float64
+			for i, wt := range s.weights {
+				off := (s.start + i) * 4
+				for c := 0; c < 4; c++ {
+					px[c] += float64(row[off+c]) * wt
+				}
+			}
+			copy(tmp[(y*w+x)*4:], px[:])
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y, s := range ys {
+		for x := 0; x < w; x++ {
+			var px [4]float64
+			for i, wt := range s.weights {
+				off := ((s.start+i)*w + x) * 4
+				for c := 0; c < 4; c++ {
+					px[c] += tmp[off+c] * wt
+				}
+			}
+
+			off := y*dst.Stride + x*4
+			for c := 0; c < 4; c++ {
+				dst.Pix[off+c] = uint8(min(255, math.Round(px[c])))
+			}
+		}
+	}
+
+	return dst
+}
+
+// span is the range of source pixels covered by a
+// destination pixel, and how much of each is covered.
+type span struct {
+	start   int
+	weights []float64
+}
+
+// spans returns the span of each of the m pixels
+// n pixels are scaled down to.
+func spans(n, m int) []span {
+	scale := float64(n) / float64(m)
+
+	res := make([]span, m)
+	for i := range res {
+		lo := float64(i) * scale
+		hi := lo + scale
+
+		start := int(lo)
+		end := min(int(math.Ceil(hi)), n)
+
+		ws := make([]float64, end-start)
+		for j := start; j < end; j++ {
+			a := math.Max(lo, float64(j))
+			b := math.Min(hi, float64(j+1))
+			ws[j-start] = (b - a) / scale
+		}
+
+		res[i] = span{start: start, weights: ws}
+	}
+
+	return res
+}
@@ -0,0 +1,48 @@
+package images
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_resize(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	// a checkerboard averages to gray
+	img := image.NewGray(image.Rect(0, 0, 4, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			if (x+y)%2 == 0 {
+				img.SetGray(x, y, color.Gray{Y: 255})
+			}
+		}
+	}
+
+	dst := resize(img, 2, 2)
+	r.Equal(image.Rect(0, 0, 2, 2), dst.Bounds())
+
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 2; x++ {
+			r.Equal(color.RGBA{R: 128, G: 128, B: 128, A: 255}, dst.RGBAAt(x, y))
+		}
+	}
+}
+
+func Test_spans(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	// 3 pixels onto 2: the middle one is split
+	ss := spans(3, 2)
+	r.Len(ss, 2)
+
+	r.Equal(0, ss[0].start)
+	r.InDeltaSlice([]float64{2.0 / 3, 1.0 / 3}, ss[0].weights, 1e-9)
+
+	r.Equal(1, ss[1].start)
+	r.InDeltaSlice([]float64{1.0 / 3, 2.0 / 3}, ss[1].weights, 1e-9)
+}
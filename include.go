@@ -13,6 +13,9 @@ type Include struct {
 
 	dir string
 	pp  sync.Once
+
+	file     string // path of the included file, as used in positions
+	contents []byte // contents of the included file, for error excerpts
 }
 
 func (inc *Include) MarshalJSON() ([]byte, error) {
@@ -105,8 +108,10 @@ func NewInclude(p *Parser, el *Element) (*Include, error) {
 	}
 
 	inc := &Include{
-		Element: el,
-		dir:     sdir,
+		Element:  el,
+		dir:      sdir,
+		file:     filepath.Join(p2.Root, p2.Filename),
+		contents: p2.Contents,
 	}
 
 	inc.Nodes = body.Nodes
@@ -125,6 +130,8 @@ func NewInclude(p *Parser, el *Element) (*Include, error) {
 			Err:      err,
 			Filename: p.Filename,
 			Root:     p.Root,
+			Contents: p.Contents,
+			Pos:      el.Pos,
 		}
 	}
 
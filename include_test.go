@@ -119,7 +119,6 @@ func Test_Include(t *testing.T) {
 </page>
 <page>
 <h1>Second Page</h1>
-
 <img src="second/assets/second.png"></img>
 </page>
 
@@ -174,12 +173,10 @@ func Test_Include_Nested(t *testing.T) {
 </page>
 <page>
 <h1>Second Page</h1>
-
 <img src="second/assets/second.png"></img>
 </page>
 <page>
 <h1>Third Page</h1>
-
 <img src="second/third/assets/third.png"></img>
 </page>
 
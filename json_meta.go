@@ -5,6 +5,8 @@ import (
 	"math"
 	"regexp"
 	"strings"
+
+	"github.com/gopherguides/hype/atomx"
 )
 
 func firstPageMetadata(doc *Document) []*Metadata {
@@ -13,7 +15,13 @@ func firstPageMetadata(doc *Document) []*Metadata {
 		return ByType[*Metadata](doc.Nodes)
 	}
 
-	return ByType[*Metadata](pages[0].Nodes)
+	// metadata from front matter is in the <head>
+	var mds []*Metadata
+	for _, head := range ByAtom(doc.Nodes, atomx.Head) {
+		mds = append(mds, ByType[*Metadata](head.Children())...)
+	}
+
+	return append(mds, ByType[*Metadata](pages[0].Nodes)...)
 }
 
 type DocumentMeta struct {
@@ -1,7 +1,6 @@
 package hype
 
 import (
-	"bytes"
 	"encoding/json"
 )
 
@@ -35,18 +34,12 @@ func (li *LI) MD() string {
 		return ""
 	}
 
-	bb := &bytes.Buffer{}
-
-	switch li.Type {
-	case "ol":
-		bb.WriteString("1. ")
-	default:
-		bb.WriteString("* ")
+	marker := "-"
+	if li.Type == "ol" {
+		marker = "1."
 	}
 
-	bb.WriteString(li.Children().MD())
-
-	return bb.String()
+	return mdListItem(li.Element, marker, mdIsLoose(Nodes{li}))
 }
 
 func NewLINodes(p *Parser, el *Element) (Nodes, error) {
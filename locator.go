@@ -0,0 +1,341 @@
+package hype
+
+import (
+	"bytes"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// locator finds the position of elements in the source
+// a document was parsed from.
+//
+// Pre-parsers, such as Markdown, change the source before it
+// is parsed as HTML, so the offsets of the HTML tokenizer are
+// not offsets in the source. Elements are found by searching
+// the original source, in document order, for the text that
+// most likely produced them: their start tag, or the Markdown
+// syntax for the element. Elements that can not be found, such
+// as those added by a pre-parser, take the position of their
+// parent. Only a start tag is a certain match, all other
+// positions are marked as approximate.
+type locator struct {
+	file  string
+	src   []byte
+	off   int             // offset to search from
+	lines []int           // offsets of the start of each line
+	skip  [][2]int        // code and comments, where elements are not parsed from
+	fence map[int]int     // offsets of code fences => end of the fenced block
+	used  map[locUse]bool // offsets already given to an element
+	tags  map[string]*regexp.Regexp
+}
+
+type locUse struct {
+	off int
+	tag string
+}
+
+var (
+	locFenceRx   = regexp.MustCompile("(?m)^[ \t]{0,3}(```+|~~~+)")
+	locCommentRx = regexp.MustCompile(`(?s)<!--.*?-->`)
+	locSpanRx    = regexp.MustCompile("`[^`\n]+`")
+	locBlankRx   = regexp.MustCompile(`\n[ \t]*\n`)
+
+	locHeadingRx = map[atom.Atom]*regexp.Regexp{}
+
+	// Markdown syntax for block elements.
+	locBlockRx = map[atom.Atom]*regexp.Regexp{
+		atom.Blockquote: regexp.MustCompile(`(?m)^[ \t]{0,3}(>)`),
+		atom.Hr:         regexp.MustCompile(`(?m)^[ \t]{0,3}((?:[-*_][ \t]*){3,})$`),
+		atom.Li:         regexp.MustCompile(`(?m)^[ \t]*([-*+]|\d+[.)])[ \t]`),
+		atom.Ol:         regexp.MustCompile(`(?m)^[ \t]*(\d+[.)])[ \t]`),
+		atom.Pre:        locFenceRx,
+		atom.Table:      regexp.MustCompile(`(?m)^[ \t]*(\|)`),
+		atom.Tr:         regexp.MustCompile(`(?m)^[ \t]*(\|)`),
+		atom.Ul:         regexp.MustCompile(`(?m)^[ \t]*([-*+])[ \t]`),
+	}
+
+	// Markdown syntax for inline elements. These are only
+	// searched for in the current block of the source.
+	locInlineRx = map[atom.Atom]*regexp.Regexp{
+		atom.A:      regexp.MustCompile(`(?:^|[^!])(\[)`),
+		atom.Code:   regexp.MustCompile("(`)"),
+		atom.Del:    regexp.MustCompile(`(~~?)`),
+		atom.Em:     regexp.MustCompile(`([*_])`),
+		atom.Img:    regexp.MustCompile(`(!\[)`),
+		atom.Strong: regexp.MustCompile(`(\*\*|__)`),
+	}
+)
+
+func init() {
+	for i, a := range []atom.Atom{atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6} {
+		locHeadingRx[a] = regexp.MustCompile(`(?m)^[ \t]{0,3}(#{` + string(rune('1'+i)) + `})(?:[ \t]|$)`)
+	}
+}
+
+func newLocator(file string, src []byte) *locator {
+	l := &locator{
+		file:  file,
+		src:   src,
+		lines: []int{0},
+		fence: map[int]int{},
+		used:  map[locUse]bool{},
+		tags:  map[string]*regexp.Regexp{},
+	}
+
+	for i, b := range src {
+		if b == '\n' {
+			l.lines = append(l.lines, i+1)
+		}
+	}
+
+	// front matter is not parsed as Markdown
+	if fm, ok := splitFrontMatter(src); ok {
+		l.skip = append(l.skip, [2]int{0, fm.end})
+	}
+
+	// fenced code blocks, closed by a fence of the same
+	// marker, that is at least as long as the opening fence
+	closers := map[string]*regexp.Regexp{}
+
+	for off := 0; off < len(src); {
+		m := locFenceRx.FindSubmatchIndex(src[off:])
+		if m == nil {
+			break
+		}
+
+		start := off + m[2]
+		marker := string(src[start : off+m[3]])
+
+		body := start + len(marker)
+		if i := bytes.IndexByte(src[body:], '\n'); i >= 0 {
+			body += i + 1
+		} else {
+			body = len(src)
+		}
+
+		end := len(src)
+		closeRx, ok := closers[marker]
+		if !ok {
+			closeRx = regexp.MustCompile(`(?m)^[ \t]{0,3}` + regexp.QuoteMeta(marker[:1]) + `{` + strconv.Itoa(len(marker)) + `,}[ \t]*$`)
+			closers[marker] = closeRx
+		}
+
+		if cm := closeRx.FindIndex(src[body:]); cm != nil {
+			end = body + cm[1]
+		}
+
+		l.fence[start] = end
+		l.skip = append(l.skip, [2]int{body, end})
+
+		off = end
+	}
+
+	for _, m := range locCommentRx.FindAllIndex(src, -1) {
+		l.skip = append(l.skip, [2]int{m[0], m[1]})
+	}
+
+	for _, m := range locSpanRx.FindAllIndex(src, -1) {
+		if !l.skipped(m[0]) {
+			l.skip = append(l.skip, [2]int{m[0] + 1, m[1]})
+		}
+	}
+
+	return l
+}
+
+// locate returns the position of the element node, whose parent
+// has already been located, and moves the search past it.
+func (l *locator) locate(node *html.Node, parent Node) Pos {
+	var ppos Pos
+	if pn, ok := parent.(Positioner); ok {
+		ppos = pn.Position()
+	}
+
+	// the parent is a guess for its children
+	ppos.Approx = ppos.IsValid()
+
+	if l == nil || node == nil || node.Type != html.ElementNode {
+		return ppos
+	}
+
+	tag := strings.ToLower(node.Data)
+
+	// the <code> of a fenced code block is
+	// found with the <pre> that holds it
+	if node.DataAtom == atom.Code && node.Parent != nil && node.Parent.DataAtom == atom.Pre {
+		if _, ok := l.fence[l.off]; ok && l.used[locUse{l.off, "pre"}] {
+			return ppos
+		}
+	}
+
+	best := -1
+	approx := false
+
+	// try searches for the first match of find, from the
+	// offset of the search to limit, that is not skipped,
+	// or used, and that is before the best match so far
+	try := func(find func(b []byte) int, limit int, guess bool) {
+		for off := l.off; off < limit; {
+			i := find(l.src[off:limit])
+			if i < 0 {
+				return
+			}
+
+			at := off + i
+			if best >= 0 && at >= best {
+				return
+			}
+
+			if l.skipped(at) || l.used[locUse{at, tag}] {
+				off = at + 1
+				continue
+			}
+
+			best = at
+			approx = guess
+			return
+		}
+	}
+
+	rx, ok := l.tags[tag]
+	if !ok {
+		rx = regexp.MustCompile(`(?i)(<` + regexp.QuoteMeta(tag) + `)[\s/>]`)
+		l.tags[tag] = rx
+	}
+	try(locMatch(rx), len(l.src), false)
+
+	if rx, ok := locHeadingRx[node.DataAtom]; ok {
+		try(locMatch(rx), len(l.src), true)
+	}
+
+	if rx, ok := locBlockRx[node.DataAtom]; ok {
+		try(locMatch(rx), len(l.src), true)
+	}
+
+	if rx, ok := locInlineRx[node.DataAtom]; ok {
+		try(locMatch(rx), l.blockEnd(), true)
+	}
+
+	if node.DataAtom == atom.P {
+		if s := firstText(node); len(s) >= 3 {
+			text := []byte(s)
+			try(func(b []byte) int {
+				return bytes.Index(b, text)
+			}, len(l.src), true)
+		}
+	}
+
+	if best < 0 {
+		return ppos
+	}
+
+	l.used[locUse{best, tag}] = true
+	l.off = best
+
+	pos := l.pos(best)
+	pos.Approx = approx
+
+	return pos
+}
+
+// locMatch returns a finder, for locate, of the offset of the
+// last group of the first match of rx, the syntax of an element.
+func locMatch(rx *regexp.Regexp) func(b []byte) int {
+	return func(b []byte) int {
+		m := rx.FindSubmatchIndex(b)
+		if m == nil {
+			return -1
+		}
+
+		return m[len(m)-2]
+	}
+}
+
+// pos returns the position of the byte at off.
+func (l *locator) pos(off int) Pos {
+	i := sort.Search(len(l.lines), func(i int) bool {
+		return l.lines[i] > off
+	}) - 1
+
+	return Pos{
+		File: l.file,
+		Line: i + 1,
+		Col:  off - l.lines[i] + 1,
+	}
+}
+
+// skipped reports whether off is inside of code,
+// or a comment, in the source.
+func (l *locator) skipped(off int) bool {
+	for _, r := range l.skip {
+		if off >= r[0] && off < r[1] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// blockEnd returns the end of the block of text the
+// search is in: the next blank line after it. If the search
+// is on the start tag of an HTML block, such as <figure>,
+// the block is the Markdown that follows the tag.
+func (l *locator) blockEnd() int {
+	from := l.off
+
+	if bytes.HasPrefix(l.src[from:], []byte("<")) {
+		if i := bytes.IndexByte(l.src[from:], '\n'); i >= 0 {
+			from += i
+		}
+	}
+
+	// skip any blank lines before the block
+	for from < len(l.src) && (l.src[from] == '\n' || l.src[from] == ' ' || l.src[from] == '\t') {
+		from++
+	}
+
+	if m := locBlankRx.FindIndex(l.src[from:]); m != nil {
+		return from + m[0]
+	}
+
+	return len(l.src)
+}
+
+// firstText returns the start of the first
+// line of text in n, to find a paragraph by.
+func firstText(n *html.Node) string {
+	for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+		if ch.Type == html.ElementNode {
+			if s := firstText(ch); len(s) > 0 {
+				return s
+			}
+			continue
+		}
+
+		if ch.Type != html.TextNode {
+			continue
+		}
+
+		s, _, _ := strings.Cut(strings.TrimSpace(ch.Data), "\n")
+		if len(s) == 0 {
+			continue
+		}
+
+		if len(s) > 24 {
+			s = s[:24]
+			for !utf8.ValidString(s) {
+				s = s[:len(s)-1]
+			}
+		}
+
+		return s
+	}
+
+	return ""
+}
@@ -0,0 +1,67 @@
+package hype
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Parser_Positions(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	p := NewParser(os.DirFS("testdata/positions"))
+
+	doc, err := p.ParseFile("hype.md")
+	r.NoError(err)
+
+	pos := func(n Node) string {
+		pn, ok := n.(Positioner)
+		r.True(ok, n)
+		return pn.Position().String()
+	}
+
+	h1, ok := FirstByType[*Heading](doc.Nodes)
+	r.True(ok)
+	r.Equal("hype.md:1:1", pos(h1))
+
+	heads := ByType[*Heading](doc.Nodes)
+	r.Len(heads, 3)
+	r.Equal("hype.md:11:1", pos(heads[1]))
+	r.Equal("sub/sub.md:1:1", pos(heads[2]))
+
+	links := ByType[*Link](doc.Nodes)
+	r.Len(links, 1)
+	r.Equal("hype.md:3:26", pos(links[0]))
+
+	fenced := ByType[*FencedCode](doc.Nodes)
+	r.Len(fenced, 1)
+	r.Equal("hype.md:5:1", pos(fenced[0]))
+
+	lis := ByType[*LI](doc.Nodes)
+	r.Len(lis, 2)
+	r.Equal("hype.md:13:1", pos(lis[0]))
+	r.Equal("hype.md:14:1", pos(lis[1]))
+
+	// the <cmd> tags in code, and comments, are skipped
+	cmds := ByType[*Cmd](doc.Nodes)
+	r.Len(cmds, 2)
+	r.Equal("hype.md:18:1", pos(cmds[0]))
+	r.Equal("sub/sub.md:5:1", pos(cmds[1]))
+
+	imgs := ByType[*Image](doc.Nodes)
+	r.Len(imgs, 1)
+	r.Equal("hype.md:24:1", pos(imgs[0]))
+
+	caps := ByType[*Figcaption](doc.Nodes)
+	r.Len(caps, 1)
+	r.Equal("hype.md:26:1", pos(caps[0]))
+
+	// only the positions of start tags are certain
+	r.False(cmds[0].Position().Approx)
+	r.False(caps[0].Position().Approx)
+	r.True(h1.Position().Approx)
+	r.True(links[0].Position().Approx)
+	r.True(lis[0].Position().Approx)
+}
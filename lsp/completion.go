@@ -0,0 +1,171 @@
+package lsp
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gopherguides/hype"
+)
+
+// completion returns the completions for the cursor: tag names,
+// from hype.DefaultElements, attribute names for the tag, the names
+// of the snippets in the src file of the tag, and runner names.
+func (s *Server) completion(params TextDocumentPositionParams) (CompletionList, error) {
+	list := CompletionList{
+		Items: []CompletionItem{},
+	}
+
+	uri := params.TextDocument.URI
+
+	text, err := s.text(uri)
+	if err != nil {
+		return list, err
+	}
+
+	tc, ok := tagAt(text, offset(text, params.Position))
+	if !ok {
+		return list, nil
+	}
+
+	var items []CompletionItem
+
+	switch {
+	case tc.InName:
+		items = tagItems()
+	case tc.InAttr:
+		items = attrItems(tc)
+	case tc.Attr == "src":
+		src, frag, ok := strings.Cut(tc.Prefix, "#")
+		if !ok {
+			break
+		}
+
+		tc.Prefix = frag
+		items = s.snippetItems(uri, src)
+	case tc.Attr == "snippet":
+		src, _, _ := strings.Cut(tc.Attrs["src"], "#")
+		items = s.snippetItems(uri, src)
+	case tc.Attr == "runner":
+		items = s.runnerItems(uri)
+	}
+
+	for _, item := range items {
+		if strings.HasPrefix(item.Label, tc.Prefix) {
+			list.Items = append(list.Items, item)
+		}
+	}
+
+	return list, nil
+}
+
+func tagItems() []CompletionItem {
+	var names []string
+	for a := range hype.DefaultElements() {
+		name := string(a)
+
+		// variants, such as "godoc#a", are not tags
+		if strings.Contains(name, "#") {
+			continue
+		}
+
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	items := make([]CompletionItem, 0, len(names))
+	for _, name := range names {
+		items = append(items, CompletionItem{
+			Label: name,
+			Kind:  KindProperty,
+		})
+	}
+
+	return items
+}
+
+// attrItems returns the attributes of the tag
+// that have not already been set on it.
+func attrItems(tc tagContext) []CompletionItem {
+	var items []CompletionItem
+
+	for _, name := range tagAttrs[tc.Name] {
+		if _, ok := tc.Attrs[name]; ok && name != tc.Prefix {
+			continue
+		}
+
+		items = append(items, CompletionItem{
+			Label: name,
+			Kind:  KindProperty,
+		})
+	}
+
+	return items
+}
+
+// snippetItems returns the names of the snippets in the
+// file src, relative to the document at uri.
+func (s *Server) snippetItems(uri string, src string) []CompletionItem {
+	if len(src) == 0 {
+		return nil
+	}
+
+	path := filepath.Join(filepath.Dir(uriPath(uri)), filepath.FromSlash(src))
+
+	snips, err := s.snippets(path)
+	if err != nil {
+		return nil
+	}
+
+	names := make([]string, 0, len(snips))
+	for name := range snips {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	items := make([]CompletionItem, 0, len(names))
+	for _, name := range names {
+		snip := snips[name]
+
+		items = append(items, CompletionItem{
+			Label:  name,
+			Kind:   KindValue,
+			Detail: fmt.Sprintf("%s:%d-%d", filepath.Base(snip.File), snip.Start, snip.End),
+			Documentation: &MarkupContent{
+				Kind:  "markdown",
+				Value: fmt.Sprintf("```%s\n%s\n```", snip.Lang, snip.Content),
+			},
+		})
+	}
+
+	return items
+}
+
+// runnerItems returns the names of the runners available
+// to the parser of the document at uri.
+func (s *Server) runnerItems(uri string) []CompletionItem {
+	p, err := s.parser(uri)
+	if err != nil {
+		return nil
+	}
+
+	names := []string{hype.HostRunnerName}
+	if p.Runners != nil {
+		names = names[:0]
+		for name := range p.Runners {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	items := make([]CompletionItem, 0, len(names))
+	for _, name := range names {
+		items = append(items, CompletionItem{
+			Label: name,
+			Kind:  KindValue,
+		})
+	}
+
+	return items
+}
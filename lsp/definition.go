@@ -0,0 +1,156 @@
+package lsp
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/gopherguides/hype"
+)
+
+// definition returns the location of the target of the tag at the
+// cursor: the file, or snippet, of a src attribute, the snippet
+// of a snippet attribute, the symbol of a symbol attribute, or
+// the figure of a <ref>. If there is no target, nil is returned.
+func (s *Server) definition(params TextDocumentPositionParams) (*Location, error) {
+	uri := params.TextDocument.URI
+
+	text, err := s.text(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	off := offset(text, params.Position)
+
+	if id, ok := refAt(text, off); ok {
+		return s.figure(uri, text, id), nil
+	}
+
+	tc, ok := tagAt(text, off)
+	if !ok {
+		return nil, nil
+	}
+
+	if tc.Name == "ref" {
+		return s.figure(uri, text, tc.Attrs["id"]), nil
+	}
+
+	dir := filepath.Dir(uriPath(uri))
+
+	src, frag, _ := strings.Cut(tc.Attrs["src"], "#")
+	if len(src) == 0 {
+		return nil, nil
+	}
+
+	path := filepath.Join(dir, filepath.FromSlash(src))
+
+	switch tc.Attr {
+	case "src":
+		if len(frag) > 0 {
+			return s.snippet(path, frag), nil
+		}
+
+		return &Location{URI: pathURI(path)}, nil
+	case "snippet":
+		return s.snippet(path, tc.Attrs["snippet"]), nil
+	case "symbol":
+		return s.symbol(uri, path, tc.Attrs["symbol"]), nil
+	}
+
+	return nil, nil
+}
+
+// figure returns the location of the <figure> with id in the
+// document at uri, including any documents it includes.
+func (s *Server) figure(uri string, text string, id string) *Location {
+	if len(id) == 0 {
+		return nil
+	}
+
+	doc, err := s.parse(uri, text)
+	if err != nil {
+		return nil
+	}
+
+	for _, f := range hype.ByType[*hype.Figure](doc.Children()) {
+		if fid, _ := f.Get("id"); fid != id {
+			continue
+		}
+
+		pos := f.Position()
+		if !pos.IsValid() {
+			return nil
+		}
+
+		loc := s.location(pos)
+		return &loc
+	}
+
+	return nil
+}
+
+// snippet returns the location of the start of the named
+// snippet in the file at path.
+func (s *Server) snippet(path string, name string) *Location {
+	snips, err := s.snippets(path)
+	if err != nil {
+		return nil
+	}
+
+	snip, ok := snips[name]
+	if !ok {
+		return nil
+	}
+
+	start := Position{Line: snip.Start - 1}
+
+	return &Location{
+		URI:   pathURI(path),
+		Range: Range{Start: start, End: start},
+	}
+}
+
+// snippets returns the snippets in the file at path.
+func (s *Server) snippets(path string) (map[string]hype.Snippet, error) {
+	text, err := s.text(pathURI(path))
+	if err != nil {
+		return nil, err
+	}
+
+	var sm hype.Snippets
+	return sm.Parse(path, []byte(text))
+}
+
+// symbol returns the location of symbol in the file at path,
+// using the extractors of the parser for the document at uri.
+func (s *Server) symbol(uri string, path string, symbol string) *Location {
+	if len(symbol) == 0 {
+		return nil
+	}
+
+	p, err := s.parser(uri)
+	if err != nil {
+		return nil
+	}
+
+	x, err := p.Extractor(path)
+	if err != nil {
+		return nil
+	}
+
+	text, err := s.text(pathURI(path))
+	if err != nil {
+		return nil
+	}
+
+	snip, err := x.Extract(path, []byte(text), symbol)
+	if err != nil || snip.Start == 0 {
+		return nil
+	}
+
+	start := Position{Line: snip.Start - 1}
+
+	return &Location{
+		URI:   pathURI(path),
+		Range: Range{Start: start, End: start},
+	}
+}
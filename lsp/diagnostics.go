@@ -0,0 +1,217 @@
+package lsp
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gopherguides/hype"
+)
+
+func (s *Server) didOpen(ctx context.Context, params DidOpenTextDocumentParams) error {
+	doc := params.TextDocument
+
+	s.mu.Lock()
+	s.docs[doc.URI] = doc.Text
+	s.mu.Unlock()
+
+	return s.publish(ctx, doc.URI)
+}
+
+func (s *Server) didChange(ctx context.Context, params DidChangeTextDocumentParams) error {
+	uri := params.TextDocument.URI
+
+	// with full sync, the last change holds the whole document
+	if n := len(params.ContentChanges); n > 0 {
+		s.mu.Lock()
+		s.docs[uri] = params.ContentChanges[n-1].Text
+		s.mu.Unlock()
+	}
+
+	return s.publish(ctx, uri)
+}
+
+func (s *Server) didSave(ctx context.Context, params DidSaveTextDocumentParams) error {
+	uri := params.TextDocument.URI
+
+	if params.Text != nil {
+		s.mu.Lock()
+		s.docs[uri] = *params.Text
+		s.mu.Unlock()
+	}
+
+	return s.publish(ctx, uri)
+}
+
+func (s *Server) didClose(params DidCloseTextDocumentParams) error {
+	uri := params.TextDocument.URI
+
+	s.mu.Lock()
+	delete(s.docs, uri)
+	stale := s.published[uri]
+	delete(s.published, uri)
+	s.mu.Unlock()
+
+	for _, u := range stale {
+		if err := s.conn.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{
+			URI:         u,
+			Diagnostics: []Diagnostic{},
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// publish parses, and validates, the document at uri, and sends
+// the issues found to the client. Issues in files the document
+// includes are published for those files. Files that no longer
+// have issues have their diagnostics cleared.
+func (s *Server) publish(ctx context.Context, uri string) error {
+	text, err := s.text(uri)
+	if err != nil {
+		return err
+	}
+
+	diags := s.diagnostics(ctx, uri, text)
+
+	s.mu.Lock()
+	stale := s.published[uri]
+
+	uris := make([]string, 0, len(diags))
+	for u := range diags {
+		uris = append(uris, u)
+	}
+	sort.Strings(uris)
+
+	s.published[uri] = uris
+	s.mu.Unlock()
+
+	for _, u := range stale {
+		if _, ok := diags[u]; !ok {
+			diags[u] = []Diagnostic{}
+			uris = append(uris, u)
+		}
+	}
+
+	// the document itself is always published, so
+	// its diagnostics are cleared once it is fixed
+	if _, ok := diags[uri]; !ok {
+		diags[uri] = []Diagnostic{}
+		uris = append(uris, uri)
+	}
+
+	for _, u := range uris {
+		if err := s.conn.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{
+			URI:         u,
+			Diagnostics: diags[u],
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// diagnostics returns the issues in the document at uri, by the
+// URI of the file they are in. Commands are not executed.
+func (s *Server) diagnostics(ctx context.Context, uri string, text string) map[string][]Diagnostic {
+	diags := map[string][]Diagnostic{}
+
+	doc, err := s.parse(uri, text)
+	if err != nil {
+		pos, _ := hype.ErrPos(err)
+
+		u, d := s.diagnostic(uri, pos, SeverityError, "parse", errText(err, pos))
+		diags[u] = append(diags[u], d)
+
+		return diags
+	}
+
+	res := hype.Validate(ctx, doc, hype.ValidateOptions{})
+
+	for _, issue := range res.Issues {
+		sev := SeverityError
+		if issue.Severity == hype.SeverityWarning {
+			sev = SeverityWarning
+		}
+
+		u, d := s.diagnostic(uri, issue.Pos, sev, string(issue.Category), issue.Message)
+		diags[u] = append(diags[u], d)
+	}
+
+	return diags
+}
+
+// diagnostic returns a diagnostic at pos, and the URI of the file it
+// is in. Without a position, it is put at the start of the document.
+func (s *Server) diagnostic(uri string, pos hype.Pos, severity int, code string, msg string) (string, Diagnostic) {
+	d := Diagnostic{
+		Severity: severity,
+		Source:   "hype",
+		Code:     code,
+		Message:  msg,
+	}
+
+	if !pos.IsValid() {
+		return uri, d
+	}
+
+	if len(pos.File) > 0 {
+		uri = pathURI(pos.File)
+	}
+
+	text, err := s.text(uri)
+	if err != nil {
+		return uri, d
+	}
+
+	start := toPosition(text, pos)
+
+	// highlight to the end of the tag, or line, the issue is on
+	line := lineAt(text, start.Line)
+	from := byteCol(line, start.Character)
+
+	to := len(line)
+	if i := strings.IndexByte(line[from:], '>'); i >= 0 {
+		to = from + i + 1
+	}
+
+	d.Range = Range{
+		Start: start,
+		End: Position{
+			Line:      start.Line,
+			Character: utf16Len(line[:to]),
+		},
+	}
+
+	return uri, d
+}
+
+// excerptRx matches the lines of a source
+// excerpt in a positioned hype error.
+var excerptRx = regexp.MustCompile(`^\s*(\d+)? \| `)
+
+// errText returns the message of err without the position,
+// and source excerpt, that the diagnostic already shows.
+func errText(err error, pos hype.Pos) string {
+	msg := err.Error()
+
+	if !pos.IsValid() {
+		return msg
+	}
+
+	lines := strings.Split(msg, "\n")
+	lines[0] = strings.TrimPrefix(lines[0], pos.String()+": ")
+
+	res := lines[:1]
+	for _, line := range lines[1:] {
+		if !excerptRx.MatchString(line) {
+			res = append(res, line)
+		}
+	}
+
+	return strings.Join(res, "\n")
+}
@@ -0,0 +1,95 @@
+package lsp
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/gopherguides/hype"
+)
+
+// hover runs the <cmd>, or <go>, tag at the cursor and
+// returns a preview of its output, as it would be rendered
+// in the document's Markdown.
+func (s *Server) hover(ctx context.Context, params TextDocumentPositionParams) (*Hover, error) {
+	uri := params.TextDocument.URI
+
+	text, err := s.text(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	tc, ok := tagAt(text, offset(text, params.Position))
+	if !ok || (tc.Name != "cmd" && tc.Name != "go") {
+		return nil, nil
+	}
+
+	doc, err := s.parse(uri, text)
+	if err != nil {
+		return nil, nil
+	}
+
+	line := strings.Count(text[:tc.Start], "\n")
+	want := hype.Pos{
+		File: filepath.Clean(uriPath(uri)),
+		Line: line + 1,
+		Col:  tc.Start - (strings.LastIndexByte(text[:tc.Start], '\n') + 1) + 1,
+	}
+
+	to := s.HoverTimeout
+	if to <= 0 {
+		to = DefaultHoverTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, to)
+	defer cancel()
+
+	var previews []string
+	for _, c := range hype.ByType[*hype.Cmd](doc.Children()) {
+		if cmdPos(c) != want {
+			continue
+		}
+
+		if err := c.Execute(ctx, doc); err != nil {
+			previews = append(previews, fmt.Sprintf("```text\n%s\n```", err))
+			continue
+		}
+
+		previews = append(previews, c.MD())
+	}
+
+	if len(previews) == 0 {
+		return nil, nil
+	}
+
+	end := len(text)
+	if i := strings.IndexByte(text[tc.Start:], '>'); i >= 0 {
+		end = tc.Start + i + 1
+	}
+
+	return &Hover{
+		Contents: MarkupContent{
+			Kind:  "markdown",
+			Value: strings.Join(previews, "\n\n"),
+		},
+		Range: &Range{
+			Start: position(text, tc.Start),
+			End:   position(text, end),
+		},
+	}, nil
+}
+
+// cmdPos returns the position of the tag c was parsed from.
+// The commands of a <go> tag are at the position of the tag.
+func cmdPos(c *hype.Cmd) hype.Pos {
+	if pos := c.Position(); pos.IsValid() {
+		return pos
+	}
+
+	if p, ok := c.Parent.(hype.Positioner); ok {
+		return p.Position()
+	}
+
+	return hype.Pos{}
+}
@@ -0,0 +1,156 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"sync"
+)
+
+// JSON-RPC error codes used by the server.
+const (
+	codeParseError       = -32700
+	codeInvalidRequest   = -32600
+	codeMethodNotFound   = -32601
+	codeInvalidParams    = -32602
+	codeInternalError    = -32603
+	codeRequestCancelled = -32800
+)
+
+// request is an incoming JSON-RPC request, or notification
+// if it has no ID.
+type request struct {
+	ID     json.RawMessage `json:"id,omitempty"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+func (r request) isNotification() bool {
+	return len(r.ID) == 0
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type notification struct {
+	JSONRPC string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  any    `json:"params"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("%d: %s", e.Code, e.Message)
+}
+
+// conn reads, and writes, JSON-RPC messages framed
+// with a Content-Length header, as used by LSP.
+type conn struct {
+	r *textproto.Reader
+	w io.Writer
+
+	mu sync.Mutex
+}
+
+func newConn(r io.Reader, w io.Writer) *conn {
+	return &conn{
+		r: textproto.NewReader(bufio.NewReader(r)),
+		w: w,
+	}
+}
+
+// read returns the next request. io.EOF is returned
+// when the client closes the stream.
+func (c *conn) read() (request, error) {
+	var req request
+
+	b, err := c.readMsg()
+	if err != nil {
+		return req, err
+	}
+
+	if err := json.Unmarshal(b, &req); err != nil {
+		return req, &rpcError{Code: codeParseError, Message: err.Error()}
+	}
+
+	return req, nil
+}
+
+// readMsg returns the body of the next message.
+func (c *conn) readMsg() ([]byte, error) {
+	h, err := c.r.ReadMIMEHeader()
+	if err != nil {
+		if err == io.ErrUnexpectedEOF && len(h) == 0 {
+			err = io.EOF
+		}
+		return nil, err
+	}
+
+	n, err := strconv.Atoi(h.Get("Content-Length"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Content-Length: %q", h.Get("Content-Length"))
+	}
+
+	b := make([]byte, n)
+	if _, err := io.ReadFull(c.r.R, b); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+func (c *conn) reply(id json.RawMessage, result any, rerr *rpcError) error {
+	res := response{
+		JSONRPC: "2.0",
+		ID:      id,
+		Error:   rerr,
+	}
+
+	if rerr == nil {
+		b, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+		res.Result = b
+	}
+
+	return c.write(res)
+}
+
+func (c *conn) notify(method string, params any) error {
+	return c.write(notification{
+		JSONRPC: "2.0",
+		Method:  method,
+		Params:  params,
+	})
+}
+
+// write writes msg, with its header, in a single write.
+// It is safe to call from more than one goroutine, such
+// as the handlers of requests that run in the background.
+func (c *conn) write(msg any) error {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	buf := fmt.Appendf(nil, "Content-Length: %d\r\n\r\n", len(b))
+	buf = append(buf, b...)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, err = c.w.Write(buf)
+	return err
+}
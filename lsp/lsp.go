@@ -0,0 +1,395 @@
+// Package lsp implements a Language Server Protocol server for
+// hype documents. It speaks JSON-RPC over a stream, usually
+// stdio, and provides diagnostics, go-to-definition, completion,
+// and hover previews of <cmd> output to editors.
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gopherguides/hype"
+)
+
+// DefaultHoverTimeout is how long a <cmd> is
+// allowed to run for a hover preview.
+const DefaultHoverTimeout = 10 * time.Second
+
+// Server is a language server for hype documents.
+type Server struct {
+	// NewParser returns the parser used for a document in dir.
+	// default: hype.NewParser(os.DirFS(dir))
+	NewParser func(dir string) *hype.Parser
+
+	// HoverTimeout limits how long a <cmd> runs for a hover
+	// preview. default: DefaultHoverTimeout
+	HoverTimeout time.Duration
+
+	conn      *conn
+	docs      map[string]string             // text of open documents, by URI
+	published map[string][]string           // URIs with diagnostics, by the URI of the document they are from
+	pending   map[string]context.CancelFunc // cancels requests running in the background, by ID
+	shutdown  bool
+
+	mu sync.RWMutex
+	wg sync.WaitGroup
+}
+
+// Serve reads requests from r, and writes responses to w, until
+// the client sends "exit", r is closed, or ctx is cancelled.
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	if s == nil {
+		return fmt.Errorf("server is nil")
+	}
+
+	s.mu.Lock()
+	s.conn = newConn(r, w)
+	s.docs = map[string]string{}
+	s.published = map[string][]string{}
+	s.pending = map[string]context.CancelFunc{}
+	s.mu.Unlock()
+
+	// requests running in the background are
+	// cancelled, and finish, before Serve returns
+	defer s.wg.Wait()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type msg struct {
+		req request
+		err error
+	}
+
+	msgs := make(chan msg)
+
+	go func() {
+		defer close(msgs)
+
+		for {
+			req, err := s.conn.read()
+
+			select {
+			case msgs <- msg{req, err}:
+			case <-ctx.Done():
+				return
+			}
+
+			var re *rpcError
+			if err != nil && !errors.As(err, &re) {
+				return
+			}
+		}
+	}()
+
+	for {
+		var m msg
+		var ok bool
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case m, ok = <-msgs:
+		}
+
+		if !ok {
+			return ctx.Err()
+		}
+
+		if m.err != nil {
+			var re *rpcError
+			if errors.As(m.err, &re) {
+				if err := s.conn.reply(nil, nil, re); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if errors.Is(m.err, io.EOF) {
+				return nil
+			}
+
+			return m.err
+		}
+
+		if m.req.Method == "exit" {
+			return nil
+		}
+
+		if err := s.handle(ctx, m.req); err != nil {
+			return err
+		}
+	}
+}
+
+// handle dispatches req to its handler, and replies if it is a request.
+func (s *Server) handle(ctx context.Context, req request) error {
+	var res any
+	var err error
+
+	s.mu.RLock()
+	shutdown := s.shutdown
+	s.mu.RUnlock()
+
+	if shutdown && !req.isNotification() {
+		return s.conn.reply(req.ID, nil, &rpcError{
+			Code:    codeInvalidRequest,
+			Message: "server is shut down",
+		})
+	}
+
+	switch req.Method {
+	case "initialize":
+		res = s.initialize()
+	case "initialized":
+	case "shutdown":
+		s.mu.Lock()
+		s.shutdown = true
+		s.mu.Unlock()
+	case "textDocument/didOpen":
+		var params DidOpenTextDocumentParams
+		if err = json.Unmarshal(req.Params, &params); err == nil {
+			err = s.didOpen(ctx, params)
+		}
+	case "textDocument/didChange":
+		var params DidChangeTextDocumentParams
+		if err = json.Unmarshal(req.Params, &params); err == nil {
+			err = s.didChange(ctx, params)
+		}
+	case "textDocument/didSave":
+		var params DidSaveTextDocumentParams
+		if err = json.Unmarshal(req.Params, &params); err == nil {
+			err = s.didSave(ctx, params)
+		}
+	case "textDocument/didClose":
+		var params DidCloseTextDocumentParams
+		if err = json.Unmarshal(req.Params, &params); err == nil {
+			err = s.didClose(params)
+		}
+	case "textDocument/definition":
+		var params TextDocumentPositionParams
+		if err = json.Unmarshal(req.Params, &params); err == nil {
+			res, err = s.definition(params)
+		}
+	case "textDocument/completion":
+		var params TextDocumentPositionParams
+		if err = json.Unmarshal(req.Params, &params); err == nil {
+			res, err = s.completion(params)
+		}
+	case "textDocument/hover":
+		// a hover runs the <cmd> at the cursor, which may take
+		// a while, so it runs in the background, and can be
+		// cancelled, while the server handles other requests
+		var params TextDocumentPositionParams
+		if err = json.Unmarshal(req.Params, &params); err == nil {
+			s.background(ctx, req, func(ctx context.Context) (any, error) {
+				return s.hover(ctx, params)
+			})
+			return nil
+		}
+	case "$/cancelRequest":
+		var params CancelParams
+		if err = json.Unmarshal(req.Params, &params); err == nil {
+			s.cancel(params.ID)
+		}
+	default:
+		if req.isNotification() {
+			// unknown notifications are ignored
+			return nil
+		}
+
+		return s.conn.reply(req.ID, nil, &rpcError{
+			Code:    codeMethodNotFound,
+			Message: fmt.Sprintf("method not found: %s", req.Method),
+		})
+	}
+
+	return s.respond(req, res, err)
+}
+
+// respond replies to req with res, or err. Notifications
+// have no reply, so their errors are sent to the client to log.
+func (s *Server) respond(req request, res any, err error) error {
+	if req.isNotification() {
+		if err != nil {
+			return s.conn.notify("window/logMessage", map[string]any{
+				"type":    1,
+				"message": fmt.Sprintf("%s: %s", req.Method, err),
+			})
+		}
+		return nil
+	}
+
+	var re *rpcError
+	if errors.As(err, &re) {
+		return s.conn.reply(req.ID, nil, re)
+	}
+
+	if err != nil {
+		code := codeInternalError
+
+		var se *json.SyntaxError
+		var te *json.UnmarshalTypeError
+		if errors.As(err, &se) || errors.As(err, &te) {
+			code = codeInvalidParams
+		}
+
+		return s.conn.reply(req.ID, nil, &rpcError{
+			Code:    code,
+			Message: err.Error(),
+		})
+	}
+
+	return s.conn.reply(req.ID, res, nil)
+}
+
+// background runs fn, the handler of req, in a goroutine,
+// with a context that "$/cancelRequest" cancels. A cancelled
+// request is replied to with a RequestCancelled error.
+func (s *Server) background(ctx context.Context, req request, fn func(context.Context) (any, error)) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	id := string(req.ID)
+
+	s.mu.Lock()
+	s.pending[id] = cancel
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+
+	go func() {
+		defer s.wg.Done()
+
+		defer func() {
+			s.mu.Lock()
+			delete(s.pending, id)
+			s.mu.Unlock()
+			cancel()
+		}()
+
+		res, err := fn(ctx)
+
+		if ctx.Err() != nil {
+			res, err = nil, &rpcError{
+				Code:    codeRequestCancelled,
+				Message: "request cancelled",
+			}
+		}
+
+		// a broken connection is reported by
+		// the reads of Serve, so it is not here
+		_ = s.respond(req, res, err)
+	}()
+}
+
+// cancel cancels the request, running in the
+// background, with the given ID, if there is one.
+func (s *Server) cancel(id json.RawMessage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if cancel, ok := s.pending[string(id)]; ok {
+		cancel()
+	}
+}
+
+func (s *Server) initialize() InitializeResult {
+	return InitializeResult{
+		Capabilities: ServerCapabilities{
+			TextDocumentSync: TextDocumentSyncOptions{
+				OpenClose: true,
+				Change:    SyncFull,
+				Save:      SaveOptions{IncludeText: true},
+			},
+			CompletionProvider: CompletionOptions{
+				TriggerCharacters: []string{"<", " ", `"`, "#"},
+			},
+			DefinitionProvider: true,
+			HoverProvider:      true,
+		},
+		ServerInfo: ServerInfo{
+			Name: "hype",
+		},
+	}
+}
+
+// text returns the text of the document at uri. Open documents
+// are read from the editor's copy, others from disk.
+func (s *Server) text(uri string) (string, error) {
+	s.mu.RLock()
+	text, ok := s.docs[uri]
+	s.mu.RUnlock()
+
+	if ok {
+		return text, nil
+	}
+
+	path := uriPath(uri)
+	if len(path) == 0 {
+		return "", fmt.Errorf("unsupported uri: %s", uri)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+// parser returns a new parser for the document at uri.
+func (s *Server) parser(uri string) (*hype.Parser, error) {
+	path := uriPath(uri)
+	if len(path) == 0 {
+		return nil, fmt.Errorf("unsupported uri: %s", uri)
+	}
+
+	dir := filepath.Dir(path)
+
+	var p *hype.Parser
+	if s.NewParser != nil {
+		p = s.NewParser(dir)
+	}
+
+	if p == nil {
+		p = hype.NewParser(os.DirFS(dir))
+	}
+
+	p.Root = dir
+	p.Filename = filepath.Base(path)
+
+	return p, nil
+}
+
+// parse parses the text of the document at uri.
+func (s *Server) parse(uri string, text string) (*hype.Document, error) {
+	p, err := s.parser(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.Parse(strings.NewReader(text))
+}
+
+// location returns the location of pos, in the file it is in.
+func (s *Server) location(pos hype.Pos) Location {
+	uri := pathURI(pos.File)
+
+	var start Position
+	if text, err := s.text(uri); err == nil {
+		start = toPosition(text, pos)
+	}
+
+	return Location{
+		URI:   uri,
+		Range: Range{Start: start, End: start},
+	}
+}
@@ -0,0 +1,397 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// client drives a Server over pipes, as an editor would.
+type client struct {
+	t    testing.TB
+	conn *conn
+	msgs chan map[string]json.RawMessage
+	done chan error
+	id   int
+}
+
+func newClient(t testing.TB, s *Server) *client {
+	t.Helper()
+
+	sr, cw := io.Pipe()
+	cr, sw := io.Pipe()
+
+	c := &client{
+		t:    t,
+		conn: newConn(cr, cw),
+		msgs: make(chan map[string]json.RawMessage, 100),
+		done: make(chan error, 1),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	go func() {
+		c.done <- s.Serve(ctx, sr, sw)
+		sw.Close()
+	}()
+
+	go func() {
+		defer close(c.msgs)
+		for {
+			b, err := c.conn.readMsg()
+			if err != nil {
+				return
+			}
+
+			var m map[string]json.RawMessage
+			if err := json.Unmarshal(b, &m); err != nil {
+				return
+			}
+			c.msgs <- m
+		}
+	}()
+
+	t.Cleanup(func() {
+		cancel()
+		cw.Close()
+	})
+
+	return c
+}
+
+// call sends a request, and decodes the result of its response into res.
+func (c *client) call(method string, params any, res any) *rpcError {
+	c.t.Helper()
+	return c.wait(c.send(method, params), res)
+}
+
+// send sends a request, and returns its ID, without
+// waiting for its response.
+func (c *client) send(method string, params any) json.RawMessage {
+	c.t.Helper()
+
+	c.id++
+	id := json.RawMessage(fmtID(c.id))
+
+	require.NoError(c.t, c.conn.write(map[string]any{
+		"jsonrpc": "2.0",
+		"id":      id,
+		"method":  method,
+		"params":  params,
+	}))
+
+	return id
+}
+
+// wait decodes the result of the response to the
+// request with id into res. Other messages are dropped.
+func (c *client) wait(id json.RawMessage, res any) *rpcError {
+	c.t.Helper()
+	r := require.New(c.t)
+
+	for {
+		m := c.next()
+		if string(m["id"]) != string(id) {
+			continue
+		}
+
+		if e, ok := m["error"]; ok {
+			var re rpcError
+			r.NoError(json.Unmarshal(e, &re))
+			return &re
+		}
+
+		r.Contains(m, "result")
+		if res != nil {
+			r.NoError(json.Unmarshal(m["result"], res))
+		}
+
+		return nil
+	}
+}
+
+func (c *client) notify(method string, params any) {
+	c.t.Helper()
+	require.NoError(c.t, c.conn.notify(method, params))
+}
+
+// diagnostics returns the next diagnostics published for uri.
+func (c *client) diagnostics(uri string) []Diagnostic {
+	c.t.Helper()
+	r := require.New(c.t)
+
+	for {
+		m := c.next()
+		if string(m["method"]) != `"textDocument/publishDiagnostics"` {
+			continue
+		}
+
+		var params PublishDiagnosticsParams
+		r.NoError(json.Unmarshal(m["params"], &params))
+
+		if params.URI == uri {
+			return params.Diagnostics
+		}
+	}
+}
+
+func (c *client) next() map[string]json.RawMessage {
+	c.t.Helper()
+
+	select {
+	case m, ok := <-c.msgs:
+		require.True(c.t, ok, "server closed the connection")
+		return m
+	case <-time.After(30 * time.Second):
+		c.t.Fatal("timed out waiting for the server")
+	}
+
+	return nil
+}
+
+func fmtID(id int) string {
+	b, _ := json.Marshal(id)
+	return string(b)
+}
+
+// open starts a client, and opens testdata/doc/hype.md,
+// with text, or its contents on disk if text is empty.
+func open(t testing.TB, text string) (*client, string) {
+	t.Helper()
+	r := require.New(t)
+
+	path, err := filepath.Abs("testdata/doc/hype.md")
+	r.NoError(err)
+
+	if len(text) == 0 {
+		b, err := os.ReadFile(path)
+		r.NoError(err)
+		text = string(b)
+	}
+
+	c := newClient(t, &Server{})
+
+	var res InitializeResult
+	r.Nil(c.call("initialize", map[string]any{}, &res))
+	c.notify("initialized", map[string]any{})
+
+	uri := pathURI(path)
+
+	c.notify("textDocument/didOpen", DidOpenTextDocumentParams{
+		TextDocument: TextDocumentItem{
+			URI:        uri,
+			LanguageID: "markdown",
+			Version:    1,
+			Text:       text,
+		},
+	})
+
+	return c, uri
+}
+
+func at(uri string, line, char int) TextDocumentPositionParams {
+	return TextDocumentPositionParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+		Position:     Position{Line: line, Character: char},
+	}
+}
+
+func Test_Server_Initialize(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	c := newClient(t, &Server{})
+
+	var res InitializeResult
+	r.Nil(c.call("initialize", map[string]any{}, &res))
+
+	caps := res.Capabilities
+	r.Equal(SyncFull, caps.TextDocumentSync.Change)
+	r.True(caps.DefinitionProvider)
+	r.True(caps.HoverProvider)
+	r.Contains(caps.CompletionProvider.TriggerCharacters, "<")
+
+	re := c.call("textDocument/unknown", map[string]any{}, nil)
+	r.NotNil(re)
+	r.Equal(codeMethodNotFound, re.Code)
+
+	r.Nil(c.call("shutdown", nil, nil))
+
+	re = c.call("textDocument/hover", at("file:///x.md", 0, 0), nil)
+	r.NotNil(re)
+	r.Equal(codeInvalidRequest, re.Code)
+
+	c.notify("exit", nil)
+
+	select {
+	case err := <-c.done:
+		r.NoError(err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("server did not exit")
+	}
+}
+
+func Test_Server_Diagnostics(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	c, uri := open(t, "")
+
+	diags := c.diagnostics(uri)
+	r.Len(diags, 1)
+
+	d := diags[0]
+	r.Equal(SeverityError, d.Severity)
+	r.Equal("asset", d.Code)
+	r.Contains(d.Message, "missing.png")
+	r.Equal(Range{
+		Start: Position{Line: 14, Character: 0},
+		End:   Position{Line: 14, Character: 23},
+	}, d.Range)
+
+	// fixing the document clears its diagnostics
+	c.notify("textDocument/didChange", DidChangeTextDocumentParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+		ContentChanges: []struct {
+			Text string `json:"text"`
+		}{
+			{Text: "# Fixed\n"},
+		},
+	})
+
+	r.Empty(c.diagnostics(uri))
+
+	// parse errors are reported at their position
+	c.notify("textDocument/didChange", DidChangeTextDocumentParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+		ContentChanges: []struct {
+			Text string `json:"text"`
+		}{
+			{Text: "# Broken\n\n<include src=\"nope.md\"></include>\n"},
+		},
+	})
+
+	diags = c.diagnostics(uri)
+	r.Len(diags, 1)
+	r.Equal("parse", diags[0].Code)
+	r.Equal(2, diags[0].Range.Start.Line)
+	r.Contains(diags[0].Message, "nope.md")
+	r.NotContains(diags[0].Message, " | ")
+}
+
+func Test_Server_Definition(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	c, uri := open(t, "")
+
+	dir := filepath.Dir(uriPath(uri))
+	mainURI := pathURI(filepath.Join(dir, "src", "main.go"))
+	introURI := pathURI(filepath.Join(dir, "intro.md"))
+
+	table := []struct {
+		name string
+		line int
+		char int
+		uri  string
+		exp  int
+	}{
+		{name: "include src", line: 2, char: 15, uri: introURI, exp: 0},
+		{name: "src fragment", line: 4, char: 25, uri: mainURI, exp: 10},
+		{name: "snippet", line: 6, char: 35, uri: mainURI, exp: 10},
+		{name: "symbol", line: 8, char: 34, uri: mainURI, exp: 4},
+		{name: "ref", line: 10, char: 26, uri: uri, exp: 16},
+	}
+
+	for _, tt := range table {
+		var loc *Location
+		r.Nil(c.call("textDocument/definition", at(uri, tt.line, tt.char), &loc), tt.name)
+		r.NotNil(loc, tt.name)
+		r.Equal(tt.uri, loc.URI, tt.name)
+		r.Equal(tt.exp, loc.Range.Start.Line, tt.name)
+	}
+
+	// no target
+	var loc *Location
+	r.Nil(c.call("textDocument/definition", at(uri, 0, 2), &loc))
+	r.Nil(loc)
+}
+
+func Test_Server_Completion(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	text := "# Completion\n\n" +
+		"<co\n" +
+		"<code s\n" +
+		"<code src=\"src/main.go#\n" +
+		"<code src=\"src/main.go\" snippet=\"\n" +
+		"<cmd runner=\"\n"
+
+	c, uri := open(t, text)
+
+	labels := func(line, char int) []string {
+		var list CompletionList
+		r.Nil(c.call("textDocument/completion", at(uri, line, char), &list))
+
+		var res []string
+		for _, item := range list.Items {
+			res = append(res, item.Label)
+		}
+		return res
+	}
+
+	r.Equal([]string{"code"}, labels(2, 3))
+	r.Equal([]string{"section", "snippet", "src", "symbol"}, labels(3, 7))
+	r.Equal([]string{"greet"}, labels(4, 23))
+	r.Equal([]string{"greet"}, labels(5, 33))
+	r.Contains(labels(6, 13), "host")
+	r.Empty(labels(0, 2))
+}
+
+func Test_Server_Hover(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	c, uri := open(t, "")
+
+	var h *Hover
+	r.Nil(c.call("textDocument/hover", at(uri, 12, 3), &h))
+	r.NotNil(h)
+	r.Equal("markdown", h.Contents.Kind)
+	r.Contains(h.Contents.Value, "$ echo hello")
+	r.Contains(h.Contents.Value, "hello")
+	r.Equal(Position{Line: 12, Character: 0}, h.Range.Start)
+
+	// not a command
+	h = nil
+	r.Nil(c.call("textDocument/hover", at(uri, 4, 3), &h))
+	r.Nil(h)
+}
+
+func Test_Server_Hover_Cancel(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	c, uri := open(t, "<cmd exec=\"sleep 30\"></cmd>\n")
+
+	id := c.send("textDocument/hover", at(uri, 0, 3))
+
+	// the server answers other requests while the hover runs
+	var list CompletionList
+	r.Nil(c.call("textDocument/completion", at(uri, 0, 1), &list))
+
+	c.notify("$/cancelRequest", CancelParams{ID: id})
+
+	start := time.Now()
+	re := c.wait(id, nil)
+	r.NotNil(re)
+	r.Equal(codeRequestCancelled, re.Code)
+	r.Less(time.Since(start), DefaultHoverTimeout)
+}
@@ -0,0 +1,163 @@
+package lsp
+
+import (
+	"regexp"
+	"strings"
+)
+
+// tagAttrs are the attributes, by tag, offered as completions.
+// Tags not listed here have none.
+var tagAttrs = map[string][]string{
+	"cmd": {
+		"code", "environ", "exec", "exit", "expect", "hide-cmd", "hide-data",
+		"no-cache", "runner", "show-duration", "src", "timeout", "truncate",
+	},
+	"code":    {"esc", "language", "range", "section", "snippet", "src", "symbol"},
+	"diagram": {"lang", "src"},
+	"figure":  {"id", "type"},
+	"go": {
+		"bug", "build", "clean", "doc", "env", "environ", "fix", "fmt",
+		"generate", "get", "help", "install", "list", "mod", "run",
+		"src", "sym", "test", "tool", "version", "vet",
+	},
+	"godoc":   {"full-pkg"},
+	"img":     {"alt", "format", "quality", "sizes", "src", "title", "widths"},
+	"include": {"src"},
+	"now":     {"gofmt"},
+	"ref":     {"id"},
+	"session": {
+		"environ", "language", "no-cache", "prompt", "runner", "shell",
+		"src", "timeout",
+	},
+	"step":    {"exec", "exit", "hide", "hide-cmd", "truncate"},
+	"toc":     {"depth", "root"},
+	"youtube": {"id", "title"},
+}
+
+// maxTagLen limits how far back from the
+// cursor the start of a tag is searched for.
+const maxTagLen = 4096
+
+var (
+	tagNameRx = regexp.MustCompile(`^[a-zA-Z][\w-]*(?:#\w+)?`)
+	tagAttrRx = regexp.MustCompile(`([\w-]+)(?:\s*=\s*("[^"]*"?|'[^']*'?|[^\s"'>]*))?`)
+	refBodyRx = regexp.MustCompile(`<ref(?:\s[^>]*)?>([^<]*)</ref>`)
+)
+
+// tagContext describes the start tag the cursor is in.
+type tagContext struct {
+	Name  string            // name of the tag, such as "code"
+	Start int               // offset of the "<" of the tag
+	Attrs map[string]string // attributes of the tag
+
+	InName bool   // the cursor is in the name of the tag
+	InAttr bool   // the cursor is where the name of an attribute goes
+	Attr   string // the cursor is in the value of this attribute
+	Prefix string // text before the cursor in the name, or value, it is in
+}
+
+// tagAt returns the start tag in text that the cursor, at off, is in.
+func tagAt(text string, off int) (tagContext, bool) {
+	var tc tagContext
+
+	off = min(max(off, 0), len(text))
+
+	start := -1
+	for i := off - 1; i >= 0 && off-i <= maxTagLen; i-- {
+		if text[i] == '>' {
+			return tc, false
+		}
+
+		if text[i] == '<' {
+			start = i
+			break
+		}
+	}
+
+	if start < 0 {
+		return tc, false
+	}
+
+	// the tag ends at its ">", or, while it is
+	// being typed, at the start of the next tag
+	end := len(text)
+	if i := strings.IndexAny(text[start+1:], "<>"); i >= 0 {
+		end = start + 1 + i
+	}
+
+	body := text[start+1 : end]
+	rel := off - (start + 1)
+
+	tc.Start = start
+	tc.Attrs = map[string]string{}
+
+	m := tagNameRx.FindStringIndex(body)
+	if m == nil {
+		// only "<" has been typed
+		if rel == 0 && !strings.HasPrefix(body, "/") && !strings.HasPrefix(body, "!") {
+			tc.InName = true
+			return tc, true
+		}
+		return tc, false
+	}
+
+	tc.Name = strings.ToLower(body[:m[1]])
+
+	if rel <= m[1] {
+		tc.InName = true
+		tc.Prefix = body[:rel]
+	}
+
+	from := m[1]
+	for _, am := range tagAttrRx.FindAllStringSubmatchIndex(body[from:], -1) {
+		ns, ne := from+am[2], from+am[3]
+		name := strings.ToLower(body[ns:ne])
+
+		var value string
+		if am[4] >= 0 {
+			vs, ve := from+am[4], from+am[5]
+			raw := body[vs:ve]
+
+			if len(raw) > 0 && (raw[0] == '"' || raw[0] == '\'') {
+				vs++
+				if len(raw) > 1 && raw[len(raw)-1] == raw[0] {
+					ve--
+				}
+			}
+
+			value = body[vs:ve]
+
+			if rel >= vs && rel <= ve {
+				tc.Attr = name
+				tc.Prefix = body[vs:rel]
+			}
+		}
+
+		tc.Attrs[name] = value
+
+		if rel >= ns && rel <= ne {
+			tc.InAttr = true
+			tc.Prefix = body[ns:rel]
+		}
+	}
+
+	if !tc.InName && !tc.InAttr && len(tc.Attr) == 0 {
+		if c := body[rel-1]; c == ' ' || c == '\t' || c == '\n' || c == '\r' {
+			tc.InAttr = true
+		}
+	}
+
+	return tc, true
+}
+
+// refAt returns the id of the <ref>id</ref>
+// whose body the cursor, at off, is in.
+func refAt(text string, off int) (string, bool) {
+	for _, m := range refBodyRx.FindAllStringSubmatchIndex(text, -1) {
+		if off >= m[2] && off <= m[3] {
+			return strings.TrimSpace(text[m[2]:m[3]]), true
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,63 @@
+package lsp
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_TagAt(t *testing.T) {
+	t.Parallel()
+
+	table := []struct {
+		name string
+		text string // "|" marks the cursor
+		ok   bool
+		exp  tagContext
+	}{
+		{name: "open", text: "a <|", ok: true, exp: tagContext{InName: true}},
+		{name: "name", text: "<co|de>", ok: true, exp: tagContext{Name: "code", InName: true, Prefix: "co"}},
+		{name: "attr", text: `<code src="a.go" |>`, ok: true, exp: tagContext{Name: "code", InAttr: true}},
+		{name: "attr name", text: `<code sn|`, ok: true, exp: tagContext{Name: "code", InAttr: true, Prefix: "sn"}},
+		{name: "value", text: `<code src="a.go#ex|">`, ok: true, exp: tagContext{Name: "code", Attr: "src", Prefix: "a.go#ex"}},
+		{name: "unquoted", text: `<toc depth=2|>`, ok: true, exp: tagContext{Name: "toc", Attr: "depth", Prefix: "2"}},
+		{name: "outside", text: `<code src="a.go"></code> |`},
+		{name: "end tag", text: `</co|de>`},
+	}
+
+	for _, tt := range table {
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+
+			off := strings.Index(tt.text, "|")
+			text := strings.Replace(tt.text, "|", "", 1)
+
+			tc, ok := tagAt(text, off)
+			r.Equal(tt.ok, ok)
+			if !ok {
+				return
+			}
+
+			r.Equal(tt.exp.Name, tc.Name)
+			r.Equal(tt.exp.InName, tc.InName)
+			r.Equal(tt.exp.InAttr, tc.InAttr)
+			r.Equal(tt.exp.Attr, tc.Attr)
+			r.Equal(tt.exp.Prefix, tc.Prefix)
+		})
+	}
+}
+
+func Test_RefAt(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	text := "See <ref>fig-1</ref> and <ref id=\"x\"></ref>."
+
+	id, ok := refAt(text, strings.Index(text, "fig")+2)
+	r.True(ok)
+	r.Equal("fig-1", id)
+
+	_, ok = refAt(text, 1)
+	r.False(ok)
+}
@@ -0,0 +1,14 @@
+package main
+
+import "fmt"
+
+// Hello returns a greeting for name.
+func Hello(name string) string {
+	return "Hello, " + name
+}
+
+func main() {
+	// snippet: greet
+	fmt.Println(Hello("World"))
+	// snippet: greet
+}
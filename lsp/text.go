@@ -0,0 +1,131 @@
+package lsp
+
+import (
+	"net/url"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gopherguides/hype"
+)
+
+// LSP positions count characters in UTF-16 code units,
+// while hype, and Go, count bytes. These helpers convert
+// between the two.
+
+// offset returns the byte offset of pos in text.
+func offset(text string, pos Position) int {
+	off := 0
+	for i := 0; i < pos.Line; i++ {
+		n := strings.IndexByte(text[off:], '\n')
+		if n < 0 {
+			return len(text)
+		}
+		off += n + 1
+	}
+
+	line := text[off:]
+	if n := strings.IndexByte(line, '\n'); n >= 0 {
+		line = line[:n]
+	}
+
+	return off + byteCol(line, pos.Character)
+}
+
+// position returns the LSP position of the byte at off in text.
+func position(text string, off int) Position {
+	off = min(max(off, 0), len(text))
+
+	line := strings.Count(text[:off], "\n")
+	start := strings.LastIndexByte(text[:off], '\n') + 1
+
+	return Position{
+		Line:      line,
+		Character: utf16Len(text[start:off]),
+	}
+}
+
+// toPosition converts a hype position, in the file holding text,
+// to an LSP position.
+func toPosition(text string, pos hype.Pos) Position {
+	if !pos.IsValid() {
+		return Position{}
+	}
+
+	line := lineAt(text, pos.Line-1)
+	col := min(max(pos.Col-1, 0), len(line))
+
+	return Position{
+		Line:      pos.Line - 1,
+		Character: utf16Len(line[:col]),
+	}
+}
+
+// lineAt returns the zero-based line n of text,
+// without its line ending.
+func lineAt(text string, n int) string {
+	for i := 0; i < n; i++ {
+		_, rest, ok := strings.Cut(text, "\n")
+		if !ok {
+			return ""
+		}
+		text = rest
+	}
+
+	line, _, _ := strings.Cut(text, "\n")
+
+	return strings.TrimSuffix(line, "\r")
+}
+
+// byteCol returns the byte offset, in line,
+// of the UTF-16 character col.
+func byteCol(line string, col int) int {
+	n := 0
+	for i, r := range line {
+		if n >= col {
+			return i
+		}
+
+		n++
+		if r >= 0x10000 {
+			n++
+		}
+	}
+
+	return len(line)
+}
+
+func utf16Len(s string) int {
+	n := 0
+	for len(s) > 0 {
+		r, size := utf8.DecodeRuneInString(s)
+		s = s[size:]
+
+		n++
+		if r >= 0x10000 {
+			n++
+		}
+	}
+
+	return n
+}
+
+// uriPath returns the file path of a file:// URI.
+func uriPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return ""
+	}
+
+	return filepath.FromSlash(u.Path)
+}
+
+// pathURI returns the file:// URI of the file at path.
+func pathURI(path string) string {
+	u := url.URL{
+		Scheme: "file",
+		Path:   filepath.ToSlash(path),
+	}
+
+	return u.String()
+}
@@ -0,0 +1,49 @@
+package lsp
+
+import (
+	"testing"
+
+	"github.com/gopherguides/hype"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Offset_Position(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	// "é" is 2 bytes and 1 UTF-16 unit, "😀" is 4 bytes and 2 units
+	text := "# é😀\n<cmd>\n"
+
+	table := []struct {
+		pos Position
+		off int
+	}{
+		{pos: Position{0, 0}, off: 0},
+		{pos: Position{0, 3}, off: 4},
+		{pos: Position{0, 5}, off: 8},
+		{pos: Position{1, 1}, off: 10},
+		{pos: Position{2, 0}, off: 15},
+	}
+
+	for _, tt := range table {
+		r.Equal(tt.off, offset(text, tt.pos), tt.pos)
+		r.Equal(tt.pos, position(text, tt.off), tt.off)
+	}
+
+	// past the end of a line
+	r.Equal(8, offset(text, Position{0, 42}))
+
+	r.Equal(Position{0, 5}, toPosition(text, hype.Pos{Line: 1, Col: 9}))
+	r.Equal(Position{}, toPosition(text, hype.Pos{}))
+}
+
+func Test_URI(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	uri := pathURI("/docs/my book/hype.md")
+	r.Equal("file:///docs/my%20book/hype.md", uri)
+	r.Equal("/docs/my book/hype.md", uriPath(uri))
+
+	r.Empty(uriPath("untitled:Untitled-1"))
+}
@@ -32,10 +32,6 @@ func Markdown() PreParseFn {
 			return nil, fmt.Errorf("markdown: %w", err)
 		}
 
-		b = bytes.ReplaceAll(b, []byte("&rsquo;"), []byte("'"))
-		b = bytes.ReplaceAll(b, []byte("&ldquo;"), []byte("\""))
-		b = bytes.ReplaceAll(b, []byte("&rdquo;"), []byte("\""))
-
 		return bytes.NewReader(b), nil
 	}
 
@@ -0,0 +1,897 @@
+package hype
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gopherguides/hype/atomx"
+)
+
+// renderMD renders nodes as normalized CommonMark, using
+// the extensions understood by the Markdown pre-parser
+// (tables, task lists, strikethrough, autolinks, footnotes,
+// heading IDs, and definition lists). Blocks are separated
+// by a single blank line.
+//
+// Nodes the renderer does not know about are rendered
+// with their own MD method, if they have one, or as HTML.
+func renderMD(nodes Nodes) string {
+	return strings.Join(mdBlocks(nodes), "\n\n")
+}
+
+// mdBlocks renders nodes as a list of blocks.
+// Consecutive inline nodes are joined into a single
+// paragraph block.
+func mdBlocks(nodes Nodes) []string {
+	var blocks []string
+
+	bb := &strings.Builder{}
+
+	flush := func() {
+		s := mdParagraph(bb.String())
+		bb.Reset()
+
+		if len(s) > 0 {
+			blocks = append(blocks, s)
+		}
+	}
+
+	for _, n := range mdFlatten(nodes) {
+		if mdIsInline(n) {
+			bb.WriteString(mdInline(n))
+			continue
+		}
+
+		flush()
+
+		s := strings.Trim(mdBlock(n), "\n")
+		if len(strings.TrimSpace(s)) == 0 {
+			continue
+		}
+
+		blocks = append(blocks, s)
+	}
+
+	flush()
+
+	return blocks
+}
+
+func mdBlock(n Node) string {
+	switch t := n.(type) {
+	case Comment:
+		return fmt.Sprintf("<!--%s-->", string(t))
+	case *Heading:
+		return mdHeading(t)
+	case *Paragraph:
+		return mdParagraph(mdInlines(t.Children()))
+	case *UL:
+		return mdList(t.Element, false)
+	case *OL:
+		return mdList(t.Element, true)
+	case *LI:
+		return mdListItem(t.Element, "-", mdIsLoose(Nodes{t}))
+	case *Table:
+		return mdTable(t)
+	case *Figure:
+		return mdFigure(t)
+	case *Figcaption:
+		return mdQuote(mdWrap("*", mdLine(mdInlines(t.Children()))))
+	case *Metadata:
+		return mdMetadata(t)
+	case *Page:
+		return renderMD(t.Children())
+	case *Body:
+		return renderMD(t.Children())
+	case *Include:
+		return renderMD(t.Children())
+	case *Cmd:
+		return renderMD(t.Children())
+	case *CmdResult:
+		return renderMD(t.Children())
+	case *Element:
+		return mdElement(t)
+	case MDNode:
+		return t.MD()
+	}
+
+	return mdHTML(n)
+}
+
+func mdElement(el *Element) string {
+	if el == nil {
+		return ""
+	}
+
+	switch el.Atom() {
+	case atomx.Hr:
+		// "---" starts a new page
+		return "***"
+	case atomx.Br:
+		return ""
+	case atomx.Blockquote:
+		return mdQuote(renderMD(el.Children()))
+	case atomx.Pre:
+		return mdPre(el)
+	case atomx.Dl:
+		return mdDefinitions(el)
+	case atomx.Div:
+		if c, _ := el.Get("class"); c == "footnotes" {
+			return mdFootnotes(el)
+		}
+	}
+
+	kids := mdFlatten(el.Children())
+	if len(kids) == 0 {
+		return el.StartTag() + el.EndTag()
+	}
+
+	allInline := true
+	for _, k := range kids {
+		if !mdIsInline(k) {
+			allInline = false
+			break
+		}
+	}
+
+	// the contents of a single line HTML block
+	// are not parsed as Markdown
+	if allInline {
+		return el.String()
+	}
+
+	return fmt.Sprintf("%s\n\n%s\n\n%s", el.StartTag(), renderMD(kids), el.EndTag())
+}
+
+var mdInlineAtoms = map[Atom]bool{
+	atomx.A:      true,
+	atomx.Abbr:   true,
+	atomx.B:      true,
+	atomx.Br:     true,
+	atomx.Cite:   true,
+	atomx.Code:   true,
+	atomx.Del:    true,
+	atomx.Dfn:    true,
+	atomx.Em:     true,
+	atomx.I:      true,
+	atomx.Input:  true,
+	atomx.Kbd:    true,
+	atomx.Mark:   true,
+	atomx.Q:      true,
+	atomx.S:      true,
+	atomx.Samp:   true,
+	atomx.Small:  true,
+	atomx.Span:   true,
+	atomx.Strike: true,
+	atomx.Strong: true,
+	atomx.Sub:    true,
+	atomx.Sup:    true,
+	atomx.Time:   true,
+	atomx.U:      true,
+	atomx.Var:    true,
+	atomx.Wbr:    true,
+}
+
+func mdIsInline(n Node) bool {
+	switch t := n.(type) {
+	case Text, *Link, *InlineCode, *Image, *Ref, *Now, *Var:
+		return true
+	case *Element:
+		return mdInlineAtoms[t.Atom()]
+	}
+
+	return false
+}
+
+// mdFlatten replaces any Nodes in nodes,
+// as returned by a ParseElementFn, with their contents.
+func mdFlatten(nodes Nodes) Nodes {
+	res := make(Nodes, 0, len(nodes))
+
+	for _, n := range nodes {
+		if ns, ok := n.(Nodes); ok {
+			res = append(res, mdFlatten(ns)...)
+			continue
+		}
+
+		res = append(res, n)
+	}
+
+	return res
+}
+
+func mdHTML(n Node) string {
+	if s, ok := n.(fmt.Stringer); ok {
+		return s.String()
+	}
+
+	return ""
+}
+
+func mdInlines(nodes Nodes) string {
+	bb := &strings.Builder{}
+
+	for _, n := range mdFlatten(nodes) {
+		bb.WriteString(mdInline(n))
+	}
+
+	return bb.String()
+}
+
+func mdInline(n Node) string {
+	switch t := n.(type) {
+	case Text:
+		return mdEscape(string(t))
+	case *Link:
+		return mdLink(t)
+	case *InlineCode:
+		return t.MD()
+	case *Image:
+		return mdImage(t)
+	case *Ref, *Now:
+		return mdInlines(n.Children())
+	case *Var:
+		if t.Value != nil {
+			return mdEscape(fmt.Sprintf("%v", t.Value))
+		}
+		return t.Element.String()
+	case *Element:
+		return mdInlineElement(t)
+	}
+
+	if mdIsInline(n) {
+		return mdHTML(n)
+	}
+
+	return strings.TrimSpace(mdBlock(n))
+}
+
+func mdInlineElement(el *Element) string {
+	switch el.Atom() {
+	case atomx.Strong, atomx.B:
+		return mdWrap("**", mdInlines(el.Children()))
+	case atomx.Em, atomx.I:
+		return mdWrap("*", mdInlines(el.Children()))
+	case atomx.Del, atomx.S, atomx.Strike:
+		return mdWrap("~~", mdInlines(el.Children()))
+	case atomx.Br:
+		return "\\\n"
+	case atomx.Input:
+		// task list items
+		if typ, _ := el.Get("type"); typ == "checkbox" {
+			if _, ok := el.Get("checked"); ok {
+				return "[x]"
+			}
+			return "[ ]"
+		}
+	case atomx.Sup:
+		if id, ok := mdFootnoteRef(el); ok {
+			return fmt.Sprintf("[^%s]", id)
+		}
+	}
+
+	return el.StartTag() + mdInlines(el.Children()) + el.EndTag()
+}
+
+// mdWrap wraps s in the given emphasis marker.
+// Leading and trailing whitespace is moved outside
+// of the markers, so they remain left and right flanking.
+func mdWrap(marker string, s string) string {
+	trimmed := strings.TrimSpace(s)
+	if len(trimmed) == 0 {
+		return s
+	}
+
+	i := strings.Index(s, trimmed)
+
+	return s[:i] + marker + trimmed + marker + s[i+len(trimmed):]
+}
+
+func mdLink(l *Link) string {
+	href, _ := l.Get("href")
+
+	text := mdLine(mdInlines(l.Children()))
+
+	if text == mdEscape(href) && strings.Contains(href, "://") {
+		return fmt.Sprintf("<%s>", href)
+	}
+
+	dest := mdDestination(href)
+
+	if title, ok := l.Get("title"); ok {
+		return fmt.Sprintf("[%s](%s %q)", text, dest, title)
+	}
+
+	return fmt.Sprintf("[%s](%s)", text, dest)
+}
+
+func mdImage(i *Image) string {
+	// images with attributes Markdown can not
+	// express, such as width, are kept as HTML
+	var extra bool
+	i.Attrs().Range(func(k string, v string) bool {
+		switch k {
+		case "src", "alt", "title":
+			return true
+		}
+		extra = true
+		return false
+	})
+
+	if extra {
+		return i.Element.String()
+	}
+
+	src, _ := i.Get("src")
+	alt, _ := i.Get("alt")
+
+	alt = mdEscape(alt)
+	dest := mdDestination(src)
+
+	if title, ok := i.Get("title"); ok {
+		return fmt.Sprintf("![%s](%s %q)", alt, dest, title)
+	}
+
+	return fmt.Sprintf("![%s](%s)", alt, dest)
+}
+
+func mdDestination(s string) string {
+	if strings.ContainsAny(s, " ()<>") {
+		return fmt.Sprintf("<%s>", strings.NewReplacer("<", `\<`, ">", `\>`).Replace(s))
+	}
+
+	return s
+}
+
+func mdHeading(h *Heading) string {
+	level := h.Level()
+	if level < 1 {
+		level = 1
+	}
+
+	s := fmt.Sprintf("%s %s", strings.Repeat("#", level), mdLine(mdInlines(h.Children())))
+
+	if id, ok := h.Get("id"); ok && len(id) > 0 {
+		s = fmt.Sprintf("%s {#%s}", s, id)
+	}
+
+	return s
+}
+
+// mdParagraph normalizes the lines of a paragraph.
+// Surrounding whitespace and blank lines are removed,
+// so the paragraph stays a single block, and lines that
+// would otherwise start a new block are escaped.
+func mdParagraph(s string) string {
+	var lines []string
+
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+
+		lines = append(lines, mdEscapeLineStart(line))
+	}
+
+	s = strings.Join(lines, "\n")
+
+	// a hard line break at the end of
+	// a paragraph is not a line break
+	return strings.TrimSuffix(s, "\\")
+}
+
+// mdLine renders s as a single line, for use
+// in headings, table cells, and link text.
+func mdLine(s string) string {
+	s = strings.ReplaceAll(s, "\\\n", " ")
+	s = strings.Join(strings.Fields(s), " ")
+	return s
+}
+
+var mdEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"`", "\\`",
+	"*", `\*`,
+	"[", `\[`,
+	"]", `\]`,
+)
+
+// mdEscape escapes the characters in s that would
+// otherwise be read as Markdown syntax.
+func mdEscape(s string) string {
+	s = mdEscaper.Replace(s)
+
+	if !strings.ContainsAny(s, "_<~") {
+		return s
+	}
+
+	rs := []rune(s)
+
+	bb := &strings.Builder{}
+
+	for i, r := range rs {
+		switch r {
+		case '_':
+			// intraword underscores are not emphasis
+			if i > 0 && i < len(rs)-1 && mdIsWord(rs[i-1]) && mdIsWord(rs[i+1]) {
+				break
+			}
+			bb.WriteRune('\\')
+		case '<':
+			if i < len(rs)-1 && (unicode.IsLetter(rs[i+1]) || strings.ContainsRune("/!?", rs[i+1])) {
+				bb.WriteRune('\\')
+			}
+		case '~':
+			if i < len(rs)-1 && rs[i+1] == '~' {
+				bb.WriteRune('\\')
+			}
+		}
+
+		bb.WriteRune(r)
+	}
+
+	return bb.String()
+}
+
+func mdIsWord(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+var mdOrderedRx = regexp.MustCompile(`^(\d+)([.)])(\s|$)`)
+
+// mdEscapeLineStart escapes the start of a paragraph line
+// that would otherwise be read as a heading, block quote,
+// list item, thematic break, or page break.
+func mdEscapeLineStart(line string) string {
+	if m := mdOrderedRx.FindStringSubmatch(line); m != nil {
+		return m[1] + `\` + line[len(m[1]):]
+	}
+
+	if len(line) == 0 {
+		return line
+	}
+
+	switch line[0] {
+	case '#', '>', '=':
+		return `\` + line
+	case '-', '+':
+		if len(line) == 1 || line[1] == ' ' || line[1] == line[0] {
+			return `\` + line
+		}
+	}
+
+	return line
+}
+
+func mdQuote(s string) string {
+	lines := strings.Split(s, "\n")
+
+	for i, line := range lines {
+		if len(line) == 0 {
+			lines[i] = ">"
+			continue
+		}
+		lines[i] = "> " + line
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// mdIndent indents every line of s, except
+// the first, and blank lines, with prefix.
+func mdIndent(s string, prefix string) string {
+	lines := strings.Split(s, "\n")
+
+	for i, line := range lines {
+		if i == 0 || len(line) == 0 {
+			continue
+		}
+		lines[i] = prefix + line
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func mdListItems(el *Element) []*Element {
+	var items []*Element
+
+	for _, n := range mdFlatten(el.Children()) {
+		switch t := n.(type) {
+		case *LI:
+			items = append(items, t.Element)
+		case *Element:
+			if t.Atom() == atomx.Li {
+				items = append(items, t)
+			}
+		}
+	}
+
+	return items
+}
+
+// mdIsLoose returns true if any of the list items
+// wraps its content in paragraphs.
+func mdIsLoose(items Nodes) bool {
+	for _, li := range items {
+		for _, n := range mdFlatten(li.Children()) {
+			if _, ok := n.(*Paragraph); ok {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func mdList(el *Element, ordered bool) string {
+	items := mdListItems(el)
+	if len(items) == 0 {
+		return ""
+	}
+
+	nodes := make(Nodes, 0, len(items))
+	for _, li := range items {
+		nodes = append(nodes, li)
+	}
+
+	loose := mdIsLoose(nodes)
+
+	start := 1
+	if s, ok := el.Get("start"); ok {
+		if i, err := strconv.Atoi(s); err == nil {
+			start = i
+		}
+	}
+
+	rendered := make([]string, 0, len(items))
+	for i, li := range items {
+		marker := "-"
+		if ordered {
+			marker = fmt.Sprintf("%d.", start+i)
+		}
+
+		rendered = append(rendered, mdListItem(li, marker, loose))
+	}
+
+	if loose {
+		return strings.Join(rendered, "\n\n")
+	}
+
+	return strings.Join(rendered, "\n")
+}
+
+// mdListItem renders a single list item. Continuation
+// lines are indented with four spaces, which nests
+// correctly for both bullet and ordered list markers.
+func mdListItem(li *Element, marker string, loose bool) string {
+	sep := "\n"
+	if loose {
+		sep = "\n\n"
+	}
+
+	body := strings.Join(mdBlocks(li.Children()), sep)
+	if len(body) == 0 {
+		return marker
+	}
+
+	return fmt.Sprintf("%s %s", marker, mdIndent(body, "    "))
+}
+
+func mdTable(tab *Table) string {
+	var rows [][]string
+	var aligns []string
+	var header bool
+
+	for i, tr := range ByAtom(tab.Children(), atomx.Tr) {
+		var row []string
+
+		for _, cell := range mdFlatten(tr.Children()) {
+			an, ok := cell.(AtomableNode)
+			if !ok {
+				continue
+			}
+
+			a := an.Atom()
+			if a != atomx.Th && a != atomx.Td {
+				continue
+			}
+
+			if i == 0 {
+				header = header || a == atomx.Th
+				aligns = append(aligns, mdCellAlign(cell))
+			}
+
+			s := mdLine(mdInlines(cell.Children()))
+			s = mdEscapePipes(s)
+			row = append(row, s)
+		}
+
+		rows = append(rows, row)
+	}
+
+	// GFM tables require a header row
+	if len(rows) == 0 || !header {
+		return tab.String()
+	}
+
+	cols := 0
+	for _, row := range rows {
+		if len(row) > cols {
+			cols = len(row)
+		}
+	}
+
+	widths := make([]int, cols)
+	for i := range widths {
+		widths[i] = 3
+	}
+
+	for _, row := range rows {
+		for i, c := range row {
+			if w := utf8.RuneCountInString(c); w > widths[i] {
+				widths[i] = w
+			}
+		}
+	}
+
+	line := func(cells []string) string {
+		bb := &strings.Builder{}
+		bb.WriteString("|")
+		for i := 0; i < cols; i++ {
+			var c string
+			if i < len(cells) {
+				c = cells[i]
+			}
+			fmt.Fprintf(bb, " %s |", mdPad(c, widths[i], aligns, i))
+		}
+		return bb.String()
+	}
+
+	lines := []string{line(rows[0])}
+
+	seps := make([]string, cols)
+	for i := range seps {
+		var align string
+		if i < len(aligns) {
+			align = aligns[i]
+		}
+
+		w := widths[i]
+
+		switch align {
+		case "left":
+			seps[i] = ":" + strings.Repeat("-", w-1)
+		case "right":
+			seps[i] = strings.Repeat("-", w-1) + ":"
+		case "center":
+			seps[i] = ":" + strings.Repeat("-", w-2) + ":"
+		default:
+			seps[i] = strings.Repeat("-", w)
+		}
+	}
+
+	lines = append(lines, line(seps))
+
+	for _, row := range rows[1:] {
+		lines = append(lines, line(row))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// mdPad pads the cell c in column i to width w,
+// according to the alignment of the column.
+func mdPad(c string, w int, aligns []string, i int) string {
+	pad := w - utf8.RuneCountInString(c)
+	if pad <= 0 {
+		return c
+	}
+
+	var align string
+	if i < len(aligns) {
+		align = aligns[i]
+	}
+
+	switch align {
+	case "right":
+		return strings.Repeat(" ", pad) + c
+	case "center":
+		left := pad / 2
+		return strings.Repeat(" ", left) + c + strings.Repeat(" ", pad-left)
+	}
+
+	return c + strings.Repeat(" ", pad)
+}
+
+func mdCellAlign(n Node) string {
+	el, ok := n.(interface{ Attrs() *Attributes })
+	if !ok {
+		return ""
+	}
+
+	ats := el.Attrs()
+
+	if a, ok := ats.Get("align"); ok {
+		return strings.ToLower(a)
+	}
+
+	style, _ := ats.Get("style")
+	for _, decl := range strings.Split(style, ";") {
+		k, v, ok := strings.Cut(decl, ":")
+		if !ok || strings.TrimSpace(k) != "text-align" {
+			continue
+		}
+		return strings.ToLower(strings.TrimSpace(v))
+	}
+
+	return ""
+}
+
+// mdEscapePipes escapes the pipes in a table cell.
+// Per GFM, this includes the pipes inside code spans.
+func mdEscapePipes(s string) string {
+	bb := &strings.Builder{}
+
+	for i, r := range s {
+		if r == '|' && (i == 0 || s[i-1] != '\\') {
+			bb.WriteRune('\\')
+		}
+		bb.WriteRune(r)
+	}
+
+	return bb.String()
+}
+
+func mdPre(el *Element) string {
+	kids := mdFlatten(el.Children())
+
+	for _, k := range kids {
+		if _, ok := k.(Text); !ok {
+			return renderMD(kids)
+		}
+	}
+
+	body := html.UnescapeString(kids.String())
+	body = strings.Trim(body, "\n")
+
+	fence := "```"
+	if n := countMaxConsecutiveChar(body, '`'); n >= 3 {
+		fence = strings.Repeat("`", n+1)
+	}
+
+	return fmt.Sprintf("%s\n%s\n%s", fence, body, fence)
+}
+
+func mdDefinitions(el *Element) string {
+	var lines []string
+
+	for _, n := range mdFlatten(el.Children()) {
+		an, ok := n.(AtomableNode)
+		if !ok {
+			continue
+		}
+
+		s := mdLine(mdInlines(n.Children()))
+
+		switch an.Atom() {
+		case atomx.Dt:
+			if len(lines) > 0 {
+				lines = append(lines, "")
+			}
+			lines = append(lines, mdEscapeLineStart(s))
+		case atomx.Dd:
+			lines = append(lines, ": "+s)
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// mdFigure renders an anchor for the figure, so that
+// references to it keep working, followed by its contents.
+func mdFigure(f *Figure) string {
+	anchor := fmt.Sprintf("<a id=%q></a>", strings.TrimPrefix(f.Link(), "#"))
+
+	blocks := append([]string{anchor}, mdBlocks(f.Children())...)
+
+	return strings.Join(blocks, "\n\n")
+}
+
+func mdMetadata(md *Metadata) string {
+	keys := md.Map.Keys()
+	if len(keys) == 0 {
+		return ""
+	}
+
+	sort.Strings(keys)
+
+	lines := []string{md.Element.StartTag()}
+	for _, k := range keys {
+		v, _ := md.Map.Get(k)
+		lines = append(lines, fmt.Sprintf("%s: %s", k, v))
+	}
+	lines = append(lines, md.Element.EndTag())
+
+	return strings.Join(lines, "\n")
+}
+
+// mdFootnoteRef returns the id of the footnote
+// referenced by a <sup class="footnote-ref"> element.
+func mdFootnoteRef(el *Element) (string, bool) {
+	for _, n := range mdFlatten(el.Children()) {
+		l, ok := n.(*Link)
+		if !ok {
+			continue
+		}
+
+		if c, _ := l.Get("class"); c != "footnote-ref" {
+			return "", false
+		}
+
+		href, _ := l.Get("href")
+		return strings.TrimPrefix(href, "#fn:"), true
+	}
+
+	return "", false
+}
+
+// mdFootnotes renders the footnotes section,
+// <div class="footnotes">, as footnote definitions.
+func mdFootnotes(el *Element) string {
+	var notes []string
+
+	for _, ol := range ByAtom(el.Children(), atomx.Ol) {
+		for _, n := range ByAtom(ol.Children(), atomx.Li) {
+			li, ok := n.(AttrNode)
+			if !ok {
+				continue
+			}
+
+			id, _ := li.Attrs().Get("id")
+			id = strings.TrimPrefix(id, "fn:")
+
+			body := renderMD(mdWithoutBackrefs(n.Children()))
+
+			notes = append(notes, fmt.Sprintf("[^%s]: %s", id, mdIndent(body, "    ")))
+		}
+	}
+
+	return strings.Join(notes, "\n\n")
+}
+
+// mdWithoutBackrefs returns a copy of the nodes of a footnote
+// without the links back to the footnote reference.
+func mdWithoutBackrefs(nodes Nodes) Nodes {
+	res := make(Nodes, 0, len(nodes))
+
+	for _, n := range mdFlatten(nodes) {
+		if l, ok := n.(*Link); ok {
+			if c, _ := l.Get("class"); c == "footnote-backref" {
+				continue
+			}
+		}
+
+		if p, ok := n.(*Paragraph); ok {
+			n = &Paragraph{
+				Element: &Element{
+					Attributes: p.Attributes,
+					HTMLNode:   p.HTMLNode,
+					Nodes:      mdWithoutBackrefs(p.Children()),
+					Parent:     p.Parent,
+					Filename:   p.Filename,
+				},
+			}
+		}
+
+		res = append(res, n)
+	}
+
+	return res
+}
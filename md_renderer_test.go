@@ -0,0 +1,149 @@
+package hype
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// mdTree returns a normalized outline of the nodes,
+// ignoring insignificant whitespace, for comparing
+// documents parsed from different Markdown sources.
+func mdTree(nodes Nodes) string {
+	bb := &strings.Builder{}
+
+	var walk func(nodes Nodes, depth int)
+	walk = func(nodes Nodes, depth int) {
+		indent := strings.Repeat("  ", depth)
+
+		for _, n := range nodes {
+			switch t := n.(type) {
+			case Nodes:
+				walk(t, depth)
+				continue
+			case Text:
+				s := strings.Join(strings.Fields(string(t)), " ")
+				if len(s) > 0 {
+					fmt.Fprintf(bb, "%s%q\n", indent, s)
+				}
+				continue
+			case Comment:
+				fmt.Fprintf(bb, "%s<!--%s-->\n", indent, strings.TrimSpace(string(t)))
+				continue
+			}
+
+			if st, ok := n.(interface{ StartTag() string }); ok {
+				fmt.Fprintf(bb, "%s%s\n", indent, st.StartTag())
+			} else {
+				fmt.Fprintf(bb, "%s%T\n", indent, n)
+			}
+
+			walk(n.Children(), depth+1)
+		}
+	}
+
+	walk(nodes, 0)
+
+	return bb.String()
+}
+
+func Test_MD_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	root := filepath.Join("testdata", "markdown", "roundtrip")
+
+	files, err := filepath.Glob(filepath.Join(root, "*.md"))
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+
+	for _, f := range files {
+		f := f
+		t.Run(filepath.Base(f), func(t *testing.T) {
+			t.Parallel()
+			r := require.New(t)
+
+			src, err := os.ReadFile(f)
+			r.NoError(err)
+
+			p := testParser(t, root)
+			doc, err := p.Parse(strings.NewReader(string(src)))
+			r.NoError(err)
+
+			md := doc.MD()
+
+			p = testParser(t, root)
+			doc2, err := p.Parse(strings.NewReader(md))
+			r.NoError(err, md)
+
+			r.Equal(mdTree(doc.Children()), mdTree(doc2.Children()), md)
+
+			// rendering the rendered Markdown again
+			// must not change it
+			r.Equal(md, doc2.MD())
+		})
+	}
+}
+
+func Test_MD_Render(t *testing.T) {
+	t.Parallel()
+
+	table := []struct {
+		name string
+		in   string
+		exp  string
+	}{
+		{
+			name: "nested lists",
+			in:   "- a\n    - b\n        - c\n- d",
+			exp:  "- a\n    - b\n        - c\n- d",
+		},
+		{
+			name: "loose list",
+			in:   "1. a\n\n    more\n\n2. b",
+			exp:  "1. a\n\n    more\n\n2. b",
+		},
+		{
+			name: "table with inline code",
+			in:   "| a | b |\n|---|--:|\n| `x` | y |",
+			exp:  "| a   |   b |\n| --- | --: |\n| `x` |   y |",
+		},
+		{
+			name: "escaped text",
+			in:   `1 \* 2 and \[x\]`,
+			exp:  `1 \* 2 and \[x\]`,
+		},
+		{
+			name: "emphasis",
+			in:   "_a_ __b__ ~~c~~",
+			exp:  "*a* **b** ~~c~~",
+		},
+		{
+			name: "thematic break",
+			in:   "a\n\n***\n\nb",
+			exp:  "a\n\n***\n\nb",
+		},
+		{
+			name: "html block",
+			in:   "<assignment>\n\nSome *x* text.\n\n</assignment>",
+			exp:  "<assignment>\n\nSome *x* text.\n\n</assignment>",
+		},
+	}
+
+	for _, tt := range table {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			r := require.New(t)
+
+			p := testParser(t, "testdata")
+			doc, err := p.Parse(strings.NewReader(tt.in))
+			r.NoError(err)
+
+			r.Equal(tt.exp, strings.TrimSpace(doc.MD()))
+		})
+	}
+}
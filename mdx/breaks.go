@@ -0,0 +1,45 @@
+package mdx
+
+import (
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+)
+
+// hardBreaks trims the spaces before a hard line break.
+//
+// The autolinks of GFM split the text of a paragraph at
+// its spaces, so the spaces before a hard line break end
+// up in the text before it, rather than being trimmed
+// with the line, and are rendered before the <br />.
+type hardBreaks struct{}
+
+func (hardBreaks) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	src := reader.Source()
+
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+
+		t, ok := n.(*ast.Text)
+		if !ok || !t.HardLineBreak() {
+			return ast.WalkContinue, nil
+		}
+
+		t.Segment = t.Segment.TrimRightSpace(src)
+
+		// the spaces are in the text before the break
+		for t.Segment.IsEmpty() {
+			prev, ok := t.PreviousSibling().(*ast.Text)
+			if !ok {
+				break
+			}
+
+			prev.Segment = prev.Segment.TrimRightSpace(src)
+			t = prev
+		}
+
+		return ast.WalkContinue, nil
+	})
+}
@@ -0,0 +1,81 @@
+package mdx
+
+import (
+	"bytes"
+	"regexp"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/util"
+)
+
+// infoAttrRx matches the key=value pairs of an info string.
+var infoAttrRx = regexp.MustCompile(`([A-Za-z_][\w:.-]*)=(?:"([^"]*)"|'([^']*)'|(\S+))`)
+
+// fencedCode renders fenced code blocks, as goldmark does.
+// For mermaid diagrams, it also renders the key=value pairs
+// of the info string, after the language, as attributes of
+// the code element, so diagrams can be configured. Other
+// blocks are left as CommonMark renders them.
+//
+//	```mermaid render=svg
+//
+//	<pre><code class="language-mermaid" render="svg">
+type fencedCode struct{}
+
+func (r fencedCode) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindFencedCodeBlock, r.render)
+}
+
+func (r fencedCode) render(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		_, _ = w.WriteString("</code></pre>\n")
+		return ast.WalkContinue, nil
+	}
+
+	n := node.(*ast.FencedCodeBlock)
+
+	_, _ = w.WriteString("<pre><code")
+
+	if lang := n.Language(source); lang != nil {
+		_, _ = w.WriteString(` class="language-`)
+		html.DefaultWriter.Write(w, lang)
+		_ = w.WriteByte('"')
+
+		var rest []byte
+		if string(lang) == "mermaid" {
+			rest = bytes.TrimPrefix(n.Info.Segment.Value(source), lang)
+		}
+
+		for _, m := range infoAttrRx.FindAllSubmatch(rest, -1) {
+			if string(m[1]) == "class" {
+				continue
+			}
+
+			val := m[2]
+			if len(val) == 0 {
+				val = m[3]
+			}
+			if len(val) == 0 {
+				val = m[4]
+			}
+
+			_ = w.WriteByte(' ')
+			_, _ = w.Write(m[1])
+			_, _ = w.WriteString(`="`)
+			_, _ = w.Write(util.EscapeHTML(val))
+			_ = w.WriteByte('"')
+		}
+	}
+
+	_ = w.WriteByte('>')
+
+	l := n.Lines().Len()
+	for i := 0; i < l; i++ {
+		line := n.Lines().At(i)
+		html.DefaultWriter.RawWrite(w, line.Value(source))
+	}
+
+	return ast.WalkContinue, nil
+}
@@ -4,14 +4,57 @@ import (
 	"bytes"
 	"fmt"
 	"io"
-	"regexp"
 	"strings"
 	"sync"
 
-	"github.com/russross/blackfriday/v2"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
 )
 
-const extensions = blackfriday.CommonExtensions | blackfriday.LaxHTMLBlocks
+// commonMark is the CommonMark engine of hype. In addition
+// to CommonMark, it supports the GitHub Flavored Markdown
+// extensions (tables, task lists, strikethrough, and
+// autolinks), footnotes, definition lists, attribute
+// blocks on headings, such as `# Title {#id .class}`, and
+// attributes in the info strings of mermaid diagrams, such
+// as "```mermaid render=svg".
+//
+// Raw HTML is passed through, so hype tags keep working.
+// The deviations from CommonMark are in docs/markdown.md.
+var commonMark = newMarkdown()
+
+// markdown is the commonMark engine, with the hypeTags.
+var markdown = newMarkdown(hypeTags{})
+
+func newMarkdown(exts ...goldmark.Extender) goldmark.Markdown {
+	exts = append([]goldmark.Extender{
+		extension.GFM,
+		extension.Footnote,
+		extension.DefinitionList,
+	}, exts...)
+
+	return goldmark.New(
+		goldmark.WithExtensions(exts...),
+		goldmark.WithParserOptions(
+			parser.WithAttribute(),
+			parser.WithASTTransformers(
+				util.Prioritized(hardBreaks{}, 100),
+			),
+		),
+		goldmark.WithRendererOptions(
+			html.WithUnsafe(),
+			html.WithXHTML(),
+			renderer.WithNodeRenderers(
+				util.Prioritized(fencedCode{}, 100),
+			),
+		),
+	)
+}
 
 // Parser for parsing Markdown
 type Parser struct {
@@ -20,9 +63,57 @@ type Parser struct {
 	lines        []string
 }
 
-func (p *Parser) render(src []byte) []byte {
-	act := blackfriday.Run(src, blackfriday.WithExtensions(extensions))
-	return act
+// render writes the HTML of the Markdown src to w. The
+// blocks of the document are wrapped in pages, but for
+// an <include>, which brings in pages of its own.
+func (p *Parser) render(w io.Writer, src []byte) error {
+	doc := markdown.Parser().Parse(text.NewReader(src))
+
+	page := &bytes.Buffer{}
+
+	// write writes b to w, ending in a new line
+	write := func(b []byte) {
+		if len(b) == 0 {
+			return
+		}
+
+		_, _ = w.Write(b)
+		if b[len(b)-1] != '\n' {
+			fmt.Fprintln(w)
+		}
+	}
+
+	flush := func() {
+		if page.Len() == 0 {
+			return
+		}
+
+		p.start(w)
+		write(page.Bytes())
+		p.end(w)
+		page.Reset()
+	}
+
+	for n := doc.FirstChild(); n != nil; n = n.NextSibling() {
+		if n.Kind() != kindInclude {
+			if err := markdown.Renderer().Render(page, src, n); err != nil {
+				return err
+			}
+			continue
+		}
+
+		flush()
+
+		bb := &bytes.Buffer{}
+		if err := markdown.Renderer().Render(bb, src, n); err != nil {
+			return err
+		}
+		write(bb.Bytes())
+	}
+
+	flush()
+
+	return nil
 }
 
 func (p *Parser) start(w io.Writer) {
@@ -50,15 +141,11 @@ func (p *Parser) parse(lines []string) ([]byte, error) {
 	bb := &bytes.Buffer{}
 
 	var chunk []string
-	var after string
 
+	// each --- starts another page
 	var ind int
 	for _, line := range lines {
 		ind++
-		if strings.HasPrefix(line, "<include") {
-			after = line
-			break
-		}
 		if strings.HasPrefix(line, "---") {
 			break
 		}
@@ -70,13 +157,9 @@ func (p *Parser) parse(lines []string) ([]byte, error) {
 	in = bytes.TrimSpace(in)
 
 	if len(in) > 0 {
-		p.start(bb)
-		bb.Write(p.render(in))
-		p.end(bb)
-	}
-
-	if len(after) > 0 {
-		fmt.Fprintln(bb, after)
+		if err := p.render(bb, in); err != nil {
+			return nil, err
+		}
 	}
 
 	if ind < len(lines) {
@@ -87,22 +170,7 @@ func (p *Parser) parse(lines []string) ([]byte, error) {
 		bb.Write(b)
 	}
 
-	rx, err := regexp.Compile("<p>(</?.+>)</p>")
-	if err != nil {
-		return nil, err
-	}
-
-	lines = []string{}
-	for _, line := range strings.Split(bb.String(), "\n") {
-		if m := rx.FindStringSubmatch(line); len(m) > 1 {
-			lines = append(lines, m[1])
-			continue
-		}
-		lines = append(lines, line)
-	}
-
-	act := []byte(strings.Join(lines, "\n"))
-	return act, nil
+	return bb.Bytes(), nil
 }
 
 // Parse parses the Markdown and returns the HTML.
@@ -18,13 +18,11 @@ func Test_Parser_ElementNode_Custom(t *testing.T) {
 	src, err := fs.ReadFile(testdata, "assignment.md")
 	r.NoError(err)
 
-	// act := blackfriday.Run(src, blackfriday.WithRenderer(&Renderer{}))
-
 	p := New()
 	act, err := p.Parse(src)
 	r.NoError(err)
 
-	exp := "<page>\n<h1>Assignment 42</h1>\n\n<assignment number=\"42\">\n\n<p>Instructions!</p>\n\n</assignment>\n</page>\n"
+	exp := "<page>\n<h1>Assignment 42</h1>\n<assignment number=\"42\">\n<p>Instructions!</p>\n</assignment>\n</page>\n"
 
 	// fmt.Println(string(act))
 	r.Equal(exp, string(act))
@@ -47,7 +45,6 @@ func Test_Parser_Parse(t *testing.T) {
 
 	exp := `<page>
 <h1>Week 1 - Getting Started with Go - TODO</h1>
-
 <p>overview</p>
 </page>
 <include src="basics.md"></include>
@@ -59,14 +56,10 @@ func Test_Parser_Parse(t *testing.T) {
 <include src="constants.md"></include>
 <page>
 <h1>Exercise (Due Wednesday)</h1>
-
-<p>Locally create a new folder named, &ldquo;gopherguides-intro-to-go&rdquo;. Inside of the folder initialize a new git repository. Next, create a new folder named <code>gopherguides-intro-to-go/week01</code>. Inside of the <code>week01</code> folder initialize a new Go module named &ldquo;github.com/YOUR-USERNAME/gopherguides-intro-to-go/week01&rdquo;.</p>
-
+<p>Locally create a new folder named, &quot;gopherguides-intro-to-go&quot;. Inside of the folder initialize a new git repository. Next, create a new folder named <code>gopherguides-intro-to-go/week01</code>. Inside of the <code>week01</code> folder initialize a new Go module named &quot;github.com/YOUR-USERNAME/gopherguides-intro-to-go/week01&quot;.</p>
 <pre><code>$ go mod init github.com/YOUR-USERNAME/gopherguides-intro-to-go/week01
 </code></pre>
-
-<p>Commit the <code>go.mod</code> to the git repository.  Next, on <a href="https://github.com/">GitHub.com</a> create a new public repository named &ldquo;gopherguides-intro-to-go&rdquo; under your account and upload your local repository following the instructions on GitHub.</p>
-
+<p>Commit the <code>go.mod</code> to the git repository.  Next, on <a href="https://github.com/">GitHub.com</a> create a new public repository named &quot;gopherguides-intro-to-go&quot; under your account and upload your local repository following the instructions on GitHub.</p>
 <pre><code class="language-text">.
 └── gopherguides-intro-to-go
     └── week01
@@ -75,16 +68,88 @@ func Test_Parser_Parse(t *testing.T) {
 </page>
 <page>
 <h1>Assignment 1 (Due Sunday)</h1>
-
 <h2>1.1</h2>
-
-<p>Write a &ldquo;Hello, World&rdquo; style Go program using the <code>main</code> package. Your file should be named <code>main.go</code>. This program <strong>must</strong> compile and print &ldquo;Hello, World!&rdquo;, with a new line after it, to the console window when run. Publish this code to your repository under your <code>week01</code> folder you created earlier this week. Next, create a branch in your local project called, <code>assignment01</code>. Using <a href="pkg.go.dev">pkg.go.dev</a> research the <code>fmt</code> package. Use the <code>fmt</code> package to print &ldquo;Printing, TODO!&rdquo;, replacing &ldquo;TODO&rdquo; with the proper printing verb to properly print the following types: <code>string (&quot;Go&quot;), int (42), bool (true)</code>. Use <code>go vet</code> to confirm you are using the correct verb. Finally, open a PR to merge your new changes into your <code>main</code> branch. This PR should contain a paragraph or two explaining the changes and how they were implemented. Submit the link to the PR to be reviewed.</p>
-
+<p>Write a &quot;Hello, World&quot; style Go program using the <code>main</code> package. Your file should be named <code>main.go</code>. This program <strong>must</strong> compile and print &quot;Hello, World!&quot;, with a new line after it, to the console window when run. Publish this code to your repository under your <code>week01</code> folder you created earlier this week. Next, create a branch in your local project called, <code>assignment01</code>. Using <a href="pkg.go.dev">pkg.go.dev</a> research the <code>fmt</code> package. Use the <code>fmt</code> package to print &quot;Printing, TODO!&quot;, replacing &quot;TODO&quot; with the proper printing verb to properly print the following types: <code>string (&quot;Go&quot;), int (42), bool (true)</code>. Use <code>go vet</code> to confirm you are using the correct verb. Finally, open a PR to merge your new changes into your <code>main</code> branch. This PR should contain a paragraph or two explaining the changes and how they were implemented. Submit the link to the PR to be reviewed.</p>
 <h2>1.2</h2>
-
 <p>Write a short essay describing your history in technology and how you feel that Go fits into your plans for your future. Additionally, write a short essay discussing any surprises you found when researching the <code>fmt</code> package. Include how does printing in Go differ from other languages you may have used before. Please be specific and cite examples. (500 words minimum)</p>
 </page>`
 
 	// fmt.Println(act)
 	r.Equal(exp, act)
 }
+
+func Test_Parser_Extensions(t *testing.T) {
+	t.Parallel()
+
+	table := []struct {
+		name string
+		in   string
+		exp  string
+	}{
+		{
+			name: "footnotes",
+			in:   "A note.[^1]\n\n[^1]: The note.",
+			exp:  `<sup id="fnref:1"><a href="#fn:1" class="footnote-ref" role="doc-noteref">1</a></sup>`,
+		},
+		{
+			name: "task lists",
+			in:   "- [x] done\n- [ ] todo",
+			exp:  `<li><input checked="" disabled="" type="checkbox" /> done</li>`,
+		},
+		{
+			name: "strikethrough",
+			in:   "~~gone~~",
+			exp:  `<del>gone</del>`,
+		},
+		{
+			name: "autolinks",
+			in:   "Visit https://hypemd.dev today.",
+			exp:  `<a href="https://hypemd.dev">https://hypemd.dev</a>`,
+		},
+		{
+			name: "definition lists",
+			in:   "Term\n: Definition",
+			exp:  "<dl>\n<dt>Term</dt>\n<dd>Definition</dd>\n</dl>",
+		},
+		{
+			name: "heading attributes",
+			in:   "# Title {#custom .big}",
+			exp:  `<h1 id="custom" class="big">Title</h1>`,
+		},
+		{
+			name: "code inside lists",
+			in:   "1. Step one:\n\n   ```go\n   x := 1\n   ```\n\n2. Step two\n\n       indented code\n",
+			exp:  "<li>\n<p>Step two</p>\n<pre><code>indented code\n</code></pre>\n</li>",
+		},
+		{
+			name: "mermaid info attributes",
+			in:   "```mermaid render=svg title=\"A & B\"\ngraph LR\n```",
+			exp:  `<pre><code class="language-mermaid" render="svg" title="A &amp; B">graph LR`,
+		},
+		{
+			name: "other info strings",
+			in:   "```go render=svg\nx := 1\n```",
+			exp:  `<pre><code class="language-go">x := 1`,
+		},
+		{
+			name: "no smart quotes",
+			in:   `"quoted" and it's`,
+			exp:  `<p>&quot;quoted&quot; and it's</p>`,
+		},
+	}
+
+	for _, tt := range table {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			r := require.New(t)
+
+			p := New()
+			p.DisablePages = true
+
+			act, err := p.Parse([]byte(tt.in))
+			r.NoError(err)
+			r.Contains(string(act), tt.exp)
+		})
+	}
+}
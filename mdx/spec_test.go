@@ -0,0 +1,58 @@
+package mdx
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// specDeviations are the HTML of the CommonMark spec examples
+// that render differently because of the GFM autolinks, which
+// link bare URLs, and email addresses. They are listed, as
+// deviations from CommonMark, in docs/markdown.md.
+var specDeviations = map[int]string{
+	608: "<p>&lt; <a href=\"https://foo.bar\">https://foo.bar</a> &gt;</p>\n",
+	611: "<p><a href=\"https://example.com\">https://example.com</a></p>\n",
+	612: "<p><a href=\"mailto:foo@bar.example.com\">foo@bar.example.com</a></p>\n",
+}
+
+// Test_CommonMark_Spec runs the examples from the CommonMark
+// spec, testdata/commonmark/spec.json, through the CommonMark engine.
+func Test_CommonMark_Spec(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	b, err := fs.ReadFile(testdata, "commonmark/spec.json")
+	r.NoError(err)
+
+	var examples []struct {
+		Markdown string `json:"markdown"`
+		HTML     string `json:"html"`
+		Example  int    `json:"example"`
+		Section  string `json:"section"`
+	}
+
+	r.NoError(json.Unmarshal(b, &examples))
+	r.NotEmpty(examples)
+
+	for _, ex := range examples {
+		ex := ex
+
+		name := fmt.Sprintf("%d/%s", ex.Example, ex.Section)
+
+		t.Run(name, func(t *testing.T) {
+			exp := ex.HTML
+			if html, ok := specDeviations[ex.Example]; ok {
+				exp = html
+			}
+
+			bb := &bytes.Buffer{}
+			require.NoError(t, commonMark.Convert([]byte(ex.Markdown), bb))
+			require.Equal(t, exp, bb.String(), ex.Markdown)
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package mdx
+
+import (
+	"regexp"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// hypeTags extends CommonMark for the tags of hype:
+//
+//   - an <include> tag, on lines of its own, is a block of
+//     its own, which can interrupt a paragraph, so the pages
+//     of the included document are not inside a paragraph,
+//     or a page, of this one.
+//   - a paragraph of a single line, which starts, and ends,
+//     with a tag, such as <code src="main.go"></code>, or
+//     **bold**, is not wrapped in a <p>.
+type hypeTags struct{}
+
+func (hypeTags) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(
+		parser.WithBlockParsers(
+			// before the HTML blocks of CommonMark
+			util.Prioritized(includeParser{}, 850),
+		),
+	)
+
+	m.Renderer().AddOptions(
+		renderer.WithNodeRenderers(
+			util.Prioritized(tagRenderer{}, 100),
+		),
+	)
+}
+
+// kindInclude is the kind of an includeBlock.
+var kindInclude = ast.NewNodeKind("Include")
+
+// includeBlock is an <include> tag, passed through as is.
+type includeBlock struct {
+	ast.BaseBlock
+}
+
+func (n *includeBlock) Kind() ast.NodeKind {
+	return kindInclude
+}
+
+func (n *includeBlock) IsRaw() bool {
+	return true
+}
+
+func (n *includeBlock) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, nil, nil)
+}
+
+var (
+	includeOpenRx  = regexp.MustCompile(`^ {0,3}<include[\s/>]`)
+	includeCloseRx = regexp.MustCompile(`</include\s*>|/>\s*$`)
+)
+
+// includeParser parses the lines of an <include> tag,
+// to its end tag, into an includeBlock.
+type includeParser struct{}
+
+func (includeParser) Trigger() []byte {
+	return []byte{'<'}
+}
+
+func (includeParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	line, segment := reader.PeekLine()
+	if !includeOpenRx.Match(line) {
+		return nil, parser.NoChildren
+	}
+
+	n := &includeBlock{}
+	n.Lines().Append(segment)
+	reader.AdvanceToEOL()
+
+	return n, parser.NoChildren
+}
+
+func (includeParser) Continue(node ast.Node, reader text.Reader, pc parser.Context) parser.State {
+	lines := node.Lines()
+	last := lines.At(lines.Len() - 1)
+	if includeCloseRx.Match(last.Value(reader.Source())) {
+		return parser.Close
+	}
+
+	line, segment := reader.PeekLine()
+	if util.IsBlank(line) {
+		return parser.Close
+	}
+
+	lines.Append(segment)
+	reader.AdvanceToEOL()
+
+	return parser.Continue | parser.NoChildren
+}
+
+func (includeParser) Close(node ast.Node, reader text.Reader, pc parser.Context) {}
+
+func (includeParser) CanInterruptParagraph() bool {
+	return true
+}
+
+func (includeParser) CanAcceptIndentedLine() bool {
+	return false
+}
+
+// tagRenderer renders the includeBlocks, and the
+// paragraphs, of hypeTags.
+type tagRenderer struct{}
+
+func (r tagRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(kindInclude, r.renderInclude)
+	reg.Register(ast.KindParagraph, r.renderParagraph)
+}
+
+func (r tagRenderer) renderInclude(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+
+	lines := node.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		line := lines.At(i)
+		_, _ = w.Write(line.Value(source))
+	}
+
+	return ast.WalkSkipChildren, nil
+}
+
+// renderParagraph renders a paragraph as CommonMark does,
+// unless it is a single line from one tag to another.
+func (r tagRenderer) renderParagraph(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+	if isTagLine(n) {
+		if !entering {
+			_ = w.WriteByte('\n')
+		}
+		return ast.WalkContinue, nil
+	}
+
+	if !entering {
+		_, _ = w.WriteString("</p>\n")
+		return ast.WalkContinue, nil
+	}
+
+	if n.Attributes() != nil {
+		_, _ = w.WriteString("<p")
+		html.RenderAttributes(w, n, html.ParagraphAttributeFilter)
+		_ = w.WriteByte('>')
+		return ast.WalkContinue, nil
+	}
+
+	_, _ = w.WriteString("<p>")
+	return ast.WalkContinue, nil
+}
+
+// isTagLine returns true if the paragraph n is a single
+// line, which starts, and ends, with a tag: every inline
+// node, but text, is rendered as, or in, a tag.
+func isTagLine(n ast.Node) bool {
+	if n.Lines().Len() != 1 || n.Attributes() != nil {
+		return false
+	}
+
+	isTag := func(c ast.Node) bool {
+		return c != nil && c.Kind() != ast.KindText && c.Kind() != ast.KindString
+	}
+
+	return isTag(n.FirstChild()) && isTag(n.LastChild())
+}
@@ -10,15 +10,31 @@ import (
 	"sync"
 
 	mermaidcmd "github.com/AlexanderGrooff/mermaid-ascii/cmd"
+	"github.com/gopherguides/hype/atomx"
+	"github.com/gopherguides/hype/mermaid"
+)
+
+// How Mermaid diagrams are rendered in HTML. Markdown
+// output is always ASCII art, which reads well as text.
+const (
+	MermaidASCII = "ascii" // ASCII art, in a code block
+	MermaidSVG   = "svg"   // an inline SVG image, in a <figure>
 )
 
 // mermaidMu protects concurrent access to the mermaid-ascii library
 // which uses package-level globals during rendering.
 var mermaidMu sync.Mutex
 
-// Mermaid is a tag that renders Mermaid diagrams as ASCII art.
-// It processes fenced code blocks with the "mermaid" language identifier
-// and converts them to ASCII art using the mermaid-ascii library.
+// Mermaid is a tag that renders Mermaid diagrams as ASCII art,
+// or SVG. It processes fenced code blocks with the "mermaid"
+// language identifier and converts them to ASCII art using the
+// mermaid-ascii library, and, to SVG, using the mermaid package.
+//
+// The render attribute, set in the info string of the block,
+// selects how the diagram is rendered in HTML, and takes
+// precedence over Parser.MermaidRender:
+//
+//	```mermaid render=svg
 type Mermaid struct {
 	*Element
 
@@ -27,6 +43,15 @@ type Mermaid struct {
 
 	// Rendered is the ASCII art output
 	Rendered string
+
+	// Render is how the diagram is rendered in HTML:
+	// MermaidASCII, or MermaidSVG
+	Render string
+
+	// SVG is the SVG output, if Render is MermaidSVG
+	SVG string
+
+	figure bool // the diagram is in a <figure>, that numbers it
 }
 
 func (m *Mermaid) MarshalJSON() ([]byte, error) {
@@ -52,10 +77,20 @@ func (m *Mermaid) MarshalJSON() ([]byte, error) {
 		mm["rendered"] = m.Rendered
 	}
 
+	if len(m.Render) > 0 {
+		mm["render"] = m.Render
+	}
+
+	if len(m.SVG) > 0 {
+		mm["svg"] = m.SVG
+	}
+
 	return json.MarshalIndent(mm, "", "  ")
 }
 
 // MD returns the markdown representation of the rendered Mermaid diagram.
+// A diagram that could only be rendered as SVG is written as its
+// source, in a mermaid block, which many Markdown viewers draw.
 func (m *Mermaid) MD() string {
 	if m == nil {
 		return ""
@@ -65,6 +100,9 @@ func (m *Mermaid) MD() string {
 	defer m.RUnlock()
 
 	if len(m.Rendered) == 0 {
+		if len(m.SVG) > 0 {
+			return fmt.Sprintf("```mermaid\n%s\n```", strings.TrimSpace(m.Source))
+		}
 		return ""
 	}
 
@@ -77,8 +115,10 @@ func (m *Mermaid) MD() string {
 }
 
 // String returns the HTML representation of the rendered Mermaid diagram.
-// It returns just the code element content since the parent pre element
-// is already provided by the markdown parser.
+// ASCII art is returned as just the code element content since the
+// parent pre element is already provided by the markdown parser.
+// SVG is returned in a <figure>, unless the diagram is already in
+// one, such as a numbered <figure id="..." type="diagram">.
 func (m *Mermaid) String() string {
 	if m == nil {
 		return ""
@@ -87,6 +127,13 @@ func (m *Mermaid) String() string {
 	m.RLock()
 	defer m.RUnlock()
 
+	if m.Render == MermaidSVG && len(m.SVG) > 0 {
+		if m.figure {
+			return m.SVG
+		}
+		return fmt.Sprintf("<figure class=\"mermaid\">%s</figure>", m.SVG)
+	}
+
 	if len(m.Rendered) == 0 {
 		return ""
 	}
@@ -96,7 +143,9 @@ func (m *Mermaid) String() string {
 		html.EscapeString(m.Rendered))
 }
 
-// Execute renders the Mermaid diagram to ASCII art.
+// Execute renders the Mermaid diagram to ASCII art, and, if
+// it is rendered as SVG, to SVG. As the markdown output of a
+// diagram rendered as SVG, ASCII art is optional.
 func (m *Mermaid) Execute(ctx context.Context, doc *Document) error {
 	if m == nil {
 		return ErrIsNil("mermaid")
@@ -116,12 +165,24 @@ func (m *Mermaid) Execute(ctx context.Context, doc *Document) error {
 	// Trim whitespace from source to avoid parsing issues
 	source := strings.TrimSpace(m.Source)
 
+	if m.Render == MermaidSVG {
+		svg, err := mermaid.SVG(source)
+		if err != nil {
+			return m.WrapErr(fmt.Errorf("failed to render mermaid diagram: %w", err))
+		}
+
+		m.SVG = svg
+	}
+
 	// Serialize access to mermaid-ascii library which uses package-level globals
 	mermaidMu.Lock()
 	output, err := mermaidcmd.RenderDiagram(source, nil)
 	mermaidMu.Unlock()
 
 	if err != nil {
+		if len(m.SVG) > 0 {
+			return nil
+		}
 		return m.WrapErr(fmt.Errorf("failed to render mermaid diagram: %w", err))
 	}
 
@@ -138,6 +199,15 @@ func NewMermaid(el *Element) (*Mermaid, error) {
 
 	m := &Mermaid{
 		Element: el,
+		Render:  MermaidASCII,
+	}
+
+	if r, ok := el.Get("render"); ok {
+		m.Render = strings.ToLower(strings.TrimSpace(r))
+	}
+
+	if m.Render != MermaidASCII && m.Render != MermaidSVG {
+		return nil, m.WrapErr(fmt.Errorf("invalid mermaid render %q: use %q, or %q", m.Render, MermaidASCII, MermaidSVG))
 	}
 
 	// Extract the mermaid source from the element's children (text content)
@@ -151,15 +221,43 @@ func NewMermaid(el *Element) (*Mermaid, error) {
 }
 
 // NewMermaidNodes creates a new Mermaid node from a fenced code block.
+// Diagrams without a render attribute are rendered as the parser's
+// MermaidRender.
 func NewMermaidNodes(p *Parser, el *Element) (Nodes, error) {
 	if el == nil {
 		return nil, ErrIsNil("element")
 	}
 
+	if _, ok := el.Get("render"); !ok && p != nil && len(p.MermaidRender) > 0 {
+		if err := el.Set("render", p.MermaidRender); err != nil {
+			return nil, err
+		}
+	}
+
 	m, err := NewMermaid(el)
 	if err != nil {
 		return nil, err
 	}
 
+	m.figure = inFigure(el.Parent)
+
 	return Nodes{m}, nil
 }
+
+// inFigure reports whether n is, or is in, a <figure>.
+func inFigure(n Node) bool {
+	for n != nil {
+		if a, ok := n.(Atomable); ok && a.Atom() == atomx.Figure {
+			return true
+		}
+
+		el, ok := n.(*Element)
+		if !ok {
+			return false
+		}
+
+		n = el.Parent
+	}
+
+	return false
+}
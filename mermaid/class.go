@@ -0,0 +1,388 @@
+package mermaid
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+)
+
+// clsClass is a class of a class diagram.
+type clsClass struct {
+	box
+
+	id         string
+	generic    string // the type parameters of the class, such as T
+	annotation string
+	attributes []string
+	methods    []string
+}
+
+// clsRelation is a relationship between two classes.
+type clsRelation struct {
+	from, to int
+	start    string // marker at the class on the left
+	end      string // marker at the class on the right
+	dotted   bool
+	label    []string
+
+	fromCard string // the cardinality at each end
+	toCard   string
+}
+
+// clsDiagram is a parsed class diagram.
+type clsDiagram struct {
+	dir       string
+	classes   []*clsClass
+	ids       map[string]int
+	relations []clsRelation
+}
+
+var (
+	relationRx   = regexp.MustCompile(`^([\w~]+)\s*(?:"([^"]*)"\s*)?(<\||\*|o|<)?(--|\.\.)(\|>|\*|o|>)?\s*(?:"([^"]*)"\s*)?([\w~]+)\s*(?::\s*(.*))?$`)
+	classRx      = regexp.MustCompile(`^class\s+([\w~]+)(?:\["[^"]*"\])?\s*(\{)?\s*(?::::\w+)?$`)
+	memberRx     = regexp.MustCompile(`^([\w~]+)\s*:\s*(.+)$`)
+	annotationRx = regexp.MustCompile(`^<<(.+?)>>\s*([\w~]*)$`)
+)
+
+// heads are the markers of the ends of relationships.
+var heads = map[string]string{
+	"<|": "triangle",
+	"|>": "triangle",
+	"*":  "diamond",
+	"o":  "odiamond",
+	"<":  "open",
+	">":  "open",
+}
+
+func class(id string, lines []string) (string, error) {
+	d, err := parseClass(lines)
+	if err != nil {
+		return "", err
+	}
+
+	return d.svg(id), nil
+}
+
+// parseClass parses the lines of a class
+// diagram, after its header.
+func parseClass(lines []string) (*clsDiagram, error) {
+	d := &clsDiagram{
+		dir: "TB",
+		ids: map[string]int{},
+	}
+
+	var open *clsClass
+
+	for i, line := range lines {
+		if open != nil {
+			switch {
+			case line == "}":
+				open = nil
+			case annotationRx.MatchString(line):
+				open.annotation = annotationRx.FindStringSubmatch(line)[1]
+			default:
+				open.member(line)
+			}
+			continue
+		}
+
+		if m := classRx.FindStringSubmatch(line); m != nil {
+			// generic classes are referred to by name
+			name, generic, _ := strings.Cut(m[1], "~")
+
+			c := d.class(name)
+			if len(generic) > 0 {
+				c.generic = strings.TrimSuffix(generic, "~")
+			}
+			if len(m[2]) > 0 {
+				open = c
+			}
+			continue
+		}
+
+		if m := relationRx.FindStringSubmatch(line); m != nil {
+			r := clsRelation{
+				from:     d.index(m[1]),
+				fromCard: m[2],
+				start:    heads[m[3]],
+				dotted:   m[4] == "..",
+				end:      heads[m[5]],
+				toCard:   m[6],
+				to:       d.index(m[7]),
+			}
+
+			if len(m[8]) > 0 {
+				r.label = label(m[8])
+			}
+
+			d.relations = append(d.relations, r)
+			continue
+		}
+
+		if m := annotationRx.FindStringSubmatch(line); m != nil && len(m[2]) > 0 {
+			d.class(m[2]).annotation = m[1]
+			continue
+		}
+
+		if m := memberRx.FindStringSubmatch(line); m != nil {
+			d.class(m[1]).member(m[2])
+			continue
+		}
+
+		fields := strings.Fields(line)
+
+		switch fields[0] {
+		case "direction":
+			if len(fields) > 1 {
+				d.dir = strings.ToUpper(fields[1])
+			}
+			continue
+		case "note", "classDef", "cssClass", "style", "click", "link", "callback", "namespace", "}":
+			continue
+		}
+
+		return nil, fmt.Errorf("line %d: unknown statement %q", i+2, line)
+	}
+
+	if open != nil {
+		return nil, fmt.Errorf("class %s: missing }", open.id)
+	}
+
+	if len(d.classes) == 0 {
+		return nil, fmt.Errorf("class diagram has no classes")
+	}
+
+	return d, nil
+}
+
+// class returns the class with the id, adding it
+// the first time it is used.
+func (d *clsDiagram) class(id string) *clsClass {
+	return d.classes[d.index(id)]
+}
+
+func (d *clsDiagram) index(id string) int {
+	id, _, _ = strings.Cut(id, "~")
+
+	if i, ok := d.ids[id]; ok {
+		return i
+	}
+
+	d.ids[id] = len(d.classes)
+	d.classes = append(d.classes, &clsClass{id: id})
+
+	return len(d.classes) - 1
+}
+
+// member adds an attribute, or a method, to the class.
+// Methods are the members with parentheses.
+func (c *clsClass) member(s string) {
+	s = strings.TrimSpace(s)
+	if len(s) == 0 {
+		return
+	}
+
+	s = strings.TrimRight(s, "$*")
+	s = generics(s)
+
+	if strings.Contains(s, "(") {
+		c.methods = append(c.methods, s)
+		return
+	}
+
+	c.attributes = append(c.attributes, s)
+}
+
+// generics writes Type~T~ as Type<T>.
+func generics(s string) string {
+	var bb strings.Builder
+
+	var open bool
+	for _, r := range s {
+		if r != '~' {
+			bb.WriteRune(r)
+			continue
+		}
+
+		if open {
+			bb.WriteRune('>')
+		} else {
+			bb.WriteRune('<')
+		}
+		open = !open
+	}
+
+	return bb.String()
+}
+
+// title returns the lines of the header of the class.
+func (c *clsClass) title() []string {
+	name := c.id
+	if len(c.generic) > 0 {
+		name += "<" + generics(c.generic) + ">"
+	}
+
+	if len(c.annotation) == 0 {
+		return []string{name}
+	}
+
+	return []string{"«" + c.annotation + "»", name}
+}
+
+// sections returns the heights of the sections of the class.
+func (c *clsClass) sections() (float64, float64, float64) {
+	section := func(n int) float64 {
+		return float64(n)*lineHeight + 10
+	}
+
+	return section(len(c.title())), section(len(c.attributes)), section(len(c.methods))
+}
+
+func (c *clsClass) size() {
+	var w float64
+	for _, ls := range [][]string{c.title(), c.attributes, c.methods} {
+		tw, _ := textSize(ls)
+		w = math.Max(w, tw)
+	}
+
+	t, a, m := c.sections()
+
+	c.w = math.Max(w+24, 80)
+	c.h = t + a + m
+}
+
+func (d *clsDiagram) svg(id string) string {
+	boxes := make([]*box, len(d.classes))
+	for i, c := range d.classes {
+		c.size()
+		boxes[i] = &c.box
+	}
+
+	var edges []edge
+	for _, r := range d.relations {
+		edges = append(edges, edge{r.from, r.to})
+	}
+
+	layered(boxes, edges, d.dir, 70, 50)
+
+	var bb bounds
+	for _, cls := range d.classes {
+		bb.add(cls.x-cls.w/2, cls.y-cls.h/2, cls.x+cls.w/2, cls.y+cls.h/2)
+	}
+
+	for _, r := range d.relations {
+		p1, mid, p2 := d.ends(r)
+		tw, th := textSize(r.label)
+		bb.add(mid.x-tw/2-4, mid.y-th/2-4, mid.x+tw/2+4, mid.y+th/2+4)
+
+		// room for the cardinalities
+		for _, p := range []point{p1, p2} {
+			bb.add(p.x-30, p.y-30, p.x+50, p.y+30)
+		}
+	}
+
+	c := &canvas{id: id}
+	c.printf(`<g transform="translate(%s,%s)">`+"\n", num(margin-bb.x0), num(margin-bb.y0))
+
+	for _, r := range d.relations {
+		d.relation(c, r)
+	}
+
+	for _, cls := range d.classes {
+		cls.draw(c)
+	}
+
+	c.printf("</g>\n")
+
+	return c.document("class", bb.x1-bb.x0+2*margin, bb.y1-bb.y0+2*margin)
+}
+
+// ends returns the ends of the line of the relationship,
+// and the point halfway along it, for its label.
+func (d *clsDiagram) ends(r clsRelation) (point, point, point) {
+	a, b := d.classes[r.from], d.classes[r.to]
+
+	if r.from == r.to {
+		x, y := a.x+a.w/2, a.y
+		return point{x, y - 10}, point{x + 50, y}, point{x, y + 10}
+	}
+
+	p1, ctrl, p2, bent := route(&a.box, &b.box, "rect", "rect")
+	if bent {
+		return p1, curveMid(p1, ctrl, p2), p2
+	}
+
+	return p1, point{(p1.x + p2.x) / 2, (p1.y + p2.y) / 2}, p2
+}
+
+func (d *clsDiagram) relation(c *canvas, r clsRelation) {
+	a, b := d.classes[r.from], d.classes[r.to]
+
+	attrs := fmt.Sprintf(` stroke="%s" stroke-width="1"`, lineColor)
+	if r.dotted {
+		attrs += ` stroke-dasharray="3,3"`
+	}
+
+	attrs += c.marker("start", r.start) + c.marker("end", r.end)
+
+	p1, mid, p2 := d.ends(r)
+
+	if r.from == r.to {
+		c.printf(`<path d="M%s,%sC%s,%s %s,%s %s,%s" fill="none"%s/>`+"\n",
+			num(p1.x), num(p1.y), num(p1.x+40), num(p1.y-20), num(p2.x+40), num(p2.y+20), num(p2.x), num(p2.y), attrs)
+	} else if _, ctrl, _, bent := route(&a.box, &b.box, "rect", "rect"); bent {
+		c.printf(`<path d="M%s,%sQ%s,%s %s,%s" fill="none"%s/>`+"\n", num(p1.x), num(p1.y), num(ctrl.x), num(ctrl.y), num(p2.x), num(p2.y), attrs)
+	} else {
+		c.line([]point{p1, p2}, attrs)
+	}
+
+	if len(r.label) > 0 {
+		tw, th := textSize(r.label)
+		c.printf(`<rect x="%s" y="%s" width="%s" height="%s" fill="#FFFFFF" opacity="0.9"/>`+"\n", num(mid.x-tw/2-2), num(mid.y-th/2), num(tw+4), num(th))
+		c.text(mid.x, mid.y, r.label, "middle", ` fill="#333333"`)
+	}
+
+	// cardinalities are beside the ends of the line
+	card := func(s string, p, q point) {
+		if len(s) == 0 {
+			return
+		}
+
+		dx, dy := q.x-p.x, q.y-p.y
+		l := math.Hypot(dx, dy)
+		if l == 0 {
+			return
+		}
+
+		x, y := p.x+dx/l*22, p.y+dy/l*22
+		c.text(x+10, y, []string{s}, "start", ` fill="#333333" font-size="12"`)
+	}
+
+	card(r.fromCard, p1, p2)
+	card(r.toCard, p2, p1)
+}
+
+func (cls *clsClass) draw(c *canvas) {
+	x, y := cls.x-cls.w/2, cls.y-cls.h/2
+	t, a, _ := cls.sections()
+
+	c.printf(`<g class="node" id="%s-%s">`+"\n", c.id, cls.id)
+	c.printf(`<rect x="%s" y="%s" width="%s" height="%s" fill="%s" stroke="%s"/>`+"\n", num(x), num(y), num(cls.w), num(cls.h), nodeFill, nodeStroke)
+
+	c.text(cls.x, y+t/2, cls.title(), "middle", ` fill="#333333" font-weight="bold"`)
+
+	for _, sy := range []float64{y + t, y + t + a} {
+		c.printf(`<line x1="%s" y1="%s" x2="%s" y2="%s" stroke="%s"/>`+"\n", num(x), num(sy), num(x+cls.w), num(sy), nodeStroke)
+	}
+
+	if len(cls.attributes) > 0 {
+		c.text(x+10, y+t+a/2, cls.attributes, "start", ` fill="#333333"`)
+	}
+
+	if len(cls.methods) > 0 {
+		c.text(x+10, y+cls.h-(cls.h-t-a)/2, cls.methods, "start", ` fill="#333333"`)
+	}
+
+	c.printf("</g>\n")
+}
@@ -0,0 +1,64 @@
+package mermaid
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseClass(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	d, err := parseClass([]string{
+		"direction LR",
+		"class Shape {",
+		"<<interface>>",
+		"+Area() float64",
+		"}",
+		"class Square~T~ {",
+		"+int side",
+		"+Area() float64$",
+		"}",
+		"Shape <|.. Square",
+		`Canvas "1" o-- "*" Shape : draws`,
+		"Canvas --> Pen",
+		"<<service>> Pen",
+		"note for Pen \"a note\"",
+	})
+	r.NoError(err)
+
+	r.Equal("LR", d.dir)
+	r.Len(d.classes, 4)
+
+	shape := d.class("Shape")
+	r.Equal("interface", shape.annotation)
+	r.Equal([]string{"+Area() float64"}, shape.methods)
+	r.Empty(shape.attributes)
+
+	sq := d.class("Square")
+	r.Equal([]string{"+int side"}, sq.attributes)
+	r.Equal([]string{"+Area() float64"}, sq.methods)
+	r.Equal([]string{"«interface»", "Shape"}, shape.title())
+	r.Equal([]string{"Square<T>"}, sq.title())
+
+	r.Equal("service", d.class("Pen").annotation)
+
+	r.Len(d.relations, 3)
+
+	impl := d.relations[0]
+	r.Equal("triangle", impl.start)
+	r.Empty(impl.end)
+	r.True(impl.dotted)
+
+	agg := d.relations[1]
+	r.Equal("odiamond", agg.start)
+	r.Equal("1", agg.fromCard)
+	r.Equal("*", agg.toCard)
+	r.Equal([]string{"draws"}, agg.label)
+
+	assoc := d.relations[2]
+	r.Empty(assoc.start)
+	r.Equal("open", assoc.end)
+	r.False(assoc.dotted)
+}
@@ -0,0 +1,581 @@
+package mermaid
+
+import (
+	"fmt"
+	"html"
+	"math"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// fcNode is a node of a flowchart.
+type fcNode struct {
+	box
+
+	id    string
+	label []string
+	shape string // rect, round, stadium, circle, ellipse, diamond, hexagon, or cylinder
+}
+
+// fcEdge is a link between two nodes of a flowchart.
+type fcEdge struct {
+	from, to int
+	label    []string
+	style    string // solid, dotted, thick, or invisible
+	start    string // marker at the start of the link
+	end      string // marker at the end of the link
+}
+
+// fcGraph is a parsed flowchart.
+type fcGraph struct {
+	kind  string // class of the SVG document; default: flowchart
+	dir   string
+	nodes []*fcNode
+	ids   map[string]int
+	edges []fcEdge
+}
+
+// shapes are the delimiters of the text of the
+// shapes of nodes, longest first.
+var shapes = []struct {
+	open, close, shape string
+}{
+	{"(((", ")))", "circle"},
+	{"((", "))", "circle"},
+	{"([", "])", "stadium"},
+	{"[[", "]]", "rect"},
+	{"[(", ")]", "cylinder"},
+	{"{{", "}}", "hexagon"},
+	{"[/", "/]", "rect"},
+	{"[\\", "\\]", "rect"},
+	{"[/", "\\]", "rect"},
+	{"[\\", "/]", "rect"},
+	{"[", "]", "rect"},
+	{"(", ")", "round"},
+	{"{", "}", "diamond"},
+	{">", "]", "rect"},
+}
+
+var (
+	textLinkRx  = regexp.MustCompile(`^(<?)(--|==|-\.)\s+(.*?)\s+(-{2,}|={2,}|\.+-)([>ox]?)`)
+	plainLinkRx = regexp.MustCompile(`^(<?)(-{2,}|={2,}|-\.+-|~{3,})([>ox]?)`)
+	pipeLabelRx = regexp.MustCompile(`^\s*\|([^|]*)\|`)
+)
+
+func flowchart(id string, header []string, lines []string) (string, error) {
+	g, err := parseFlowchart(header, lines)
+	if err != nil {
+		return "", err
+	}
+
+	return g.svg(id), nil
+}
+
+// parseFlowchart parses a flowchart, from the
+// direction in its header, and the lines after it.
+func parseFlowchart(header []string, lines []string) (*fcGraph, error) {
+	g := &fcGraph{
+		dir: "TB",
+		ids: map[string]int{},
+	}
+
+	if len(header) > 0 {
+		dir := strings.ToUpper(strings.TrimSuffix(header[0], ";"))
+		switch dir {
+		case "TD", "TB":
+		case "BT", "LR", "RL":
+			g.dir = dir
+		default:
+			return nil, fmt.Errorf("unknown flowchart direction %q", header[0])
+		}
+	}
+
+	for i, line := range lines {
+		for _, st := range statements(line) {
+			if err := g.statement(st); err != nil {
+				return nil, fmt.Errorf("line %d: %w", i+2, err)
+			}
+		}
+	}
+
+	return g, nil
+}
+
+// statements splits line on the semicolons
+// that are not in the text of a node.
+func statements(line string) []string {
+	var res []string
+
+	var depth int
+	var quoted bool
+	var start int
+
+	for i, r := range line {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case quoted:
+		case strings.ContainsRune("[({", r):
+			depth++
+		case strings.ContainsRune("])}", r):
+			depth--
+		case r == ';' && depth <= 0:
+			res = append(res, line[start:i])
+			start = i + 1
+		}
+	}
+
+	res = append(res, line[start:])
+
+	var sts []string
+	for _, s := range res {
+		if s = strings.TrimSpace(s); len(s) > 0 {
+			sts = append(sts, s)
+		}
+	}
+
+	return sts
+}
+
+// ignored are the statements of flowcharts
+// that do not change the layout.
+var ignored = map[string]bool{
+	"class":     true,
+	"classDef":  true,
+	"click":     true,
+	"direction": true,
+	"end":       true,
+	"linkStyle": true,
+	"style":     true,
+	"subgraph":  true,
+}
+
+func (g *fcGraph) statement(s string) error {
+	if ignored[strings.Fields(s)[0]] {
+		return nil
+	}
+
+	sc := &scanner{s: s}
+
+	from, err := g.group(sc)
+	if err != nil {
+		return err
+	}
+
+	for {
+		sc.space()
+		if sc.done() {
+			return nil
+		}
+
+		e, err := link(sc)
+		if err != nil {
+			return err
+		}
+
+		sc.space()
+
+		to, err := g.group(sc)
+		if err != nil {
+			return err
+		}
+
+		for _, f := range from {
+			for _, t := range to {
+				e.from, e.to = f, t
+				g.edges = append(g.edges, e)
+			}
+		}
+
+		from = to
+	}
+}
+
+// group parses nodes joined by &, such as "A & B".
+func (g *fcGraph) group(sc *scanner) ([]int, error) {
+	var res []int
+
+	for {
+		i, err := g.node(sc)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, i)
+
+		sc.space()
+		if !sc.accept("&") {
+			return res, nil
+		}
+		sc.space()
+	}
+}
+
+// node parses a node, such as `A`, or `A[Text]`, and
+// returns its index. A node is added the first time its
+// id is used, and its text, and shape, may be set by
+// any use of it.
+func (g *fcGraph) node(sc *scanner) (int, error) {
+	id := sc.ident()
+	if len(id) == 0 {
+		return 0, fmt.Errorf("expected a node at %q", sc.rest())
+	}
+
+	i, ok := g.ids[id]
+	if !ok {
+		i = len(g.nodes)
+		g.ids[id] = i
+		g.nodes = append(g.nodes, &fcNode{
+			id:    id,
+			label: []string{id},
+			shape: "rect",
+		})
+	}
+
+	n := g.nodes[i]
+
+	for _, sh := range shapes {
+		rest := sc.rest()
+		if !strings.HasPrefix(rest, sh.open) || !strings.Contains(rest[len(sh.open):], sh.close) {
+			continue
+		}
+
+		sc.i += len(sh.open)
+
+		text, err := sc.until(sh.close)
+		if err != nil {
+			return 0, fmt.Errorf("node %s: %w", id, err)
+		}
+
+		n.label = label(text)
+		n.shape = sh.shape
+		break
+	}
+
+	// classes, such as A:::warning
+	if sc.accept(":::") {
+		sc.ident()
+	}
+
+	return i, nil
+}
+
+// link parses a link, such as "-->", "-. text .->", or "==>|text|".
+func link(sc *scanner) (fcEdge, error) {
+	e := fcEdge{style: "solid"}
+
+	var body, head string
+
+	if m := textLinkRx.FindStringSubmatch(sc.rest()); m != nil {
+		sc.i += len(m[0])
+		e.start = m[1]
+		body = m[2] + m[4]
+		e.label = label(m[3])
+		head = m[5]
+	} else if m := plainLinkRx.FindStringSubmatch(sc.rest()); m != nil {
+		sc.i += len(m[0])
+		e.start = m[1]
+		body = m[2]
+		head = m[3]
+	} else {
+		return e, fmt.Errorf("expected a link at %q", sc.rest())
+	}
+
+	if m := pipeLabelRx.FindStringSubmatch(sc.rest()); m != nil {
+		sc.i += len(m[0])
+		e.label = label(m[1])
+	}
+
+	switch {
+	case strings.HasPrefix(body, "~"):
+		e.style = "invisible"
+	case strings.Contains(body, "."):
+		e.style = "dotted"
+	case strings.Contains(body, "="):
+		e.style = "thick"
+	}
+
+	heads := map[string]string{">": "arrow", "o": "circle", "x": "cross", "<": "arrow"}
+	e.start = heads[e.start]
+	e.end = heads[head]
+
+	return e, nil
+}
+
+// size sets the size of the node, for its text, and shape.
+func (n *fcNode) size() {
+	tw, th := textSize(n.label)
+
+	n.w, n.h = tw+30, th+20
+
+	switch n.shape {
+	case "circle":
+		d := math.Max(tw+20, th+20)
+		n.w, n.h = d, d
+	case "diamond":
+		n.w, n.h = tw*1.4+40, th*1.4+40
+	case "ellipse":
+		n.w, n.h = tw*1.3+30, th+24
+	case "hexagon":
+		n.w = tw + 50
+	case "stadium":
+		n.w = tw + th + 30
+	case "cylinder":
+		n.h = th + 36
+	}
+}
+
+// clipShape is the shape nodes are clipped to, for edges.
+func (n *fcNode) clipShape() string {
+	switch n.shape {
+	case "circle", "ellipse":
+		return "ellipse"
+	case "diamond":
+		return "diamond"
+	}
+
+	return "rect"
+}
+
+func (g *fcGraph) svg(id string) string {
+	boxes := make([]*box, len(g.nodes))
+	for i, n := range g.nodes {
+		n.size()
+		boxes[i] = &n.box
+	}
+
+	var edges []edge
+	for _, e := range g.edges {
+		edges = append(edges, edge{e.from, e.to})
+	}
+
+	rankSep := 50.0
+	for _, e := range g.edges {
+		if len(e.label) > 0 {
+			rankSep = 70
+			break
+		}
+	}
+
+	layered(boxes, edges, g.dir, rankSep, 40)
+
+	var bb bounds
+	for _, n := range g.nodes {
+		bb.add(n.x-n.w/2, n.y-n.h/2, n.x+n.w/2, n.y+n.h/2)
+	}
+
+	for _, e := range g.edges {
+		x, y := g.labelAt(e)
+		tw, th := textSize(e.label)
+		bb.add(x-tw/2-4, y-th/2-4, x+tw/2+4, y+th/2+4)
+
+		if e.from == e.to {
+			n := g.nodes[e.from]
+			bb.add(n.x, n.y-n.h/2-30, n.x+n.w/2+45, n.y)
+		}
+	}
+
+	c := &canvas{id: id}
+	c.printf(`<g transform="translate(%s,%s)">`+"\n", num(margin-bb.x0), num(margin-bb.y0))
+
+	c.printf("<g class=\"edges\">\n")
+	for _, e := range g.edges {
+		g.edge(c, e)
+	}
+	c.printf("</g>\n")
+
+	c.printf("<g class=\"nodes\">\n")
+	for _, n := range g.nodes {
+		n.draw(c)
+	}
+	c.printf("</g>\n")
+
+	// edge labels are drawn over the nodes, and edges
+	c.printf("<g class=\"edge-labels\">\n")
+	for _, e := range g.edges {
+		if len(e.label) == 0 || e.style == "invisible" {
+			continue
+		}
+
+		x, y := g.labelAt(e)
+
+		tw, th := textSize(e.label)
+		c.printf(`<rect x="%s" y="%s" width="%s" height="%s" fill="#E8E8E8" opacity="0.9"/>`+"\n", num(x-tw/2-3), num(y-th/2-1), num(tw+6), num(th+2))
+		c.text(x, y, e.label, "middle", ` fill="#333333"`)
+	}
+	c.printf("</g>\n</g>\n")
+
+	kind := g.kind
+	if len(kind) == 0 {
+		kind = "flowchart"
+	}
+
+	return c.document(kind, bb.x1-bb.x0+2*margin, bb.y1-bb.y0+2*margin)
+}
+
+func (g *fcGraph) edge(c *canvas, e fcEdge) {
+	if e.style == "invisible" {
+		return
+	}
+
+	a, b := g.nodes[e.from], g.nodes[e.to]
+
+	attrs := fmt.Sprintf(` stroke="%s" stroke-width="1.5"`, lineColor)
+	switch e.style {
+	case "dotted":
+		attrs += ` stroke-dasharray="3,3"`
+	case "thick":
+		attrs = fmt.Sprintf(` stroke="%s" stroke-width="3.5"`, lineColor)
+	}
+
+	attrs += c.marker("start", e.start) + c.marker("end", e.end)
+
+	if e.from == e.to {
+		// a loop on the top right corner of the node
+		x, y := a.x+a.w/2, a.y-a.h/2
+		c.printf(`<path d="M%s,%sC%s,%s %s,%s %s,%s" fill="none"%s/>`+"\n",
+			num(x-10), num(y), num(x+30), num(y-40), num(x+40), num(y+10), num(x), num(y+10), attrs)
+		return
+	}
+
+	p1, ctrl, p2, bent := route(&a.box, &b.box, a.clipShape(), b.clipShape())
+	if !bent {
+		c.line([]point{p1, p2}, attrs)
+		return
+	}
+
+	c.printf(`<path d="M%s,%sQ%s,%s %s,%s" fill="none"%s/>`+"\n", num(p1.x), num(p1.y), num(ctrl.x), num(ctrl.y), num(p2.x), num(p2.y), attrs)
+}
+
+// labelAt returns the center of the label of the edge.
+func (g *fcGraph) labelAt(e fcEdge) (float64, float64) {
+	a, b := g.nodes[e.from], g.nodes[e.to]
+
+	if e.from == e.to {
+		return a.x + a.w/2 + 20, a.y - a.h/2 - 10
+	}
+
+	p1, ctrl, p2, bent := route(&a.box, &b.box, a.clipShape(), b.clipShape())
+	if bent {
+		m := curveMid(p1, ctrl, p2)
+		return m.x, m.y
+	}
+
+	return (p1.x + p2.x) / 2, (p1.y + p2.y) / 2
+}
+
+func (n *fcNode) draw(c *canvas) {
+	x, y, w, h := n.x-n.w/2, n.y-n.h/2, n.w, n.h
+
+	attrs := fmt.Sprintf(` fill="%s" stroke="%s" stroke-width="1"`, nodeFill, nodeStroke)
+
+	c.printf(`<g class="node" id="%s-%s">`, c.id, html.EscapeString(n.id))
+
+	switch n.shape {
+	case "round":
+		c.printf(`<rect x="%s" y="%s" width="%s" height="%s" rx="8" ry="8"%s/>`, num(x), num(y), num(w), num(h), attrs)
+	case "stadium":
+		c.printf(`<rect x="%s" y="%s" width="%s" height="%s" rx="%s" ry="%s"%s/>`, num(x), num(y), num(w), num(h), num(h/2), num(h/2), attrs)
+	case "circle":
+		c.printf(`<circle cx="%s" cy="%s" r="%s"%s/>`, num(n.x), num(n.y), num(w/2), attrs)
+	case "ellipse":
+		c.printf(`<ellipse cx="%s" cy="%s" rx="%s" ry="%s"%s/>`, num(n.x), num(n.y), num(w/2), num(h/2), attrs)
+	case "diamond":
+		c.printf(`<polygon points="%s,%s %s,%s %s,%s %s,%s"%s/>`,
+			num(n.x), num(y), num(x+w), num(n.y), num(n.x), num(y+h), num(x), num(n.y), attrs)
+	case "hexagon":
+		d := h / 4
+		c.printf(`<polygon points="%s,%s %s,%s %s,%s %s,%s %s,%s %s,%s"%s/>`,
+			num(x+d), num(y), num(x+w-d), num(y), num(x+w), num(n.y), num(x+w-d), num(y+h), num(x+d), num(y+h), num(x), num(n.y), attrs)
+	case "cylinder":
+		ry := 7.0
+		c.printf(`<path d="M%s,%sa%s,%s 0 0,0 %s,0a%s,%s 0 0,0 %s,0v%sa%s,%s 0 0,1 %s,0v%s"%s/>`,
+			num(x), num(y+ry), num(w/2), num(ry), num(w), num(w/2), num(ry), num(-w), num(h-2*ry), num(w/2), num(ry), num(w), num(-(h - 2*ry)), attrs)
+	default:
+		c.printf(`<rect x="%s" y="%s" width="%s" height="%s"%s/>`, num(x), num(y), num(w), num(h), attrs)
+	}
+
+	c.printf("\n")
+	c.text(n.x, n.y, n.label, "middle", ` fill="#333333"`)
+	c.printf("</g>\n")
+}
+
+// scanner reads the tokens of a statement.
+type scanner struct {
+	s string
+	i int
+}
+
+func (sc *scanner) rest() string {
+	return sc.s[sc.i:]
+}
+
+func (sc *scanner) done() bool {
+	return sc.i >= len(sc.s)
+}
+
+func (sc *scanner) space() {
+	for !sc.done() && (sc.s[sc.i] == ' ' || sc.s[sc.i] == '\t') {
+		sc.i++
+	}
+}
+
+func (sc *scanner) accept(s string) bool {
+	if strings.HasPrefix(sc.rest(), s) {
+		sc.i += len(s)
+		return true
+	}
+
+	return false
+}
+
+// ident reads an id, of letters, digits, and underscores.
+// A dash, that is not the start of a link, is part of an id.
+func (sc *scanner) ident() string {
+	start := sc.i
+
+	for i, r := range sc.rest() {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+			continue
+		}
+
+		if r == '-' && isIdentStart(sc.rest()[i+1:]) {
+			continue
+		}
+
+		sc.i = start + i
+		return sc.s[start:sc.i]
+	}
+
+	sc.i = len(sc.s)
+	return sc.s[start:]
+}
+
+func isIdentStart(s string) bool {
+	for _, r := range s {
+		return unicode.IsLetter(r) || unicode.IsDigit(r)
+	}
+
+	return false
+}
+
+// until reads the text before end, which may be quoted.
+func (sc *scanner) until(end string) (string, error) {
+	rest := sc.rest()
+
+	if strings.HasPrefix(rest, `"`) {
+		if q := strings.Index(rest[1:], `"`); q >= 0 && strings.HasPrefix(rest[q+2:], end) {
+			sc.i += q + 2 + len(end)
+			return rest[:q+2], nil
+		}
+	}
+
+	i := strings.Index(rest, end)
+	if i < 0 {
+		return "", fmt.Errorf("missing %q", end)
+	}
+
+	sc.i += i + len(end)
+
+	return rest[:i], nil
+}
@@ -0,0 +1,80 @@
+package mermaid
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseFlowchart(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	g, err := parseFlowchart([]string{"LR"}, []string{
+		`A[Start] --> B(Round) & C{"Choice"}`,
+		`B -.-> D([Stadium]); C == yes ==> E((Circle))`,
+		`E ---|plain| F{{Hex}}:::warning`,
+		`F <--> G[(DB)]`,
+		`G ~~~ A`,
+		`classDef warning fill:#f96`,
+		`subgraph one`,
+		`my-node --x A`,
+		`end`,
+	})
+	r.NoError(err)
+
+	r.Equal("LR", g.dir)
+
+	type node struct{ id, label, shape string }
+
+	var nodes []node
+	for _, n := range g.nodes {
+		nodes = append(nodes, node{n.id, n.label[0], n.shape})
+	}
+
+	r.Equal([]node{
+		{"A", "Start", "rect"},
+		{"B", "Round", "round"},
+		{"C", "Choice", "diamond"},
+		{"D", "Stadium", "stadium"},
+		{"E", "Circle", "circle"},
+		{"F", "Hex", "hexagon"},
+		{"G", "DB", "cylinder"},
+		{"my-node", "my-node", "rect"},
+	}, nodes)
+
+	type link struct {
+		from, to   string
+		label      string
+		style      string
+		start, end string
+	}
+
+	var links []link
+	for _, e := range g.edges {
+		var l string
+		if len(e.label) > 0 {
+			l = e.label[0]
+		}
+		links = append(links, link{g.nodes[e.from].id, g.nodes[e.to].id, l, e.style, e.start, e.end})
+	}
+
+	r.Equal([]link{
+		{"A", "B", "", "solid", "", "arrow"},
+		{"A", "C", "", "solid", "", "arrow"},
+		{"B", "D", "", "dotted", "", "arrow"},
+		{"C", "E", "yes", "thick", "", "arrow"},
+		{"E", "F", "plain", "solid", "", ""},
+		{"F", "G", "", "solid", "arrow", "arrow"},
+		{"G", "A", "", "invisible", "", ""},
+		{"my-node", "A", "", "solid", "", "cross"},
+	}, links)
+}
+
+func Test_statements(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	act := statements(`A[a;b] --> B; B --> C;`)
+	r.Equal([]string{"A[a;b] --> B", "B --> C"}, act)
+}
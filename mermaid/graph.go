@@ -0,0 +1,143 @@
+package mermaid
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+)
+
+// Graph is a flowchart built in Go, rather than parsed from
+// Mermaid source, so graphs written in other languages, such
+// as Graphviz DOT, can be drawn with the flowchart layout.
+type Graph struct {
+	Kind  string // class of the SVG document; default: flowchart
+	Dir   string // TB, BT, LR, or RL; default: TB
+	Nodes []Node
+	Edges []Edge
+}
+
+// Node is a node of a Graph.
+type Node struct {
+	ID    string
+	Label string // lines are separated by "\n"; default: ID
+	Shape string // rect, round, stadium, circle, ellipse, diamond, hexagon, or cylinder; default: rect
+}
+
+// Edge is a link between two nodes of a Graph.
+// Nodes that are not in Graph.Nodes are added,
+// as rectangles, the first time they are used.
+type Edge struct {
+	From  string
+	To    string
+	Label string
+	Style string // solid, dotted, thick, or invisible; default: solid
+	Start string // marker at the start: arrow, open, circle, cross, triangle, diamond, odiamond, or none
+	End   string // marker at the end, as Start
+}
+
+// SVG renders the graph as an SVG document.
+func (g Graph) SVG() (string, error) {
+	fg := &fcGraph{
+		kind: g.Kind,
+		dir:  "TB",
+		ids:  map[string]int{},
+	}
+
+	switch dir := strings.ToUpper(g.Dir); dir {
+	case "", "TB", "TD":
+	case "BT", "LR", "RL":
+		fg.dir = dir
+	default:
+		return "", fmt.Errorf("unknown graph direction %q", g.Dir)
+	}
+
+	add := func(n Node) (int, error) {
+		if i, ok := fg.ids[n.ID]; ok {
+			return i, nil
+		}
+
+		shape := n.Shape
+		switch shape {
+		case "":
+			shape = "rect"
+		case "rect", "round", "stadium", "circle", "ellipse", "diamond", "hexagon", "cylinder":
+		default:
+			return 0, fmt.Errorf("node %s: unknown shape %q", n.ID, n.Shape)
+		}
+
+		text := n.Label
+		if len(text) == 0 {
+			text = n.ID
+		}
+
+		i := len(fg.nodes)
+		fg.ids[n.ID] = i
+		fg.nodes = append(fg.nodes, &fcNode{
+			id:    n.ID,
+			label: strings.Split(text, "\n"),
+			shape: shape,
+		})
+
+		return i, nil
+	}
+
+	for _, n := range g.Nodes {
+		if _, err := add(n); err != nil {
+			return "", err
+		}
+	}
+
+	for _, e := range g.Edges {
+		from, err := add(Node{ID: e.From})
+		if err != nil {
+			return "", err
+		}
+
+		to, err := add(Node{ID: e.To})
+		if err != nil {
+			return "", err
+		}
+
+		fe := fcEdge{
+			from:  from,
+			to:    to,
+			style: e.Style,
+		}
+
+		switch fe.style {
+		case "":
+			fe.style = "solid"
+		case "solid", "dotted", "thick", "invisible":
+		default:
+			return "", fmt.Errorf("edge %s -> %s: unknown style %q", e.From, e.To, e.Style)
+		}
+
+		if len(e.Label) > 0 {
+			fe.label = strings.Split(e.Label, "\n")
+		}
+
+		for _, m := range []struct {
+			name string
+			dst  *string
+		}{{e.Start, &fe.start}, {e.End, &fe.end}} {
+			if len(m.name) == 0 || m.name == "none" {
+				continue
+			}
+
+			if _, ok := markers[m.name]; !ok {
+				return "", fmt.Errorf("edge %s -> %s: unknown marker %q", e.From, e.To, m.name)
+			}
+
+			*m.dst = m.name
+		}
+
+		fg.edges = append(fg.edges, fe)
+	}
+
+	// ids are unique to the graph, so more than
+	// one graph can be inlined in a page
+	h := fnv.New32a()
+	fmt.Fprintf(h, "%+v", g)
+
+	return fg.svg(fmt.Sprintf("mermaid-%08x", h.Sum32())), nil
+}
@@ -0,0 +1,50 @@
+package mermaid
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Graph_SVG(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	g := Graph{
+		Kind: "dot",
+		Dir:  "LR",
+		Nodes: []Node{
+			{ID: "a", Label: "Start\nhere", Shape: "ellipse"},
+			{ID: "b & c", Shape: "diamond"},
+		},
+		Edges: []Edge{
+			{From: "a", To: "b & c", Label: "go", End: "arrow"},
+			{From: "b & c", To: "d", Style: "dotted", Start: "odiamond", End: "none"},
+		},
+	}
+
+	svg, err := g.SVG()
+	r.NoError(err)
+
+	r.Contains(svg, `class="mermaid dot"`)
+	r.Contains(svg, `<ellipse `)
+	r.Contains(svg, `dy="0.35em">here</tspan>`)
+	r.Contains(svg, `-b &amp; c"`)
+	r.Contains(svg, `dy="0.35em">d</tspan>`)
+	r.Contains(svg, `stroke-dasharray="3,3"`)
+	r.Contains(svg, `-odiamond)`)
+
+	again, err := g.SVG()
+	r.NoError(err)
+	r.Equal(svg, again)
+
+	for _, bad := range []Graph{
+		{Dir: "up"},
+		{Nodes: []Node{{ID: "a", Shape: "star"}}},
+		{Edges: []Edge{{From: "a", To: "b", Style: "wavy"}}},
+		{Edges: []Edge{{From: "a", To: "b", End: "heart"}}},
+	} {
+		_, err := bad.SVG()
+		r.Error(err)
+	}
+}
@@ -0,0 +1,356 @@
+package mermaid
+
+import (
+	"math"
+	"sort"
+)
+
+// box is a node of a graph to lay out. The
+// position of a box is the point at its center.
+type box struct {
+	w, h float64
+	x, y float64
+
+	rank  int
+	order int
+}
+
+// edge connects two boxes, by index.
+type edge struct {
+	from, to int
+}
+
+// layered lays out the boxes of a directed graph in layers,
+// or ranks, so that edges point in the direction dir: "TB",
+// "BT", "LR", or "RL". Boxes are ranked by the longest path
+// to them, and ordered, within ranks, by the positions of
+// the boxes they are connected to, so edges cross less. It
+// returns the width, and height, of the layout.
+func layered(boxes []*box, edges []edge, dir string, rankSep, nodeSep float64) (float64, float64) {
+	if len(boxes) == 0 {
+		return 0, 0
+	}
+
+	dag := acyclic(len(boxes), edges)
+	rank(boxes, dag)
+	ranks := order(boxes, dag)
+
+	horizontal := dir == "LR" || dir == "RL"
+
+	// breadth is the size of a box along its rank,
+	// and depth the size across it
+	breadth := func(b *box) float64 {
+		if horizontal {
+			return b.h
+		}
+		return b.w
+	}
+
+	depth := func(b *box) float64 {
+		if horizontal {
+			return b.w
+		}
+		return b.h
+	}
+
+	parents := make([][]int, len(boxes))
+	for _, e := range dag {
+		parents[e.to] = append(parents[e.to], e.from)
+	}
+
+	along := make([]float64, len(boxes))
+	across := make([]float64, len(boxes))
+
+	var pos float64
+	for _, rk := range ranks {
+		var d float64
+		for _, i := range rk {
+			d = math.Max(d, depth(boxes[i]))
+		}
+
+		prev := math.Inf(-1)
+		for _, i := range rk {
+			b := boxes[i]
+
+			// center under the boxes above, if any,
+			// without overlapping the box before
+			want := prev
+			if ps := parents[i]; len(ps) > 0 {
+				var sum float64
+				for _, p := range ps {
+					sum += along[p]
+				}
+				want = sum / float64(len(ps))
+			}
+
+			if !math.IsInf(prev, -1) {
+				want = math.Max(want, prev+nodeSep+breadth(b)/2)
+			} else if math.IsInf(want, -1) {
+				want = breadth(b) / 2
+			}
+
+			along[i] = want
+			across[i] = pos + d/2
+			prev = want + breadth(b)/2
+		}
+
+		pos += d + rankSep
+	}
+
+	// move the layout to the origin
+	lo := math.Inf(1)
+	for i, b := range boxes {
+		lo = math.Min(lo, along[i]-breadth(b)/2)
+	}
+
+	var hi float64
+	for i, b := range boxes {
+		along[i] -= lo
+		hi = math.Max(hi, along[i]+breadth(b)/2)
+	}
+
+	size := pos - rankSep
+
+	for i, b := range boxes {
+		a, c := along[i], across[i]
+
+		if dir == "BT" || dir == "RL" {
+			c = size - c
+		}
+
+		if horizontal {
+			b.x, b.y = c, a
+			continue
+		}
+
+		b.x, b.y = a, c
+	}
+
+	if horizontal {
+		return size, hi
+	}
+
+	return hi, size
+}
+
+// acyclic returns the edges of the graph, with the edges that
+// close cycles reversed, and without loops, or duplicates.
+func acyclic(n int, edges []edge) []edge {
+	out := make([][]int, n)
+	for _, e := range edges {
+		if e.from != e.to {
+			out[e.from] = append(out[e.from], e.to)
+		}
+	}
+
+	const (
+		unseen = iota
+		active
+		done
+	)
+
+	state := make([]int, n)
+	seen := map[edge]bool{}
+
+	var res []edge
+	add := func(e edge) {
+		if !seen[e] {
+			seen[e] = true
+			res = append(res, e)
+		}
+	}
+
+	var visit func(v int)
+	visit = func(v int) {
+		state[v] = active
+		for _, w := range out[v] {
+			switch state[w] {
+			case unseen:
+				add(edge{v, w})
+				visit(w)
+			case active:
+				add(edge{w, v})
+			default:
+				add(edge{v, w})
+			}
+		}
+		state[v] = done
+	}
+
+	for v := range out {
+		if state[v] == unseen {
+			visit(v)
+		}
+	}
+
+	return res
+}
+
+// rank sets the rank of each box to the length
+// of the longest path to it.
+func rank(boxes []*box, dag []edge) {
+	in := make([]int, len(boxes))
+	out := make([][]int, len(boxes))
+	for _, e := range dag {
+		in[e.to]++
+		out[e.from] = append(out[e.from], e.to)
+	}
+
+	var queue []int
+	for i, b := range boxes {
+		b.rank = 0
+		if in[i] == 0 {
+			queue = append(queue, i)
+		}
+	}
+
+	for len(queue) > 0 {
+		v := queue[0]
+		queue = queue[1:]
+
+		for _, w := range out[v] {
+			boxes[w].rank = max(boxes[w].rank, boxes[v].rank+1)
+			in[w]--
+			if in[w] == 0 {
+				queue = append(queue, w)
+			}
+		}
+	}
+}
+
+// order orders the boxes of each rank, by the average order
+// of the boxes they are connected to in the rank before,
+// then after, and returns the boxes of each rank, in order.
+func order(boxes []*box, dag []edge) [][]int {
+	var n int
+	for _, b := range boxes {
+		n = max(n, b.rank+1)
+	}
+
+	ranks := make([][]int, n)
+	for i, b := range boxes {
+		ranks[b.rank] = append(ranks[b.rank], i)
+	}
+
+	up := make([][]int, len(boxes))
+	down := make([][]int, len(boxes))
+	for _, e := range dag {
+		up[e.to] = append(up[e.to], e.from)
+		down[e.from] = append(down[e.from], e.to)
+	}
+
+	renumber := func(rk []int) {
+		for o, i := range rk {
+			boxes[i].order = o
+		}
+	}
+
+	for _, rk := range ranks {
+		renumber(rk)
+	}
+
+	sweep := func(rk []int, adj [][]int) {
+		keys := make(map[int]float64, len(rk))
+		for _, i := range rk {
+			keys[i] = float64(boxes[i].order)
+			if len(adj[i]) == 0 {
+				continue
+			}
+
+			var sum float64
+			for _, j := range adj[i] {
+				sum += float64(boxes[j].order)
+			}
+			keys[i] = sum / float64(len(adj[i]))
+		}
+
+		sort.SliceStable(rk, func(a, b int) bool {
+			return keys[rk[a]] < keys[rk[b]]
+		})
+
+		renumber(rk)
+	}
+
+	for range 4 {
+		for r := 1; r < n; r++ {
+			sweep(ranks[r], up)
+		}
+		for r := n - 2; r >= 0; r-- {
+			sweep(ranks[r], down)
+		}
+	}
+
+	// a final sweep down, as boxes are placed under those above
+	for r := 1; r < n; r++ {
+		sweep(ranks[r], up)
+	}
+
+	return ranks
+}
+
+// clip returns the point where the line from the center
+// of b to p leaves b, drawn as shape: "rect", "diamond",
+// or "ellipse".
+func clip(b *box, shape string, p point) point {
+	dx, dy := p.x-b.x, p.y-b.y
+	if dx == 0 && dy == 0 {
+		return p
+	}
+
+	hw, hh := b.w/2, b.h/2
+
+	var t float64
+	switch shape {
+	case "diamond":
+		t = 1 / (math.Abs(dx)/hw + math.Abs(dy)/hh)
+	case "ellipse":
+		t = 1 / math.Sqrt((dx/hw)*(dx/hw)+(dy/hh)*(dy/hh))
+	default:
+		t = math.Inf(1)
+		if dx != 0 {
+			t = hw / math.Abs(dx)
+		}
+		if dy != 0 {
+			t = math.Min(t, hh/math.Abs(dy))
+		}
+	}
+
+	t = math.Min(t, 1)
+
+	return point{b.x + dx*t, b.y + dy*t}
+}
+
+// route returns the ends of an edge from a to b, drawn as the
+// shapes sa, and sb. Edges between boxes of adjacent ranks
+// are straight. Others, which may cross the boxes of the
+// ranks between them, are bent, away from them, through
+// the control point of a quadratic curve.
+func route(a, b *box, sa, sb string) (p1, ctrl, p2 point, bent bool) {
+	span := b.rank - a.rank
+	if span == 1 || span == -1 && a.order != b.order {
+		p1 = clip(a, sa, point{b.x, b.y})
+		p2 = clip(b, sb, point{a.x, a.y})
+		return p1, point{}, p2, false
+	}
+
+	dx, dy := b.x-a.x, b.y-a.y
+	l := math.Hypot(dx, dy)
+	if l == 0 {
+		return point{a.x, a.y}, point{}, point{b.x, b.y}, false
+	}
+
+	bow := math.Min(30+20*math.Abs(float64(span)), 120)
+
+	mid := point{(a.x + b.x) / 2, (a.y + b.y) / 2}
+	ctrl = point{mid.x - dy/l*bow, mid.y + dx/l*bow}
+
+	p1 = clip(a, sa, ctrl)
+	p2 = clip(b, sb, ctrl)
+
+	return p1, ctrl, p2, true
+}
+
+// curveMid returns the point halfway along a quadratic curve.
+func curveMid(p1, ctrl, p2 point) point {
+	return point{(p1.x + 2*ctrl.x + p2.x) / 4, (p1.y + 2*ctrl.y + p2.y) / 4}
+}
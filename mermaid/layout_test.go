@@ -0,0 +1,88 @@
+package mermaid
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testBoxes(n int) []*box {
+	boxes := make([]*box, n)
+	for i := range boxes {
+		boxes[i] = &box{w: 40, h: 20}
+	}
+
+	return boxes
+}
+
+func Test_layered(t *testing.T) {
+	t.Parallel()
+
+	t.Run("TB", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		// a diamond: 0 -> 1, 0 -> 2, 1 -> 3, 2 -> 3
+		boxes := testBoxes(4)
+		w, h := layered(boxes, []edge{{0, 1}, {0, 2}, {1, 3}, {2, 3}}, "TB", 30, 10)
+
+		r.Equal([]int{0, 1, 1, 2}, []int{boxes[0].rank, boxes[1].rank, boxes[2].rank, boxes[3].rank})
+
+		r.Less(boxes[0].y, boxes[1].y)
+		r.Equal(boxes[1].y, boxes[2].y)
+		r.Less(boxes[1].y, boxes[3].y)
+
+		// side by side, without overlapping
+		r.GreaterOrEqual(boxes[2].x-boxes[1].x, 50.0)
+
+		r.Equal(90.0, w)
+		r.Equal(120.0, h)
+	})
+
+	t.Run("LR", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		boxes := testBoxes(3)
+		layered(boxes, []edge{{0, 1}, {1, 2}}, "LR", 30, 10)
+
+		r.Less(boxes[0].x, boxes[1].x)
+		r.Less(boxes[1].x, boxes[2].x)
+		r.Equal(boxes[0].y, boxes[2].y)
+	})
+
+	t.Run("BT", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		boxes := testBoxes(2)
+		layered(boxes, []edge{{0, 1}}, "BT", 30, 10)
+
+		r.Greater(boxes[0].y, boxes[1].y)
+	})
+
+	t.Run("cycle", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		boxes := testBoxes(3)
+		layered(boxes, []edge{{0, 1}, {1, 2}, {2, 0}, {1, 1}}, "TB", 30, 10)
+
+		r.Equal([]int{0, 1, 2}, []int{boxes[0].rank, boxes[1].rank, boxes[2].rank})
+	})
+}
+
+func Test_clip(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	b := &box{w: 40, h: 20, x: 0, y: 0}
+
+	r.Equal(point{0, 10}, clip(b, "rect", point{0, 100}))
+	r.Equal(point{20, 0}, clip(b, "rect", point{100, 0}))
+	r.Equal(point{20, 0}, clip(b, "ellipse", point{100, 0}))
+
+	p := clip(b, "diamond", point{100, 50})
+	r.InDelta(10.0, p.x, 1e-9)
+	r.InDelta(5.0, p.y, 1e-9)
+}
@@ -0,0 +1,248 @@
+// Package mermaid renders Mermaid diagrams to SVG, in Go,
+// without a browser, or Node.js.
+//
+// Flowcharts (graph, and flowchart), sequence diagrams
+// (sequenceDiagram), and class diagrams (classDiagram) are
+// supported. Styling directives, such as classDef, style,
+// and click, are accepted, and ignored.
+//
+//	svg, err := mermaid.SVG("graph LR\n    A[Start] --> B[End]")
+//
+// Graph draws flowcharts built in Go, such as those
+// of other diagram languages, with the same layout.
+package mermaid
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"html"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	fontSize   = 14
+	lineHeight = fontSize * 1.3
+	margin     = 8
+
+	fontFamily = "trebuchet ms, verdana, arial, sans-serif"
+	lineColor  = "#333333"
+	nodeFill   = "#ECECFF"
+	nodeStroke = "#9370DB"
+	noteFill   = "#FFF5AD"
+	noteStroke = "#AAAA33"
+)
+
+// SVG renders the Mermaid diagram src as an SVG document.
+func SVG(src string) (string, error) {
+	lines := split(src)
+	if len(lines) == 0 {
+		return "", fmt.Errorf("mermaid diagram is empty")
+	}
+
+	header := strings.Fields(lines[0])
+	kind := strings.TrimSuffix(header[0], ";")
+
+	// ids are unique to the source, so more than
+	// one diagram can be inlined in a page
+	h := fnv.New32a()
+	h.Write([]byte(src))
+	id := fmt.Sprintf("mermaid-%08x", h.Sum32())
+
+	switch kind {
+	case "graph", "flowchart":
+		return flowchart(id, header[1:], lines[1:])
+	case "sequenceDiagram":
+		return sequence(id, lines[1:])
+	case "classDiagram", "classDiagram-v2":
+		return class(id, lines[1:])
+	}
+
+	return "", fmt.Errorf("unsupported mermaid diagram type %q", kind)
+}
+
+// split returns the trimmed lines of src,
+// without blank lines, and comments.
+func split(src string) []string {
+	var res []string
+
+	for _, line := range strings.Split(src, "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) == 0 || strings.HasPrefix(line, "%%") {
+			continue
+		}
+
+		res = append(res, line)
+	}
+
+	return res
+}
+
+var brRx = regexp.MustCompile(`(?i)<br\s*/?>`)
+
+// label returns the lines of the text of a label,
+// without the quotes it may be written in.
+func label(s string) []string {
+	s = strings.TrimSpace(s)
+	if len(s) > 1 && strings.HasPrefix(s, `"`) && strings.HasSuffix(s, `"`) {
+		s = s[1 : len(s)-1]
+	}
+
+	s = strings.ReplaceAll(s, `\n`, "\n")
+	s = brRx.ReplaceAllString(s, "\n")
+
+	return strings.Split(s, "\n")
+}
+
+// textWidth estimates the width of s, as SVG
+// text can not be measured without a browser.
+func textWidth(s string) float64 {
+	var w float64
+
+	for _, r := range s {
+		switch {
+		case strings.ContainsRune("il.,:;|!'`", r):
+			w += 0.3
+		case strings.ContainsRune("mwMW@", r):
+			w += 0.9
+		case r >= 'A' && r <= 'Z':
+			w += 0.68
+		case r > 0x2e80:
+			// wide characters
+			w += 1
+		default:
+			w += 0.56
+		}
+	}
+
+	return w * fontSize
+}
+
+// textSize returns the width, and height, of lines.
+func textSize(lines []string) (float64, float64) {
+	var w float64
+	for _, l := range lines {
+		w = math.Max(w, textWidth(l))
+	}
+
+	return w, float64(len(lines)) * lineHeight
+}
+
+// num formats a coordinate.
+func num(f float64) string {
+	return strconv.FormatFloat(math.Round(f*10)/10, 'f', -1, 64)
+}
+
+// canvas writes the elements of an SVG document.
+type canvas struct {
+	bytes.Buffer
+	id string
+}
+
+func (c *canvas) printf(format string, args ...any) {
+	fmt.Fprintf(&c.Buffer, format, args...)
+}
+
+// text writes lines centered, vertically, on y. The anchor
+// is "start", "middle", or "end".
+func (c *canvas) text(x, y float64, lines []string, anchor string, attrs string) {
+	top := y - float64(len(lines)-1)*lineHeight/2
+
+	c.printf(`<text text-anchor="%s"%s>`, anchor, attrs)
+	for i, l := range lines {
+		c.printf(`<tspan x="%s" y="%s" dy="0.35em">%s</tspan>`, num(x), num(top+float64(i)*lineHeight), html.EscapeString(l))
+	}
+	c.printf("</text>\n")
+}
+
+// line writes a line through the points.
+func (c *canvas) line(pts []point, attrs string) {
+	c.printf(`<path d="`)
+	for i, p := range pts {
+		cmd := "L"
+		if i == 0 {
+			cmd = "M"
+		}
+		c.printf("%s%s,%s", cmd, num(p.x), num(p.y))
+	}
+	c.printf(`" fill="none"%s/>`+"\n", attrs)
+}
+
+// marker returns the attribute that references the marker name.
+func (c *canvas) marker(end string, name string) string {
+	if len(name) == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf(` marker-%s="url(#%s-%s)"`, end, c.id, name)
+}
+
+// markers are the shapes drawn at the ends of lines. Markers
+// at the start of lines are reversed, so they point away from
+// the line, as they do at the end.
+var markers = map[string]string{
+	"arrow":    `viewBox="0 0 10 10" refX="10" refY="5" markerWidth="8" markerHeight="8"><path d="M0,0L10,5L0,10z" fill="#333333"/>`,
+	"open":     `viewBox="0 0 10 10" refX="10" refY="5" markerWidth="8" markerHeight="8"><path d="M0,0L10,5L0,10" fill="none" stroke="#333333" stroke-width="1.5"/>`,
+	"circle":   `viewBox="0 0 10 10" refX="10" refY="5" markerWidth="8" markerHeight="8"><circle cx="5" cy="5" r="4" fill="#FFFFFF" stroke="#333333" stroke-width="1.5"/>`,
+	"cross":    `viewBox="0 0 10 10" refX="8" refY="5" markerWidth="8" markerHeight="8"><path d="M1,1L9,9M1,9L9,1" stroke="#333333" stroke-width="2"/>`,
+	"triangle": `viewBox="0 0 20 20" refX="20" refY="10" markerWidth="14" markerHeight="14" markerUnits="userSpaceOnUse"><path d="M1,1L20,10L1,19z" fill="#FFFFFF" stroke="#333333"/>`,
+	"diamond":  `viewBox="0 0 20 10" refX="20" refY="5" markerWidth="18" markerHeight="9" markerUnits="userSpaceOnUse"><path d="M0,5L10,0L20,5L10,10z" fill="#333333"/>`,
+	"odiamond": `viewBox="0 0 20 10" refX="20" refY="5" markerWidth="18" markerHeight="9" markerUnits="userSpaceOnUse"><path d="M0.5,5L10,0.5L19.5,5L10,9.5z" fill="#FFFFFF" stroke="#333333"/>`,
+}
+
+// document wraps the body in an SVG element of the
+// size w x h, with the markers it uses.
+func (c *canvas) document(kind string, w, h float64) string {
+	bb := &bytes.Buffer{}
+
+	w, h = math.Ceil(w), math.Ceil(h)
+
+	fmt.Fprintf(bb, `<svg xmlns="http://www.w3.org/2000/svg" id="%s" class="mermaid %s" role="img" width="%s" height="%s" viewBox="0 0 %s %s" font-family="%s" font-size="%d">`+"\n",
+		c.id, kind, num(w), num(h), num(w), num(h), fontFamily, fontSize)
+
+	body := c.String()
+
+	var defs []string
+	for _, name := range []string{"arrow", "open", "circle", "cross", "triangle", "diamond", "odiamond"} {
+		if !strings.Contains(body, fmt.Sprintf("#%s-%s)", c.id, name)) {
+			continue
+		}
+
+		defs = append(defs, fmt.Sprintf(`<marker id="%s-%s" orient="auto-start-reverse" %s</marker>`, c.id, name, markers[name]))
+	}
+
+	if len(defs) > 0 {
+		fmt.Fprintf(bb, "<defs>%s</defs>\n", strings.Join(defs, ""))
+	}
+
+	bb.WriteString(body)
+	bb.WriteString("</svg>")
+
+	return bb.String()
+}
+
+// point is a point of the diagram.
+type point struct {
+	x, y float64
+}
+
+// bounds is the bounding box of the elements of a diagram.
+type bounds struct {
+	x0, y0 float64
+	x1, y1 float64
+	set    bool
+}
+
+// add grows the bounds to include the box from x0,y0 to x1,y1.
+func (b *bounds) add(x0, y0, x1, y1 float64) {
+	if !b.set {
+		*b = bounds{x0, y0, x1, y1, true}
+		return
+	}
+
+	b.x0, b.y0 = math.Min(b.x0, x0), math.Min(b.y0, y0)
+	b.x1, b.y1 = math.Max(b.x1, x1), math.Max(b.y1, y1)
+}
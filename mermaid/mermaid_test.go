@@ -0,0 +1,153 @@
+package mermaid
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// wellFormed fails the test if svg is not well formed XML.
+func wellFormed(t testing.TB, svg string) {
+	t.Helper()
+
+	dec := xml.NewDecoder(strings.NewReader(svg))
+	for {
+		_, err := dec.Token()
+		if err == io.EOF {
+			return
+		}
+		require.NoError(t, err, svg)
+	}
+}
+
+func Test_SVG(t *testing.T) {
+	t.Parallel()
+
+	table := []struct {
+		name string
+		src  string
+		exp  []string
+	}{
+		{
+			name: "flowchart",
+			src: `graph TD
+    A[Start] --> B{Is it?}
+    B -->|Yes| C[OK]
+    C --> D[Rethink]
+    D --> B
+    B -- No --> E((End))`,
+			exp: []string{`class="mermaid flowchart"`, ">Start<", ">Is it?<", ">Yes<", ">No<", "<polygon", "<circle", "marker-end"},
+		},
+		{
+			name: "sequence",
+			src: `sequenceDiagram
+    participant A as Alice
+    actor B as Bob
+    A->>B: Hello Bob, how are you?
+    B-->>A: Fine & you?
+    loop Every minute
+        A-)B: ping
+    end
+    Note right of B: Bob thinks`,
+			exp: []string{`class="mermaid sequence"`, ">Alice<", ">Bob<", "Fine &amp; you?", ">loop<", "[Every minute]", ">Bob thinks<", "stroke-dasharray"},
+		},
+		{
+			name: "class",
+			src: `classDiagram
+    Animal <|-- Duck
+    Animal : +int age
+    Animal : +isMammal() bool
+    class Duck {
+        <<interface>>
+        +swim()
+    }
+    class List~T~`,
+			exp: []string{`class="mermaid class"`, ">Animal<", ">+int age<", ">+isMammal() bool<", "«interface»", "List&lt;T&gt;", "-triangle)"},
+		},
+	}
+
+	for _, tt := range table {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			r := require.New(t)
+
+			act, err := SVG(tt.src)
+			r.NoError(err)
+
+			r.True(strings.HasPrefix(act, `<svg xmlns="http://www.w3.org/2000/svg"`))
+			wellFormed(t, act)
+
+			for _, exp := range tt.exp {
+				r.Contains(act, exp)
+			}
+
+			// rendering is deterministic
+			again, err := SVG(tt.src)
+			r.NoError(err)
+			r.Equal(act, again)
+		})
+	}
+}
+
+func Test_SVG_Errors(t *testing.T) {
+	t.Parallel()
+
+	table := []struct {
+		name string
+		src  string
+		err  string
+	}{
+		{name: "empty", src: "\n  %% nothing\n", err: "empty"},
+		{name: "unsupported", src: "pie\n  \"a\" : 1", err: `unsupported mermaid diagram type "pie"`},
+		{name: "direction", src: "graph XY\n  A --> B", err: "unknown flowchart direction"},
+		{name: "link", src: "graph TD\n  A --> ", err: "expected a node"},
+		{name: "node", src: "graph TD\n  A[Start --> B", err: "expected a link"},
+		{name: "sequence end", src: "sequenceDiagram\n  A->>B: hi\n  end", err: "end without a block"},
+		{name: "sequence block", src: "sequenceDiagram\n  loop forever\n  A->>B: hi", err: "missing end"},
+		{name: "sequence statement", src: "sequenceDiagram\n  A hi B", err: "unknown statement"},
+		{name: "class", src: "classDiagram\n  class A {\n  +x", err: "missing }"},
+	}
+
+	for _, tt := range table {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			r := require.New(t)
+
+			_, err := SVG(tt.src)
+			r.Error(err)
+			r.Contains(err.Error(), tt.err)
+		})
+	}
+}
+
+func Test_SVG_IDs(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	a, err := SVG("graph LR\n  A --> B")
+	r.NoError(err)
+
+	b, err := SVG("graph LR\n  A --> C")
+	r.NoError(err)
+
+	// diagrams inlined in the same page
+	// do not share the ids of markers
+	id := func(s string) string {
+		s = s[strings.Index(s, `id="`)+4:]
+		return s[:strings.Index(s, `"`)]
+	}
+
+	r.NotEqual(id(a), id(b))
+	r.Contains(a, `url(#`+id(a)+`-arrow)`)
+}
+
+func Test_label(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	r.Equal([]string{"a", "b", "c"}, label(`"a<br>b<br/>c"`))
+	r.Equal([]string{"one"}, label("  one "))
+}
@@ -0,0 +1,461 @@
+package mermaid
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// seqActor is a participant of a sequence diagram.
+type seqActor struct {
+	id    string
+	label []string
+	actor bool // drawn as a stick figure
+
+	w, h float64
+	x    float64
+}
+
+// seqEvent is a message, note, or part of a
+// frame, of a sequence diagram, in order.
+type seqEvent struct {
+	kind  string // message, note, start, else, or end
+	from  int
+	to    int
+	label []string
+
+	line string // solid, or dotted
+	head string // marker at the end of a message
+	side string // left, right, or over, for notes
+	num  int    // number of the message, with autonumber
+}
+
+// seqDiagram is a parsed sequence diagram.
+type seqDiagram struct {
+	actors []*seqActor
+	ids    map[string]int
+	events []seqEvent
+}
+
+var (
+	participantRx = regexp.MustCompile(`^(participant|actor)\s+(.+?)(?:\s+as\s+(.+))?$`)
+	messageRx     = regexp.MustCompile(`^([^\s:+<>-]+)\s*(--?)(>>|>|x|\))\s*[+-]?\s*([^\s:+<>-]+)\s*(?::(.*))?$`)
+	noteRx        = regexp.MustCompile(`(?i)^note\s+(left of|right of|over)\s+([^:]+?)\s*:(.*)$`)
+	frameRx       = regexp.MustCompile(`^(loop|alt|opt|par|critical|break|rect)\b\s*(.*)$`)
+	dividerRx     = regexp.MustCompile(`^(else|and|option)\b\s*(.*)$`)
+)
+
+func sequence(id string, lines []string) (string, error) {
+	d, err := parseSequence(lines)
+	if err != nil {
+		return "", err
+	}
+
+	return d.svg(id), nil
+}
+
+// parseSequence parses the lines of a sequence
+// diagram, after its header.
+func parseSequence(lines []string) (*seqDiagram, error) {
+	d := &seqDiagram{
+		ids: map[string]int{},
+	}
+
+	var auto bool
+	var n int
+	var depth int
+
+	for i, line := range lines {
+		if m := participantRx.FindStringSubmatch(line); m != nil {
+			a := d.actor(m[2])
+			a.actor = m[1] == "actor"
+			if len(m[3]) > 0 {
+				a.label = label(m[3])
+			}
+			continue
+		}
+
+		if m := messageRx.FindStringSubmatch(line); m != nil {
+			e := seqEvent{
+				kind:  "message",
+				from:  d.index(m[1]),
+				to:    d.index(m[4]),
+				label: label(m[5]),
+				line:  "solid",
+			}
+
+			if m[2] == "--" {
+				e.line = "dotted"
+			}
+
+			e.head = map[string]string{">>": "arrow", ">": "", "x": "cross", ")": "open"}[m[3]]
+
+			if auto {
+				n++
+				e.num = n
+			}
+
+			d.events = append(d.events, e)
+			continue
+		}
+
+		if m := noteRx.FindStringSubmatch(line); m != nil {
+			ids := strings.Split(m[2], ",")
+
+			e := seqEvent{
+				kind:  "note",
+				side:  strings.Fields(strings.ToLower(m[1]))[0],
+				from:  d.index(ids[0]),
+				label: label(m[3]),
+			}
+
+			e.to = e.from
+			if len(ids) > 1 {
+				e.to = d.index(ids[1])
+			}
+
+			d.events = append(d.events, e)
+			continue
+		}
+
+		if m := frameRx.FindStringSubmatch(line); m != nil {
+			depth++
+			d.events = append(d.events, seqEvent{kind: "start", side: m[1], label: label(m[2])})
+			continue
+		}
+
+		if m := dividerRx.FindStringSubmatch(line); m != nil {
+			if depth == 0 {
+				return nil, fmt.Errorf("line %d: %s outside of a block", i+2, m[1])
+			}
+			d.events = append(d.events, seqEvent{kind: "else", label: label(m[2])})
+			continue
+		}
+
+		fields := strings.Fields(line)
+
+		switch fields[0] {
+		case "end":
+			if depth == 0 {
+				return nil, fmt.Errorf("line %d: end without a block", i+2)
+			}
+			depth--
+			d.events = append(d.events, seqEvent{kind: "end"})
+			continue
+		case "autonumber":
+			auto = true
+			continue
+		case "activate", "deactivate", "title", "title:", "box", "links", "link", "properties", "details", "create", "destroy":
+			continue
+		}
+
+		return nil, fmt.Errorf("line %d: unknown statement %q", i+2, line)
+	}
+
+	if depth > 0 {
+		return nil, fmt.Errorf("missing end of a block")
+	}
+
+	if len(d.actors) == 0 {
+		return nil, fmt.Errorf("sequence diagram has no participants")
+	}
+
+	return d, nil
+}
+
+// actor returns the participant with the id,
+// adding it the first time it is used.
+func (d *seqDiagram) actor(id string) *seqActor {
+	return d.actors[d.index(id)]
+}
+
+func (d *seqDiagram) index(id string) int {
+	id = strings.TrimSpace(id)
+
+	if i, ok := d.ids[id]; ok {
+		return i
+	}
+
+	d.ids[id] = len(d.actors)
+	d.actors = append(d.actors, &seqActor{id: id, label: []string{id}})
+
+	return len(d.actors) - 1
+}
+
+// seqFrame is a loop, alt, or other block, being laid out.
+type seqFrame struct {
+	kind   string
+	label  []string
+	top    float64
+	lo, hi int       // the participants in the frame
+	elses  []float64 // the tops of the else sections
+	labels [][]string
+}
+
+// columns sets the centers of the participants, far
+// enough apart for the messages, and notes, between them.
+func (d *seqDiagram) columns() {
+	type need struct {
+		lo, hi int
+		dist   float64
+	}
+
+	var needs []need
+	for _, e := range d.events {
+		tw, _ := textSize(e.label)
+
+		switch {
+		case e.kind == "message" && e.from == e.to:
+			if e.from+1 < len(d.actors) {
+				needs = append(needs, need{e.from, e.from + 1, tw + 70})
+			}
+		case e.kind == "message":
+			needs = append(needs, need{min(e.from, e.to), max(e.from, e.to), tw + 40})
+		case e.kind == "note" && e.side == "right" && e.from+1 < len(d.actors):
+			needs = append(needs, need{e.from, e.from + 1, tw + 60})
+		case e.kind == "note" && e.side == "left" && e.from > 0:
+			needs = append(needs, need{e.from - 1, e.from, tw + 60})
+		}
+	}
+
+	sort.SliceStable(needs, func(i, j int) bool {
+		return needs[i].hi < needs[j].hi
+	})
+
+	for i, a := range d.actors {
+		tw, th := textSize(a.label)
+		a.w = math.Max(tw+30, 100)
+		a.h = th + 22
+		if a.actor {
+			a.h = th + 40
+		}
+
+		if i == 0 {
+			a.x = a.w / 2
+			continue
+		}
+
+		prev := d.actors[i-1]
+		a.x = prev.x + (prev.w+a.w)/2 + 40
+
+		for _, n := range needs {
+			if n.hi == i {
+				a.x = math.Max(a.x, d.actors[n.lo].x+n.dist)
+			}
+		}
+	}
+}
+
+func (d *seqDiagram) svg(id string) string {
+	d.columns()
+
+	var head float64
+	for _, a := range d.actors {
+		head = math.Max(head, a.h)
+	}
+
+	c := &canvas{id: id}
+
+	lo, hi := math.Inf(1), math.Inf(-1)
+	grow := func(x0, x1 float64) {
+		lo = math.Min(lo, x0)
+		hi = math.Max(hi, x1)
+	}
+
+	for _, a := range d.actors {
+		grow(a.x-a.w/2, a.x+a.w/2)
+	}
+
+	// the body is drawn after the lifelines, which
+	// are only known once the events are laid out
+	body := &canvas{id: id}
+	y := head + 20
+
+	var frames []*seqFrame
+
+	touch := func(i, j int) {
+		for _, f := range frames {
+			f.lo = min(f.lo, i, j)
+			f.hi = max(f.hi, i, j)
+		}
+	}
+
+	for _, e := range d.events {
+		tw, th := textSize(e.label)
+
+		switch e.kind {
+		case "message":
+			touch(e.from, e.to)
+
+			a, b := d.actors[e.from], d.actors[e.to]
+
+			attrs := fmt.Sprintf(` stroke="%s" stroke-width="1.5"`, lineColor)
+			if e.line == "dotted" {
+				attrs += ` stroke-dasharray="3,3"`
+			}
+			attrs += body.marker("end", e.head)
+
+			if e.from == e.to {
+				ly := y + 4
+				body.text(a.x+50, ly+th/2, e.label, "start", ` fill="#333333"`)
+				body.printf(`<path d="M%s,%sC%s,%s %s,%s %s,%s" fill="none"%s/>`+"\n",
+					num(a.x), num(ly), num(a.x+40), num(ly-6), num(a.x+40), num(ly+30), num(a.x+2), num(ly+24), attrs)
+				grow(a.x, a.x+50+tw)
+				d.number(body, e, a.x, ly)
+				y = ly + 24 + 16
+				continue
+			}
+
+			ly := y + th + 6
+			body.text((a.x+b.x)/2, y+th/2, e.label, "middle", ` fill="#333333"`)
+			body.line([]point{{a.x, ly}, {b.x, ly}}, attrs)
+			d.number(body, e, a.x, ly)
+			y = ly + 18
+
+		case "note":
+			touch(e.from, e.to)
+
+			a, b := d.actors[e.from], d.actors[e.to]
+			w, h := tw+20, th+16
+
+			var x0 float64
+			switch e.side {
+			case "left":
+				x0 = a.x - 12 - w
+			case "right":
+				x0 = a.x + 12
+			default:
+				l, r := math.Min(a.x, b.x), math.Max(a.x, b.x)
+				w = math.Max(w, r-l+40)
+				x0 = (l+r)/2 - w/2
+			}
+
+			body.printf(`<rect x="%s" y="%s" width="%s" height="%s" fill="%s" stroke="%s"/>`+"\n", num(x0), num(y), num(w), num(h), noteFill, noteStroke)
+			body.text(x0+w/2, y+h/2, e.label, "middle", ` fill="#333333"`)
+			grow(x0, x0+w)
+			y += h + 12
+
+		case "start":
+			frames = append(frames, &seqFrame{
+				kind:  e.side,
+				label: e.label,
+				top:   y,
+				lo:    len(d.actors),
+				hi:    -1,
+			})
+			y += th + 20
+
+		case "else":
+			f := frames[len(frames)-1]
+			f.elses = append(f.elses, y)
+			f.labels = append(f.labels, e.label)
+			y += th + 14
+
+		case "end":
+			f := frames[len(frames)-1]
+			frames = frames[:len(frames)-1]
+
+			if f.hi < 0 {
+				f.lo, f.hi = 0, len(d.actors)-1
+			}
+
+			for _, p := range frames {
+				p.lo, p.hi = min(p.lo, f.lo), max(p.hi, f.hi)
+			}
+
+			// the frame is drawn in the body, under
+			// its messages, once it is laid out
+			depth := float64(len(frames))
+			x0 := d.actors[f.lo].x - d.actors[f.lo].w/2 - 10 + depth*6
+			x1 := d.actors[f.hi].x + d.actors[f.hi].w/2 + 10 - depth*6
+
+			f.top -= 4
+			drawFrame(body, f, x0, x1, y)
+			grow(x0, x1)
+			y += 14
+		}
+	}
+
+	bottom := y + 10
+
+	for _, a := range d.actors {
+		c.printf(`<line x1="%s" y1="%s" x2="%s" y2="%s" stroke="#999999" stroke-width="0.5"/>`+"\n", num(a.x), num(a.h), num(a.x), num(bottom))
+	}
+
+	c.Write(body.Bytes())
+
+	for _, a := range d.actors {
+		d.drawActor(c, a, 0)
+		d.drawActor(c, a, bottom)
+	}
+
+	out := &canvas{id: id}
+	out.printf(`<g transform="translate(%s,%d)">`+"\n", num(margin-lo), margin)
+	out.Write(c.Bytes())
+	out.printf("</g>\n")
+
+	return out.document("sequence", hi-lo+2*margin, bottom+head+2*margin)
+}
+
+// number draws the number of a message, with autonumber.
+func (d *seqDiagram) number(c *canvas, e seqEvent, x, y float64) {
+	if e.num == 0 {
+		return
+	}
+
+	c.printf(`<circle cx="%s" cy="%s" r="9" fill="%s"/>`, num(x), num(y), lineColor)
+	c.text(x, y, []string{strconv.Itoa(e.num)}, "middle", ` fill="#FFFFFF" font-size="11"`)
+}
+
+func drawFrame(c *canvas, f *seqFrame, x0, x1, bottom float64) {
+	attrs := fmt.Sprintf(` fill="none" stroke="%s" stroke-width="1"`, nodeStroke)
+
+	c.printf(`<rect x="%s" y="%s" width="%s" height="%s"%s/>`+"\n", num(x0), num(f.top), num(x1-x0), num(bottom-f.top), attrs)
+
+	// the kind of the frame, in a tab on its corner
+	tw := textWidth(f.kind) + 16
+	c.printf(`<path d="M%s,%sh%sv12l-6,8h%sz" fill="%s" stroke="%s"/>`+"\n", num(x0), num(f.top), num(tw), num(-tw+6), nodeFill, nodeStroke)
+	c.text(x0+tw/2-3, f.top+10, []string{f.kind}, "middle", ` fill="#333333" font-weight="bold"`)
+
+	if len(f.label) > 0 && len(f.label[0]) > 0 {
+		c.text((x0+x1)/2, f.top+10, bracket(f.label), "middle", ` fill="#333333" font-weight="bold"`)
+	}
+
+	for i, ey := range f.elses {
+		c.printf(`<line x1="%s" y1="%s" x2="%s" y2="%s" stroke="%s" stroke-dasharray="3,3"/>`+"\n", num(x0), num(ey), num(x1), num(ey), nodeStroke)
+
+		if ls := f.labels[i]; len(ls) > 0 && len(ls[0]) > 0 {
+			c.text((x0+x1)/2, ey+10, bracket(ls), "middle", ` fill="#333333" font-weight="bold"`)
+		}
+	}
+}
+
+// bracket wraps the text of a frame in brackets, as mermaid does.
+func bracket(lines []string) []string {
+	res := append([]string(nil), lines...)
+	res[0] = "[" + res[0]
+	res[len(res)-1] += "]"
+
+	return res
+}
+
+// drawActor draws the box, or stick figure, of a
+// participant, at the top of the diagram, or the bottom.
+func (d *seqDiagram) drawActor(c *canvas, a *seqActor, y float64) {
+	if !a.actor {
+		c.printf(`<rect x="%s" y="%s" width="%s" height="%s" rx="3" ry="3" fill="%s" stroke="%s"/>`+"\n", num(a.x-a.w/2), num(y), num(a.w), num(a.h), nodeFill, nodeStroke)
+		c.text(a.x, y+a.h/2, a.label, "middle", ` fill="#333333"`)
+		return
+	}
+
+	// a stick figure, above its name
+	x := a.x
+	attrs := fmt.Sprintf(` fill="none" stroke="%s" stroke-width="1.5"`, lineColor)
+	c.printf(`<circle cx="%s" cy="%s" r="5"%s/>`, num(x), num(y+6), attrs)
+	c.printf(`<path d="M%s,%sv10M%s,%sh16M%s,%sl-7,8M%s,%sl7,8"%s/>`+"\n",
+		num(x), num(y+11), num(x-8), num(y+14), num(x), num(y+21), num(x), num(y+21), attrs)
+	c.text(x, y+a.h-8, a.label, "middle", ` fill="#333333"`)
+}
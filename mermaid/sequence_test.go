@@ -0,0 +1,74 @@
+package mermaid
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseSequence(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	d, err := parseSequence([]string{
+		"autonumber",
+		"participant W as Web Server",
+		"actor U",
+		"U->>W: GET /",
+		"activate W",
+		"W-->>+U: 200 OK",
+		"alt cached",
+		"W-xU: stale",
+		"else fresh",
+		"W-)U",
+		"end",
+		"Note over U,W: done",
+	})
+	r.NoError(err)
+
+	r.Len(d.actors, 2)
+	r.Equal([]string{"Web Server"}, d.actors[0].label)
+	r.False(d.actors[0].actor)
+	r.True(d.actors[1].actor)
+
+	var kinds []string
+	for _, e := range d.events {
+		kinds = append(kinds, e.kind)
+	}
+	r.Equal([]string{"message", "message", "start", "message", "else", "message", "end", "note"}, kinds)
+
+	get := d.events[0]
+	r.Equal(1, get.from)
+	r.Equal(0, get.to)
+	r.Equal("solid", get.line)
+	r.Equal("arrow", get.head)
+	r.Equal(1, get.num)
+
+	ok := d.events[1]
+	r.Equal("dotted", ok.line)
+	r.Equal([]string{"200 OK"}, ok.label)
+	r.Equal(2, ok.num)
+
+	r.Equal("cross", d.events[3].head)
+	r.Equal("open", d.events[5].head)
+
+	note := d.events[7]
+	r.Equal("over", note.side)
+	r.Equal(1, note.from)
+	r.Equal(0, note.to)
+}
+
+func Test_sequence_columns(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	d, err := parseSequence([]string{
+		"A->>B: a message that is much wider than the boxes of the participants",
+	})
+	r.NoError(err)
+
+	d.columns()
+
+	a, b := d.actors[0], d.actors[1]
+	r.Greater(b.x-a.x, textWidth("a message that is much wider than the boxes of the participants"))
+}
@@ -197,3 +197,94 @@ func Test_Mermaid_Execute_ErrorPropagation(t *testing.T) {
 		r.NotNil(errors.Unwrap(err), "error should be unwrappable")
 	})
 }
+
+func Test_Mermaid_SVG(t *testing.T) {
+	t.Parallel()
+
+	t.Run("parser default", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		p := testParser(t, "testdata/mermaid/graph")
+		p.MermaidRender = MermaidSVG
+
+		doc, err := p.ParseFile("hype.md")
+		r.NoError(err)
+
+		r.NoError(doc.Execute(context.Background()))
+
+		html := doc.String()
+		r.Contains(html, `<figure class="mermaid"><svg xmlns="http://www.w3.org/2000/svg"`)
+		r.Contains(html, ">Decision<")
+		r.NotContains(html, "<pre>")
+
+		// markdown is still ASCII art
+		md := doc.MD()
+		r.Contains(md, "```\n")
+		r.NotContains(md, "<svg")
+	})
+
+	t.Run("render attribute", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		p := testParser(t, "testdata/mermaid/svg")
+
+		doc, err := p.ParseFile("hype.md")
+		r.NoError(err)
+
+		r.NoError(doc.Execute(context.Background()))
+
+		ms := ByType[*Mermaid](doc.Nodes)
+		r.Len(ms, 2)
+		for _, m := range ms {
+			r.Equal(MermaidSVG, m.Render)
+			r.NotEmpty(m.SVG)
+		}
+
+		html := doc.String()
+
+		// class diagrams are SVG only, in their own figure
+		r.Contains(html, `<figure class="mermaid"><svg`)
+		r.Contains(html, `class="mermaid class"`)
+
+		// a diagram in a figure is numbered by it
+		r.Equal(1, strings.Count(html, `<figure class="mermaid">`))
+		r.Contains(html, `class="mermaid flowchart"`)
+		r.Contains(html, "Diagram 1.1")
+
+		// markdown falls back to the source, for
+		// diagrams that are not drawn as ASCII art
+		md := doc.MD()
+		r.Contains(md, "```mermaid\nclassDiagram")
+	})
+
+	t.Run("parser default is overridden by the tag", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		el := NewEl("code", nil)
+		r.NoError(el.Set("render", MermaidASCII))
+		el.Nodes = Nodes{Text("graph LR\n    A --> B")}
+
+		p := testParser(t, "testdata/mermaid/graph")
+		p.MermaidRender = MermaidSVG
+
+		nodes, err := NewMermaidNodes(p, el)
+		r.NoError(err)
+		r.Equal(MermaidASCII, nodes[0].(*Mermaid).Render)
+	})
+
+	t.Run("invalid render", func(t *testing.T) {
+		t.Parallel()
+		r := require.New(t)
+
+		el := NewEl("code", nil)
+		r.NoError(el.Set("render", "png"))
+		el.Nodes = Nodes{Text("graph LR\n    A --> B")}
+
+		_, err := NewMermaid(el)
+		r.Error(err)
+		r.Contains(err.Error(), `invalid mermaid render "png"`)
+	})
+}
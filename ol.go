@@ -31,7 +31,7 @@ func (ol *OL) MD() string {
 		return ""
 	}
 
-	return ol.Children().MD()
+	return mdList(ol.Element, true)
 }
 
 func NewOLNodes(p *Parser, el *Element) (Nodes, error) {
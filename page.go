@@ -71,7 +71,7 @@ func (page *Page) MD() string {
 		return ""
 	}
 
-	return page.Children().MD()
+	return renderMD(page.Children())
 }
 
 func NewPage(el *Element) (*Page, error) {
@@ -41,7 +41,7 @@ func (p *Paragraph) MD() string {
 		return ""
 	}
 
-	return p.Children().MD()
+	return mdParagraph(mdInlines(p.Children()))
 }
 
 func NewParagraphNodes(p *Parser, el *Element) (Nodes, error) {
@@ -13,6 +13,7 @@ type ParseError struct {
 	Filename string
 	Root     string
 	Contents []byte
+	Pos      Pos // position of the element the error occurred at, if known
 }
 
 func (pe ParseError) MarshalJSON() ([]byte, error) {
@@ -24,10 +25,23 @@ func (pe ParseError) MarshalJSON() ([]byte, error) {
 		"type":     toType(pe),
 	}
 
+	if pe.Pos.IsValid() {
+		mm["pos"] = pe.Pos
+	}
+
 	return json.MarshalIndent(mm, "", "  ")
 }
 
 func (pe ParseError) Error() string {
+	if pe.Pos.IsValid() {
+		var src []byte
+		if filepath.Join(pe.Root, pe.Filename) == pe.Pos.File {
+			src = pe.Contents
+		}
+
+		return diagnostic(pe.Pos, src, pe.message())
+	}
+
 	sb := &strings.Builder{}
 
 	var lines []string
@@ -48,6 +62,15 @@ func (pe ParseError) Error() string {
 	return strings.TrimSpace(s)
 }
 
+// Position returns the position the error occurred at.
+func (pe ParseError) Position() Pos {
+	return pe.Pos
+}
+
+func (pe ParseError) message() string {
+	return fmt.Sprintf("parse error: %s", errMessage(pe.Err, pe.Pos))
+}
+
 func (pe ParseError) Unwrap() error {
 	if _, ok := pe.Err.(unwrapper); ok {
 		return errors.Unwrap(pe.Err)
@@ -25,20 +25,30 @@ type ParseElementFn func(p *Parser, el *Element) (Nodes, error)
 type Parser struct {
 	fs.FS
 
-	DisablePages  bool
-	DocIDGen      func() (string, error) // default: uuid.NewV4().String()
-	Filename      string                 // only set when Parser.ParseFile() is used
-	LinkCheck     LinkCheckConfig
-	LinkValidator *LinkValidator
-	NodeParsers   map[Atom]ParseElementFn
-	NowFn         func() time.Time // default: time.Now()
-	PreParsers    PreParsers
-	Root          string
-	Section       int
-	Vars          syncx.Map[string, any]
-	Contents      []byte // a copy of the contents being parsed - set just before parsing
-
-	mu sync.RWMutex
+	DefaultRunner    string                     // runner used when a <cmd> has no runner attribute; default: HostRunnerName
+	DiagramRenderers map[string]DiagramRenderer // renderers of <diagram> tags, by language; default: DefaultDiagramRenderers()
+	DisablePages     bool
+	DocIDGen         func() (string, error) // default: uuid.NewV4().String()
+	ExecCache        *ExecCache             // optional cache of command results, and diagrams; default: nil (disabled)
+	Extractors       map[string]Extractor   // symbol extractors for <code symbol> tags, by file extension; default: DefaultExtractors()
+	Filename         string                 // only set when Parser.ParseFile() is used
+	LinkCheck        LinkCheckConfig
+	LinkValidator    *LinkValidator
+	MermaidRender    string // how mermaid diagrams without a render attribute are rendered in HTML; default: MermaidASCII
+	NodeParsers      map[Atom]ParseElementFn
+	NowFn            func() time.Time // default: time.Now()
+	PreParsers       PreParsers
+	RefHref          RefHref // hrefs of refs to figures in other documents of a book, for the export format; default: RelativeRefHref("")
+	Root             string
+	Runners          map[string]Runner // runners available to <cmd> tags; default: DefaultRunners()
+	Section          int
+	Vars             syncx.Map[string, any]
+	Contents         []byte // a copy of the contents being parsed - set just before parsing
+
+	diagrams    syncx.Map[string, string] // rendered diagrams, by key
+	frontMatter *frontMatter              // set by the FrontMatter pre-parser
+	loc         *locator
+	mu          sync.RWMutex
 }
 
 func (p *Parser) MarshalJSON() ([]byte, error) {
@@ -116,6 +126,13 @@ func (p *Parser) ParseFile(name string) (doc *Document, err error) {
 }
 
 func (p *Parser) Parse(r io.Reader) (doc *Document, err error) {
+	return p.parse(r, false)
+}
+
+// parse parses r into a new document. A fragment, such as the
+// body of a <figcaption>, is part of the document being parsed,
+// so its elements are located in the contents of that document.
+func (p *Parser) parse(r io.Reader, fragment bool) (doc *Document, err error) {
 	if p == nil {
 		return nil, ErrIsNil("parser")
 	}
@@ -128,7 +145,12 @@ func (p *Parser) Parse(r io.Reader) (doc *Document, err error) {
 	if err != nil {
 		return nil, err
 	}
-	p.Contents = b
+
+	if !fragment || p.loc == nil {
+		p.Contents = b
+		p.loc = newLocator(filepath.Join(p.Root, p.Filename), b)
+		p.frontMatter = nil
+	}
 
 	r = bytes.NewReader(b)
 
@@ -159,6 +181,15 @@ func (p *Parser) Parse(r io.Reader) (doc *Document, err error) {
 		doc.Title = FindTitle(doc.Nodes)
 	}
 
+	if fm := p.frontMatter; fm != nil && !fragment {
+		doc.FrontMatter = fm.data
+		doc.frontMatter = fm.raw
+
+		if err := doc.mergeFrontMatter(); err != nil {
+			return nil, err
+		}
+	}
+
 	// post parse
 	err = doc.Nodes.PostParse(p, doc, err)
 	if err != nil {
@@ -204,6 +235,7 @@ func (p *Parser) ParseFolder(name string) (doc Documents, err error) {
 			Err:      err,
 			Filename: name,
 			Root:     p.Root,
+			Pos:      errPos(err, nil),
 		}
 	}()
 
@@ -342,7 +374,7 @@ func (p *Parser) ParseFragment(r io.Reader) (Nodes, error) {
 		return nil, ErrIsNil("parser")
 	}
 
-	doc, err := p.Parse(r)
+	doc, err := p.parse(r, true)
 	if err != nil {
 		return nil, err
 	}
@@ -404,12 +436,19 @@ func (p *Parser) Sub(dir string) (*Parser, error) {
 	}
 
 	p2 := &Parser{
-		FS:            p.FS,
-		Root:          filepath.Join(p.Root, dir),
-		PreParsers:    p.PreParsers,
-		NodeParsers:   p.NodeParsers,
-		LinkCheck:     p.LinkCheck,
-		LinkValidator: p.LinkValidator,
+		FS:               p.FS,
+		Root:             filepath.Join(p.Root, dir),
+		PreParsers:       p.PreParsers,
+		NodeParsers:      p.NodeParsers,
+		LinkCheck:        p.LinkCheck,
+		LinkValidator:    p.LinkValidator,
+		ExecCache:        p.ExecCache,
+		Runners:          p.Runners,
+		DefaultRunner:    p.DefaultRunner,
+		Extractors:       p.Extractors,
+		DiagramRenderers: p.DiagramRenderers,
+		MermaidRender:    p.MermaidRender,
+		RefHref:          p.RefHref,
 	}
 
 	if len(dir) == 0 || dir == "." {
@@ -437,6 +476,7 @@ func (p *Parser) element(node *html.Node, parent Node) (Node, error) {
 		HTMLNode:   node,
 		Parent:     parent,
 		Filename:   p.Filename,
+		Pos:        p.loc.locate(node, parent),
 	}
 
 	var nodes Nodes
@@ -455,23 +495,41 @@ func (p *Parser) element(node *html.Node, parent Node) (Node, error) {
 	el.Nodes = nodes
 
 	fn, ok := p.NodeParsers[el.Atom()]
-	if ok {
-		return fn(p, el)
+	if !ok {
+		return el, nil
+	}
+
+	nodes, err = fn(p, el)
+	if err == nil || !el.Pos.IsValid() {
+		return nodes, err
+	}
+
+	if _, ok := ErrPos(err); ok {
+		return nodes, err
 	}
 
-	return el, nil
+	return nodes, ParseError{
+		Err:      err,
+		Filename: p.Filename,
+		Root:     p.Root,
+		Contents: p.Contents,
+		Pos:      el.Pos,
+	}
 }
 
 // NewParser returns a fully initialized Parser.
-// This includes the Markdown pre-parser and the
-// default node parsers.
+// This includes the front matter and Markdown
+// pre-parsers and the default node parsers.
 func NewParser(cab fs.FS) *Parser {
 	return &Parser{
-		FS:          cab,
-		NodeParsers: DefaultElements(),
-		PreParsers:  PreParsers{VarProcessor(), GoTemplates(), Markdown()},
-		Section:     1,
-		Vars:        syncx.Map[string, any]{},
+		FS:               cab,
+		DiagramRenderers: DefaultDiagramRenderers(),
+		Extractors:       DefaultExtractors(),
+		NodeParsers:      DefaultElements(),
+		PreParsers:       PreParsers{FrontMatter(), VarProcessor(), GoTemplates(), Markdown()},
+		Runners:          DefaultRunners(),
+		Section:          1,
+		Vars:             syncx.Map[string, any]{},
 	}
 }
 
@@ -528,5 +586,6 @@ func (p *Parser) ensureParseError(err error) error {
 		Filename: p.Filename,
 		Root:     p.Root,
 		Contents: p.Contents,
+		Pos:      errPos(err, nil),
 	}
 }
@@ -0,0 +1,999 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/gopherguides/hype"
+	"github.com/gopherguides/hype/blog"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const (
+	margin    = 54 // page margins, in points
+	bodySize  = 11 // body text size, in points
+	codeSize  = 9  // code block text size, in points
+	indent    = 18 // indentation of lists and block quotes
+	cellPad   = 4  // padding of table cells and code blocks
+	lineRatio = 1.4
+)
+
+var headingSizes = map[int]float64{
+	1: 22,
+	2: 17,
+	3: 14,
+	4: 12,
+	5: 11,
+	6: 11,
+}
+
+type font struct {
+	family string
+	bold   bool
+	italic bool
+	size   float64
+	color  [3]int
+}
+
+func (f font) style() string {
+	var s string
+	if f.bold {
+		s += "B"
+	}
+	if f.italic {
+		s += "I"
+	}
+	return s
+}
+
+// renderer lays out the HTML of hype documents on PDF pages.
+type renderer struct {
+	pdf *fpdf.Fpdf
+	tr  func(string) string // translates UTF-8 to the encoding of the fonts
+	hl  *blog.Highlighter
+
+	fsys    fs.FS          // file system of the current document
+	links   map[string]int // element IDs of the current document, and their links
+	figures map[string]int // figure IDs of the book, and their links
+
+	toc     []int // links of the table of contents entries
+	pages   []int // pages of the table of contents entries
+	outline int   // level of the last bookmark
+
+	font   font
+	href   string // external link of the text being written
+	link   int    // internal link of the text being written
+	fresh  bool   // nothing has been written on the current page
+	space  bool   // the last text written ended with a space, or a line was started
+	noGap  bool   // the next block continues the current line, such as in a list item
+	images int
+
+	file     string   // file name of the current document
+	warnings []string // content that is left out, or replaced
+}
+
+func newRenderer(size, style string) *renderer {
+	pdf := fpdf.New("P", "pt", size, "")
+	pdf.SetMargins(margin, margin, margin)
+	pdf.SetAutoPageBreak(true, margin)
+
+	r := &renderer{
+		pdf:     pdf,
+		tr:      pdf.UnicodeTranslatorFromDescriptor(""),
+		hl:      blog.NewHighlighter(style, false),
+		outline: -1,
+		font: font{
+			family: "Helvetica",
+			size:   bodySize,
+		},
+	}
+
+	pdf.SetFooterFunc(func() {
+		// the title page is not numbered
+		if pdf.PageNo() == 1 {
+			return
+		}
+
+		pdf.SetY(-margin + 12)
+		pdf.SetFont("Helvetica", "", 9)
+		pdf.SetTextColor(110, 110, 110)
+		pdf.CellFormat(0, 12, strconv.Itoa(pdf.PageNo()), "", 0, "C", false, 0, "")
+	})
+
+	return r
+}
+
+func (r *renderer) lh() float64 {
+	return r.font.size * lineRatio
+}
+
+func (r *renderer) setFont() {
+	f := r.font
+	r.pdf.SetFont(f.family, f.style(), f.size)
+	r.pdf.SetTextColor(f.color[0], f.color[1], f.color[2])
+}
+
+func (r *renderer) addPage() {
+	r.pdf.AddPage()
+	r.fresh = true
+	r.space = true
+}
+
+// width returns the width of the page between the margins.
+func (r *renderer) width() float64 {
+	pw, _ := r.pdf.GetPageSize()
+	lm, _, rm, _ := r.pdf.GetMargins()
+	return pw - lm - rm
+}
+
+// ensure starts a new page if there is less than h
+// points of space left on the current page.
+func (r *renderer) ensure(h float64) {
+	_, ph := r.pdf.GetPageSize()
+	if r.pdf.GetY()+h > ph-margin {
+		r.addPage()
+	}
+}
+
+// endLine moves to the start of the next line,
+// if anything has been written on the current line.
+func (r *renderer) endLine() {
+	lm, _, _, _ := r.pdf.GetMargins()
+	if r.pdf.GetX() > lm+0.5 && !r.noGap {
+		r.pdf.Ln(r.lh())
+	}
+	r.space = true
+}
+
+// gap adds vertical space before a block.
+func (r *renderer) gap(h float64) {
+	if r.noGap {
+		r.noGap = false
+		return
+	}
+
+	if r.fresh {
+		return
+	}
+
+	r.pdf.Ln(h)
+}
+
+// block writes a block element, separated by
+// vertical space from the content before it.
+func (r *renderer) block(gap float64, fn func()) {
+	r.endLine()
+	r.gap(gap)
+	fn()
+	r.endLine()
+}
+
+// withFont calls fn with the font changed by set.
+func (r *renderer) withFont(set func(f *font), fn func()) {
+	f := r.font
+	set(&r.font)
+	fn()
+	r.font = f
+}
+
+// withIndent calls fn with the left margin moved by w.
+func (r *renderer) withIndent(w float64, fn func()) {
+	lm, _, _, _ := r.pdf.GetMargins()
+	r.pdf.SetLeftMargin(lm + w)
+	r.pdf.SetX(lm + w)
+	fn()
+	r.pdf.SetLeftMargin(lm)
+}
+
+func (r *renderer) titlePage(title, author string) {
+	r.addPage()
+
+	_, ph := r.pdf.GetPageSize()
+	r.pdf.SetY(ph / 3)
+
+	r.pdf.SetFont("Helvetica", "B", 28)
+	r.pdf.SetTextColor(0, 0, 0)
+	r.pdf.MultiCell(0, 36, r.tr(title), "", "C", false)
+
+	if len(author) > 0 {
+		r.pdf.Ln(12)
+		r.pdf.SetFont("Helvetica", "", 14)
+		r.pdf.MultiCell(0, 20, r.tr(author), "", "C", false)
+	}
+}
+
+// contentsPages writes the table of contents. Each entry links
+// to its heading, which sets the destination of the link when
+// it is written.
+func (r *renderer) contentsPages(toc []Entry) {
+	r.addPage()
+
+	pdf := r.pdf
+	pdf.SetFont("Helvetica", "B", headingSizes[1])
+	pdf.SetTextColor(0, 0, 0)
+	pdf.CellFormat(0, headingSizes[1]*lineRatio, "Contents", "", 1, "L", false, 0, "")
+	pdf.Ln(8)
+
+	w := r.width()
+	lm, _, _, _ := pdf.GetMargins()
+
+	r.toc = make([]int, len(toc))
+	for i, e := range toc {
+		link := pdf.AddLink()
+		r.toc[i] = link
+
+		style := ""
+		if e.Level == 1 {
+			style = "B"
+			pdf.Ln(4)
+		}
+
+		pdf.SetFont("Helvetica", style, bodySize)
+
+		page := ""
+		if e.Page > 0 {
+			page = strconv.Itoa(e.Page)
+		}
+
+		in := float64(e.Level-1) * indent
+		pdf.SetX(lm + in)
+		pdf.CellFormat(w-in-36, bodySize*lineRatio, r.tr(e.Text), "", 0, "L", false, link, "")
+		pdf.CellFormat(36, bodySize*lineRatio, page, "", 1, "R", false, link, "")
+	}
+}
+
+// chapter writes a document, starting on a new page.
+func (r *renderer) chapter(doc *hype.Document, body *html.Node) {
+	r.fsys = doc.FS
+	r.file = filepath.Join(doc.Root, doc.Filename)
+	r.links = map[string]int{}
+
+	// create the links for every element with an ID up front,
+	// so that links to elements later in the document work
+	var ids func(n *html.Node)
+	ids = func(n *html.Node) {
+		if id := attr(n, "id"); len(id) > 0 && !isRef(n) {
+			link, ok := r.figures[id]
+			if !ok {
+				link = r.pdf.AddLink()
+			}
+			r.links[id] = link
+		}
+		for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+			ids(ch)
+		}
+	}
+	ids(body)
+
+	r.addPage()
+
+	for ch := body.FirstChild; ch != nil; ch = ch.NextSibling {
+		r.node(ch)
+	}
+}
+
+// figureLinks creates the links for the figures in body, so
+// that refs in other chapters can link to them.
+func (r *renderer) figureLinks(body *html.Node) {
+	if r.figures == nil {
+		r.figures = map[string]int{}
+	}
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if id := attr(n, "id"); len(id) > 0 && n.Data == "figure" {
+			r.figures[id] = r.pdf.AddLink()
+		}
+		for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+			walk(ch)
+		}
+	}
+	walk(body)
+}
+
+func (r *renderer) children(n *html.Node) {
+	for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+		r.node(ch)
+	}
+}
+
+func (r *renderer) node(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		r.text(n.Data)
+		return
+	case html.ElementNode:
+	default:
+		return
+	}
+
+	if link, ok := r.links[attr(n, "id")]; ok && !isRef(n) {
+		r.pdf.SetLink(link, r.pdf.GetY(), r.pdf.PageNo())
+	}
+
+	if level := headingLevel(n); level > 0 {
+		r.heading(n, level)
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.Head, atom.Script, atom.Style, atom.Template:
+	case atom.Svg:
+		r.svg(n)
+	case atom.P, atom.Div, atom.Section, atom.Article, atom.Details:
+		r.block(6, func() { r.children(n) })
+	case atom.Summary, atom.Dt:
+		r.block(6, func() {
+			r.withFont(func(f *font) { f.bold = true }, func() { r.children(n) })
+		})
+	case atom.Dd:
+		r.block(2, func() {
+			r.withIndent(indent, func() { r.children(n) })
+		})
+	case atom.Figure:
+		r.block(10, func() { r.children(n) })
+	case atom.Figcaption:
+		r.block(4, func() {
+			r.withFont(func(f *font) {
+				f.italic = true
+				f.size = bodySize - 1
+			}, func() { r.children(n) })
+		})
+	case atom.Blockquote:
+		r.block(6, func() {
+			r.withIndent(indent, func() {
+				r.withFont(func(f *font) { f.color = [3]int{90, 90, 90} }, func() { r.children(n) })
+			})
+		})
+	case atom.Ul, atom.Ol:
+		r.list(n)
+	case atom.Pre:
+		r.code(n)
+	case atom.Table:
+		r.table(n)
+	case atom.Img:
+		r.image(n)
+	case atom.Hr:
+		r.block(8, func() {
+			lm, _, _, _ := r.pdf.GetMargins()
+			y := r.pdf.GetY()
+			r.pdf.SetDrawColor(200, 200, 200)
+			r.pdf.Line(lm, y, lm+r.width(), y)
+			r.pdf.Ln(8)
+		})
+	case atom.Br:
+		r.pdf.Ln(r.lh())
+		r.space = true
+	case atom.Strong, atom.B:
+		r.withFont(func(f *font) { f.bold = true }, func() { r.children(n) })
+	case atom.Em, atom.I:
+		r.withFont(func(f *font) { f.italic = true }, func() { r.children(n) })
+	case atom.Code, atom.Kbd, atom.Samp:
+		r.withFont(func(f *font) {
+			f.family = "Courier"
+			f.color = [3]int{150, 30, 60}
+		}, func() { r.children(n) })
+	case atom.Sup, atom.Sub:
+		r.withFont(func(f *font) { f.size *= 0.75 }, func() { r.children(n) })
+	case atom.A:
+		r.anchor(n)
+	case atom.Input:
+		if attr(n, "type") == "checkbox" {
+			if _, ok := hasAttr(n, "checked"); ok {
+				r.text("[x] ")
+			} else {
+				r.text("[ ] ")
+			}
+		}
+	default:
+		// hype elements, such as <page> and <cmd>,
+		// and any other elements, are written as
+		// their children
+		if n.Data == "page" && !r.fresh {
+			r.addPage()
+		}
+
+		r.children(n)
+	}
+}
+
+// text writes inline text, with runs of white space
+// collapsed into a single space.
+func (r *renderer) text(s string) {
+	var sb strings.Builder
+	space := r.space
+	for _, c := range s {
+		if unicode.IsSpace(c) {
+			if !space {
+				sb.WriteByte(' ')
+			}
+			space = true
+			continue
+		}
+
+		space = false
+		sb.WriteRune(c)
+	}
+
+	s = sb.String()
+	if len(s) == 0 {
+		return
+	}
+
+	r.setFont()
+
+	lh := r.lh()
+	t := r.tr(s)
+
+	switch {
+	case len(r.href) > 0:
+		r.pdf.WriteLinkString(lh, t, r.href)
+	case r.link > 0:
+		r.pdf.WriteLinkID(lh, t, r.link)
+	default:
+		r.pdf.Write(lh, t)
+	}
+
+	r.space = space
+	r.fresh = false
+	r.noGap = false
+}
+
+func (r *renderer) anchor(n *html.Node) {
+	href := attr(n, "href")
+
+	var link int
+	if id, ok := strings.CutPrefix(href, "#"); ok {
+		link, ok = r.links[id]
+		if !ok {
+			link = r.figures[id]
+		}
+		href = ""
+	}
+
+	if link == 0 && len(href) == 0 {
+		r.children(n)
+		return
+	}
+
+	oh, ol := r.href, r.link
+	r.href, r.link = href, link
+
+	r.withFont(func(f *font) { f.color = [3]int{3, 102, 214} }, func() { r.children(n) })
+
+	r.href, r.link = oh, ol
+}
+
+func (r *renderer) heading(n *html.Node, level int) {
+	size := headingSizes[level]
+
+	r.endLine()
+	r.gap(size * 0.8)
+
+	// keep the heading with the lines after it
+	r.ensure(size*lineRatio + 3*bodySize*lineRatio)
+
+	text := collapse(textContent(n))
+
+	if level <= TOCDepth && r.toc != nil {
+		i := len(r.pages)
+		if i < len(r.toc) {
+			r.pdf.SetLink(r.toc[i], r.pdf.GetY(), r.pdf.PageNo())
+		}
+		r.pages = append(r.pages, r.pdf.PageNo())
+	}
+
+	// bookmark levels can not skip a level
+	bl := level - 1
+	if bl > r.outline+1 {
+		bl = r.outline + 1
+	}
+	r.outline = bl
+	r.pdf.Bookmark(r.tr(text), bl, -1)
+
+	r.withFont(func(f *font) {
+		f.bold = true
+		f.size = size
+		f.color = [3]int{0, 0, 0}
+	}, func() {
+		r.children(n)
+		r.endLine()
+	})
+
+	r.pdf.Ln(size * 0.3)
+	r.noGap = false
+}
+
+func (r *renderer) list(n *html.Node) {
+	r.block(4, func() {
+		num := 1
+		if s, err := strconv.Atoi(attr(n, "start")); err == nil {
+			num = s
+		}
+
+		for li := n.FirstChild; li != nil; li = li.NextSibling {
+			if li.DataAtom != atom.Li {
+				continue
+			}
+
+			marker := "•"
+			if n.DataAtom == atom.Ol {
+				marker = strconv.Itoa(num) + "."
+				num++
+			}
+
+			r.item(li, marker)
+		}
+	})
+}
+
+func (r *renderer) item(li *html.Node, marker string) {
+	r.endLine()
+	r.gap(2)
+
+	lm, _, _, _ := r.pdf.GetMargins()
+
+	r.setFont()
+	r.pdf.SetX(lm)
+	r.pdf.CellFormat(indent, r.lh(), r.tr(marker), "", 0, "L", false, 0, "")
+
+	r.pdf.SetLeftMargin(lm + indent)
+	r.noGap = true
+	r.space = true
+	r.fresh = false
+
+	r.children(li)
+
+	r.noGap = false
+	r.endLine()
+	r.pdf.SetLeftMargin(lm)
+}
+
+// code writes a code block, highlighted with the chroma
+// style of the book, on a shaded background. Long lines
+// are wrapped.
+func (r *renderer) code(n *html.Node) {
+	lang := ""
+	if c := findElement(n, "code"); c != nil {
+		for _, class := range strings.Fields(attr(c, "class")) {
+			if l, ok := strings.CutPrefix(class, "language-"); ok {
+				lang = l
+			}
+		}
+	}
+
+	src := strings.TrimRight(textContent(n), "\n")
+	src = strings.ReplaceAll(src, "\t", "    ")
+
+	r.endLine()
+	r.gap(6)
+
+	pdf := r.pdf
+	lm, _, _, _ := pdf.GetMargins()
+	w := r.width()
+	lh := codeSize * 1.35
+
+	pdf.SetFont("Courier", "", codeSize)
+	cols := int((w - 2*cellPad) / pdf.GetStringWidth("m"))
+
+	lines := splitSpans(r.hl.Spans(src, lang), cols)
+
+	pdf.SetFillColor(246, 248, 250)
+
+	shade := func(h float64) {
+		y := pdf.GetY()
+		pdf.Rect(lm, y, w, h, "F")
+		pdf.SetXY(lm, y+h)
+	}
+
+	r.ensure(lh + 2*cellPad)
+	shade(cellPad)
+
+	for _, line := range lines {
+		r.ensure(lh)
+
+		y := pdf.GetY()
+		pdf.Rect(lm, y, w, lh, "F")
+		pdf.SetXY(lm+cellPad, y)
+
+		for _, sp := range line {
+			style := ""
+			if sp.Bold {
+				style += "B"
+			}
+			if sp.Italic {
+				style += "I"
+			}
+			pdf.SetFont("Courier", style, codeSize)
+
+			cr, cg, cb := hexColor(sp.Color)
+			pdf.SetTextColor(cr, cg, cb)
+
+			t := r.tr(sp.Text)
+			pdf.CellFormat(pdf.GetStringWidth(t), lh, t, "", 0, "L", false, 0, "")
+		}
+
+		pdf.SetXY(lm, y+lh)
+	}
+
+	shade(cellPad)
+
+	r.fresh = false
+	r.space = true
+}
+
+// splitSpans splits the spans into lines, wrapping
+// lines that are longer than cols characters.
+func splitSpans(spans []blog.Span, cols int) [][]blog.Span {
+	if cols < 1 {
+		cols = 1
+	}
+
+	lines := [][]blog.Span{nil}
+	col := 0
+
+	add := func(sp blog.Span, s string) {
+		if len(s) == 0 {
+			return
+		}
+		sp.Text = s
+		lines[len(lines)-1] = append(lines[len(lines)-1], sp)
+	}
+
+	for _, sp := range spans {
+		var cur []rune
+		for _, c := range sp.Text {
+			if c == '\n' || col == cols {
+				add(sp, string(cur))
+				cur = nil
+				col = 0
+				lines = append(lines, nil)
+
+				if c == '\n' {
+					continue
+				}
+			}
+
+			cur = append(cur, c)
+			col++
+		}
+
+		add(sp, string(cur))
+	}
+
+	return lines
+}
+
+func (r *renderer) table(n *html.Node) {
+	var rows []*html.Node
+
+	var find func(n *html.Node)
+	find = func(n *html.Node) {
+		for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+			switch ch.DataAtom {
+			case atom.Tr:
+				rows = append(rows, ch)
+			case atom.Thead, atom.Tbody, atom.Tfoot:
+				find(ch)
+			}
+		}
+	}
+	find(n)
+
+	cols := 0
+	for _, row := range rows {
+		if c := len(cells(row)); c > cols {
+			cols = c
+		}
+	}
+
+	if cols == 0 {
+		return
+	}
+
+	r.endLine()
+	r.gap(6)
+
+	pdf := r.pdf
+	lm, _, _, _ := pdf.GetMargins()
+	cw := r.width() / float64(cols)
+	lh := (bodySize - 1) * lineRatio
+
+	pdf.SetDrawColor(200, 200, 200)
+	pdf.SetFillColor(240, 242, 245)
+	pdf.SetTextColor(0, 0, 0)
+
+	for _, row := range rows {
+		cs := cells(row)
+
+		header := false
+		texts := make([]string, len(cs))
+		for i, c := range cs {
+			texts[i] = r.tr(collapse(textContent(c)))
+			if c.DataAtom == atom.Th {
+				header = true
+			}
+		}
+
+		style := ""
+		if header {
+			style = "B"
+		}
+		pdf.SetFont("Helvetica", style, bodySize-1)
+
+		h := lh
+		for _, t := range texts {
+			if lines := len(pdf.SplitLines([]byte(t), cw-2*cellPad)); float64(lines)*lh > h {
+				h = float64(lines) * lh
+			}
+		}
+		h += 2 * cellPad
+
+		r.ensure(h)
+		y := pdf.GetY()
+
+		for i := 0; i < cols; i++ {
+			x := lm + float64(i)*cw
+
+			box := "D"
+			if header {
+				box = "FD"
+			}
+			pdf.Rect(x, y, cw, h, box)
+
+			if i >= len(texts) {
+				continue
+			}
+
+			pdf.SetXY(x+cellPad, y+cellPad)
+			pdf.MultiCell(cw-2*cellPad, lh, texts[i], "", cellAlign(cs[i]), false)
+		}
+
+		pdf.SetXY(lm, y+h)
+	}
+
+	r.fresh = false
+	r.space = true
+}
+
+func cells(row *html.Node) []*html.Node {
+	var cs []*html.Node
+	for c := row.FirstChild; c != nil; c = c.NextSibling {
+		if c.DataAtom == atom.Td || c.DataAtom == atom.Th {
+			cs = append(cs, c)
+		}
+	}
+	return cs
+}
+
+func cellAlign(n *html.Node) string {
+	a := attr(n, "align")
+	if len(a) == 0 {
+		if _, s, ok := strings.Cut(attr(n, "style"), "text-align:"); ok {
+			a, _, _ = strings.Cut(strings.TrimSpace(s), ";")
+		}
+	}
+
+	switch strings.TrimSpace(a) {
+	case "center":
+		return "C"
+	case "right":
+		return "R"
+	}
+
+	return "L"
+}
+
+// image writes a PNG, JPEG, or GIF image, scaled to fit the page.
+// Remote images, and images in other formats, are written as
+// their alt text.
+// svg writes a placeholder, with the caption of its figure,
+// for inline SVG, such as a diagram. SVG can not be drawn,
+// and its text is not readable on its own, so a warning is
+// recorded too.
+func (r *renderer) svg(n *html.Node) {
+	label := "diagram"
+	if fc := figcaption(n); fc != nil {
+		if caption := collapse(textContent(fc)); len(caption) > 0 {
+			label += ": " + caption
+		}
+	}
+
+	r.block(6, func() {
+		r.withFont(func(f *font) { f.italic = true }, func() {
+			r.text("[" + label + "]")
+		})
+	})
+
+	r.warnings = append(r.warnings, fmt.Sprintf("%s: inline SVG can not be drawn in a PDF, a placeholder is written instead: [%s]", r.file, label))
+}
+
+func (r *renderer) image(n *html.Node) {
+	src := attr(n, "src")
+	alt := attr(n, "alt")
+
+	placeholder := func() {
+		if len(alt) == 0 {
+			alt = src
+		}
+		r.withFont(func(f *font) { f.italic = true }, func() {
+			r.text("[" + alt + "]")
+		})
+	}
+
+	if r.fsys == nil || len(src) == 0 || strings.Contains(src, ":") || strings.HasPrefix(src, "//") {
+		placeholder()
+		return
+	}
+
+	data, err := fs.ReadFile(r.fsys, path.Clean(strings.TrimPrefix(src, "/")))
+	if err != nil {
+		placeholder()
+		return
+	}
+
+	// fpdf errors are fatal to the whole document,
+	// so check that the image can be decoded first
+	_, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		placeholder()
+		return
+	}
+
+	if format == "jpeg" {
+		format = "jpg"
+	}
+
+	r.images++
+	name := fmt.Sprintf("image-%d", r.images)
+
+	opts := fpdf.ImageOptions{
+		ImageType: format,
+		ReadDpi:   true,
+	}
+
+	info := r.pdf.RegisterImageOptionsReader(name, opts, bytes.NewReader(data))
+	if info == nil {
+		return
+	}
+
+	iw, ih := info.Extent()
+
+	w := r.width()
+	if iw > w {
+		ih = ih * w / iw
+		iw = w
+	}
+
+	_, ph := r.pdf.GetPageSize()
+	if max := ph - 2*margin; ih > max {
+		iw = iw * max / ih
+		ih = max
+	}
+
+	r.endLine()
+	r.gap(6)
+	r.ensure(ih)
+
+	lm, _, _, _ := r.pdf.GetMargins()
+	r.pdf.ImageOptions(name, lm+(w-iw)/2, r.pdf.GetY(), iw, ih, true, opts, 0, "")
+
+	r.fresh = false
+	r.space = true
+}
+
+// isRef returns true for <ref> elements, which have the
+// same ID as the figure they refer to.
+func isRef(n *html.Node) bool {
+	return n.Data == "ref"
+}
+
+func headingLevel(n *html.Node) int {
+	if n.Type != html.ElementNode {
+		return 0
+	}
+
+	switch n.DataAtom {
+	case atom.H1:
+		return 1
+	case atom.H2:
+		return 2
+	case atom.H3:
+		return 3
+	case atom.H4:
+		return 4
+	case atom.H5:
+		return 5
+	case atom.H6:
+		return 6
+	}
+
+	return 0
+}
+
+func hexColor(s string) (int, int, int) {
+	s = strings.TrimPrefix(s, "#")
+	if len(s) != 6 {
+		return 0, 0, 0
+	}
+
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return 0, 0, 0
+	}
+
+	return int(v >> 16 & 0xff), int(v >> 8 & 0xff), int(v & 0xff)
+}
+
+func attr(n *html.Node, key string) string {
+	v, _ := hasAttr(n, key)
+	return v
+}
+
+func hasAttr(n *html.Node, key string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+
+	sb := &strings.Builder{}
+	for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+		sb.WriteString(textContent(ch))
+	}
+
+	return sb.String()
+}
+
+func collapse(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// figcaption returns the caption of the closest
+// figure n is in, or nil if there is none.
+func figcaption(n *html.Node) *html.Node {
+	for p := n.Parent; p != nil; p = p.Parent {
+		if p.DataAtom != atom.Figure {
+			continue
+		}
+
+		if fc := findElement(p, "figcaption"); fc != nil {
+			return fc
+		}
+	}
+
+	return nil
+}
+
+func findElement(n *html.Node, name string) *html.Node {
+	if n.Type == html.ElementNode && n.Data == name {
+		return n
+	}
+
+	for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+		if f := findElement(ch, name); f != nil {
+			return f
+		}
+	}
+
+	return nil
+}
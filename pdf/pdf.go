@@ -0,0 +1,244 @@
+// Package pdf writes hype documents as print-ready PDF files.
+//
+// The layout is done in Go, so no browser, or any other external
+// tool, is needed. Each <page> of a document starts a new PDF page,
+// code blocks are highlighted with the same chroma styles as the
+// blog, and a table of contents is generated from the headings.
+//
+// Text is set in the standard PDF fonts, which only support the
+// Windows-1252 character set; other characters are replaced.
+package pdf
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/gopherguides/hype"
+	"golang.org/x/net/html"
+)
+
+const (
+	// DefaultPageSize is the page size used if none is set.
+	DefaultPageSize = "Letter"
+
+	// DefaultStyle is the chroma style used for code blocks
+	// if none is set. A light style is used, as the output
+	// is meant to be printed.
+	DefaultStyle = "github"
+
+	// TOCDepth is the deepest heading level included
+	// in the table of contents.
+	TOCDepth = 2
+)
+
+// Book is a PDF rendering of one, or more, documents.
+type Book struct {
+	Title    string
+	Author   string
+	PageSize string    // "Letter", "A4", "A5", "Legal", ...; default: DefaultPageSize
+	Style    string    // chroma style for code blocks; default: DefaultStyle
+	Modified time.Time // creation date of the file; default: the time it is written
+	NoTOC    bool      // do not generate a table of contents
+
+	// Warnings are set by Write to the content of the book
+	// that could not be written as it is, such as inline SVG
+	// diagrams, which are replaced by a placeholder.
+	Warnings []string
+
+	chapters []chapter
+}
+
+type chapter struct {
+	doc  *hype.Document
+	body *html.Node
+}
+
+// New returns a book with the given documents, in order.
+// The documents should already be executed.
+//
+// The title and author are read from the <metadata> tags of
+// the documents. If there is no title in the metadata, title is
+// used, or, if that is empty, the title of the first document.
+func New(title string, docs hype.Documents) (*Book, error) {
+	if len(docs) == 0 {
+		return nil, fmt.Errorf("pdf: no documents")
+	}
+
+	b := &Book{}
+
+	// the book is a single file, so refs to
+	// other chapters link to the figure's ID
+	err := docs.ResolveRefs(func(from, to *hype.Document, fig *hype.Figure) (string, error) {
+		return fig.Link(), nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("pdf: %w", err)
+	}
+
+	for _, doc := range docs {
+		if doc == nil {
+			return nil, hype.ErrIsNil("document")
+		}
+
+		md := hype.ExtractMeta(doc).Metadata
+
+		if len(b.Title) == 0 {
+			b.Title = md["title"]
+		}
+
+		if len(b.Author) == 0 {
+			b.Author = md["author"]
+		}
+
+		root, err := html.Parse(strings.NewReader(doc.String()))
+		if err != nil {
+			return nil, fmt.Errorf("pdf: %s: %w", doc.Filename, err)
+		}
+
+		body := findElement(root, "body")
+		if body == nil {
+			return nil, fmt.Errorf("pdf: %s: missing body", doc.Filename)
+		}
+
+		b.chapters = append(b.chapters, chapter{
+			doc:  doc,
+			body: body,
+		})
+	}
+
+	if len(b.Title) == 0 {
+		b.Title = title
+	}
+
+	if len(b.Title) == 0 {
+		b.Title = docs[0].Title
+	}
+
+	return b, nil
+}
+
+// Write writes the book to w as a PDF file.
+//
+// The book is laid out twice: the first time to find the
+// pages the headings end up on, and the second time to
+// write the table of contents with those page numbers.
+func (b *Book) Write(w io.Writer) error {
+	if b == nil {
+		return hype.ErrIsNil("book")
+	}
+
+	if len(b.chapters) == 0 {
+		return fmt.Errorf("pdf: no documents")
+	}
+
+	toc := b.contents()
+
+	r, err := b.layout(toc)
+	if err != nil {
+		return err
+	}
+
+	if len(toc) > 0 {
+		for i, p := range r.pages {
+			toc[i].Page = p
+		}
+
+		r, err = b.layout(toc)
+		if err != nil {
+			return err
+		}
+	}
+
+	b.Warnings = r.warnings
+
+	return r.pdf.Output(w)
+}
+
+// layout renders the whole book into a new PDF.
+func (b *Book) layout(toc []Entry) (*renderer, error) {
+	size := b.PageSize
+	if len(size) == 0 {
+		size = DefaultPageSize
+	}
+
+	style := b.Style
+	if len(style) == 0 {
+		style = DefaultStyle
+	}
+
+	mod := b.Modified
+	if mod.IsZero() {
+		mod = time.Now()
+	}
+
+	r := newRenderer(size, style)
+
+	pdf := r.pdf
+	pdf.SetTitle(b.Title, true)
+	pdf.SetAuthor(b.Author, true)
+	pdf.SetCreator("hype", true)
+	pdf.SetCreationDate(mod)
+	pdf.SetModificationDate(mod)
+	pdf.SetCatalogSort(true)
+
+	// figures can be referred to from other chapters
+	for _, ch := range b.chapters {
+		r.figureLinks(ch.body)
+	}
+
+	r.titlePage(b.Title, b.Author)
+
+	if len(toc) > 0 {
+		r.contentsPages(toc)
+	}
+
+	for _, ch := range b.chapters {
+		r.chapter(ch.doc, ch.body)
+	}
+
+	if err := pdf.Error(); err != nil {
+		return nil, fmt.Errorf("pdf: %w", err)
+	}
+
+	return r, nil
+}
+
+// Entry is an entry in the table of contents.
+type Entry struct {
+	Level int
+	Text  string
+	Page  int
+}
+
+// contents returns the entries of the table of contents,
+// in the order the headings appear in the book.
+func (b *Book) contents() []Entry {
+	if b.NoTOC {
+		return nil
+	}
+
+	var toc []Entry
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if level := headingLevel(n); level > 0 && level <= TOCDepth {
+			toc = append(toc, Entry{
+				Level: level,
+				Text:  collapse(textContent(n)),
+			})
+			return
+		}
+
+		for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+			walk(ch)
+		}
+	}
+
+	for _, ch := range b.chapters {
+		walk(ch.body)
+	}
+
+	return toc
+}
@@ -0,0 +1,186 @@
+package pdf
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gopherguides/hype"
+	"github.com/gopherguides/hype/blog"
+	"github.com/stretchr/testify/require"
+)
+
+func testBook(t testing.TB) *Book {
+	t.Helper()
+	r := require.New(t)
+
+	p := hype.NewParser(os.DirFS("testdata/book"))
+	p.Root = "testdata/book"
+
+	docs, err := p.ParseExecuteFolder(context.Background(), "book")
+	r.NoError(err)
+	r.Len(docs, 2)
+
+	b, err := New("Book", docs)
+	r.NoError(err)
+
+	b.Modified = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	return b
+}
+
+// uncompressed lays out the book, with the table of contents,
+// and returns the PDF without compressing the page contents.
+func uncompressed(t testing.TB, b *Book) string {
+	t.Helper()
+	r := require.New(t)
+
+	bb := &bytes.Buffer{}
+	r.NoError(b.Write(bb))
+	r.True(strings.HasPrefix(bb.String(), "%PDF-"))
+
+	toc := b.contents()
+	rd, err := b.layout(toc)
+	r.NoError(err)
+
+	for i, p := range rd.pages {
+		toc[i].Page = p
+	}
+
+	rd, err = b.layout(toc)
+	r.NoError(err)
+
+	rd.pdf.SetCompression(false)
+
+	bb.Reset()
+	r.NoError(rd.pdf.Output(bb))
+
+	return bb.String()
+}
+
+func Test_New(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	b := testBook(t)
+
+	r.Equal("The Gopher Book", b.Title)
+	r.Equal("Ada Gopher, Rob Gopher", b.Author)
+	r.Len(b.chapters, 2)
+
+	exp := []Entry{
+		{Level: 1, Text: "Introduction"},
+		{Level: 2, Text: "Getting Started"},
+		{Level: 1, Text: "Usage"},
+		{Level: 2, Text: "Running"},
+		{Level: 2, Text: "Images"},
+	}
+	r.Equal(exp, b.contents())
+
+	b.NoTOC = true
+	r.Empty(b.contents())
+
+	_, err := New("", nil)
+	r.Error(err)
+}
+
+func Test_Book_Write(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	b := testBook(t)
+	out := uncompressed(t, b)
+
+	// title page
+	r.Contains(out, "(The Gopher Book)Tj")
+	r.Contains(out, "(Ada Gopher, Rob Gopher)Tj")
+
+	// table of contents, with the page numbers of the
+	// title page, contents, and the two chapters
+	r.Contains(out, "(Contents)Tj")
+	r.Contains(out, "(Getting Started)Tj")
+	r.Contains(out, "(3)Tj")
+	r.Contains(out, "(4)Tj")
+
+	// outline
+	r.Contains(out, "/Title (Requirements)")
+
+	// highlighted code, wrapped to fit the page
+	r.Contains(out, "(package)Tj")
+	r.Contains(out, "(println)Tj")
+	r.Contains(out, "(ot even close\")Tj")
+
+	// table
+	r.Contains(out, "(Value)Tj")
+
+	// images; svg is not supported, so the alt text is written
+	r.Contains(out, "/Subtype /Image")
+	r.Contains(out, "([a vector gopher])Tj")
+
+	// inline svg, such as a diagram, is replaced by
+	// a placeholder, with its caption, and a warning
+	r.NotContains(out, "(start)Tj")
+	r.Contains(out, "([diagram: Figure 2.2: The flow of a request])Tj")
+	r.Len(b.Warnings, 1)
+	r.Contains(b.Warnings[0], "02-usage/hype.md: inline SVG")
+
+	// figures are numbered by the ref processor, and
+	// referred to from their own, and other, chapters
+	r.Contains(out, "(Figure 2.1:)Tj")
+	r.Equal(2, strings.Count(out, "(Figure 2.1)Tj"))
+
+	// links
+	r.Contains(out, "/URI (https://go.dev)")
+	r.Contains(out, "(running)Tj")
+}
+
+func Test_Book_Write_Errors(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	var b *Book
+	r.Error(b.Write(io.Discard))
+
+	b = &Book{}
+	r.Error(b.Write(io.Discard))
+}
+
+func Test_splitSpans(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	spans := []blog.Span{
+		{Text: "func", Color: "#cf222e"},
+		{Text: " main() {\n\tabcdefgh\n}"},
+	}
+
+	lines := splitSpans(spans, 6)
+
+	var act []string
+	for _, line := range lines {
+		var s string
+		for _, sp := range line {
+			s += sp.Text
+		}
+		act = append(act, s)
+	}
+
+	exp := []string{"func m", "ain() ", "{", "\tabcde", "fgh", "}"}
+	r.Equal(exp, act)
+	r.Equal("#cf222e", lines[0][0].Color)
+}
+
+func Test_hexColor(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	cr, cg, cb := hexColor("#cf222e")
+	r.Equal([]int{0xcf, 0x22, 0x2e}, []int{cr, cg, cb})
+
+	cr, cg, cb = hexColor("")
+	r.Equal([]int{0, 0, 0}, []int{cr, cg, cb})
+}
@@ -0,0 +1,169 @@
+package hype
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Pos is a position in a source file.
+//
+// The position of an element written as an HTML tag, such as
+// <cmd>, is that of its start tag. Elements written in Markdown,
+// such as headings, or added by a pre-parser, have a position
+// that is searched for in the source, or taken from their parent,
+// and may be off; Approx is true for them.
+type Pos struct {
+	File   string `json:"file,omitempty"`
+	Line   int    `json:"line,omitempty"`   // 1-based
+	Col    int    `json:"col,omitempty"`    // 1-based, in bytes
+	Approx bool   `json:"approx,omitempty"` // the position was guessed, rather than found
+}
+
+// IsValid reports whether the position has a line.
+func (pos Pos) IsValid() bool {
+	return pos.Line > 0
+}
+
+// String returns the position as "file:line:col",
+// the format used by compilers, editors, and CI annotations.
+func (pos Pos) String() string {
+	if !pos.IsValid() {
+		return pos.File
+	}
+
+	s := fmt.Sprintf("%d:%d", pos.Line, pos.Col)
+	if len(pos.File) > 0 {
+		s = pos.File + ":" + s
+	}
+
+	return s
+}
+
+// Excerpt returns the line at pos in src, with a
+// caret under the column:
+//
+//	12 | <cmd exec="false"></cmd>
+//	   | ^
+//
+// An empty string is returned if pos is not in src.
+func (pos Pos) Excerpt(src []byte) string {
+	if !pos.IsValid() {
+		return ""
+	}
+
+	lines := bytes.Split(src, []byte("\n"))
+	if pos.Line > len(lines) {
+		return ""
+	}
+
+	line := string(bytes.TrimRight(lines[pos.Line-1], "\r"))
+
+	// keep the tabs before the column so
+	// the caret lines up with the source
+	col := min(max(pos.Col-1, 0), len(line))
+	pad := strings.Map(func(r rune) rune {
+		if r == '\t' {
+			return r
+		}
+		return ' '
+	}, line[:col])
+
+	num := fmt.Sprintf("%5d", pos.Line)
+	gutter := strings.Repeat(" ", len(num))
+
+	return fmt.Sprintf("%s | %s\n%s | %s^", num, line, gutter, pad)
+}
+
+// Positioner is implemented by nodes, and errors,
+// that know where they are in a source file.
+type Positioner interface {
+	Position() Pos
+}
+
+// ErrPos returns the source position of err, if it,
+// or any error it wraps, has one.
+func ErrPos(err error) (Pos, bool) {
+	var pe Positioner
+	if !errors.As(err, &pe) {
+		return Pos{}, false
+	}
+
+	pos := pe.Position()
+
+	return pos, pos.IsValid()
+}
+
+// errPos returns the position of err, or,
+// if it does not have one, the position of n.
+func errPos(err error, n any) Pos {
+	if pos, ok := ErrPos(err); ok {
+		return pos
+	}
+
+	if pn, ok := n.(Positioner); ok {
+		return pn.Position()
+	}
+
+	return Pos{}
+}
+
+// diagnostic renders msg in the compiler style,
+// "file:line:col: msg", followed by an excerpt of
+// the source, if src holds the contents of pos.File.
+// Any further lines of msg come after the excerpt.
+func diagnostic(pos Pos, src []byte, msg string) string {
+	head, rest, _ := strings.Cut(msg, "\n")
+
+	lines := []string{fmt.Sprintf("%s: %s", pos, head)}
+
+	if ex := pos.Excerpt(src); len(ex) > 0 {
+		lines = append(lines, ex)
+	}
+
+	if len(rest) > 0 {
+		lines = append(lines, rest)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// errMessage returns the message of err. If err is a hype
+// error at pos, its message is returned without the position,
+// so it is not repeated by the error wrapping it.
+func errMessage(err error, pos Pos) string {
+	if err == nil {
+		return ""
+	}
+
+	if m, ok := err.(interface {
+		Positioner
+		message() string
+	}); ok && m.Position() == pos {
+		return m.message()
+	}
+
+	return err.Error()
+}
+
+// docSource returns the contents of the file pos is in, if
+// it is the file d was parsed from, or a file it includes.
+func docSource(d *Document, pos Pos) []byte {
+	if d == nil {
+		return nil
+	}
+
+	if d.Parser != nil && filepath.Join(d.Root, d.Filename) == pos.File {
+		return d.Parser.Contents
+	}
+
+	for _, inc := range ByType[*Include](d.Nodes) {
+		if inc.file == pos.File {
+			return inc.contents
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,124 @@
+package hype
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Pos_String(t *testing.T) {
+	t.Parallel()
+
+	table := []struct {
+		name string
+		pos  Pos
+		exp  string
+	}{
+		{name: "full", pos: Pos{File: "hype.md", Line: 3, Col: 5}, exp: "hype.md:3:5"},
+		{name: "no file", pos: Pos{Line: 3, Col: 5}, exp: "3:5"},
+		{name: "no line", pos: Pos{File: "hype.md"}, exp: "hype.md"},
+		{name: "zero", exp: ""},
+	}
+
+	for _, tt := range table {
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+			r.Equal(tt.exp, tt.pos.String())
+		})
+	}
+}
+
+func Test_Pos_Excerpt(t *testing.T) {
+	t.Parallel()
+
+	src := []byte("# Hello\n\n\t<cmd exec=\"false\"></cmd>\r\n")
+
+	table := []struct {
+		name string
+		pos  Pos
+		exp  string
+	}{
+		{name: "first line", pos: Pos{Line: 1, Col: 1}, exp: "    1 | # Hello\n      | ^"},
+		{name: "tab", pos: Pos{Line: 3, Col: 2}, exp: "    3 | \t<cmd exec=\"false\"></cmd>\n      | \t^"},
+		{name: "column", pos: Pos{Line: 3, Col: 7}, exp: "    3 | \t<cmd exec=\"false\"></cmd>\n      | \t     ^"},
+		{name: "past end", pos: Pos{Line: 10, Col: 1}},
+		{name: "invalid", pos: Pos{}},
+	}
+
+	for _, tt := range table {
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+			r.Equal(tt.exp, tt.pos.Excerpt(src))
+		})
+	}
+}
+
+func Test_ErrPos(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	pos := Pos{File: "hype.md", Line: 3, Col: 1}
+
+	_, ok := ErrPos(io.EOF)
+	r.False(ok)
+
+	_, ok = ErrPos(ExecuteError{Err: io.EOF})
+	r.False(ok)
+
+	err := fmt.Errorf("wrapped: %w", ExecuteError{Err: io.EOF, Pos: pos})
+
+	act, ok := ErrPos(err)
+	r.True(ok)
+	r.Equal(pos, act)
+}
+
+func Test_Execute_Error_Position(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	p := NewParser(os.DirFS("testdata/positions"))
+
+	doc, err := p.ParseFile("hype.md")
+	r.NoError(err)
+
+	err = doc.Execute(context.Background())
+	r.Error(err)
+
+	var ce CmdError
+	r.True(errors.As(err, &ce))
+	r.Equal(Pos{File: "sub/sub.md", Line: 5, Col: 1}, ce.Pos)
+
+	exp := `sub/sub.md:5:1: execute error: cmd error: exit status 1
+    5 | <cmd exec="false"></cmd>
+      | ^
+cmd: $ false
+exit: 1
+document: Positions`
+
+	r.Equal(exp, err.Error())
+}
+
+func Test_Parse_Error_Position(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	p := NewParser(nil)
+	p.Filename = "hype.md"
+
+	_, err := p.Parse(strings.NewReader("# Hello\n\nSome text.\n\n<code src=\"main.go\" section=\"x\"></code>\n"))
+	r.Error(err)
+
+	pos, ok := ErrPos(err)
+	r.True(ok)
+	r.Equal(Pos{File: "hype.md", Line: 5, Col: 1}, pos)
+
+	act := err.Error()
+	r.Contains(act, "hype.md:5:1: parse error: ")
+	r.Contains(act, "\n    5 | <code src=\"main.go\" section=\"x\"></code>\n      | ^")
+}
@@ -28,6 +28,7 @@ func (list Nodes) PostExecute(ctx context.Context, d *Document, err error) error
 					OrigErr:      err,
 					Err:          err2,
 					PostExecuter: pe,
+					Pos:          errPos(err2, n),
 				}
 			}
 		}
@@ -15,6 +15,7 @@ type PostExecuteError struct {
 	OrigErr      error
 	Root         string
 	PostExecuter any
+	Pos          Pos // position of the node the error occurred at, if known
 }
 
 func (pee PostExecuteError) MarshalJSON() ([]byte, error) {
@@ -28,10 +29,18 @@ func (pee PostExecuteError) MarshalJSON() ([]byte, error) {
 		"type":          toType(pee),
 	}
 
+	if pee.Pos.IsValid() {
+		mm["pos"] = pee.Pos
+	}
+
 	return json.MarshalIndent(mm, "", "  ")
 }
 
 func (e PostExecuteError) Error() string {
+	if e.Pos.IsValid() {
+		return diagnostic(e.Pos, docSource(e.Document, e.Pos), e.message())
+	}
+
 	sb := &strings.Builder{}
 
 	var lines []string
@@ -60,6 +69,21 @@ func (e PostExecuteError) Error() string {
 	return strings.TrimSpace(s)
 }
 
+// Position returns the position the error occurred at.
+func (e PostExecuteError) Position() Pos {
+	return e.Pos
+}
+
+func (e PostExecuteError) message() string {
+	msg := fmt.Sprintf("post execute error: %s", errMessage(e.Err, e.Pos))
+
+	if e.OrigErr != nil {
+		msg += fmt.Sprintf("\noriginal error: %s", e.OrigErr)
+	}
+
+	return msg
+}
+
 func (e PostExecuteError) Unwrap() error {
 	if _, ok := e.Err.(unwrapper); ok {
 		return errors.Unwrap(e.Err)
@@ -15,6 +15,7 @@ type PostParseError struct {
 	OrigErr    error
 	Root       string
 	PostParser any
+	Pos        Pos // position of the node the error occurred at, if known
 }
 
 func (ppe PostParseError) MarshalJSON() ([]byte, error) {
@@ -28,10 +29,18 @@ func (ppe PostParseError) MarshalJSON() ([]byte, error) {
 		"type":         toType(ppe),
 	}
 
+	if ppe.Pos.IsValid() {
+		mm["pos"] = ppe.Pos
+	}
+
 	return json.MarshalIndent(mm, "", "  ")
 }
 
 func (ppe PostParseError) Error() string {
+	if ppe.Pos.IsValid() {
+		return diagnostic(ppe.Pos, docSource(ppe.Document, ppe.Pos), ppe.message())
+	}
+
 	sb := &strings.Builder{}
 
 	var lines []string
@@ -60,6 +69,21 @@ func (ppe PostParseError) Error() string {
 	return strings.TrimSpace(s)
 }
 
+// Position returns the position the error occurred at.
+func (ppe PostParseError) Position() Pos {
+	return ppe.Pos
+}
+
+func (ppe PostParseError) message() string {
+	msg := fmt.Sprintf("post parse error: %s", errMessage(ppe.Err, ppe.Pos))
+
+	if ppe.OrigErr != nil {
+		msg += fmt.Sprintf("\noriginal error: %s", ppe.OrigErr)
+	}
+
+	return msg
+}
+
 func (ppe PostParseError) Unwrap() error {
 	if _, ok := ppe.Err.(unwrapper); ok {
 		return errors.Unwrap(ppe.Err)
@@ -27,6 +27,7 @@ func (list Nodes) PostParse(p *Parser, d *Document, err error) error {
 					Filename: p.Filename,
 					OrigErr:  err,
 					Root:     p.Root,
+					Pos:      errPos(err2, n),
 				}
 			}
 			continue
@@ -44,6 +45,7 @@ func (list Nodes) PostParse(p *Parser, d *Document, err error) error {
 					OrigErr:    err,
 					PostParser: pp,
 					Root:       p.Root,
+					Pos:        errPos(err2, n),
 				}
 			}
 		}
@@ -59,6 +61,7 @@ func (list Nodes) PostParse(p *Parser, d *Document, err error) error {
 				Filename: p.Filename,
 				OrigErr:  err,
 				Root:     p.Root,
+				Pos:      errPos(err2, n),
 			}
 		}
 	}
@@ -0,0 +1,32 @@
+package hype
+
+// NewPreNodes implements the ParseElementFn type. A <pre> that
+// only holds a Diagram, or a Mermaid diagram rendered as SVG,
+// is replaced by the diagram, as an image is not preformatted
+// text.
+func NewPreNodes(p *Parser, el *Element) (Nodes, error) {
+	if el == nil {
+		return nil, ErrIsNil("element")
+	}
+
+	kids := el.Nodes
+	if len(kids) == 1 {
+		// parsed tags are returned as Nodes
+		if ns, ok := kids[0].(Nodes); ok {
+			kids = ns
+		}
+	}
+
+	if len(kids) == 1 {
+		switch k := kids[0].(type) {
+		case *Diagram:
+			return Nodes{k}, nil
+		case *Mermaid:
+			if k.Render == MermaidSVG {
+				return Nodes{k}, nil
+			}
+		}
+	}
+
+	return Nodes{el}, nil
+}
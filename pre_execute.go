@@ -33,6 +33,7 @@ func (list Nodes) PreExecute(ctx context.Context, d *Document) error {
 					Filename:    d.Filename,
 					PreExecuter: pe,
 					Root:        d.Root,
+					Pos:         errPos(err, n),
 				}
 			}
 		}
@@ -44,6 +45,7 @@ func (list Nodes) PreExecute(ctx context.Context, d *Document) error {
 				Filename:    d.Filename,
 				PreExecuter: pe,
 				Root:        d.Root,
+				Pos:         errPos(err, n),
 			}
 		}
 	}
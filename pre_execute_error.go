@@ -14,6 +14,7 @@ type PreExecuteError struct {
 	Filename    string
 	Root        string
 	PreExecuter any
+	Pos         Pos // position of the node the error occurred at, if known
 }
 
 func (pee PreExecuteError) MarshalJSON() ([]byte, error) {
@@ -26,10 +27,18 @@ func (pee PreExecuteError) MarshalJSON() ([]byte, error) {
 		"type":         toType(pee),
 	}
 
+	if pee.Pos.IsValid() {
+		mm["pos"] = pee.Pos
+	}
+
 	return json.MarshalIndent(mm, "", "  ")
 }
 
 func (pee PreExecuteError) Error() string {
+	if pee.Pos.IsValid() {
+		return diagnostic(pee.Pos, docSource(pee.Document, pee.Pos), pee.message())
+	}
+
 	sb := &strings.Builder{}
 
 	var lines []string
@@ -54,6 +63,17 @@ func (pee PreExecuteError) Error() string {
 	return strings.TrimSpace(s)
 }
 
+// Position returns the position the error occurred at.
+func (pee PreExecuteError) Position() Pos {
+	return pee.Pos
+}
+
+func (pee PreExecuteError) message() string {
+	msg := fmt.Sprintf("pre execute error: %s", errMessage(pee.Err, pee.Pos))
+
+	return msg
+}
+
 func (pee PreExecuteError) Unwrap() error {
 	if _, ok := pee.Err.(unwrapper); ok {
 		return errors.Unwrap(pee.Err)
@@ -14,6 +14,7 @@ type PreParseError struct {
 	Filename  string
 	Root      string
 	PreParser any
+	Pos       Pos // position in the source the error occurred at, if known
 }
 
 func (e PreParseError) MarshalJSON() ([]byte, error) {
@@ -26,10 +27,23 @@ func (e PreParseError) MarshalJSON() ([]byte, error) {
 		"type":       toType(e),
 	}
 
+	if e.Pos.IsValid() {
+		mm["pos"] = e.Pos
+	}
+
 	return json.MarshalIndent(mm, "", "  ")
 }
 
 func (e PreParseError) Error() string {
+	if e.Pos.IsValid() {
+		var src []byte
+		if filepath.Join(e.Root, e.Filename) == e.Pos.File {
+			src = e.Contents
+		}
+
+		return diagnostic(e.Pos, src, e.message())
+	}
+
 	sb := strings.Builder{}
 
 	var lines []string
@@ -54,6 +68,21 @@ func (e PreParseError) Error() string {
 	return strings.TrimSpace(s)
 }
 
+// Position returns the position the error occurred at.
+func (e PreParseError) Position() Pos {
+	return e.Pos
+}
+
+func (e PreParseError) message() string {
+	msg := fmt.Sprintf("pre-parse error: %s", errMessage(e.Err, e.Pos))
+
+	if e.PreParser != nil {
+		msg += fmt.Sprintf("\npre parser: %s", toType(e.PreParser))
+	}
+
+	return msg
+}
+
 func (e PreParseError) Unwrap() error {
 	if _, ok := e.Err.(unwrapper); ok {
 		return errors.Unwrap(e.Err)
@@ -32,6 +32,7 @@ func (list PreParsers) PreParse(p *Parser, r io.Reader) (io.Reader, error) {
 				Filename:  p.Filename,
 				PreParser: pp,
 				Root:      p.Root,
+				Pos:       errPos(err, nil),
 			}
 		}
 	}
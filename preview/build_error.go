@@ -0,0 +1,85 @@
+package preview
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+
+	"github.com/gopherguides/hype"
+)
+
+// Kinds of BuildError.
+const (
+	ErrorParse   = "parse"   // a hype.ParseError
+	ErrorExecute = "execute" // a hype.ExecuteError
+	ErrorCmd     = "cmd"     // a hype.CmdError, a command that failed
+	ErrorBuild   = "build"   // any other error
+)
+
+// BuildError describes a failed build, for the error
+// overlay of the preview.
+type BuildError struct {
+	Kind    string   `json:"kind"`
+	Message string   `json:"message"`        // the whole error, as printed
+	File    string   `json:"file,omitempty"` // the failing file, relative to the previewed directory
+	Line    int      `json:"line,omitempty"`
+	Col     int      `json:"col,omitempty"`
+	Args    []string `json:"args,omitempty"` // the arguments of a failed command
+	Exit    int      `json:"exit,omitempty"` // the exit code of a failed command
+	Output  string   `json:"output,omitempty"`
+}
+
+// NewBuildError returns the details of err, from the most
+// specific hype error it wraps. File paths are made relative
+// to root, where they are in it.
+func NewBuildError(err error, root string) *BuildError {
+	if err == nil {
+		return nil
+	}
+
+	be := &BuildError{
+		Kind:    ErrorBuild,
+		Message: err.Error(),
+	}
+
+	var pe hype.ParseError
+	var ee hype.ExecuteError
+	var ce hype.CmdError
+
+	switch {
+	case errors.As(err, &ce):
+		// the file of the <cmd> is in the root of its
+		// document, not the directory the command ran in
+		be.Kind = ErrorCmd
+		be.File = ce.Filename
+		if errors.As(err, &ee) {
+			be.File = filepath.Join(ee.Root, ce.Filename)
+		}
+		be.Args = ce.Args
+		be.Exit = ce.Exit
+		be.Output = string(ce.Output)
+	case errors.As(err, &ee):
+		be.Kind = ErrorExecute
+		be.File = filepath.Join(ee.Root, ee.Filename)
+	case errors.As(err, &pe):
+		be.Kind = ErrorParse
+		be.File = filepath.Join(pe.Root, pe.Filename)
+	}
+
+	if pos, ok := hype.ErrPos(err); ok {
+		be.Line = pos.Line
+		be.Col = pos.Col
+
+		if len(pos.File) > 0 {
+			be.File = pos.File
+		}
+	}
+
+	if len(be.File) > 0 && len(root) > 0 {
+		if rel, err := filepath.Rel(root, be.File); err == nil && !strings.HasPrefix(rel, "..") {
+			be.File = filepath.ToSlash(rel)
+		}
+	}
+
+	return be
+}
@@ -0,0 +1,125 @@
+package preview
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gopherguides/hype"
+	"github.com/markbates/clam"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewBuildError(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		files  map[string]string
+		kind   string
+		line   int
+		exit   int
+		output string
+	}{
+		{
+			name:   "cmd",
+			source: "# Hello\n\n<cmd exec=\"sh -c 'echo oops; exit 3'\"></cmd>\n",
+			kind:   ErrorCmd,
+			line:   3,
+			exit:   3,
+			output: "oops",
+		},
+		{
+			name:   "cmd in src",
+			source: "# Hello\n\n<cmd src=\"sub\" exec=\"sh -c 'echo oops; exit 3'\"></cmd>\n",
+			files:  map[string]string{"sub/x.txt": "x"},
+			kind:   ErrorCmd,
+			line:   3,
+			exit:   3,
+			output: "oops",
+		},
+		{
+			name:   "execute",
+			source: "# Hello\n\n<code src=\"main.go\" symbol=\"Missing\"></code>\n",
+			files:  map[string]string{"main.go": "package main\n"},
+			kind:   ErrorExecute,
+			line:   3,
+		},
+		{
+			name:   "parse",
+			source: "# Hello\n\n<include src=\"missing.md\"></include>\n",
+			kind:   ErrorParse,
+			line:   3,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := require.New(t)
+
+			dir := t.TempDir()
+			r.NoError(os.WriteFile(filepath.Join(dir, "test.md"), []byte(tc.source), 0644))
+
+			for name, body := range tc.files {
+				fp := filepath.Join(dir, name)
+				r.NoError(os.MkdirAll(filepath.Dir(fp), 0755))
+				r.NoError(os.WriteFile(fp, []byte(body), 0644))
+			}
+
+			cfg := DefaultConfig()
+			cfg.File = "test.md"
+
+			srv := New(cfg, nil)
+
+			err := srv.build(context.Background(), dir)
+			r.Error(err)
+
+			be := NewBuildError(err, dir)
+			r.Equal(tc.kind, be.Kind)
+			r.Equal(err.Error(), be.Message)
+			r.Equal("test.md", be.File)
+			r.Equal(tc.line, be.Line)
+			r.Equal(tc.exit, be.Exit)
+			r.Contains(be.Output, tc.output)
+
+			if tc.kind == ErrorCmd {
+				r.NotEmpty(be.Args)
+			}
+		})
+	}
+}
+
+func TestNewBuildError_Other(t *testing.T) {
+	r := require.New(t)
+
+	r.Nil(NewBuildError(nil, ""))
+
+	be := NewBuildError(errors.New("boom"), "/project")
+	r.Equal(&BuildError{Kind: ErrorBuild, Message: "boom"}, be)
+}
+
+func TestNewBuildError_Cmd_No_Pos(t *testing.T) {
+	r := require.New(t)
+
+	// a command that ran in a sub directory, with
+	// no position, is in the root of its document
+	err := hype.ExecuteError{
+		Err: hype.CmdError{
+			RunError: clam.RunError{
+				Err:  errors.New("exit status 3"),
+				Dir:  "/project/sub",
+				Exit: 3,
+			},
+			Filename: "test.md",
+		},
+		Filename: "test.md",
+		Root:     "/project",
+	}
+
+	be := NewBuildError(err, "/project")
+	r.Equal(ErrorCmd, be.Kind)
+	r.Equal("test.md", be.File)
+	r.Equal(3, be.Exit)
+	r.Zero(be.Line)
+}
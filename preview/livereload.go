@@ -1,14 +1,37 @@
 package preview
 
 import (
+	"encoding/json"
 	"net/http"
 	"sync"
 
 	"golang.org/x/net/websocket"
 )
 
+// Types of the messages sent to the browsers.
+const (
+	MessageReload   = "reload"   // the build succeeded, reload the page
+	MessageUpdate   = "update"   // the build succeeded, swap in the new content
+	MessageBuilding = "building" // a build has started
+	MessageError    = "error"    // the build failed, show the error
+	MessageSlide    = "slide"    // a browser moved to a slide, show it too
+)
+
+// Message is sent to the browsers, as JSON, over the
+// live reload websocket.
+type Message struct {
+	Type  string      `json:"type"`
+	Error *BuildError `json:"error,omitempty"` // the error of a MessageError
+	Title string      `json:"title,omitempty"` // the title of the page, of a MessageUpdate
+	HTML  string      `json:"html,omitempty"`  // the content of the page, of a MessageUpdate
+	Nav   string      `json:"nav,omitempty"`   // the navigation of a project page, of a MessageUpdate
+	Slide int         `json:"slide,omitempty"` // the number of the slide, from 1, of a MessageSlide
+}
+
 type LiveReload struct {
 	clients map[*websocket.Conn]bool
+	err     *BuildError // error of the last build, if it failed
+	slide   int         // slide shown by the last MessageSlide
 	mu      sync.RWMutex
 }
 
@@ -18,10 +41,19 @@ func NewLiveReload() *LiveReload {
 	}
 }
 
+// HandleWebSocket adds a browser to the clients. If the last
+// build failed, its error is sent to the browser as it connects,
+// so a page loaded after the failure shows it too. Likewise, a
+// browser is sent the slide the others show.
+//
+// A MessageSlide from a browser is relayed to the others, so
+// the windows of a presentation show the same slide.
 func (lr *LiveReload) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 	handler := websocket.Handler(func(ws *websocket.Conn) {
 		lr.mu.Lock()
 		lr.clients[ws] = true
+		be := lr.err
+		slide := lr.slide
 		lr.mu.Unlock()
 
 		defer func() {
@@ -31,17 +63,107 @@ func (lr *LiveReload) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 			_ = ws.Close()
 		}()
 
+		if be != nil {
+			_ = websocket.JSON.Send(ws, Message{Type: MessageError, Error: be})
+		}
+
+		if slide > 0 {
+			_ = websocket.JSON.Send(ws, Message{Type: MessageSlide, Slide: slide})
+		}
+
 		for {
-			var msg string
-			if err := websocket.Message.Receive(ws, &msg); err != nil {
+			var data string
+			if err := websocket.Message.Receive(ws, &data); err != nil {
 				break
 			}
+
+			var msg Message
+			if err := json.Unmarshal([]byte(data), &msg); err != nil {
+				continue
+			}
+
+			if msg.Type == MessageSlide && msg.Slide > 0 {
+				lr.showSlide(ws, msg.Slide)
+			}
 		}
 	})
 	handler.ServeHTTP(w, r)
 }
 
+// Reload tells the browsers to reload the page, and clears
+// the error of the last build.
 func (lr *LiveReload) Reload() {
+	lr.mu.Lock()
+	lr.err = nil
+	lr.mu.Unlock()
+
+	lr.Send(Message{Type: MessageReload})
+}
+
+// Update sends each browser the message returned by fn for
+// the path of the page it shows, such as a MessageUpdate with
+// the new content of the page, and clears the error of the
+// last build.
+func (lr *LiveReload) Update(fn func(path string) Message) {
+	lr.mu.Lock()
+	lr.err = nil
+	clients := make([]*websocket.Conn, 0, len(lr.clients))
+	for client := range lr.clients {
+		clients = append(clients, client)
+	}
+	lr.mu.Unlock()
+
+	for _, client := range clients {
+		_ = websocket.JSON.Send(client, fn(clientPath(client)))
+	}
+}
+
+// clientPath returns the path of the page a browser shows,
+// as sent in the path query parameter of the websocket.
+func clientPath(ws *websocket.Conn) string {
+	if ws == nil || ws.Request() == nil {
+		return ""
+	}
+
+	return ws.Request().URL.Query().Get("path")
+}
+
+// Building tells the browsers a build has started.
+func (lr *LiveReload) Building() {
+	lr.Send(Message{Type: MessageBuilding})
+}
+
+// Error tells the browsers the build failed with be, which
+// they show over the page until the next successful build.
+func (lr *LiveReload) Error(be *BuildError) {
+	lr.mu.Lock()
+	lr.err = be
+	lr.mu.Unlock()
+
+	lr.Send(Message{Type: MessageError, Error: be})
+}
+
+// showSlide tells every browser, but from, which sent it,
+// to show the slide n.
+func (lr *LiveReload) showSlide(from *websocket.Conn, n int) {
+	lr.mu.Lock()
+	lr.slide = n
+	clients := make([]*websocket.Conn, 0, len(lr.clients))
+	for client := range lr.clients {
+		if client != from {
+			clients = append(clients, client)
+		}
+	}
+	lr.mu.Unlock()
+
+	msg := Message{Type: MessageSlide, Slide: n}
+	for _, client := range clients {
+		_ = websocket.JSON.Send(client, msg)
+	}
+}
+
+// Send sends msg to every browser.
+func (lr *LiveReload) Send(msg Message) {
 	lr.mu.RLock()
 	clients := make([]*websocket.Conn, 0, len(lr.clients))
 	for client := range lr.clients {
@@ -50,7 +172,7 @@ func (lr *LiveReload) Reload() {
 	lr.mu.RUnlock()
 
 	for _, client := range clients {
-		_ = websocket.Message.Send(client, "reload")
+		_ = websocket.JSON.Send(client, msg)
 	}
 }
 
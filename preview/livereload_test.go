@@ -78,12 +78,141 @@ func TestLiveReload_Reload(t *testing.T) {
 
 	select {
 	case msg := <-msgChan:
-		r.Equal("reload", msg)
+		r.JSONEq(`{"type":"reload"}`, msg)
 	case <-time.After(time.Second):
 		t.Fatal("timeout waiting for reload message")
 	}
 }
 
+func TestLiveReload_Error(t *testing.T) {
+	r := require.New(t)
+
+	lr := NewLiveReload()
+
+	server := httptest.NewServer(liveReloadHandler{lr})
+	defer server.Close()
+
+	wsURL := "ws" + server.URL[4:] + "/"
+
+	ws, err := websocket.Dial(wsURL, "", server.URL)
+	r.NoError(err)
+	defer func() { _ = ws.Close() }()
+
+	time.Sleep(50 * time.Millisecond)
+
+	receive := func(ws *websocket.Conn) Message {
+		t.Helper()
+
+		_ = ws.SetReadDeadline(time.Now().Add(time.Second))
+
+		var msg Message
+		r.NoError(websocket.JSON.Receive(ws, &msg))
+		return msg
+	}
+
+	lr.Building()
+	r.Equal(Message{Type: MessageBuilding}, receive(ws))
+
+	be := &BuildError{Kind: ErrorCmd, Message: "cmd error: exit status 1", Exit: 1}
+	lr.Error(be)
+	r.Equal(Message{Type: MessageError, Error: be}, receive(ws))
+
+	// a page loaded after the failure gets the error too
+	ws2, err := websocket.Dial(wsURL, "", server.URL)
+	r.NoError(err)
+	defer func() { _ = ws2.Close() }()
+
+	r.Equal(Message{Type: MessageError, Error: be}, receive(ws2))
+
+	lr.Reload()
+	r.Equal(Message{Type: MessageReload}, receive(ws))
+
+	lr.mu.RLock()
+	r.Nil(lr.err)
+	lr.mu.RUnlock()
+}
+
+func TestLiveReload_Update(t *testing.T) {
+	r := require.New(t)
+
+	lr := NewLiveReload()
+	lr.err = &BuildError{Kind: ErrorBuild, Message: "boom"}
+
+	server := httptest.NewServer(liveReloadHandler{lr})
+	defer server.Close()
+
+	wsURL := "ws" + server.URL[4:] + "/?path=%2F02-usage%2F"
+
+	ws, err := websocket.Dial(wsURL, "", server.URL)
+	r.NoError(err)
+	defer func() { _ = ws.Close() }()
+
+	_ = ws.SetReadDeadline(time.Now().Add(time.Second))
+
+	var msg Message
+	r.NoError(websocket.JSON.Receive(ws, &msg))
+	r.Equal(MessageError, msg.Type)
+
+	lr.Update(func(path string) Message {
+		return Message{Type: MessageUpdate, HTML: "<p>" + path + "</p>"}
+	})
+
+	msg = Message{}
+	r.NoError(websocket.JSON.Receive(ws, &msg))
+	r.Equal(Message{Type: MessageUpdate, HTML: "<p>/02-usage/</p>"}, msg)
+
+	lr.mu.RLock()
+	r.Nil(lr.err)
+	lr.mu.RUnlock()
+}
+
+func TestLiveReload_Slide(t *testing.T) {
+	r := require.New(t)
+
+	lr := NewLiveReload()
+
+	server := httptest.NewServer(liveReloadHandler{lr})
+	defer server.Close()
+
+	wsURL := "ws" + server.URL[4:] + "/"
+
+	presenter, err := websocket.Dial(wsURL, "", server.URL)
+	r.NoError(err)
+	defer func() { _ = presenter.Close() }()
+
+	audience, err := websocket.Dial(wsURL, "", server.URL)
+	r.NoError(err)
+	defer func() { _ = audience.Close() }()
+
+	time.Sleep(50 * time.Millisecond)
+	r.Equal(2, lr.ClientCount())
+
+	// not JSON, ignored
+	r.NoError(websocket.Message.Send(presenter, "hello"))
+	r.NoError(websocket.JSON.Send(presenter, Message{Type: MessageSlide, Slide: 3}))
+
+	_ = audience.SetReadDeadline(time.Now().Add(time.Second))
+
+	var msg Message
+	r.NoError(websocket.JSON.Receive(audience, &msg))
+	r.Equal(Message{Type: MessageSlide, Slide: 3}, msg)
+
+	// the sender is not sent its own slide
+	_ = presenter.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	r.Error(websocket.JSON.Receive(presenter, &msg))
+
+	// a window opened later shows the same slide
+	late, err := websocket.Dial(wsURL, "", server.URL)
+	r.NoError(err)
+	defer func() { _ = late.Close() }()
+
+	_ = late.SetReadDeadline(time.Now().Add(time.Second))
+
+	msg = Message{}
+	r.NoError(websocket.JSON.Receive(late, &msg))
+	r.Equal(Message{Type: MessageSlide, Slide: 3}, msg)
+}
+
 type liveReloadHandler struct {
 	lr *LiveReload
 }
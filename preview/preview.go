@@ -3,6 +3,7 @@ package preview
 import (
 	"context"
 	"fmt"
+	"html"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -43,7 +44,7 @@ var defaultExcludes = []string{
 }
 
 type Config struct {
-	File          string
+	File          string // file to preview, or a directory to preview every hype.md in
 	Port          int
 	WatchDirs     []string
 	Extensions    []string
@@ -78,7 +79,12 @@ type Server struct {
 	liveReload *LiveReload
 
 	currentHTML string
+	pages       map[string]string  // pages of a project, by route
+	updates     map[string]Message // content of the pages, by route, to swap in
 	pwd         string
+	deps        *hype.DepGraph // dependencies of the last successful build
+	depsRoot    string         // directory the paths in deps are relative to
+	depsFiles   []string       // documents of the last successful build, relative to depsRoot
 	mu          sync.RWMutex
 
 	stdout func(format string, args ...any)
@@ -174,33 +180,36 @@ func (s *Server) build(ctx context.Context, pwd string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	fileDir := filepath.Dir(s.config.File)
-	fileName := filepath.Base(s.config.File)
-
-	var rootDir string
-	if filepath.IsAbs(fileDir) {
-		rootDir = fileDir
-	} else if fileDir != "." && fileDir != "" {
-		rootDir = filepath.Join(pwd, fileDir)
-	} else {
-		rootDir = pwd
+	rootDir, project := s.rootDir(pwd)
+	if project {
+		return s.buildProject(ctx, rootDir)
 	}
 
+	fileName := filepath.Base(s.config.File)
+
 	parserFS := os.DirFS(rootDir)
 
 	p := s.parser
 	if p == nil {
 		p = hype.NewParser(parserFS)
+		p.MermaidRender = hype.MermaidSVG
 	} else {
 		p.FS = parserFS
 	}
 	p.Root = rootDir
 
+	s.deps = nil
+
 	doc, err := p.ParseFile(fileName)
 	if err != nil {
 		return fmt.Errorf("parse error: %w", err)
 	}
 
+	deps, err := doc.Deps()
+	if err != nil {
+		return fmt.Errorf("dependency error: %w", err)
+	}
+
 	execCtx := ctx
 	if s.config.Timeout > 0 {
 		var cancel context.CancelFunc
@@ -212,20 +221,141 @@ func (s *Server) build(ctx context.Context, pwd string) error {
 		return fmt.Errorf("execute error: %w", err)
 	}
 
-	html, err := s.wrapHTML(doc.String())
+	content := doc.String()
+
+	html, err := s.wrapHTML(content)
 	if err != nil {
 		return fmt.Errorf("wrap HTML error: %w", err)
 	}
 	s.currentHTML = html
+	s.pages = nil
+	s.updates = map[string]Message{
+		"/": {Type: MessageUpdate, Title: pageTitle(""), HTML: content},
+	}
+	s.deps = deps
+	s.depsRoot = rootDir
+	s.depsFiles = []string{fileName}
 	return nil
 }
 
+// rootDir returns the absolute directory of the previewed
+// file, or, if the file is a directory, the directory itself,
+// and true, to preview it as a project.
+func (s *Server) rootDir(pwd string) (string, bool) {
+	fp := s.config.File
+	if !filepath.IsAbs(fp) {
+		fp = filepath.Join(pwd, fp)
+	}
+	fp = filepath.Clean(fp)
+
+	if info, err := os.Stat(fp); err == nil && info.IsDir() {
+		return fp, true
+	}
+
+	return filepath.Dir(fp), false
+}
+
+// needsRebuild reports whether any of the changed files
+// affect the previewed documents. If the dependencies of the
+// documents are not known, for example after a failed build,
+// or a file outside of the documents' directory changed,
+// a rebuild is always needed. In a project, any change to a
+// hype.md is rebuilt, as it may be a new document.
+func (s *Server) needsRebuild(files []string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.deps == nil {
+		return true
+	}
+
+	if s.cssChanged(files) {
+		return true
+	}
+
+	for _, f := range files {
+		rel, err := filepath.Rel(s.depsRoot, f)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			return true
+		}
+
+		if s.pages != nil && filepath.Base(f) == "hype.md" {
+			return true
+		}
+
+		for _, name := range s.depsFiles {
+			if s.deps.DependsOn(name, filepath.ToSlash(rel)) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// update returns the message that swaps the content of the
+// page at path for the content of the last build. If the page
+// is no longer in the project, the browser is told to reload.
+func (s *Server) update(path string) Message {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// every path shows the document, if it is not a project
+	if s.pages == nil {
+		path = "/"
+	}
+
+	if msg, ok := s.updates[path]; ok {
+		return msg
+	}
+
+	return Message{Type: MessageReload}
+}
+
+// cssChanged reports whether the custom CSS file is
+// one of the changed files.
+func (s *Server) cssChanged(files []string) bool {
+	if s.config.CustomCSS == "" {
+		return false
+	}
+
+	for _, f := range files {
+		if filepath.Clean(f) == filepath.Clean(s.config.CustomCSS) {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (s *Server) handleRequest(w http.ResponseWriter, r *http.Request) {
 	if r.URL.Path == "/" || r.URL.Path == "" {
 		s.handlePreview(w, r)
 		return
 	}
 
+	s.mu.RLock()
+	pages := s.pages
+	root := s.pwd
+	if pages != nil {
+		root = s.depsRoot
+	}
+	s.mu.RUnlock()
+
+	if pages != nil {
+		if page, ok := pages[r.URL.Path]; ok {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			_, _ = fmt.Fprint(w, page)
+			return
+		}
+
+		// relative links of a page need the trailing slash
+		if _, ok := pages[r.URL.Path+"/"]; ok {
+			http.Redirect(w, r, r.URL.Path+"/", http.StatusMovedPermanently)
+			return
+		}
+	}
+
 	urlPath := strings.TrimPrefix(r.URL.Path, "/")
 	cleanPath := filepath.Clean(urlPath)
 
@@ -234,9 +364,9 @@ func (s *Server) handleRequest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	filePath := filepath.Join(s.pwd, cleanPath)
+	filePath := filepath.Join(root, cleanPath)
 
-	relPath, err := filepath.Rel(s.pwd, filePath)
+	relPath, err := filepath.Rel(root, filePath)
 	if err != nil || strings.HasPrefix(relPath, "..") {
 		http.Error(w, "Forbidden", http.StatusForbidden)
 		return
@@ -244,6 +374,13 @@ func (s *Server) handleRequest(w http.ResponseWriter, r *http.Request) {
 
 	info, err := os.Stat(filePath)
 	if err != nil || info.IsDir() {
+		// a project has a page per document, so
+		// other paths are not found
+		if pages != nil {
+			http.NotFound(w, r)
+			return
+		}
+
 		s.handlePreview(w, r)
 		return
 	}
@@ -266,21 +403,13 @@ func (s *Server) startWatcher(ctx context.Context, pwd string) (*fsnotify.Watche
 		return nil, err
 	}
 
-	fileDir := filepath.Dir(s.config.File)
-	if fileDir == "" {
-		fileDir = "."
-	}
-
 	// Normalize all paths to absolute and deduplicate
 	seen := make(map[string]bool)
 	var watchDirs []string
 
-	// Always include the source file's directory first
-	absFileDir := fileDir
-	if !filepath.IsAbs(fileDir) {
-		absFileDir = filepath.Join(pwd, fileDir)
-	}
-	absFileDir = filepath.Clean(absFileDir)
+	// Always include the source file's directory, or the
+	// project's, first
+	absFileDir, _ := s.rootDir(pwd)
 	seen[absFileDir] = true
 	watchDirs = append(watchDirs, absFileDir)
 
@@ -367,15 +496,31 @@ func (s *Server) watchLoop(ctx context.Context, watcher *fsnotify.Watcher, pwd s
 				s.stdout("Changed: %s\n", relPath)
 			}
 		}
+		if !s.needsRebuild(files) {
+			if s.config.Verbose {
+				s.stdout("No changes affect %s, skipping rebuild.\n", s.config.File)
+			}
+			return
+		}
+
 		s.stdout("Rebuilding...\n")
+		s.liveReload.Building()
 
 		if err := s.build(ctx, pwd); err != nil {
 			s.stderr("Build error: %v\n", err)
+			s.liveReload.Error(NewBuildError(err, pwd))
+			return
+		}
+
+		// the styles are only in the head of the page
+		if s.cssChanged(files) {
+			s.stdout("Done. Reloading browser...\n")
+			s.liveReload.Reload()
 			return
 		}
 
-		s.stdout("Done. Reloading browser...\n")
-		s.liveReload.Reload()
+		s.stdout("Done. Updating browser...\n")
+		s.liveReload.Update(s.update)
 	}
 
 	for {
@@ -469,31 +614,274 @@ func (s *Server) shouldWatch(path string, pwd string) bool {
 }
 
 func (s *Server) wrapHTML(content string) (string, error) {
+	return s.layout("", "", content)
+}
+
+// navCSS lays out the navigation of a project beside the page.
+const navCSS = `
+body.preview-project { display: flex; align-items: flex-start; max-width: none; margin: 0; padding: 0; }
+body.preview-project .markdown-body { flex: 1; min-width: 0; max-width: 980px; margin: 0 auto; padding: 32px; }
+.preview-nav { position: sticky; top: 0; flex: 0 0 18rem; max-height: 100vh; overflow-y: auto; box-sizing: border-box; padding: 24px 16px; border-right: 1px solid rgba(127, 127, 127, 0.3); font-size: 14px; }
+.preview-nav ol, .preview-nav ul { padding-left: 1.2em; }
+.preview-nav .preview-home { font-weight: 600; }
+.preview-nav a[aria-current="page"] { font-weight: 600; }
+`
+
+// overlayCSS styles the build error overlay, and the
+// indicator shown while a build runs.
+const overlayCSS = `
+html.hype-building::after { content: "Rebuilding\2026"; position: fixed; top: 12px; right: 12px; z-index: 10001; padding: 4px 10px; border-radius: 4px; background: #24292f; color: #fff; font: 12px sans-serif; opacity: 0.85; }
+#hype-error-overlay { position: fixed; inset: 0; z-index: 10000; overflow-y: auto; background: rgba(0, 0, 0, 0.66); }
+#hype-error-overlay .hype-error-box { position: relative; max-width: 960px; margin: 48px auto; padding: 24px 32px; border-top: 6px solid #cf222e; border-radius: 6px; background: #fff; color: #1f2328; font: 14px/1.5 -apple-system, BlinkMacSystemFont, "Segoe UI", sans-serif; }
+#hype-error-overlay .hype-error-title { margin: 0 0 8px; color: #cf222e; font-size: 20px; }
+#hype-error-overlay .hype-error-file { margin-bottom: 12px; font-family: ui-monospace, SFMono-Regular, Menlo, monospace; }
+#hype-error-overlay pre { margin: 0 0 12px; padding: 12px; overflow-x: auto; border-radius: 4px; background: #f6f8fa; color: #1f2328; font: 13px/1.45 ui-monospace, SFMono-Regular, Menlo, monospace; white-space: pre-wrap; }
+#hype-error-overlay .hype-error-output { background: #24292f; color: #f6f8fa; }
+#hype-error-overlay .hype-error-hint { color: #656d76; font-size: 12px; }
+#hype-error-overlay .hype-error-close { position: absolute; top: 12px; right: 16px; border: 0; background: none; color: #656d76; font-size: 24px; cursor: pointer; }
+`
+
+// pageTitle returns the title of a preview page, of a
+// document with the title, if any.
+func pageTitle(title string) string {
+	if len(title) == 0 {
+		return "Hype Preview"
+	}
+
+	return title + " - Hype Preview"
+}
+
+// layout wraps the content in a preview page, with the
+// title, and the navigation of a project, nav, if any.
+func (s *Server) layout(title string, nav string, content string) (string, error) {
 	css, err := s.getCSS()
 	if err != nil {
 		return "", err
 	}
 
+	title = html.EscapeString(pageTitle(title))
+
+	css += overlayCSS
+
+	body := "<body>"
+	if len(nav) > 0 {
+		css += navCSS
+		body = "<body class=\"preview-project\">\n" + nav
+	}
+
 	return fmt.Sprintf(`<!DOCTYPE html>
 <html lang="en">
 <head>
     <meta charset="UTF-8">
     <meta name="viewport" content="width=device-width, initial-scale=1.0">
-    <title>Hype Preview</title>
+    <title>%s</title>
     <style>
 %s
     </style>
 </head>
-<body>
+%s
     <article class="markdown-body">
 %s
     </article>
 <script>
 (function() {
-    var ws = new WebSocket('ws://' + location.host + '/_livereload');
+    var overlay;
+
+    function hideOverlay() {
+        if (overlay) {
+            overlay.remove();
+            overlay = null;
+        }
+    }
+
+    function add(parent, tag, className, text) {
+        var el = document.createElement(tag);
+        el.className = className;
+        el.textContent = text;
+        parent.appendChild(el);
+        return el;
+    }
+
+    function showOverlay(err) {
+        hideOverlay();
+        var titles = {parse: 'Parse error', execute: 'Execute error', cmd: 'Command failed', build: 'Build error'};
+
+        overlay = document.createElement('div');
+        overlay.id = 'hype-error-overlay';
+        var box = add(overlay, 'div', 'hype-error-box', '');
+        var close = add(box, 'button', 'hype-error-close', '\u00d7');
+        close.title = 'Dismiss';
+        close.onclick = hideOverlay;
+
+        add(box, 'h2', 'hype-error-title', titles[err.kind] || titles.build);
+
+        if (err.file) {
+            var loc = err.file;
+            if (err.line) {
+                loc += ':' + err.line + ':' + err.col;
+            }
+            add(box, 'div', 'hype-error-file', loc);
+        }
+
+        add(box, 'pre', 'hype-error-message', err.message);
+
+        if (err.args && err.args.length) {
+            var cmd = '$ ' + err.args.join(' ');
+            if (err.exit) {
+                cmd += '\nexit status ' + err.exit;
+            }
+            add(box, 'pre', 'hype-error-cmd', cmd);
+        }
+
+        if (err.output) {
+            add(box, 'pre', 'hype-error-output', err.output);
+        }
+
+        add(box, 'div', 'hype-error-hint', 'The page updates once the build succeeds.');
+        document.body.appendChild(overlay);
+    }
+
+    // sameShell reports whether a and b are the same node,
+    // but for their children.
+    function sameShell(a, b) {
+        if (a.nodeType !== b.nodeType || a.nodeName !== b.nodeName) {
+            return false;
+        }
+        if (a.nodeType !== Node.ELEMENT_NODE) {
+            return true;
+        }
+        if (a.attributes.length !== b.attributes.length) {
+            return false;
+        }
+        for (var i = 0; i < b.attributes.length; i++) {
+            if (a.getAttribute(b.attributes[i].name) !== b.attributes[i].value) {
+                return false;
+            }
+        }
+        return true;
+    }
+
+    // patch makes the children of cur those of next, keeping
+    // the nodes that did not change. Children are matched by
+    // id, or else by position. The nodes that changed are
+    // added to changed.
+    function patch(cur, next, changed) {
+        var olds = Array.prototype.slice.call(cur.childNodes);
+        var news = Array.prototype.slice.call(next.childNodes);
+        var ids = {};
+        var used = new Set();
+        var j = 0;
+
+        olds.forEach(function(n) {
+            if (n.id) {
+                ids[n.id] = n;
+            }
+        });
+
+        news.forEach(function(n, i) {
+            var old = n.id ? ids[n.id] : null;
+            if (!old) {
+                while (j < olds.length && used.has(olds[j])) {
+                    j++;
+                }
+                old = olds[j++];
+            }
+
+            var ref = cur.childNodes[i] || null;
+
+            if (old && !used.has(old) && sameShell(old, n)) {
+                used.add(old);
+                if (old.nodeType === Node.ELEMENT_NODE) {
+                    patch(old, n, changed);
+                } else if (old.nodeValue !== n.nodeValue) {
+                    old.nodeValue = n.nodeValue;
+                    changed.add(cur);
+                }
+                if (old !== ref) {
+                    cur.insertBefore(old, ref);
+                }
+                return;
+            }
+
+            cur.insertBefore(n, ref);
+            changed.add(n.nodeType === Node.ELEMENT_NODE ? n : cur);
+        });
+
+        olds.forEach(function(n) {
+            if (!used.has(n) && n.parentNode === cur) {
+                cur.removeChild(n);
+                changed.add(cur);
+            }
+        });
+    }
+
+    // anchor returns the first element with an id in view,
+    // and where it is, to keep it in place after an update.
+    function anchor(root) {
+        var els = root.querySelectorAll('[id]');
+        for (var i = 0; i < els.length; i++) {
+            var top = els[i].getBoundingClientRect().top;
+            if (top >= 0) {
+                return {id: els[i].id, top: top};
+            }
+        }
+        return null;
+    }
+
+    function update(msg) {
+        var article = document.querySelector('article.markdown-body');
+        if (!article) {
+            location.reload();
+            return;
+        }
+
+        var at = anchor(article);
+        var changed = new Set();
+
+        var next = document.createElement('article');
+        next.innerHTML = msg.html;
+        patch(article, next, changed);
+
+        var nav = document.querySelector('.preview-nav');
+        if (nav && msg.nav) {
+            var wrap = document.createElement('div');
+            wrap.innerHTML = msg.nav;
+            patch(nav, wrap.firstElementChild, changed);
+        }
+
+        document.title = msg.title;
+
+        if (at) {
+            var el = document.getElementById(at.id);
+            if (el) {
+                window.scrollBy(0, el.getBoundingClientRect().top - at.top);
+            }
+        }
+
+        // scripts of the page, such as a syntax highlighter,
+        // can redo their work on only the changed elements
+        document.dispatchEvent(new CustomEvent('hype:update', {detail: {changed: Array.from(changed)}}));
+    }
+
+    var ws = new WebSocket('ws://' + location.host + '/_livereload?path=' + encodeURIComponent(location.pathname));
     ws.onmessage = function(e) {
-        if (e.data === 'reload') {
+        var msg = JSON.parse(e.data);
+        switch (msg.type) {
+        case 'reload':
             location.reload();
+            break;
+        case 'update':
+            document.documentElement.classList.remove('hype-building');
+            hideOverlay();
+            update(msg);
+            break;
+        case 'building':
+            document.documentElement.classList.add('hype-building');
+            break;
+        case 'error':
+            document.documentElement.classList.remove('hype-building');
+            showOverlay(msg.error);
+            break;
         }
     };
     ws.onclose = function() {
@@ -508,7 +896,7 @@ func (s *Server) wrapHTML(content string) (string, error) {
 })();
 </script>
 </body>
-</html>`, css, content), nil
+</html>`, title, css, body, content), nil
 }
 
 func (s *Server) getCSS() (string, error) {
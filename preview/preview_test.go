@@ -46,6 +46,8 @@ func TestServer_wrapHTML(t *testing.T) {
 	r.Contains(html, content)
 	r.Contains(html, "/_livereload")
 	r.Contains(html, "WebSocket")
+	r.Contains(html, "hype-error-overlay")
+	r.Contains(html, "hype:update")
 }
 
 func TestServer_wrapHTML_DifferentTheme(t *testing.T) {
@@ -254,6 +256,51 @@ func TestServer_Build(t *testing.T) {
 	r.Contains(srv.currentHTML, "Hello World")
 	r.Contains(srv.currentHTML, "This is a test")
 	r.Contains(srv.currentHTML, "<!DOCTYPE html>")
+
+	// every path shows the document
+	msg := srv.update("/any/path")
+	r.Equal(MessageUpdate, msg.Type)
+	r.Equal("Hype Preview", msg.Title)
+	r.Contains(msg.HTML, "Hello World")
+	r.NotContains(msg.HTML, "<!DOCTYPE html>")
+	r.Empty(msg.Nav)
+}
+
+func TestServer_needsRebuild(t *testing.T) {
+	r := require.New(t)
+
+	tmpDir := t.TempDir()
+	r.NoError(os.WriteFile(filepath.Join(tmpDir, "test.md"), []byte("# Hello\n\n<include src=\"part.md\"></include>"), 0644))
+	r.NoError(os.WriteFile(filepath.Join(tmpDir, "part.md"), []byte("# Part"), 0644))
+	r.NoError(os.WriteFile(filepath.Join(tmpDir, "notes.md"), []byte("# Notes"), 0644))
+
+	cfg := DefaultConfig()
+	cfg.File = "test.md"
+
+	srv := New(cfg, nil)
+
+	// unknown dependencies always rebuild
+	r.True(srv.needsRebuild([]string{filepath.Join(tmpDir, "notes.md")}))
+
+	r.NoError(srv.build(context.Background(), tmpDir))
+
+	r.True(srv.needsRebuild([]string{filepath.Join(tmpDir, "test.md")}))
+	r.True(srv.needsRebuild([]string{filepath.Join(tmpDir, "part.md")}))
+	r.False(srv.needsRebuild([]string{filepath.Join(tmpDir, "notes.md")}))
+	r.True(srv.needsRebuild([]string{filepath.Join(filepath.Dir(tmpDir), "other.md")}))
+}
+
+func TestServer_cssChanged(t *testing.T) {
+	r := require.New(t)
+
+	cfg := DefaultConfig()
+	srv := New(cfg, nil)
+
+	r.False(srv.cssChanged([]string{"/project/custom.css"}))
+
+	srv.config.CustomCSS = "/project/custom.css"
+	r.True(srv.cssChanged([]string{"/project/hype.md", "/project/./custom.css"}))
+	r.False(srv.cssChanged([]string{"/project/hype.md"}))
 }
 
 func TestServer_SetOutput(t *testing.T) {
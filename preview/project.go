@@ -0,0 +1,307 @@
+package preview
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/gobuffalo/flect"
+	"github.com/gopherguides/hype"
+)
+
+// navDepth is the deepest heading level listed in the
+// navigation of a project.
+const navDepth = 3
+
+// page is a document of a previewed project.
+type page struct {
+	doc   *hype.Document
+	file  string // path of the document, relative to the project
+	route string // URL path of the page, such as /01-intro/
+}
+
+// href returns the escaped URL of the page, with the
+// fragment frag, if any.
+func (pg *page) href(frag string) string {
+	u := url.URL{Path: pg.route, Fragment: frag}
+	return u.String()
+}
+
+// title returns the title of the page, numbered by the
+// section of the document, if it has one.
+func (pg *page) title() string {
+	title := pg.doc.Title
+	if len(title) == 0 {
+		title = pg.file
+	}
+
+	if pg.doc.SectionID > 0 {
+		return fmt.Sprintf("%d. %s", pg.doc.SectionID, title)
+	}
+
+	return title
+}
+
+// buildProject builds every hype.md in the directory root,
+// as ParseFolder finds them, into a page of its own. The page
+// of a hype.md in root itself is served at /, otherwise an
+// index of the pages is.
+func (s *Server) buildProject(ctx context.Context, root string) error {
+	p := s.parser
+	if p == nil {
+		p = hype.NewParser(os.DirFS(root))
+		p.MermaidRender = hype.MermaidSVG
+	} else {
+		p.FS = os.DirFS(root)
+	}
+	p.Root = root
+
+	s.deps = nil
+
+	docs, err := p.ParseFolder(".")
+	if err != nil {
+		return fmt.Errorf("parse error: %w", err)
+	}
+
+	if len(docs) == 0 {
+		return fmt.Errorf("no hype.md files found in %s", root)
+	}
+
+	deps := hype.NewDepGraph()
+	pages := make([]*page, 0, len(docs))
+	files := make([]string, 0, len(docs))
+
+	for _, doc := range docs {
+		dir, err := filepath.Rel(root, doc.Root)
+		if err != nil {
+			return err
+		}
+		dir = filepath.ToSlash(dir)
+
+		g, err := doc.Deps()
+		if err != nil {
+			return fmt.Errorf("dependency error: %w", err)
+		}
+		deps.Merge(dir, g)
+
+		pg := &page{
+			doc:   doc,
+			file:  path.Join(dir, doc.Filename),
+			route: "/",
+		}
+
+		if dir != "." {
+			pg.route = "/" + dir + "/"
+		}
+
+		pages = append(pages, pg)
+		files = append(files, pg.file)
+	}
+
+	execCtx := ctx
+	if s.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		execCtx, cancel = context.WithTimeout(ctx, s.config.Timeout)
+		defer cancel()
+	}
+
+	if err := docs.Execute(execCtx); err != nil {
+		return fmt.Errorf("execute error: %w", err)
+	}
+
+	for _, pg := range pages {
+		if err := rewriteLinks(pg, pages); err != nil {
+			return fmt.Errorf("%s: %w", pg.file, err)
+		}
+	}
+
+	title := flect.Titleize(filepath.Base(root))
+
+	built := make(map[string]string, len(pages)+1)
+	updates := make(map[string]Message, len(pages)+1)
+
+	render := func(route string, title string, nav string, content string) error {
+		out, err := s.layout(title, nav, content)
+		if err != nil {
+			return fmt.Errorf("wrap HTML error: %w", err)
+		}
+
+		built[route] = out
+		updates[route] = Message{Type: MessageUpdate, Title: pageTitle(title), HTML: content, Nav: nav}
+		return nil
+	}
+
+	for _, pg := range pages {
+		nav, err := projectNav(title, pages, pg)
+		if err != nil {
+			return fmt.Errorf("%s: %w", pg.file, err)
+		}
+
+		if err := render(pg.route, pg.doc.Title, nav, pg.doc.String()); err != nil {
+			return err
+		}
+	}
+
+	if _, ok := built["/"]; !ok {
+		nav, err := projectNav(title, pages, nil)
+		if err != nil {
+			return err
+		}
+
+		if err := render("/", title, nav, projectIndex(title, pages)); err != nil {
+			return err
+		}
+	}
+
+	s.currentHTML = built["/"]
+	s.pages = built
+	s.updates = updates
+	s.deps = deps
+	s.depsRoot = root
+	s.depsFiles = files
+	return nil
+}
+
+// rewriteLinks rewrites the relative links of the page to
+// other documents of the project, to the routes of their
+// pages. A link to a document may be to its hype.md, to the
+// hype.html it is exported as, or to its directory.
+func rewriteLinks(pg *page, pages []*page) error {
+	routes := make(map[string]*page, len(pages))
+	for _, p := range pages {
+		routes[p.file] = p
+	}
+
+	dir := path.Dir(pg.file)
+
+	for _, l := range hype.ByType[*hype.Link](pg.doc.Children()) {
+		href, ok := l.Get("href")
+		if !ok {
+			continue
+		}
+
+		u, err := url.Parse(href)
+		if err != nil || len(u.Scheme) > 0 || len(u.Host) > 0 {
+			continue
+		}
+
+		if len(u.Path) == 0 || strings.HasPrefix(u.Path, "/") {
+			continue
+		}
+
+		target := path.Join(dir, u.Path)
+
+		candidates := []string{
+			target,
+			strings.TrimSuffix(target, ".html") + ".md",
+			path.Join(target, "hype.md"),
+		}
+
+		for _, c := range candidates {
+			to, ok := routes[c]
+			if !ok {
+				continue
+			}
+
+			if err := l.Set("href", to.href(u.Fragment)); err != nil {
+				return err
+			}
+			break
+		}
+	}
+
+	return nil
+}
+
+// projectNav returns the navigation of a project: a link to
+// each page, and the table of contents of the current page,
+// cur. If cur is nil, the index is the current page.
+func projectNav(title string, pages []*page, cur *page) (string, error) {
+	bb := &bytes.Buffer{}
+
+	fmt.Fprintf(bb, "<nav class=\"preview-nav\">\n<a class=\"preview-home\" href=\"/\">%s</a>\n<ol>\n", html.EscapeString(title))
+
+	for _, pg := range pages {
+		if pg != cur {
+			fmt.Fprintf(bb, "<li><a href=\"%s\">%s</a></li>\n", pg.href(""), html.EscapeString(pg.title()))
+			continue
+		}
+
+		fmt.Fprintf(bb, "<li><a href=\"%s\" aria-current=\"page\">%s</a>", pg.href(""), html.EscapeString(pg.title()))
+
+		toc, err := pageToC(pg)
+		if err != nil {
+			return "", err
+		}
+
+		bb.WriteString(toc)
+		bb.WriteString("</li>\n")
+	}
+
+	bb.WriteString("</ol>\n</nav>")
+
+	return bb.String(), nil
+}
+
+// pageToC returns the table of contents of the page, of the
+// headings below the title, to navDepth. Headings without an
+// id are given one, so they can be linked to.
+func pageToC(pg *page) (string, error) {
+	headings := hype.ByType[*hype.Heading](pg.doc.Children())
+
+	seen := map[string]int{}
+	for _, h := range headings {
+		if id, ok := h.Get("id"); ok && len(id) > 0 {
+			seen[id] = 1
+		}
+	}
+
+	var filtered []*hype.Heading
+	var slugs []string
+
+	for _, h := range headings {
+		id, ok := h.Get("id")
+		if !ok || len(id) == 0 {
+			id = hype.UniqueSlug(h.Children().String(), seen)
+			if err := h.Set("id", id); err != nil {
+				return "", err
+			}
+		}
+
+		if h.Level() == 1 || h.Level() > navDepth {
+			continue
+		}
+
+		filtered = append(filtered, h)
+		slugs = append(slugs, id)
+	}
+
+	nodes, err := hype.GenerateToC(pg.doc.Parser, filtered, slugs)
+	if err != nil {
+		return "", err
+	}
+
+	return nodes.String(), nil
+}
+
+// projectIndex returns the index of a project, without a
+// hype.md of its own.
+func projectIndex(title string, pages []*page) string {
+	bb := &bytes.Buffer{}
+
+	fmt.Fprintf(bb, "<h1>%s</h1>\n<ol class=\"preview-index\">\n", html.EscapeString(title))
+
+	for _, pg := range pages {
+		fmt.Fprintf(bb, "<li><a href=\"%s\">%s</a></li>\n", pg.href(""), html.EscapeString(pg.title()))
+	}
+
+	bb.WriteString("</ol>")
+
+	return bb.String()
+}
@@ -0,0 +1,154 @@
+package preview
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// writeProject writes a project of two chapters, that
+// link to each other, to dir.
+func writeProject(t testing.TB, dir string) {
+	t.Helper()
+
+	files := map[string]string{
+		"01-intro/hype.md":   "# Intro\n\nRead [the setup](../02-usage/hype.md#setup), or [usage](../02-usage/).\n\n## Goals\n\n![logo](logo.png)\n\nSee [Go](https://go.dev).\n",
+		"01-intro/logo.png":  "PNG content",
+		"02-usage/hype.md":   "# Usage\n\n## Setup\n\nBack to the [intro](../01-intro/hype.html).\n\n### Install\n\n#### Details\n",
+		"02-usage/notes.txt": "notes",
+	}
+
+	for name, body := range files {
+		fp := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(fp), 0755))
+		require.NoError(t, os.WriteFile(fp, []byte(body), 0644))
+	}
+}
+
+func testProjectServer(t testing.TB) (*Server, string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	writeProject(t, dir)
+
+	cfg := DefaultConfig()
+	cfg.File = "."
+
+	srv := New(cfg, nil)
+	srv.pwd = dir
+
+	require.NoError(t, srv.build(context.Background(), dir))
+
+	return srv, dir
+}
+
+func TestServer_Build_Project(t *testing.T) {
+	r := require.New(t)
+
+	srv, _ := testProjectServer(t)
+
+	r.Len(srv.pages, 3)
+	r.Contains(srv.pages, "/")
+	r.Contains(srv.pages, "/01-intro/")
+	r.Contains(srv.pages, "/02-usage/")
+
+	index := srv.pages["/"]
+	r.Equal(index, srv.currentHTML)
+	r.Contains(index, `<ol class="preview-index">`)
+	r.Contains(index, `<a href="/01-intro/">1. Intro</a>`)
+	r.Contains(index, `<a href="/02-usage/">2. Usage</a>`)
+
+	intro := srv.pages["/01-intro/"]
+	r.Contains(intro, "<title>Intro - Hype Preview</title>")
+	r.Contains(intro, `<body class="preview-project">`)
+	r.Contains(intro, `<a href="/01-intro/" aria-current="page">1. Intro</a>`)
+	r.Contains(intro, `<a href="#goals">Goals</a>`)
+	r.Contains(intro, `<h2 id="goals">Goals</h2>`)
+	r.Contains(intro, `href="/02-usage/#setup"`)
+	r.Contains(intro, `href="/02-usage/"`)
+	r.Contains(intro, `href="https://go.dev"`)
+	r.Contains(intro, `src="logo.png"`)
+
+	usage := srv.pages["/02-usage/"]
+	r.Contains(usage, `href="/01-intro/"`)
+	r.Contains(usage, `<a href="#install">Install</a>`)
+	r.NotContains(usage, `<a href="#details">`)
+	r.Contains(usage, `<h4 id="details">Details</h4>`)
+
+	msg := srv.update("/02-usage/")
+	r.Equal(MessageUpdate, msg.Type)
+	r.Equal("Usage - Hype Preview", msg.Title)
+	r.Contains(msg.HTML, `<h2 id="setup">Setup</h2>`)
+	r.Contains(msg.Nav, `<a href="/02-usage/" aria-current="page">2. Usage</a>`)
+
+	r.Equal(MessageUpdate, srv.update("/").Type)
+
+	// pages not in the project reload
+	r.Equal(Message{Type: MessageReload}, srv.update("/03-missing/"))
+}
+
+func TestServer_Build_Project_Empty(t *testing.T) {
+	r := require.New(t)
+
+	dir := t.TempDir()
+
+	cfg := DefaultConfig()
+	cfg.File = dir
+
+	srv := New(cfg, nil)
+
+	err := srv.build(context.Background(), dir)
+	r.Error(err)
+	r.Contains(err.Error(), "no hype.md files found")
+}
+
+func TestServer_handleRequest_Project(t *testing.T) {
+	srv, _ := testProjectServer(t)
+
+	tests := []struct {
+		name     string
+		path     string
+		status   int
+		contains string
+	}{
+		{name: "index", path: "/", status: http.StatusOK, contains: "preview-index"},
+		{name: "page", path: "/02-usage/", status: http.StatusOK, contains: "<h2 id=\"setup\">Setup</h2>"},
+		{name: "redirect", path: "/02-usage", status: http.StatusMovedPermanently},
+		{name: "static", path: "/01-intro/logo.png", status: http.StatusOK, contains: "PNG content"},
+		{name: "not found", path: "/03-missing/", status: http.StatusNotFound},
+		{name: "traversal", path: "/../../etc/passwd", status: http.StatusForbidden},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := require.New(t)
+
+			req := httptest.NewRequest("GET", tc.path, nil)
+			w := httptest.NewRecorder()
+
+			srv.handleRequest(w, req)
+
+			resp := w.Result()
+			body, _ := io.ReadAll(resp.Body)
+
+			r.Equal(tc.status, resp.StatusCode)
+			r.Contains(string(body), tc.contains)
+		})
+	}
+}
+
+func TestServer_needsRebuild_Project(t *testing.T) {
+	r := require.New(t)
+
+	srv, dir := testProjectServer(t)
+
+	r.True(srv.needsRebuild([]string{filepath.Join(dir, "02-usage", "hype.md")}))
+	r.True(srv.needsRebuild([]string{filepath.Join(dir, "03-new", "hype.md")}))
+	r.False(srv.needsRebuild([]string{filepath.Join(dir, "02-usage", "notes.txt")}))
+}
@@ -12,6 +12,8 @@ import (
 type Ref struct {
 	*Element
 	*Figure
+
+	external bool // the figure is in another document, or an include
 }
 
 func (r *Ref) MarshalJSON() ([]byte, error) {
@@ -54,6 +56,12 @@ func (r *Ref) PostExecute(ctx context.Context, doc *Document, err error) error {
 	}
 
 	if r.Figure == nil {
+		// the figure may be in another document
+		// of the book, see Documents.ResolveRefs
+		if doc != nil && doc.inBook() {
+			return nil
+		}
+
 		return fmt.Errorf("%w: %s", ErrIsNil("figure"), r.StartTag())
 	}
 
@@ -0,0 +1,313 @@
+package hype
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/markbates/clam"
+)
+
+// DefaultAllowedEnv is the list of host environment
+// variables that are passed through to commands run
+// by a RestrictedRunner when RestrictedRunner.AllowEnv is nil.
+var DefaultAllowedEnv = []string{
+	"GOCACHE",
+	"GOMODCACHE",
+	"GOPATH",
+	"GOROOT",
+	"LANG",
+	"PATH",
+}
+
+// DefaultRestrictedMaxSize is the size, in bytes, of the largest
+// working directory a RestrictedRunner copies, when
+// RestrictedRunner.MaxSize is 0.
+const DefaultRestrictedMaxSize = 100 << 20
+
+var _ Runner = &RestrictedRunner{}
+
+// RestrictedRunner runs commands in a restricted environment.
+//
+//   - the command runs in a scratch copy of its working directory,
+//     which is removed afterwards. The working directory, the
+//     `src` of the tag, or the root of the document, is required,
+//     and is not copied if it is larger than MaxSize.
+//   - the command runs in its own user, and mount, namespaces, in
+//     which every file system is read-only, except for the scratch
+//     directory, the GOCACHE passed through to it, if any, and the
+//     directories listed in Writable.
+//   - only the environment variables listed in AllowEnv are
+//     passed through from the host, plus any variables set
+//     by the tag itself (for example with `environ`). HOME is
+//     set to the scratch directory, and TMPDIR to a directory
+//     next to it.
+//   - the command is found in the PATH of that environment,
+//     not in the PATH of the host.
+//   - unless Network is true, the command runs in its own
+//     network namespace with no network access.
+//
+// The namespaces require Linux, with unprivileged user
+// namespaces; on other platforms, Run returns an error.
+// The command is started by re-executing the current program,
+// which sets up the namespaces before any main function runs.
+type RestrictedRunner struct {
+	AllowEnv []string // host environment variables to pass through; default: DefaultAllowedEnv
+	MaxSize  int64    // largest working directory, in bytes, to copy; default: DefaultRestrictedMaxSize
+	Network  bool     // allow network access; default: false
+	TempDir  string   // directory to create scratch copies in; default: os.TempDir()
+	Writable []string // host directories the command may write to; default: none
+}
+
+func (rr *RestrictedRunner) Run(ctx context.Context, cmd *clam.Cmd, args ...string) (*clam.Result, error) {
+	if rr == nil {
+		return nil, ErrIsNil("restricted runner")
+	}
+
+	if cmd == nil {
+		return nil, ErrIsNil("cmd")
+	}
+
+	if len(args) == 0 {
+		return nil, fmt.Errorf("no command specified")
+	}
+
+	// the working directory of the process is never
+	// copied, as it may be far larger than the document
+	src := cmd.Dir
+	if len(src) == 0 {
+		return nil, fmt.Errorf("restricted runner: working directory is not set")
+	}
+
+	max := rr.MaxSize
+	if max <= 0 {
+		max = DefaultRestrictedMaxSize
+	}
+
+	if err := checkDirSize(src, max); err != nil {
+		return nil, fmt.Errorf("restricted runner: %w", err)
+	}
+
+	base, err := os.MkdirTemp(rr.TempDir, "hype-run-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(base)
+
+	scratch := filepath.Join(base, "work")
+	tmp := filepath.Join(base, "tmp")
+
+	if err := os.Mkdir(tmp, 0700); err != nil {
+		return nil, err
+	}
+
+	if err := copyDir(scratch, src); err != nil {
+		return nil, fmt.Errorf("could not copy %q to scratch directory: %w", src, err)
+	}
+
+	env := rr.env(scratch, tmp, cmd.Env)
+
+	name, err := lookPath(args[0], env)
+	if err != nil {
+		return nil, err
+	}
+
+	writable := append([]string{base}, rr.Writable...)
+	for _, e := range env {
+		if v, ok := strings.CutPrefix(e, "GOCACHE="); ok && len(v) > 0 {
+			writable = append(writable, v)
+		}
+	}
+
+	c, err := restrictCommand(ctx, name, args, env, writable, rr.Network)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Dir = scratch
+
+	if cmd.Stdin != nil {
+		c.Stdin = cmd.Stdin
+	}
+
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	c.Stdout = stdout
+	c.Stderr = stderr
+
+	res := &clam.Result{
+		Args: args,
+		Dir:  scratch,
+		Env:  env,
+		Exit: -1,
+	}
+
+	now := time.Now()
+	res.Err = c.Run()
+	res.Duration = time.Since(now)
+
+	if c.ProcessState != nil {
+		res.Exit = c.ProcessState.ExitCode()
+	}
+
+	res.Stdout = bytes.TrimSpace(stdout.Bytes())
+	res.Stderr = bytes.TrimSpace(stderr.Bytes())
+
+	if res.Err == nil {
+		return res, nil
+	}
+
+	return res, clam.RunError{
+		Err:    res.Err,
+		Args:   res.Args,
+		Dir:    res.Dir,
+		Env:    res.Env,
+		Exit:   res.Exit,
+		Output: append(res.Stdout, res.Stderr...),
+	}
+}
+
+func (rr *RestrictedRunner) env(home string, tmp string, extra []string) []string {
+	allow := rr.AllowEnv
+	if allow == nil {
+		allow = DefaultAllowedEnv
+	}
+
+	env := []string{"GOWORK=off", "HOME=" + home, "TMPDIR=" + tmp}
+
+	for _, k := range allow {
+		// the temporary directory of the host is read-only
+		if k == "TMPDIR" {
+			continue
+		}
+
+		if v, ok := os.LookupEnv(k); ok {
+			env = append(env, fmt.Sprintf("%s=%s", k, v))
+		}
+	}
+
+	for _, e := range extra {
+		e = strings.TrimSpace(e)
+		if len(e) == 0 {
+			continue
+		}
+		env = append(env, e)
+	}
+
+	return env
+}
+
+// lookPath finds the executable name in the PATH of env.
+// A name with a path separator is used as is.
+func lookPath(name string, env []string) (string, error) {
+	if strings.ContainsRune(name, '/') || strings.ContainsRune(name, filepath.Separator) {
+		return name, nil
+	}
+
+	var path string
+	for _, e := range env {
+		if v, ok := strings.CutPrefix(e, "PATH="); ok {
+			path = v
+		}
+	}
+
+	for _, dir := range filepath.SplitList(path) {
+		if len(dir) == 0 {
+			continue
+		}
+
+		fp := filepath.Join(dir, name)
+
+		info, err := os.Stat(fp)
+		if err != nil || info.IsDir() || info.Mode().Perm()&0111 == 0 {
+			continue
+		}
+
+		return fp, nil
+	}
+
+	return "", fmt.Errorf("restricted runner: %q not found in PATH %q", name, path)
+}
+
+// checkDirSize returns an error if the regular files under
+// dir are larger, in total, than max bytes.
+func checkDirSize(dir string, max int64) error {
+	var size int64
+
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		size += info.Size()
+		if size > max {
+			return fmt.Errorf("%q is larger than %d bytes", dir, max)
+		}
+
+		return nil
+	})
+}
+
+// copyDir copies the regular files and directories
+// under src into dst. Symlinks and other special files
+// are not copied.
+func copyDir(dst string, src string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+
+		target := filepath.Join(dst, rel)
+
+		if d.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		in, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+
+		out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+		if err != nil {
+			return err
+		}
+
+		if _, err := io.Copy(out, in); err != nil {
+			out.Close()
+			return err
+		}
+
+		return out.Close()
+	})
+}
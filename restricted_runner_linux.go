@@ -0,0 +1,256 @@
+//go:build linux
+
+package hype
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+const (
+	// restrictedExecEnv is set, in the environment of the
+	// re-executed program, to the command it runs once the
+	// namespaces of the RestrictedRunner are set up.
+	restrictedExecEnv = "HYPE_RESTRICTED_EXEC"
+
+	// restrictedWritableEnv is the list of directories that
+	// the re-executed program leaves writable.
+	restrictedWritableEnv = "HYPE_RESTRICTED_WRITABLE"
+)
+
+// stRDOnly is the statfs flag of a read-only mount.
+const stRDOnly = 0x1
+
+// lockedMountFlags are the statfs flags, and their mount
+// flags, that are locked on the mounts of a user namespace,
+// and must be kept when the mounts are remounted read-only.
+var lockedMountFlags = []struct {
+	st int64
+	ms uintptr
+}{
+	{st: 0x2, ms: syscall.MS_NOSUID},
+	{st: 0x4, ms: syscall.MS_NODEV},
+	{st: 0x8, ms: syscall.MS_NOEXEC},
+	{st: 0x400, ms: syscall.MS_NOATIME},
+	{st: 0x800, ms: syscall.MS_NODIRATIME},
+	{st: 0x1000, ms: syscall.MS_RELATIME},
+}
+
+func init() {
+	name, ok := os.LookupEnv(restrictedExecEnv)
+	if !ok {
+		return
+	}
+
+	if err := restrictedExec(name); err != nil {
+		fmt.Fprintf(os.Stderr, "restricted runner: %s\n", err)
+		os.Exit(126)
+	}
+}
+
+// restrictCommand returns the command that runs name, with
+// args, and env, in new user, and mount, namespaces, and,
+// unless network access is allowed, a new network namespace,
+// leaving it with only a loopback interface.
+//
+// Mounts can only be changed from inside of the namespace,
+// so the current program is run, in the namespaces, and, in
+// its init, before main, it makes every file system read-only,
+// except for the writable directories, and runs the command.
+func restrictCommand(ctx context.Context, name string, args []string, env []string, writable []string, network bool) (*exec.Cmd, error) {
+	flags := syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS
+	if !network {
+		flags |= syscall.CLONE_NEWNET
+	}
+
+	c := exec.CommandContext(ctx, "/proc/self/exe")
+	c.Args = args
+	c.Env = append(env,
+		restrictedExecEnv+"="+name,
+		restrictedWritableEnv+"="+strings.Join(writable, string(os.PathListSeparator)),
+	)
+	c.SysProcAttr = &syscall.SysProcAttr{
+		Cloneflags: uintptr(flags),
+		UidMappings: []syscall.SysProcIDMap{
+			{ContainerID: os.Getuid(), HostID: os.Getuid(), Size: 1},
+		},
+		GidMappings: []syscall.SysProcIDMap{
+			{ContainerID: os.Getgid(), HostID: os.Getgid(), Size: 1},
+		},
+	}
+
+	return c, nil
+}
+
+// restrictedExec makes every file system read-only, except
+// for the writable directories, and replaces the program
+// with the command name.
+func restrictedExec(name string) error {
+	writable := filepath.SplitList(os.Getenv(restrictedWritableEnv))
+
+	env := make([]string, 0, len(os.Environ()))
+	for _, e := range os.Environ() {
+		if strings.HasPrefix(e, restrictedExecEnv+"=") || strings.HasPrefix(e, restrictedWritableEnv+"=") {
+			continue
+		}
+		env = append(env, e)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+
+	// keep the mounts from propagating to the host
+	if err := syscall.Mount("", "/", "", syscall.MS_REC|syscall.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("private mounts: %w", err)
+	}
+
+	// a writable directory is bound to itself, so it is a
+	// mount of its own that stays writable
+	var keep []string
+	for _, dir := range writable {
+		if len(dir) == 0 {
+			continue
+		}
+
+		// mountinfo has the real paths of the mounts
+		dir, err := filepath.EvalSymlinks(dir)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if err := syscall.Mount(dir, dir, "", syscall.MS_BIND|syscall.MS_REC, ""); err != nil {
+			return fmt.Errorf("bind %q: %w", dir, err)
+		}
+
+		keep = append(keep, dir)
+	}
+
+	mounts, err := mountPoints()
+	if err != nil {
+		return err
+	}
+
+	for _, mp := range mounts {
+		if isWithinAny(keep, mp) {
+			continue
+		}
+
+		if err := remountReadOnly(mp); err != nil {
+			return err
+		}
+	}
+
+	// the working directory is still on the mount it was
+	// on before the writable directories were bound
+	if err := os.Chdir(wd); err != nil {
+		return err
+	}
+
+	return syscall.Exec(name, os.Args, env)
+}
+
+// remountReadOnly remounts the mount at mp read-only,
+// keeping the flags that are locked in a user namespace.
+func remountReadOnly(mp string) error {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(mp, &st); err != nil {
+		// a mount that can not be reached, such as one that
+		// is hidden by another mount, can not be written to
+		return nil
+	}
+
+	if int64(st.Flags)&stRDOnly != 0 {
+		return nil
+	}
+
+	flags := uintptr(syscall.MS_REMOUNT | syscall.MS_BIND | syscall.MS_RDONLY)
+
+	for _, f := range lockedMountFlags {
+		if int64(st.Flags)&f.st != 0 {
+			flags |= f.ms
+		}
+	}
+
+	if err := syscall.Mount("", mp, "", flags, ""); err != nil {
+		return fmt.Errorf("remount %q read-only: %w", mp, err)
+	}
+
+	return nil
+}
+
+// mountPoints returns the mount points of the
+// mount namespace, from /proc/self/mountinfo.
+func mountPoints() ([]string, error) {
+	f, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var mounts []string
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) < 5 {
+			continue
+		}
+
+		mounts = append(mounts, unescapeMountPoint(fields[4]))
+	}
+
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	return mounts, nil
+}
+
+// unescapeMountPoint replaces the octal escapes, such
+// as \040 for a space, of a path in mountinfo.
+func unescapeMountPoint(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) {
+			if n, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				sb.WriteByte(byte(n))
+				i += 3
+				continue
+			}
+		}
+		sb.WriteByte(s[i])
+	}
+
+	return sb.String()
+}
+
+// isWithinAny returns true if path is, or is inside
+// of, any of dirs.
+func isWithinAny(dirs []string, path string) bool {
+	for _, dir := range dirs {
+		if isWithin(dir, path) {
+			return true
+		}
+	}
+
+	return false
+}
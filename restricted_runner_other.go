@@ -0,0 +1,17 @@
+//go:build !linux
+
+package hype
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"runtime"
+)
+
+// restrictCommand returns the command run by the
+// RestrictedRunner. Its namespaces require Linux,
+// so on other platforms it returns an error.
+func restrictCommand(ctx context.Context, name string, args []string, env []string, writable []string, network bool) (*exec.Cmd, error) {
+	return nil, fmt.Errorf("restricted runner: isolation is not supported on %s", runtime.GOOS)
+}
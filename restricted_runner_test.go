@@ -0,0 +1,209 @@
+package hype
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/markbates/clam"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_RestrictedRunner_Env(t *testing.T) {
+	t.Setenv("HYPE_TEST_SECRET", "s3cret")
+
+	r := require.New(t)
+
+	rr := &RestrictedRunner{
+		AllowEnv: []string{"PATH"},
+		Network:  true,
+	}
+
+	cmd := &clam.Cmd{
+		Dir: t.TempDir(),
+		Env: []string{"GREETING=hello"},
+	}
+
+	res, err := rr.Run(context.Background(), cmd, "env")
+	skipRestricted(t, res, err)
+	r.NoError(err)
+
+	out := string(res.Stdout)
+	r.Contains(out, "GREETING=hello")
+	r.Contains(out, "PATH=")
+	r.NotContains(out, "HYPE_TEST_SECRET")
+	r.Contains(out, "HOME="+res.Dir+"\n")
+	r.NotContains(out, "HYPE_RESTRICTED")
+}
+
+// skipRestricted skips the test if the namespaces
+// of the RestrictedRunner are not available.
+func skipRestricted(t testing.TB, res *clam.Result, err error) {
+	t.Helper()
+
+	if runtime.GOOS != "linux" {
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "not supported")
+		t.Skipf("the restricted runner is not supported on %s", runtime.GOOS)
+	}
+
+	if err != nil && (res == nil || res.Exit == -1) {
+		// user namespaces may be disabled, for example inside of some containers
+		t.Skipf("user namespaces are not available: %s", err)
+	}
+}
+
+func Test_RestrictedRunner_LookPath(t *testing.T) {
+	r := require.New(t)
+
+	bin := t.TempDir()
+	fp := filepath.Join(bin, "hype-test-tool")
+	r.NoError(os.WriteFile(fp, []byte("#!/bin/sh\necho tool\n"), 0755))
+
+	// only on the host PATH, not in the allowed environment
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	rr := &RestrictedRunner{
+		AllowEnv: []string{},
+		Network:  true,
+	}
+
+	_, err := rr.Run(context.Background(), &clam.Cmd{Dir: t.TempDir()}, "hype-test-tool")
+	r.Error(err)
+	r.Contains(err.Error(), "not found in PATH")
+
+	rr.AllowEnv = []string{"PATH"}
+
+	res, err := rr.Run(context.Background(), &clam.Cmd{Dir: t.TempDir()}, "hype-test-tool")
+	skipRestricted(t, res, err)
+	r.NoError(err)
+	r.Equal("tool", string(res.Stdout))
+
+	act, err := lookPath("hype-test-tool", []string{"PATH=/nonexistent" + string(os.PathListSeparator) + bin})
+	r.NoError(err)
+	r.Equal(fp, act)
+}
+
+func Test_RestrictedRunner_Scratch(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	dir := t.TempDir()
+	r.NoError(os.WriteFile(filepath.Join(dir, "in.txt"), []byte("hello"), 0644))
+
+	rr := &RestrictedRunner{
+		Network: true,
+	}
+
+	cmd := &clam.Cmd{
+		Dir: dir,
+	}
+
+	res, err := rr.Run(context.Background(), cmd, "sh", "-c", "cat in.txt && echo changed > in.txt && touch out.txt")
+	skipRestricted(t, res, err)
+	r.NoError(err)
+	r.Equal("hello", string(res.Stdout))
+	r.NotEqual(dir, res.Dir)
+
+	b, err := os.ReadFile(filepath.Join(dir, "in.txt"))
+	r.NoError(err)
+	r.Equal("hello", string(b))
+
+	_, err = os.Stat(filepath.Join(dir, "out.txt"))
+	r.True(os.IsNotExist(err))
+
+	// the scratch directory is removed after the run
+	_, err = os.Stat(res.Dir)
+	r.True(os.IsNotExist(err))
+}
+
+func Test_RestrictedRunner_Exit(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	rr := &RestrictedRunner{
+		Network: true,
+	}
+
+	res, err := rr.Run(context.Background(), &clam.Cmd{Dir: t.TempDir()}, "sh", "-c", "echo oops >&2; exit 3")
+	skipRestricted(t, res, err)
+	r.Error(err)
+	r.Equal(3, res.Exit)
+	r.Equal("oops", string(res.Stderr))
+
+	_, ok := err.(clam.RunError)
+	r.True(ok)
+}
+
+func Test_RestrictedRunner_Network(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	rr := &RestrictedRunner{}
+
+	res, err := rr.Run(context.Background(), &clam.Cmd{Dir: t.TempDir()}, "cat", "/proc/net/dev")
+	skipRestricted(t, res, err)
+	r.NoError(err)
+
+	// only the header lines and the loopback interface
+	var ifaces []string
+	for _, l := range strings.Split(string(res.Stdout), "\n")[2:] {
+		name, _, _ := strings.Cut(strings.TrimSpace(l), ":")
+		ifaces = append(ifaces, name)
+	}
+	r.Equal([]string{"lo"}, ifaces)
+}
+
+func Test_RestrictedRunner_ReadOnly(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	outside := t.TempDir()
+	writable := t.TempDir()
+
+	rr := &RestrictedRunner{
+		Network:  true,
+		Writable: []string{writable},
+	}
+
+	script := fmt.Sprintf("touch %s/a; touch %s/b; touch $TMPDIR/c; touch d", outside, writable)
+
+	res, err := rr.Run(context.Background(), &clam.Cmd{Dir: t.TempDir()}, "sh", "-c", script)
+	skipRestricted(t, res, err)
+	r.NoError(err)
+	r.Contains(string(res.Stderr), "Read-only file system")
+
+	// only the file outside of the writable directories
+	r.Equal(1, strings.Count(string(res.Stderr), "touch:"))
+
+	_, err = os.Stat(filepath.Join(outside, "a"))
+	r.True(os.IsNotExist(err))
+
+	_, err = os.Stat(filepath.Join(writable, "b"))
+	r.NoError(err)
+}
+
+func Test_RestrictedRunner_Dir(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	rr := &RestrictedRunner{
+		Network: true,
+		MaxSize: 4,
+	}
+
+	_, err := rr.Run(context.Background(), &clam.Cmd{}, "ls")
+	r.Error(err)
+	r.Contains(err.Error(), "working directory is not set")
+
+	dir := t.TempDir()
+	r.NoError(os.WriteFile(filepath.Join(dir, "big.txt"), []byte("hello"), 0644))
+
+	_, err = rr.Run(context.Background(), &clam.Cmd{Dir: dir}, "ls")
+	r.Error(err)
+	r.Contains(err.Error(), "is larger than 4 bytes")
+}
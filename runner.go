@@ -0,0 +1,98 @@
+package hype
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/markbates/clam"
+)
+
+const (
+	// HostRunnerName is the name of the runner that executes
+	// commands directly on the host. It is used when a <cmd>
+	// does not request a runner and Parser.DefaultRunner is empty.
+	HostRunnerName = "host"
+
+	// RestrictedRunnerName is the name of the built-in
+	// RestrictedRunner.
+	RestrictedRunnerName = "restricted"
+)
+
+// Runner executes the command described by cmd and args.
+// A Runner must return a non-nil *clam.Result whenever
+// the command was started, even if the command failed.
+type Runner interface {
+	Run(ctx context.Context, cmd *clam.Cmd, args ...string) (*clam.Result, error)
+}
+
+// RunnerFn is a function that implements the Runner interface.
+type RunnerFn func(ctx context.Context, cmd *clam.Cmd, args ...string) (*clam.Result, error)
+
+func (fn RunnerFn) Run(ctx context.Context, cmd *clam.Cmd, args ...string) (*clam.Result, error) {
+	return fn(ctx, cmd, args...)
+}
+
+// HostRunner runs commands directly on the host
+// with the full environment of the current process.
+type HostRunner struct{}
+
+func (HostRunner) Run(ctx context.Context, cmd *clam.Cmd, args ...string) (*clam.Result, error) {
+	if cmd == nil {
+		return nil, ErrIsNil("cmd")
+	}
+
+	return cmd.Run(ctx, args...)
+}
+
+// DefaultRunners returns the built-in runners:
+// HostRunnerName and RestrictedRunnerName.
+func DefaultRunners() map[string]Runner {
+	return map[string]Runner{
+		HostRunnerName:       HostRunner{},
+		RestrictedRunnerName: &RestrictedRunner{},
+	}
+}
+
+// ErrRunnerNotFound is returned when a <cmd> requests
+// a runner that has not been configured on the Parser.
+type ErrRunnerNotFound string
+
+func (e ErrRunnerNotFound) Error() string {
+	return fmt.Sprintf("runner not configured: %q", string(e))
+}
+
+// Runner returns the runner registered under name.
+// If name is empty, Parser.DefaultRunner is used,
+// falling back to HostRunnerName.
+//
+// If Parser.Runners is nil, only the HostRunner is available.
+func (p *Parser) Runner(name string) (Runner, error) {
+	if p == nil {
+		return nil, ErrIsNil("parser")
+	}
+
+	if len(name) == 0 {
+		name = p.DefaultRunner
+	}
+
+	if len(name) == 0 {
+		name = HostRunnerName
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.Runners == nil {
+		if name == HostRunnerName {
+			return HostRunner{}, nil
+		}
+		return nil, ErrRunnerNotFound(name)
+	}
+
+	r, ok := p.Runners[name]
+	if !ok || r == nil {
+		return nil, ErrRunnerNotFound(name)
+	}
+
+	return r, nil
+}
@@ -0,0 +1,88 @@
+package hype
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/markbates/clam"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Parser_Runner(t *testing.T) {
+	t.Parallel()
+
+	table := []struct {
+		name    string
+		p       *Parser
+		runner  string
+		err     bool
+		expType any
+	}{
+		{name: "default", p: NewParser(nil), expType: HostRunner{}},
+		{name: "host", p: NewParser(nil), runner: HostRunnerName, expType: HostRunner{}},
+		{name: "restricted", p: NewParser(nil), runner: RestrictedRunnerName, expType: &RestrictedRunner{}},
+		{name: "unknown", p: NewParser(nil), runner: "docker", err: true},
+		{name: "nil runners", p: &Parser{}, expType: HostRunner{}},
+		{name: "nil runners restricted", p: &Parser{}, runner: RestrictedRunnerName, err: true},
+		{name: "parser default", p: &Parser{
+			DefaultRunner: RestrictedRunnerName,
+			Runners:       DefaultRunners(),
+		}, expType: &RestrictedRunner{}},
+		{name: "host removed", p: &Parser{
+			Runners: map[string]Runner{RestrictedRunnerName: &RestrictedRunner{}},
+		}, err: true},
+	}
+
+	for _, tt := range table {
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+
+			rn, err := tt.p.Runner(tt.runner)
+			if tt.err {
+				r.Error(err)
+				r.True(errors.As(err, new(ErrRunnerNotFound)))
+				return
+			}
+
+			r.NoError(err)
+			r.IsType(tt.expType, rn)
+		})
+	}
+}
+
+func Test_Cmd_Execute_Runner(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	var called []string
+
+	p := NewParser(nil)
+	p.Runners["fake"] = RunnerFn(func(ctx context.Context, cmd *clam.Cmd, args ...string) (*clam.Result, error) {
+		called = append(called, strings.Join(args, " "))
+		return &clam.Result{
+			Args:   args,
+			Stdout: []byte("from fake"),
+		}, nil
+	})
+
+	el := NewEl("cmd", nil)
+	r.NoError(el.Set("exec", "echo hello"))
+	r.NoError(el.Set("runner", "fake"))
+	r.NoError(el.Set("hide-cmd", ""))
+
+	c, err := NewCmd(el)
+	r.NoError(err)
+
+	doc := &Document{Parser: p}
+
+	r.NoError(c.Execute(context.Background(), doc))
+	r.Equal([]string{"echo hello"}, called)
+	r.Equal("from fake", string(c.Result().Stdout))
+
+	r.NoError(el.Set("runner", "missing"))
+	err = c.Execute(context.Background(), doc)
+	r.Error(err)
+	r.True(errors.Is(err, CmdError{}))
+}
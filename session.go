@@ -0,0 +1,477 @@
+package hype
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gopherguides/hype/atomx"
+	"github.com/markbates/clam"
+)
+
+// Session is a tag representing a shell session: a list of
+// <step> commands, run one after another, in one shell, so
+// the working directory, and environment, set by a step are
+// seen by the steps after it. The session renders a single
+// transcript, with the prompt, and output, of each step:
+//
+//	<session src="src">
+//	<step exec="mkdir hello"></step>
+//	<step exec="cd hello"></step>
+//	<step exec="go mod init hello"></step>
+//	<step exec="go run ." exit="1" truncate="10"></step>
+//	</session>
+//
+// Steps accept the exit, hide-cmd, truncate, and replace-N
+// attributes of <cmd>, and a hide attribute, to run a step
+// without showing it. The session accepts the environ, src,
+// runner, no-cache, language, and replace-N attributes of
+// <cmd>, which apply to every step, a shell attribute, to
+// choose the shell (default: sh), and a prompt attribute
+// (default: $).
+type Session struct {
+	*Element
+
+	Env     []string
+	Shell   string
+	Prompt  string
+	Steps   []*SessionStep
+	Timeout time.Duration
+
+	res *clam.Result
+}
+
+// SessionStep is a command of a Session.
+type SessionStep struct {
+	*Element
+
+	// Exec is the command, as it is passed to the shell
+	Exec         string
+	ExpectedExit int
+
+	// Ran is true if the step was run
+	Ran    bool
+	Exit   int
+	Stdout []byte
+	Stderr []byte
+}
+
+func (s *Session) MarshalJSON() ([]byte, error) {
+	if s == nil {
+		return nil, ErrIsNil("session")
+	}
+
+	s.RLock()
+	defer s.RUnlock()
+
+	m, err := s.JSONMap()
+	if err != nil {
+		return nil, err
+	}
+
+	m["type"] = toType(s)
+	m["shell"] = s.Shell
+	m["prompt"] = s.Prompt
+	m["timeout"] = s.Timeout.String()
+
+	if len(s.Env) > 0 {
+		m["env"] = s.Env
+	}
+
+	steps := make([]map[string]any, 0, len(s.Steps))
+	for _, st := range s.Steps {
+		sm := map[string]any{
+			"exec":          st.Exec,
+			"expected_exit": st.ExpectedExit,
+		}
+
+		if st.Ran {
+			sm["exit"] = st.Exit
+			sm["stdout"] = string(st.Stdout)
+			sm["stderr"] = string(st.Stderr)
+		}
+
+		steps = append(steps, sm)
+	}
+
+	m["steps"] = steps
+
+	return json.MarshalIndent(m, "", "  ")
+}
+
+func (s *Session) MD() string {
+	if s == nil {
+		return ""
+	}
+
+	return s.Children().MD()
+}
+
+// Execute runs the steps of the session, in order, in one
+// shell, and replaces the body of the tag with the transcript.
+// The session stops at the first step that does not exit
+// with its expected exit code, and returns a CmdError, at the
+// position of the step.
+func (s *Session) Execute(ctx context.Context, doc *Document) error {
+	if s == nil {
+		return ErrIsNil("session")
+	}
+
+	if s.Element == nil {
+		return ErrIsNil("element")
+	}
+
+	if doc == nil {
+		return ErrIsNil("document")
+	}
+
+	var cancel context.CancelFunc
+	ctx, cancel = context.WithTimeout(ctx, s.Timeout)
+	defer cancel()
+
+	script, marker := s.script()
+
+	// the session is run as a <cmd>, to use
+	// its runner, and cache, attributes
+	c := &Cmd{
+		Element: s.Element,
+		Args:    []string{s.Shell, "-c", script},
+		Env:     s.Env,
+	}
+
+	cmd := &clam.Cmd{
+		Env: s.Env,
+	}
+
+	if src, ok := s.Get("src"); ok {
+		cmd.Dir = filepath.Join(doc.Root, src)
+	}
+
+	res, err := c.run(ctx, doc, cmd, c.Args)
+	if res == nil {
+		return c.newError(err)
+	}
+
+	exits := sessionExits(res.Stdout, marker)
+	stdout := sessionOutputs(res.Stdout, marker, len(s.Steps))
+	stderr := sessionOutputs(res.Stderr, marker, len(s.Steps))
+
+	s.Lock()
+	defer s.Unlock()
+
+	s.res = res
+
+	for i, st := range s.Steps {
+		exit, ok := exits[i]
+		if !ok {
+			// the shell ended during the step, such as by
+			// a timeout, or an exit in the step itself
+			if err == nil {
+				err = fmt.Errorf("session ended before the step finished")
+			}
+
+			return st.newError(res, err, stdout[i], stderr[i])
+		}
+
+		st.Ran = true
+		st.Exit = exit
+		st.Stdout = stdout[i]
+		st.Stderr = stderr[i]
+
+		if !st.exitOK() {
+			return st.newError(res, fmt.Errorf("exit status %d", exit), st.Stdout, st.Stderr)
+		}
+	}
+
+	body, err := s.transcript(res)
+	if err != nil {
+		return s.WrapErr(err)
+	}
+
+	pre := NewEl(atomx.Pre, s)
+	cel := &FencedCode{
+		Element: NewEl(atomx.Code, pre),
+	}
+
+	lang := Language(s.Attrs(), "shell")
+
+	if err := cel.Set("language", lang); err != nil {
+		return err
+	}
+
+	if err := cel.Set("class", "language-"+lang); err != nil {
+		return err
+	}
+
+	cel.Nodes = Nodes{Text(body)}
+	pre.Nodes = Nodes{cel}
+
+	s.Nodes = Nodes{pre}
+
+	return nil
+}
+
+// Result returns the result of running the session's
+// shell, with the output of every step, and the markers
+// between them.
+func (s *Session) Result() *clam.Result {
+	s.RLock()
+	defer s.RUnlock()
+	return s.res
+}
+
+// script returns the shell script that runs the steps, and
+// the marker it writes, to stdout, and stderr, before each
+// step, and, with its exit code, to stdout after each step.
+// The marker is a hash of the steps, so the script, and the
+// cache key of the session, do not change between runs.
+func (s *Session) script() (string, string) {
+	h := sha256.New()
+	for _, st := range s.Steps {
+		fmt.Fprintln(h, st.Exec)
+	}
+
+	marker := "__hype_session_" + hex.EncodeToString(h.Sum(nil))[:16]
+
+	bb := &strings.Builder{}
+
+	for i, st := range s.Steps {
+		fmt.Fprintf(bb, "printf '\\n%s %d\\n'; printf '\\n%s %d\\n' >&2\n", marker, i, marker, i)
+		fmt.Fprintf(bb, "%s\n", st.Exec)
+		fmt.Fprintf(bb, "__hype_exit=$?\n")
+		fmt.Fprintf(bb, "printf '\\n%s %d %%d\\n' \"$__hype_exit\"\n", marker, i)
+
+		switch st.ExpectedExit {
+		case -1:
+			fmt.Fprintf(bb, "[ \"$__hype_exit\" -ne 0 ] || exit 0\n")
+		default:
+			fmt.Fprintf(bb, "[ \"$__hype_exit\" -eq %d ] || exit 0\n", st.ExpectedExit)
+		}
+	}
+
+	return bb.String(), marker
+}
+
+// transcript returns the prompt, and output, of each step
+// that is not hidden.
+func (s *Session) transcript(res *clam.Result) (string, error) {
+	var blocks []string
+
+	for _, st := range s.Steps {
+		if _, ok := st.Get("hide"); ok {
+			continue
+		}
+
+		var lines []string
+
+		if _, ok := st.Get("hide-cmd"); !ok {
+			lines = append(lines, html.EscapeString(fmt.Sprintf("%s %s", s.Prompt, st.Exec)))
+		}
+
+		var out []string
+
+		if o := strings.TrimSpace(string(st.Stdout)); len(o) > 0 {
+			out = append(out, o)
+		}
+
+		if o := strings.TrimSpace(string(st.Stderr)); len(o) > 0 {
+			out = append(out, o)
+		}
+
+		body, err := applyReplacements(s.Attrs(), strings.Join(out, "\n\n"))
+		if err != nil {
+			return "", st.WrapErr(err)
+		}
+
+		body, err = resultBody(&clam.Result{Dir: res.Dir}, st.Attrs(), body)
+		if err != nil {
+			return "", st.WrapErr(err)
+		}
+
+		if len(body) > 0 {
+			lines = append(lines, body)
+		}
+
+		if len(lines) > 0 {
+			blocks = append(blocks, strings.Join(lines, "\n"))
+		}
+	}
+
+	return strings.Join(blocks, "\n"), nil
+}
+
+func (st *SessionStep) exitOK() bool {
+	switch st.ExpectedExit {
+	case -1:
+		return st.Exit != 0
+	default:
+		return st.Exit == st.ExpectedExit
+	}
+}
+
+func (st *SessionStep) newError(res *clam.Result, err error, stdout, stderr []byte) error {
+	return CmdError{
+		RunError: clam.RunError{
+			Err:    err,
+			Args:   []string{st.Exec},
+			Dir:    res.Dir,
+			Exit:   st.Exit,
+			Output: append(append([]byte{}, stdout...), stderr...),
+		},
+		Filename: st.Filename,
+		Pos:      st.Pos,
+	}
+}
+
+// sessionExits returns the exit code of each step
+// that finished, from the markers in stdout.
+func sessionExits(b []byte, marker string) map[int]int {
+	exits := map[int]int{}
+
+	scan := bufio.NewScanner(bytes.NewReader(b))
+	scan.Buffer(nil, len(b)+1)
+
+	for scan.Scan() {
+		ff := strings.Fields(scan.Text())
+		if len(ff) != 3 || ff[0] != marker {
+			continue
+		}
+
+		i, err := strconv.Atoi(ff[1])
+		if err != nil {
+			continue
+		}
+
+		exit, err := strconv.Atoi(ff[2])
+		if err != nil {
+			continue
+		}
+
+		exits[i] = exit
+	}
+
+	return exits
+}
+
+// sessionOutputs splits the output of the session's shell
+// into the output of each of its n steps.
+func sessionOutputs(b []byte, marker string, n int) [][]byte {
+	outs := make([][]byte, n)
+
+	step := -1
+	for _, line := range bytes.SplitAfter(b, []byte("\n")) {
+		ff := strings.Fields(string(line))
+		if len(ff) >= 2 && ff[0] == marker {
+			step = -1
+			if i, err := strconv.Atoi(ff[1]); err == nil && len(ff) == 2 && i < n {
+				step = i
+			}
+			continue
+		}
+
+		if step >= 0 {
+			outs[step] = append(outs[step], line...)
+		}
+	}
+
+	return outs
+}
+
+// NewSession creates a new Session from the given element,
+// and its <step> elements.
+func NewSession(el *Element) (*Session, error) {
+	if el == nil {
+		return nil, ErrIsNil("element")
+	}
+
+	s := &Session{
+		Element: el,
+		Shell:   "sh",
+		Prompt:  "$",
+	}
+
+	if sh, ok := el.Get("shell"); ok && len(strings.TrimSpace(sh)) > 0 {
+		s.Shell = strings.TrimSpace(sh)
+	}
+
+	if pr, ok := el.Get("prompt"); ok {
+		s.Prompt = pr
+	}
+
+	if en, ok := el.Get("environ"); ok {
+		s.Env = append(s.Env, strings.Split(en, ",")...)
+	}
+
+	for _, n := range ByAtom(el.Children(), atomx.Step) {
+		sel, ok := n.(*Element)
+		if !ok {
+			continue
+		}
+
+		st, err := newSessionStep(sel)
+		if err != nil {
+			return nil, err
+		}
+
+		s.Steps = append(s.Steps, st)
+	}
+
+	if len(s.Steps) == 0 {
+		return nil, s.WrapErr(fmt.Errorf("session has no <step> commands"))
+	}
+
+	// each step may take as long as a <cmd>
+	s.Timeout = time.Second * 30 * time.Duration(len(s.Steps))
+
+	if to, ok := el.Get("timeout"); ok {
+		var err error
+		s.Timeout, err = time.ParseDuration(to)
+		if err != nil {
+			return nil, s.WrapErr(err)
+		}
+	}
+
+	return s, nil
+}
+
+func newSessionStep(el *Element) (*SessionStep, error) {
+	st := &SessionStep{
+		Element: el,
+	}
+
+	ex, err := el.ValidAttr("exec")
+	if err != nil {
+		return nil, err
+	}
+
+	st.Exec = strings.TrimSpace(ex)
+
+	if ee, ok := el.Get("exit"); ok {
+		st.ExpectedExit, err = strconv.Atoi(ee)
+		if err != nil {
+			return nil, st.WrapErr(err)
+		}
+	}
+
+	return st, nil
+}
+
+// NewSessionNodes implements the ParseElementFn
+// type, for <session> tags.
+func NewSessionNodes(p *Parser, el *Element) (Nodes, error) {
+	s, err := NewSession(el)
+	if err != nil {
+		return nil, err
+	}
+
+	return Nodes{s}, nil
+}
@@ -0,0 +1,107 @@
+package hype
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Session_Execute_UnexpectedExit(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	p := testParser(t, "testdata/auto/commands/session")
+
+	doc, err := p.Parse(strings.NewReader(`# Failing
+
+<session src="src">
+<step exec="cd sub"></step>
+<step exec="cat missing.txt"></step>
+<step exec="echo never"></step>
+</session>`))
+	r.NoError(err)
+
+	err = doc.Execute(context.Background())
+	r.Error(err)
+
+	var ce CmdError
+	r.True(errors.As(err, &ce))
+	r.Equal([]string{"cat missing.txt"}, ce.Args)
+	r.Equal(1, ce.Exit)
+	r.Equal(5, ce.Pos.Line)
+	r.Contains(string(ce.Output), "missing.txt")
+
+	s, ok := FirstByType[*Session](doc.Nodes)
+	r.True(ok)
+	r.True(s.Steps[0].Ran)
+	r.True(s.Steps[1].Ran)
+	r.False(s.Steps[2].Ran)
+}
+
+func Test_NewSession(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	p := testParser(t, "testdata/auto/commands/session")
+
+	doc, err := p.Parse(strings.NewReader(`<session shell="bash" prompt="%" timeout="5s" environ="A=1,B=2">
+<step exec="make build" exit="-1"></step>
+</session>`))
+	r.NoError(err)
+
+	s, ok := FirstByType[*Session](doc.Nodes)
+	r.True(ok)
+	r.Equal("bash", s.Shell)
+	r.Equal("%", s.Prompt)
+	r.Equal([]string{"A=1", "B=2"}, s.Env)
+	r.Equal("5s", s.Timeout.String())
+	r.Len(s.Steps, 1)
+	r.Equal("make build", s.Steps[0].Exec)
+	r.Equal(-1, s.Steps[0].ExpectedExit)
+}
+
+func Test_NewSession_Errors(t *testing.T) {
+	t.Parallel()
+
+	table := []struct {
+		name string
+		in   string
+		err  string
+	}{
+		{name: "no steps", in: `<session></session>`, err: "session has no <step> commands"},
+		{name: "no exec", in: `<session><step></step></session>`, err: "exec"},
+		{name: "bad exit", in: `<session><step exec="ls" exit="x"></step></session>`, err: "invalid syntax"},
+		{name: "bad timeout", in: `<session timeout="x"><step exec="ls"></step></session>`, err: "invalid duration"},
+	}
+
+	for _, tt := range table {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			r := require.New(t)
+
+			p := testParser(t, "testdata/auto/commands/session")
+
+			_, err := p.Parse(strings.NewReader(tt.in))
+			r.Error(err)
+			r.Contains(err.Error(), tt.err)
+		})
+	}
+}
+
+func Test_sessionOutputs(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	m := "__hype_session_x"
+	out := "\n" + m + " 0\nfirst\n" + m + " 0 0\n\n" + m + " 1\nsecond\nline" + "\n" + m + " 1 3\n"
+
+	outs := sessionOutputs([]byte(out), m, 2)
+	r.Equal("first\n", string(outs[0]))
+	r.Equal("second\nline\n", string(outs[1]))
+
+	r.Equal(map[int]int{0: 0, 1: 3}, sessionExits([]byte(out), m))
+}
@@ -5,19 +5,45 @@ import (
 	"embed"
 	"fmt"
 	"html/template"
+	"io"
+	"io/fs"
 	"net/http"
 	"os"
 	"time"
 
 	"github.com/gopherguides/hype"
+	"github.com/gopherguides/hype/preview"
 )
 
+// ThemeTemplate is the file of a theme with its template.
+const ThemeTemplate = "slides.html"
+
+const (
+	// PresenterPath is the path of the presenter window.
+	PresenterPath = "/presenter"
+
+	// SyncPath is the path of the websocket that keeps the
+	// windows of the slides on the same slide.
+	SyncPath = "/_slides"
+)
+
+// HTMLTemplate is the template of the slides, unless the
+// App has a Theme. It is executed with the title, and the
+// body, of the slides, and the CSS of the highlighted code.
+//
 //go:embed templates/slides.html
 var HTMLTemplate string
 
 //go:embed templates/assets/app.js
 var AppJS string
 
+// PresenterTemplate is the template of the presenter window.
+// It is executed with the title, and the number, of the
+// slides, and the notes of each slide.
+//
+//go:embed templates/presenter.html
+var PresenterTemplate string
+
 //go:embed templates/assets/*.*
 var AssetsFS embed.FS
 
@@ -41,6 +67,18 @@ type App struct {
 	PWD      string
 	Parser   *hype.Parser // If nil, a default parser is used.
 
+	// Theme replaces the HTMLTemplate with the ThemeTemplate
+	// file in it. Its assets are served from /assets/, as
+	// those of the document are, and are found there first.
+	Theme fs.FS
+
+	// Client downloads the remote assets of an Export.
+	// If nil, http.DefaultClient is used.
+	Client *http.Client
+
+	// Sync keeps the audience, and presenter, windows on the
+	// same slide. If nil, the windows are not kept in sync.
+	Sync *preview.LiveReload
 }
 
 func (a *App) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -48,6 +86,17 @@ func (a *App) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "nil app", http.StatusInternalServerError)
 		return
 	}
+
+	if r != nil && r.URL.Path == SyncPath {
+		if a.Sync == nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		a.Sync.HandleWebSocket(w, r)
+		return
+	}
+
 	if err := a.serve(w, r); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
@@ -71,39 +120,87 @@ func (a *App) serve(w http.ResponseWriter, r *http.Request) error {
 		return fmt.Errorf("nil request")
 	}
 
-	cab := os.DirFS(a.PWD)
-	p := hype.NewParser(cab)
-	p.Root = a.PWD
-
 	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
 	defer cancel()
 
-	doc, err := p.ParseExecuteFile(ctx, a.FileName)
+	if r.URL.Path == PresenterPath {
+		return a.RenderPresenter(ctx, w)
+	}
+
+	return a.Render(ctx, w)
+}
+
+// Render parses, and executes, the document, and writes its
+// pages, as slides, to w, in the template of the theme. The
+// speaker notes are removed from the slides.
+func (a *App) Render(ctx context.Context, w io.Writer) error {
+	return a.render(ctx, w, false)
+}
+
+// RenderPresenter writes the presenter window of the slides
+// to w: the current, and next, slide, the speaker notes of
+// the current slide, and a timer.
+func (a *App) RenderPresenter(ctx context.Context, w io.Writer) error {
+	doc, pages, err := a.pages(ctx)
+	if err != nil {
+		return err
+	}
+
+	notes := make([]template.HTML, 0, len(pages))
+	for _, page := range pages {
+		notes = append(notes, template.HTML(pageNotes(page)))
+	}
+
+	tmpl, err := template.New("presenter").Parse(PresenterTemplate)
 	if err != nil {
-		return fmt.Errorf("pwd: %q, file: %q, err: %w", a.PWD, a.FileName, err)
+		return err
 	}
 
-	pages, err := doc.Pages()
+	data := map[string]any{
+		"title": doc.Title,
+		"count": len(pages),
+		"notes": notes,
+	}
+
+	return tmpl.Execute(w, data)
+}
+
+// render writes the slides to w. If notes is true, the
+// speaker notes are kept in the slides, hidden, for the
+// notes panel, rather than removed.
+func (a *App) render(ctx context.Context, w io.Writer, notes bool) error {
+	doc, pages, err := a.pages(ctx)
 	if err != nil {
 		return err
 	}
 
+	for _, n := range hype.ByType[*Notes](doc.Children()) {
+		n.Show = notes
+	}
+
 	var nodes hype.Nodes
-	for i, page := range pages {
-		page.Set("id", fmt.Sprintf("page-%d", i))
+	for _, page := range pages {
 		nodes = append(nodes, page)
 	}
 
-	bs := nodes.String()
+	if err := highlightCode(nodes); err != nil {
+		return err
+	}
+
+	css, err := highlightCSS()
+	if err != nil {
+		return err
+	}
 
-	tmpl, err := template.New("slides").Parse(HTMLTemplate)
+	tmpl, err := a.template()
 	if err != nil {
 		return err
 	}
 
 	data := map[string]any{
-		"title": doc.Title,
-		"body":  template.HTML(bs),
+		"title":     doc.Title,
+		"body":      template.HTML(nodes.String()),
+		"highlight": template.CSS(css),
 	}
 
 	err = tmpl.Execute(w, data)
@@ -113,3 +210,58 @@ func (a *App) serve(w http.ResponseWriter, r *http.Request) error {
 
 	return nil
 }
+
+// pages parses, and executes, the document, and returns it,
+// and its pages, each with the id of its slide.
+func (a *App) pages(ctx context.Context) (*hype.Document, []*hype.Page, error) {
+	if a == nil {
+		return nil, nil, fmt.Errorf("nil app")
+	}
+
+	cab := os.DirFS(a.PWD)
+	p := hype.NewParser(cab)
+	p.Root = a.PWD
+	p.MermaidRender = hype.MermaidSVG
+	p.NodeParsers["notes"] = NewNotesNodes
+
+	doc, err := p.ParseExecuteFile(ctx, a.FileName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("pwd: %q, file: %q, err: %w", a.PWD, a.FileName, err)
+	}
+
+	all, err := doc.Pages()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// the markdown is wrapped in a page of its own, so a
+	// page holding the <page>s of the document is not a slide
+	var pages []*hype.Page
+	for _, page := range all {
+		if len(hype.ByType[*hype.Page](page.Children())) == 0 {
+			pages = append(pages, page)
+		}
+	}
+
+	for i, page := range pages {
+		page.Set("id", fmt.Sprintf("page-%d", i))
+	}
+
+	return doc, pages, nil
+}
+
+// template returns the template of the theme, or,
+// without a theme, the HTMLTemplate.
+func (a *App) template() (*template.Template, error) {
+	src := HTMLTemplate
+
+	if a.Theme != nil {
+		b, err := fs.ReadFile(a.Theme, ThemeTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("theme: %w", err)
+		}
+		src = string(b)
+	}
+
+	return template.New("slides").Parse(src)
+}
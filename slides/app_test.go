@@ -0,0 +1,97 @@
+package slides
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gopherguides/hype/preview"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+)
+
+func Test_App_RenderPresenter(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	app := testApp(t)
+
+	bb := &bytes.Buffer{}
+	r.NoError(app.RenderPresenter(context.Background(), bb))
+
+	act := bb.String()
+	r.Contains(act, "<title>Presenter - Hello</title>")
+	r.Contains(act, `data-count="2"`)
+	r.Contains(act, "<div class=\"presenter-slide-notes\">\n<p>Say <em>hello</em>.</p>\n</div>")
+	r.Contains(act, `<div class="presenter-slide-notes"></div>`)
+	r.Contains(act, `<script src="/templates/assets/presenter.js">`)
+}
+
+func Test_App_ServeHTTP(t *testing.T) {
+	t.Parallel()
+
+	app := testApp(t)
+
+	table := []struct {
+		path     string
+		status   int
+		contains string
+	}{
+		{path: "/", status: http.StatusOK, contains: `<main class="slides">`},
+		{path: PresenterPath, status: http.StatusOK, contains: `<body class="presenter" data-count="2">`},
+		// without Sync, the windows are not kept in sync
+		{path: SyncPath, status: http.StatusNotFound},
+	}
+
+	for _, tt := range table {
+		t.Run(tt.path, func(t *testing.T) {
+			r := require.New(t)
+
+			w := httptest.NewRecorder()
+			app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			res := w.Result()
+			body, err := io.ReadAll(res.Body)
+			r.NoError(err)
+
+			r.Equal(tt.status, res.StatusCode)
+			r.Contains(string(body), tt.contains)
+		})
+	}
+}
+
+func Test_App_Sync(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	app := testApp(t)
+	app.Sync = preview.NewLiveReload()
+
+	srv := httptest.NewServer(app)
+	defer srv.Close()
+
+	wsURL := "ws" + srv.URL[4:] + SyncPath
+
+	presenter, err := websocket.Dial(wsURL, "", srv.URL)
+	r.NoError(err)
+	defer func() { _ = presenter.Close() }()
+
+	audience, err := websocket.Dial(wsURL, "", srv.URL)
+	r.NoError(err)
+	defer func() { _ = audience.Close() }()
+
+	time.Sleep(50 * time.Millisecond)
+	r.Equal(2, app.Sync.ClientCount())
+
+	r.NoError(websocket.JSON.Send(presenter, preview.Message{Type: preview.MessageSlide, Slide: 2}))
+
+	_ = audience.SetReadDeadline(time.Now().Add(time.Second))
+
+	var msg preview.Message
+	r.NoError(websocket.JSON.Receive(audience, &msg))
+	r.Equal(preview.Message{Type: preview.MessageSlide, Slide: 2}, msg)
+}
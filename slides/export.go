@@ -0,0 +1,437 @@
+package slides
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/markbates/fsx"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Export writes the slides to w as a single HTML file that
+// works offline. Every stylesheet, script, font, and image
+// the slides use is embedded in the file. Remote assets are
+// downloaded; local assets are found in the theme, the
+// built-in assets, and the directory of the document, in
+// that order, as the slides server serves them.
+func (a *App) Export(ctx context.Context, w io.Writer) error {
+	if a == nil {
+		return fmt.Errorf("nil app")
+	}
+
+	if w == nil {
+		return fmt.Errorf("nil writer")
+	}
+
+	// there is no presenter window offline, so the
+	// notes are kept for the notes panel
+	bb := &bytes.Buffer{}
+	if err := a.render(ctx, bb, true); err != nil {
+		return err
+	}
+
+	doc, err := html.Parse(bb)
+	if err != nil {
+		return err
+	}
+
+	em := &embedder{
+		ctx:    ctx,
+		client: a.Client,
+		fsys:   a.assets(),
+	}
+
+	if em.client == nil {
+		em.client = http.DefaultClient
+	}
+
+	if err := em.node(doc); err != nil {
+		return fmt.Errorf("slides: export: %w", err)
+	}
+
+	return html.Render(w, doc)
+}
+
+// assets returns the file system the local assets of the
+// slides are found in.
+func (a *App) assets() fs.FS {
+	cab := &fsx.ArrayFS{}
+
+	if a.Theme != nil {
+		cab.Append(a.Theme)
+	}
+
+	cab.Append(AssetsFS)
+
+	if len(a.PWD) > 0 {
+		cab.Append(os.DirFS(a.PWD))
+	}
+
+	return cab
+}
+
+// cssURLPattern matches the url() of a style sheet.
+var cssURLPattern = regexp.MustCompile(`url\(\s*(?:"([^"]*)"|'([^']*)'|([^'")\s]*))\s*\)`)
+
+// embedder embeds the assets of an HTML document.
+type embedder struct {
+	ctx    context.Context
+	client *http.Client
+	fsys   fs.FS
+}
+
+func (em *embedder) node(n *html.Node) error {
+	if n.Type == html.ElementNode {
+		if err := em.element(n); err != nil {
+			return err
+		}
+	}
+
+	// a <link> is replaced, so the next
+	// sibling is found before the node
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		if err := em.node(c); err != nil {
+			return err
+		}
+		c = next
+	}
+
+	return nil
+}
+
+func (em *embedder) element(n *html.Node) error {
+	switch n.DataAtom {
+	case atom.Link:
+		href := attr(n, "href")
+		rel := strings.Fields(strings.ToLower(attr(n, "rel")))
+
+		if len(href) == 0 {
+			return nil
+		}
+
+		for _, r := range rel {
+			switch r {
+			case "stylesheet":
+				return em.stylesheet(n, href)
+			case "icon", "apple-touch-icon":
+				return em.attr(n, "href", "")
+			}
+		}
+	case atom.Script:
+		src := attr(n, "src")
+		if len(src) == 0 {
+			return nil
+		}
+
+		b, _, err := em.fetch(src, "")
+		if err != nil {
+			return err
+		}
+
+		removeAttr(n, "src")
+		removeAttr(n, "integrity")
+		removeAttr(n, "crossorigin")
+
+		// the script must not end the element early
+		js := strings.ReplaceAll(string(b), "</script", `<\/script`)
+		setText(n, js)
+	case atom.Style:
+		if n.FirstChild == nil {
+			return nil
+		}
+
+		css, err := em.css(n.FirstChild.Data, "")
+		if err != nil {
+			return err
+		}
+
+		setText(n, css)
+	case atom.Img, atom.Source, atom.Video, atom.Audio, atom.Track:
+		for _, k := range []string{"src", "poster"} {
+			if err := em.attr(n, k, ""); err != nil {
+				return err
+			}
+		}
+
+		if err := em.srcset(n); err != nil {
+			return err
+		}
+	}
+
+	if style := attr(n, "style"); strings.Contains(style, "url(") {
+		css, err := em.css(style, "")
+		if err != nil {
+			return err
+		}
+		setAttr(n, "style", css)
+	}
+
+	return nil
+}
+
+// stylesheet replaces the <link> of a style sheet with
+// a <style> element holding the style sheet.
+func (em *embedder) stylesheet(n *html.Node, href string) error {
+	b, base, err := em.fetch(href, "")
+	if err != nil {
+		return err
+	}
+
+	css, err := em.css(string(b), base)
+	if err != nil {
+		return err
+	}
+
+	style := &html.Node{
+		Type:     html.ElementNode,
+		Data:     "style",
+		DataAtom: atom.Style,
+	}
+
+	if media := attr(n, "media"); len(media) > 0 {
+		setAttr(style, "media", media)
+	}
+
+	setText(style, css)
+
+	n.Parent.InsertBefore(style, n)
+	n.Parent.RemoveChild(n)
+
+	return nil
+}
+
+// css embeds the url()s of the style sheet, found at base.
+func (em *embedder) css(css string, base string) (string, error) {
+	var err error
+
+	css = cssURLPattern.ReplaceAllStringFunc(css, func(m string) string {
+		if err != nil {
+			return m
+		}
+
+		sm := cssURLPattern.FindStringSubmatch(m)
+		ref := sm[1] + sm[2] + sm[3]
+
+		if len(ref) == 0 || strings.HasPrefix(ref, "data:") || strings.HasPrefix(ref, "#") {
+			return m
+		}
+
+		var uri string
+		uri, err = em.dataURI(ref, base)
+
+		return fmt.Sprintf("url(%q)", uri)
+	})
+
+	return css, err
+}
+
+// attr embeds the asset of the attribute k of n.
+func (em *embedder) attr(n *html.Node, k string, base string) error {
+	ref := attr(n, k)
+	if len(ref) == 0 || strings.HasPrefix(ref, "data:") {
+		return nil
+	}
+
+	uri, err := em.dataURI(ref, base)
+	if err != nil {
+		return err
+	}
+
+	setAttr(n, k, uri)
+
+	return nil
+}
+
+// srcset embeds each image of the srcset attribute of n.
+func (em *embedder) srcset(n *html.Node) error {
+	set := attr(n, "srcset")
+	if len(set) == 0 {
+		return nil
+	}
+
+	var out []string
+
+	for _, c := range strings.Split(set, ",") {
+		fields := strings.Fields(c)
+		if len(fields) == 0 {
+			continue
+		}
+
+		uri := fields[0]
+		if !strings.HasPrefix(uri, "data:") {
+			var err error
+			uri, err = em.dataURI(uri, "")
+			if err != nil {
+				return err
+			}
+		}
+
+		out = append(out, strings.Join(append([]string{uri}, fields[1:]...), " "))
+	}
+
+	setAttr(n, "srcset", strings.Join(out, ", "))
+
+	return nil
+}
+
+func (em *embedder) dataURI(ref string, base string) (string, error) {
+	b, loc, err := em.fetch(ref, base)
+	if err != nil {
+		return "", err
+	}
+
+	typ := mime.TypeByExtension(path.Ext(strings.SplitN(loc, "?", 2)[0]))
+	if len(typ) == 0 {
+		typ = http.DetectContentType(b)
+	}
+
+	// parameters, such as the charset, are not needed
+	typ, _, _ = strings.Cut(typ, ";")
+
+	return fmt.Sprintf("data:%s;base64,%s", typ, base64.StdEncoding.EncodeToString(b)), nil
+}
+
+// fetch returns the contents of the asset ref, relative to
+// base, and where it was found.
+func (em *embedder) fetch(ref string, base string) ([]byte, string, error) {
+	loc, err := resolveAsset(ref, base)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if isRemote(loc) {
+		b, err := em.download(loc)
+		if err != nil {
+			return nil, "", fmt.Errorf("asset %q: %w", ref, err)
+		}
+
+		return b, loc, nil
+	}
+
+	b, err := fs.ReadFile(em.fsys, loc)
+	if err != nil {
+		return nil, "", fmt.Errorf("asset %q: %w", ref, err)
+	}
+
+	return b, loc, nil
+}
+
+func (em *embedder) download(loc string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(em.ctx, http.MethodGet, loc, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := em.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", loc, res.Status)
+	}
+
+	return io.ReadAll(res.Body)
+}
+
+// resolveAsset returns the location of ref, relative to
+// base: a URL, for a remote asset, or else a path in the
+// file system of the assets.
+func resolveAsset(ref string, base string) (string, error) {
+	if strings.HasPrefix(ref, "//") {
+		ref = "https:" + ref
+	}
+
+	if isRemote(ref) {
+		return ref, nil
+	}
+
+	if isRemote(base) {
+		bu, err := url.Parse(base)
+		if err != nil {
+			return "", err
+		}
+
+		ru, err := url.Parse(ref)
+		if err != nil {
+			return "", err
+		}
+
+		return bu.ResolveReference(ru).String(), nil
+	}
+
+	u, err := url.Parse(ref)
+	if err != nil {
+		return "", err
+	}
+
+	p := u.Path
+	if !strings.HasPrefix(p, "/") && len(base) > 0 {
+		p = path.Join(path.Dir(base), p)
+	}
+
+	p = path.Clean(strings.TrimPrefix(p, "/"))
+	if p == ".." || strings.HasPrefix(p, "../") {
+		return "", fmt.Errorf("asset %q is outside of the slides", ref)
+	}
+
+	return p, nil
+}
+
+func isRemote(s string) bool {
+	return strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://")
+}
+
+func attr(n *html.Node, k string) string {
+	for _, a := range n.Attr {
+		if a.Key == k {
+			return a.Val
+		}
+	}
+
+	return ""
+}
+
+func setAttr(n *html.Node, k string, v string) {
+	for i, a := range n.Attr {
+		if a.Key == k {
+			n.Attr[i].Val = v
+			return
+		}
+	}
+
+	n.Attr = append(n.Attr, html.Attribute{Key: k, Val: v})
+}
+
+func removeAttr(n *html.Node, k string) {
+	attrs := n.Attr[:0]
+	for _, a := range n.Attr {
+		if a.Key != k {
+			attrs = append(attrs, a)
+		}
+	}
+	n.Attr = attrs
+}
+
+// setText replaces the children of n with the text s.
+func setText(n *html.Node, s string) {
+	for c := n.FirstChild; c != nil; c = n.FirstChild {
+		n.RemoveChild(c)
+	}
+
+	n.AppendChild(&html.Node{Type: html.TextNode, Data: s})
+}
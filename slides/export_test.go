@@ -0,0 +1,222 @@
+package slides
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testSlides = `<page>
+
+# Hello
+
+<img src="assets/logo.png" alt="logo">
+
+<notes>
+
+Say *hello*.
+
+</notes>
+
+</page>
+
+<page>
+
+# Code
+
+` + "```go\nfunc main() {}\n```" + `
+
+</page>
+`
+
+func testApp(t testing.TB) *App {
+	t.Helper()
+
+	dir := t.TempDir()
+
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "assets"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "hype.md"), []byte(testSlides), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "assets", "logo.png"), []byte("\x89PNG\r\n\x1a\nlogo"), 0644))
+
+	return &App{
+		FileName: "hype.md",
+		PWD:      dir,
+	}
+}
+
+func Test_App_Render(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	app := testApp(t)
+
+	bb := &bytes.Buffer{}
+	r.NoError(app.Render(context.Background(), bb))
+
+	act := bb.String()
+	r.Contains(act, `<page id="page-0">`)
+	r.Contains(act, `<page id="page-1">`)
+	r.NotContains(act, `<page id="page-2">`)
+	r.Contains(act, `<pre class="chroma">`)
+	r.Contains(act, `.chroma`)
+	r.Contains(act, `<link rel="stylesheet" href="/templates/assets/app.css">`)
+	r.NotContains(act, "cdn")
+
+	// the audience does not see the notes
+	r.NotContains(act, "hello</em>")
+	r.NotContains(act, "notes>")
+}
+
+func Test_App_Render_Highlight(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	app := testApp(t)
+
+	src := "<page>\n\n```c++\nint main() { return 0; }\n```\n\n```shell-session\n$ echo \"<hi>\"\n<hi>\n```\n\n</page>\n"
+	r.NoError(os.WriteFile(filepath.Join(app.PWD, "hype.md"), []byte(src), 0644))
+
+	bb := &bytes.Buffer{}
+	r.NoError(app.Render(context.Background(), bb))
+
+	act := bb.String()
+	r.Equal(2, strings.Count(act, `<pre class="chroma">`))
+	r.Contains(act, `<span class="kt">int</span>`)
+	r.Contains(act, `<span class="gp">$</span>`)
+	r.Contains(act, `&lt;hi&gt;`)
+	r.NotContains(act, `"<hi>"`)
+}
+
+func Test_App_Export(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	app := testApp(t)
+
+	bb := &bytes.Buffer{}
+	r.NoError(app.Export(context.Background(), bb))
+
+	act := bb.String()
+	r.NotContains(act, `<link rel="stylesheet"`)
+	r.NotContains(act, `<script src=`)
+	r.NotContains(act, `src="assets/`)
+
+	// the built-in assets
+	r.Contains(act, "@media print")
+	r.Contains(act, "ArrowRight")
+
+	r.Contains(act, `src="data:image/png;base64,`)
+	r.Contains(act, "<aside class=\"notes\">\n<p>Say <em>hello</em>.</p>\n</aside>")
+}
+
+func Test_App_Export_Theme(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/lib.js":
+			_, _ = w.Write([]byte(`console.log("</script>")`))
+		case "/lib.css":
+			_, _ = w.Write([]byte(`body { background: url(img/bg.gif); }`))
+		case "/img/bg.gif":
+			_, _ = w.Write([]byte("GIF89a"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	app := testApp(t)
+	app.Client = srv.Client()
+	app.Theme = fstest.MapFS{
+		ThemeTemplate: {Data: []byte(`<!DOCTYPE html>
+<html><head><title>{{ .title }}</title>
+<link rel="stylesheet" href="/assets/theme.css">
+<link rel="stylesheet" href="` + srv.URL + `/lib.css">
+<link rel="icon" href="/assets/icon.svg">
+</head><body>{{ .body }}<script src="` + srv.URL + `/lib.js"></script></body></html>`)},
+		"assets/theme.css":          {Data: []byte(`@font-face { font-family: Gopher; src: url("fonts/gopher.woff2") format("woff2"); }`)},
+		"assets/fonts/gopher.woff2": {Data: []byte("wOF2")},
+		"assets/icon.svg":           {Data: []byte(`<svg xmlns="http://www.w3.org/2000/svg"></svg>`)},
+	}
+
+	bb := &bytes.Buffer{}
+	r.NoError(app.Export(context.Background(), bb))
+
+	act := bb.String()
+	r.Contains(act, "<title>Hello</title>")
+	r.Contains(act, `url("data:font/woff2;base64,`)
+	r.Contains(act, `url("data:image/gif;base64,`)
+	r.Contains(act, `<link rel="icon" href="data:image/svg+xml;base64,`)
+	r.Contains(act, `console.log("<\/script>")`)
+	r.NotContains(act, srv.URL)
+}
+
+func Test_App_Export_Missing_Asset(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	app := testApp(t)
+	app.Theme = fstest.MapFS{
+		ThemeTemplate: {Data: []byte(`<html><head><link rel="stylesheet" href="/assets/missing.css"></head><body>{{ .body }}</body></html>`)},
+	}
+
+	err := app.Export(context.Background(), &bytes.Buffer{})
+	r.Error(err)
+	r.Contains(err.Error(), `asset "/assets/missing.css"`)
+}
+
+func Test_App_Render_Theme_Missing_Template(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	app := testApp(t)
+	app.Theme = fstest.MapFS{}
+
+	err := app.Render(context.Background(), &bytes.Buffer{})
+	r.Error(err)
+	r.True(strings.HasPrefix(err.Error(), "theme:"))
+}
+
+func Test_resolveAsset(t *testing.T) {
+	t.Parallel()
+
+	table := []struct {
+		ref  string
+		base string
+		exp  string
+		err  bool
+	}{
+		{ref: "/templates/assets/app.css", exp: "templates/assets/app.css"},
+		{ref: "assets/logo.png?v=2#x", exp: "assets/logo.png"},
+		{ref: "fonts/a.woff2", base: "assets/theme.css", exp: "assets/fonts/a.woff2"},
+		{ref: "/assets/a.woff2", base: "assets/css/theme.css", exp: "assets/a.woff2"},
+		{ref: "//cdn.example.com/a.js", exp: "https://cdn.example.com/a.js"},
+		{ref: "../img/a.png", base: "https://cdn.example.com/css/a.css", exp: "https://cdn.example.com/img/a.png"},
+		{ref: "../../etc/passwd", base: "assets/theme.css", err: true},
+	}
+
+	for _, tt := range table {
+		t.Run(tt.ref, func(t *testing.T) {
+			r := require.New(t)
+
+			act, err := resolveAsset(tt.ref, tt.base)
+			if tt.err {
+				r.Error(err)
+				return
+			}
+
+			r.NoError(err)
+			r.Equal(tt.exp, act)
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package slides
+
+import (
+	"bytes"
+	"html"
+
+	"github.com/alecthomas/chroma/v2"
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/gopherguides/hype"
+	"github.com/gopherguides/hype/atomx"
+)
+
+// highlightStyle is the chroma style of the highlighted code.
+const highlightStyle = "monokai"
+
+// highlightFormatter writes the highlighted code, as spans,
+// into the <code> of the fenced code, whose <pre> is kept.
+var highlightFormatter = chromahtml.New(
+	chromahtml.WithClasses(true),
+	chromahtml.TabWidth(4),
+	chromahtml.PreventSurroundingPre(true),
+)
+
+// highlightCode highlights the fenced code of the nodes,
+// so the slides do not need a script to highlight it.
+// Code whose language has no lexer is left as it is.
+func highlightCode(nodes hype.Nodes) error {
+	style := styles.Get(highlightStyle)
+
+	for _, code := range hype.ByType[*hype.FencedCode](nodes) {
+		lexer := lexers.Get(code.Lang())
+		if lexer == nil {
+			continue
+		}
+
+		src := html.UnescapeString(code.Children().String())
+
+		it, err := chroma.Coalesce(lexer).Tokenise(nil, src)
+		if err != nil {
+			return err
+		}
+
+		bb := &bytes.Buffer{}
+		if err := highlightFormatter.Format(bb, style, it); err != nil {
+			return err
+		}
+
+		code.Lock()
+		code.Nodes = hype.Nodes{hype.Text(bb.String())}
+		code.Unlock()
+
+		pre, ok := code.Parent.(*hype.Element)
+		if !ok || pre.Atom() != atomx.Pre {
+			continue
+		}
+
+		if err := pre.Set("class", "chroma"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// highlightCSS returns the CSS of the highlighted code.
+func highlightCSS() (string, error) {
+	bb := &bytes.Buffer{}
+	if err := highlightFormatter.WriteCSS(bb, styles.Get(highlightStyle)); err != nil {
+		return "", err
+	}
+
+	return bb.String(), nil
+}
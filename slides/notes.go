@@ -0,0 +1,49 @@
+package slides
+
+import (
+	"github.com/gopherguides/hype"
+)
+
+// Notes are the speaker notes of a slide, from a <notes>
+// element in its page. They are shown in the presenter
+// window, rather than to the audience.
+type Notes struct {
+	*hype.Element
+
+	// Show renders the notes, as a hidden <aside class="notes">
+	// of the slide, rather than removing them from it.
+	Show bool
+}
+
+func (n *Notes) String() string {
+	if n == nil || !n.Show {
+		return ""
+	}
+
+	return `<aside class="notes">` + n.Children().String() + `</aside>`
+}
+
+// MD returns nothing, as the notes are not part
+// of the document.
+func (n *Notes) MD() string {
+	return ""
+}
+
+// NewNotesNodes is the hype.ParseElementFn of <notes>.
+func NewNotesNodes(p *hype.Parser, el *hype.Element) (hype.Nodes, error) {
+	if el == nil {
+		return nil, hype.ErrIsNil("element")
+	}
+
+	return hype.Nodes{&Notes{Element: el}}, nil
+}
+
+// pageNotes returns the HTML of the notes of the page.
+func pageNotes(page *hype.Page) string {
+	var s string
+	for _, n := range hype.ByType[*Notes](page.Children()) {
+		s += n.Children().String()
+	}
+
+	return s
+}
@@ -1,48 +1,121 @@
-$(function () {
-  var queryParams = new URLSearchParams(window.location.search);
+(function () {
+  var pages = document.querySelectorAll("page");
+  var notes = document.querySelector(".speaker-notes");
+  var number = document.querySelector(".slide-number");
+  var current = 0;
+  var ws;
 
-  let pages = $("page");
-  let pl = pages.length;
-  let currentPage = 0;
+  // the page is in the hash, so it works for a file too,
+  // or in the page query parameter, of older links
+  function fromLocation() {
+    var m = /^#(\d+)$/.exec(window.location.hash);
+    if (m) {
+      return parseInt(m[1], 10) - 1;
+    }
+
+    var params = new URLSearchParams(window.location.search);
+    if (params.has("page")) {
+      return parseInt(params.get("page"), 10);
+    }
+
+    return 0;
+  }
+
+  // show shows the page i, and tells the other windows,
+  // unless it was one of them that moved
+  function show(i, remote) {
+    if (pages.length === 0) {
+      return;
+    }
+
+    current = Math.max(0, Math.min(i, pages.length - 1));
+
+    pages.forEach(function (p, n) {
+      p.classList.toggle("current", n === current);
+    });
+
+    if (number) {
+      number.textContent = current + 1 + " / " + pages.length;
+    }
+
+    if (notes) {
+      var aside = pages[current].querySelector("aside.notes");
+      notes.innerHTML = aside ? aside.innerHTML : "";
+    }
+
+    var hash = "#" + (current + 1);
+    if (window.location.hash !== hash) {
+      history.replaceState(null, "", hash);
+    }
 
-  console.log(queryParams);
-  if (queryParams.has("page")) {
-    i = parseInt(queryParams.get("page"));
-    if (i >= 0 && i < pl) {
-      console.log("Setting page to " + i);
-      currentPage = i;
+    if (!remote && ws && ws.readyState === WebSocket.OPEN) {
+      ws.send(JSON.stringify({ type: "slide", slide: current + 1 }));
     }
   }
 
-  changePage = function () {
-    console.log("Changing page to " + currentPage);
-    $("page").hide();
-    $(pages[currentPage]).show();
-    queryParams.set("page", currentPage);
-    history.pushState(null, null, "?" + queryParams.toString());
-  };
-
-  console.log(currentPage);
-  changePage();
-
-  $(document).keydown(function (e) {
-    switch (e.keyCode) {
-      case 39:
-        currentPage++;
-        if (currentPage >= pl) {
-          currentPage = pl;
-          break;
+  document.addEventListener("keydown", function (e) {
+    if (e.altKey || e.ctrlKey || e.metaKey) {
+      return;
+    }
+
+    switch (e.key) {
+      case "ArrowRight":
+      case "ArrowDown":
+      case "PageDown":
+      case " ":
+        show(current + 1);
+        break;
+      case "ArrowLeft":
+      case "ArrowUp":
+      case "PageUp":
+        show(current - 1);
+        break;
+      case "Home":
+        show(0);
+        break;
+      case "End":
+        show(pages.length - 1);
+        break;
+      case "n":
+        if (notes) {
+          notes.hidden = !notes.hidden;
         }
-        changePage();
         break;
-      case 37:
-        currentPage--;
-        if (currentPage < 0) {
-          currentPage = 0;
-          break;
+      case "p":
+        if (!ws) {
+          return;
         }
-        changePage();
+        window.open("/presenter#" + (current + 1), "hype-presenter");
         break;
+      default:
+        return;
     }
+
+    e.preventDefault();
   });
-});
+
+  window.addEventListener("hashchange", function () {
+    show(fromLocation());
+  });
+
+  show(fromLocation(), true);
+
+  // served slides are kept in sync with the presenter window,
+  // unless they are the slides shown in it
+  var served = /^https?:$/.test(window.location.protocol);
+  if (served && window.top === window.self) {
+    var proto = window.location.protocol === "https:" ? "wss:" : "ws:";
+    ws = new WebSocket(proto + "//" + window.location.host + "/_slides");
+
+    ws.onmessage = function (e) {
+      var msg = JSON.parse(e.data);
+      if (msg.type === "slide") {
+        show(msg.slide - 1, true);
+      }
+    };
+
+    ws.onerror = function () {
+      ws = null;
+    };
+  }
+})();
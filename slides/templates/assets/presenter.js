@@ -0,0 +1,115 @@
+(function () {
+  var count = parseInt(document.body.dataset.count, 10) || 0;
+  var current = document.querySelector(".presenter-current iframe");
+  var next = document.querySelector(".presenter-next iframe");
+  var end = document.querySelector(".presenter-end");
+  var notes = document.querySelector(".presenter-notes-body");
+  var number = document.querySelector(".presenter-number");
+  var timer = document.querySelector(".presenter-timer");
+  var slideNotes = document.querySelectorAll(".presenter-slide-notes");
+  var slide = 1;
+  var ws;
+
+  // show shows the slide n, counted from 1, and tells the
+  // other windows, unless it was one of them that moved
+  function show(n, remote) {
+    if (count === 0) {
+      return;
+    }
+
+    slide = Math.max(1, Math.min(n, count));
+
+    // only the hash changes, so the slides are not reloaded
+    current.src = "/#" + slide;
+
+    if (slide < count) {
+      next.src = "/#" + (slide + 1);
+      next.hidden = false;
+      end.hidden = true;
+    } else {
+      next.hidden = true;
+      end.hidden = false;
+    }
+
+    notes.innerHTML = slideNotes[slide - 1] ? slideNotes[slide - 1].innerHTML : "";
+    number.textContent = slide + " / " + count;
+
+    var hash = "#" + slide;
+    if (window.location.hash !== hash) {
+      history.replaceState(null, "", hash);
+    }
+
+    if (!remote && ws && ws.readyState === WebSocket.OPEN) {
+      ws.send(JSON.stringify({ type: "slide", slide: slide }));
+    }
+  }
+
+  var started = Date.now();
+
+  function pad(n) {
+    return n < 10 ? "0" + n : "" + n;
+  }
+
+  function tick() {
+    var s = Math.floor((Date.now() - started) / 1000);
+    var h = Math.floor(s / 3600);
+    var t = pad(Math.floor((s % 3600) / 60)) + ":" + pad(s % 60);
+    timer.textContent = h > 0 ? h + ":" + t : t;
+  }
+
+  function reset() {
+    started = Date.now();
+    tick();
+  }
+
+  timer.addEventListener("click", reset);
+  setInterval(tick, 1000);
+
+  document.addEventListener("keydown", function (e) {
+    if (e.altKey || e.ctrlKey || e.metaKey) {
+      return;
+    }
+
+    switch (e.key) {
+      case "ArrowRight":
+      case "ArrowDown":
+      case "PageDown":
+      case " ":
+        show(slide + 1);
+        break;
+      case "ArrowLeft":
+      case "ArrowUp":
+      case "PageUp":
+        show(slide - 1);
+        break;
+      case "Home":
+        show(1);
+        break;
+      case "End":
+        show(count);
+        break;
+      case "r":
+        reset();
+        break;
+      default:
+        return;
+    }
+
+    e.preventDefault();
+  });
+
+  var m = /^#(\d+)$/.exec(window.location.hash);
+  show(m ? parseInt(m[1], 10) : 1, true);
+
+  var proto = window.location.protocol === "https:" ? "wss:" : "ws:";
+  // the server sends the slide the other windows show, if
+  // any, so the presenter window picks up where they are
+  ws = new WebSocket(proto + "//" + window.location.host + "/_slides");
+
+  ws.onmessage = function (e) {
+    var msg = JSON.parse(e.data);
+    if (msg.type === "slide") {
+      show(msg.slide, true);
+    }
+  };
+})();
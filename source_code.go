@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html"
 	"io/fs"
@@ -144,6 +145,11 @@ func (code *SourceCode) Execute(ctx context.Context, d *Document) error {
 		return code.parseSnippets(d, src, x)
 	}
 
+	if x, ok := code.Get("symbol"); ok {
+		code.Unlock()
+		return code.parseSymbol(d, src, x)
+	}
+
 	if x, ok := code.Get("range"); ok {
 		code.Unlock()
 		return code.parseRange(d, src, x)
@@ -226,6 +232,76 @@ func (code *SourceCode) parseRange(d *Document, src string, name string) error {
 	return nil
 }
 
+func (code *SourceCode) parseSymbol(d *Document, src string, name string) error {
+	if d == nil {
+		return ErrIsNil("document")
+	}
+
+	if err := code.validate(); err != nil {
+		return err
+	}
+
+	snip, err := extractSymbol(d, code.Position(), src, name)
+	if err != nil {
+		return code.WrapErr(err)
+	}
+
+	snip.Content = html.EscapeString(snip.Content)
+
+	code.Lock()
+	defer code.Unlock()
+
+	if len(snip.Lang) == 0 {
+		snip.Lang = code.Lang
+	}
+
+	return code.setSnippet(snip)
+}
+
+// extractSymbol reads src from the document's file system
+// and extracts the named symbol from it, using the extractor
+// the document's parser has for the file type.
+//
+// A SymbolError without a position, such as for a symbol
+// that is not in the file at all, is given pos, the position
+// of the <code> tag in the document.
+func extractSymbol(d *Document, pos Pos, src string, name string) (Snippet, error) {
+	p := d.Parser
+	if p == nil {
+		p = &Parser{}
+	}
+
+	x, err := p.Extractor(src)
+	if err != nil {
+		return Snippet{}, err
+	}
+
+	b, err := fs.ReadFile(d.FS, src)
+	if err != nil {
+		return Snippet{}, fmt.Errorf("failed to read file %q: %w", src, err)
+	}
+
+	snip, err := x.Extract(src, b, name)
+	if err == nil {
+		return snip, nil
+	}
+
+	var se SymbolError
+	if !errors.As(err, &se) || se.Line > 0 || !pos.IsValid() {
+		return snip, err
+	}
+
+	if len(se.Msg) == 0 {
+		se.Msg = fmt.Sprintf("symbol %q not found in %s", se.Symbol, se.File)
+	}
+
+	se.File = pos.File
+	se.Line = pos.Line
+	se.Col = pos.Col
+
+	return snip, se
+}
+
 func (code *SourceCode) parseSnippets(d *Document, src string, name string) error {
 	if d == nil {
 		return ErrIsNil("document")
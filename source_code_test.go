@@ -24,6 +24,7 @@ func Test_SourceCode_MD(t *testing.T) {
 	}{
 		{name: "full"},
 		{name: "snippet"},
+		{name: "symbol"},
 	}
 
 	for _, tc := range tcs {
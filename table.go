@@ -139,42 +139,9 @@ func NewTableNodes(p *Parser, el *Element) (Nodes, error) {
 }
 
 func (tab *Table) MD() string {
-	if tab == nil {
+	if tab == nil || tab.Element == nil {
 		return ""
 	}
 
-	bb := &strings.Builder{}
-
-	heads := ByAtom(tab.Children(), atomx.Th)
-	cols := make([]string, 0, len(heads))
-	if len(heads) > 0 {
-		for _, h := range heads {
-			c := h.Children().MD()
-			cols = append(cols, c)
-			fmt.Fprintf(bb, "| %s ", c)
-		}
-		bb.WriteString("|\n")
-	}
-
-	if len(cols) > 0 {
-		for _, h := range cols {
-			fmt.Fprintf(bb, "| %s ", strings.Repeat("-", len(h)))
-		}
-		bb.WriteString("|\n")
-	}
-
-	rows := ByAtom(tab.Children(), atomx.Tr)
-	for _, r := range rows {
-		tds := ByAtom(r.Children(), atomx.Td)
-		if len(tds) == 0 {
-			continue
-		}
-
-		for _, td := range tds {
-			fmt.Fprintf(bb, "| %s ", td.Children().MD())
-		}
-		bb.WriteString("|\n")
-	}
-
-	return bb.String()
+	return mdTable(tab)
 }
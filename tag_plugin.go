@@ -0,0 +1,546 @@
+package hype
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/markbates/clam"
+)
+
+const (
+	// TagPluginPrefix is the prefix of the name of a tag
+	// plugin executable, such as hype-plugin-quiz.
+	TagPluginPrefix = "hype-plugin-"
+
+	// TagPluginProtocol is the version of the protocol
+	// spoken between hype, and tag plugins.
+	TagPluginProtocol = 1
+
+	// TagPluginPathEnv is the environment variable with the
+	// list of directories, like $PATH, to find tag plugins in.
+	// Plugins are only searched for if it is set.
+	TagPluginPathEnv = "HYPE_PLUGIN_PATH"
+)
+
+const (
+	// TagPluginDescribe is the type of the request sent
+	// when a plugin is loaded, to learn its tags.
+	TagPluginDescribe = "describe"
+
+	// TagPluginRender is the type of the request sent for
+	// each tag of the plugin, when a document is executed.
+	TagPluginRender = "render"
+)
+
+// TagPlugin is an external executable that implements custom
+// tags, such as <quiz>, without changes to hype.
+//
+// A plugin is run once for every request. It reads one
+// TagPluginRequest, as JSON, from stdin, and writes one
+// TagPluginResponse, as JSON, to stdout. When it is loaded, the
+// plugin is sent a describe request, and responds with the names
+// of its tags; the name of the plugin is used if it has none.
+// When a document is executed, the plugin is sent a render
+// request for each of its tags, with the attributes, and
+// children, of the tag, and the document, and responds with the
+// HTML, or Markdown, that replaces the tag.
+//
+// A plugin that exits with a non-zero status, or responds with
+// an error, fails the execution of the document.
+type TagPlugin struct {
+	// Name is the name of the plugin, such as quiz
+	// for hype-plugin-quiz
+	Name string
+
+	// Path is the path to the executable
+	Path string
+
+	// Tags are the names of the tags the plugin renders
+	Tags []string
+
+	// Timeout is the maximum time of a request; default: 30s
+	Timeout time.Duration
+}
+
+// TagPluginRequest is the request sent to a TagPlugin, on stdin.
+type TagPluginRequest struct {
+	Version  int                `json:"version"`
+	Type     string             `json:"type"`
+	Tag      string             `json:"tag,omitempty"`
+	Attrs    map[string]string  `json:"attrs,omitempty"`
+	Body     string             `json:"body,omitempty"`  // HTML of the children of the tag
+	Nodes    []ASTNode          `json:"nodes,omitempty"` // children of the tag, in the AST schema
+	Pos      *Pos               `json:"pos,omitempty"`
+	Document *TagPluginDocument `json:"document,omitempty"`
+}
+
+// TagPluginDocument is the document of a TagPluginRequest.
+type TagPluginDocument struct {
+	Filename    string         `json:"filename,omitempty"`
+	Root        string         `json:"root,omitempty"`
+	Title       string         `json:"title,omitempty"`
+	Section     int            `json:"section,omitempty"`
+	FrontMatter map[string]any `json:"front_matter,omitempty"`
+}
+
+// TagPluginResponse is the response of a TagPlugin, on stdout.
+type TagPluginResponse struct {
+	// Tags are the names of the tags of the plugin,
+	// in response to a describe request
+	Tags []string `json:"tags,omitempty"`
+
+	// HTML replaces the tag, in response to a render request.
+	// It is parsed like the body of a document, so it may be
+	// Markdown, and may use hype tags, such as <cmd>.
+	HTML string `json:"html,omitempty"`
+
+	// Markdown, if set, replaces the tag in Markdown exports.
+	// If HTML is empty, Markdown replaces the tag in HTML too.
+	Markdown string `json:"markdown,omitempty"`
+
+	// Error fails the request
+	Error string `json:"error,omitempty"`
+}
+
+// NewTagPlugin loads the plugin executable at path, and
+// sends it a describe request for the names of its tags.
+func NewTagPlugin(ctx context.Context, path string) (*TagPlugin, error) {
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	if runtime.GOOS != "windows" {
+		name = filepath.Base(path)
+	}
+
+	name = strings.TrimPrefix(name, TagPluginPrefix)
+	if len(name) == 0 {
+		return nil, fmt.Errorf("plugin %q has no name", path)
+	}
+
+	pl := &TagPlugin{
+		Name: name,
+		Path: path,
+	}
+
+	res, err := pl.Call(ctx, nil, "", TagPluginRequest{
+		Type: TagPluginDescribe,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, tag := range res.Tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if len(tag) == 0 {
+			continue
+		}
+		pl.Tags = append(pl.Tags, tag)
+	}
+
+	if len(pl.Tags) == 0 {
+		pl.Tags = []string{name}
+	}
+
+	return pl, nil
+}
+
+// FindTagPlugins loads the executables, named with the
+// TagPluginPrefix, in dirs. As with $PATH, the first plugin
+// with a name is used. Every plugin found is run, to describe
+// its tags, so dirs must only hold trusted executables; there
+// are no default directories, such as those of $PATH.
+func FindTagPlugins(ctx context.Context, dirs ...string) ([]*TagPlugin, error) {
+	paths := map[string]string{}
+
+	for _, dir := range dirs {
+		if len(dir) == 0 {
+			continue
+		}
+
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			// as with $PATH, missing directories are skipped
+			continue
+		}
+
+		for _, e := range entries {
+			name := e.Name()
+			if e.IsDir() || !strings.HasPrefix(name, TagPluginPrefix) {
+				continue
+			}
+
+			fi, err := e.Info()
+			if err != nil {
+				return nil, err
+			}
+
+			if runtime.GOOS == "windows" {
+				if !strings.EqualFold(filepath.Ext(name), ".exe") {
+					continue
+				}
+				name = strings.TrimSuffix(name, filepath.Ext(name))
+			} else if fi.Mode().Perm()&0111 == 0 {
+				continue
+			}
+
+			if _, ok := paths[name]; ok {
+				continue
+			}
+
+			paths[name] = filepath.Join(dir, e.Name())
+		}
+	}
+
+	names := make([]string, 0, len(paths))
+	for name := range paths {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	plugs := make([]*TagPlugin, 0, len(names))
+	for _, name := range names {
+		pl, err := NewTagPlugin(ctx, paths[name])
+		if err != nil {
+			return nil, err
+		}
+
+		plugs = append(plugs, pl)
+	}
+
+	return plugs, nil
+}
+
+// Call runs the plugin, with rn, in dir, with the request, and
+// returns its response. An error in the response is returned as
+// an error. If rn is nil, the plugin is run with the HostRunner.
+func (pl *TagPlugin) Call(ctx context.Context, rn Runner, dir string, req TagPluginRequest) (TagPluginResponse, error) {
+	var resp TagPluginResponse
+
+	if pl == nil {
+		return resp, ErrIsNil("plugin")
+	}
+
+	req.Version = TagPluginProtocol
+
+	b, err := json.Marshal(req)
+	if err != nil {
+		return resp, err
+	}
+
+	to := pl.Timeout
+	if to <= 0 {
+		to = 30 * time.Second
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, to)
+	defer cancel()
+
+	cmd := &clam.Cmd{
+		Dir:   dir,
+		Stdin: bytes.NewReader(b),
+	}
+
+	if rn == nil {
+		rn = HostRunner{}
+	}
+
+	res, err := rn.Run(ctx, cmd, pl.Path)
+	if err != nil {
+		if res != nil && len(res.Stderr) > 0 {
+			return resp, fmt.Errorf("plugin %s: %w: %s", pl.Name, err, res.Stderr)
+		}
+		return resp, fmt.Errorf("plugin %s: %w", pl.Name, err)
+	}
+
+	if err := json.Unmarshal(res.Stdout, &resp); err != nil {
+		return resp, fmt.Errorf("plugin %s: invalid response: %w", pl.Name, err)
+	}
+
+	if len(resp.Error) > 0 {
+		return resp, fmt.Errorf("plugin %s: %s", pl.Name, resp.Error)
+	}
+
+	return resp, nil
+}
+
+// ParseElement implements the ParseElementFn
+// type, for the tags of the plugin.
+func (pl *TagPlugin) ParseElement(p *Parser, el *Element) (Nodes, error) {
+	if pl == nil {
+		return nil, ErrIsNil("plugin")
+	}
+
+	if el == nil {
+		return nil, ErrIsNil("element")
+	}
+
+	return Nodes{&PluginElement{Element: el, Plugin: pl}}, nil
+}
+
+// UsePlugin registers the tags of the plugin. It is an
+// error to register a tag that already has a parser,
+// such as a built-in tag.
+func (p *Parser) UsePlugin(pl *TagPlugin) error {
+	if p == nil {
+		return ErrIsNil("parser")
+	}
+
+	if pl == nil {
+		return ErrIsNil("plugin")
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.NodeParsers == nil {
+		p.NodeParsers = map[Atom]ParseElementFn{}
+	}
+
+	for _, tag := range pl.Tags {
+		if _, ok := p.NodeParsers[Atom(tag)]; ok {
+			return fmt.Errorf("plugin %s: tag <%s> is already registered", pl.Name, tag)
+		}
+	}
+
+	for _, tag := range pl.Tags {
+		p.NodeParsers[Atom(tag)] = pl.ParseElement
+	}
+
+	return nil
+}
+
+// PluginElement is a tag rendered by a TagPlugin. When it is
+// executed, it is replaced by the response of the plugin.
+type PluginElement struct {
+	*Element
+
+	Plugin *TagPlugin
+
+	markdown string
+	rendered bool
+}
+
+func (pe *PluginElement) MarshalJSON() ([]byte, error) {
+	if pe == nil {
+		return nil, ErrIsNil("plugin element")
+	}
+
+	pe.RLock()
+	defer pe.RUnlock()
+
+	m, err := pe.JSONMap()
+	if err != nil {
+		return nil, err
+	}
+
+	m["type"] = toType(pe)
+
+	if pe.Plugin != nil {
+		m["plugin"] = pe.Plugin.Name
+	}
+
+	if len(pe.markdown) > 0 {
+		m["markdown"] = pe.markdown
+	}
+
+	return json.MarshalIndent(m, "", "  ")
+}
+
+// String returns the nodes that replaced the tag, or,
+// before it is executed, the tag.
+func (pe *PluginElement) String() string {
+	if pe == nil || pe.Element == nil {
+		return ""
+	}
+
+	pe.RLock()
+	rendered := pe.rendered
+	pe.RUnlock()
+
+	if !rendered {
+		return pe.Element.String()
+	}
+
+	return pe.Children().String()
+}
+
+// MD returns the Markdown of the response of the
+// plugin, or of the nodes that replaced the tag.
+func (pe *PluginElement) MD() string {
+	if pe == nil || pe.Element == nil {
+		return ""
+	}
+
+	pe.RLock()
+	md := pe.markdown
+	pe.RUnlock()
+
+	if len(md) > 0 {
+		return md
+	}
+
+	return pe.Children().MD()
+}
+
+// Execute sends a render request to the plugin, and replaces
+// the children of the tag with the nodes of its response.
+func (pe *PluginElement) Execute(ctx context.Context, doc *Document) error {
+	if pe == nil {
+		return ErrIsNil("plugin element")
+	}
+
+	if pe.Element == nil {
+		return ErrIsNil("element")
+	}
+
+	if doc == nil {
+		return ErrIsNil("document")
+	}
+
+	// the plugin is run with the default runner of the
+	// document, as a <cmd> without a runner attribute is
+	var rn Runner = HostRunner{}
+	if doc.Parser != nil {
+		r, err := doc.Parser.Runner("")
+		if err != nil {
+			return err
+		}
+		rn = r
+	}
+
+	req := pe.request(doc)
+
+	res, err := pe.Plugin.Call(ctx, rn, doc.Root, req)
+	if err != nil {
+		return err
+	}
+
+	body := res.HTML
+	if len(body) == 0 {
+		body = res.Markdown
+	}
+
+	nodes, err := pe.parseBody(ctx, doc, body)
+	if err != nil {
+		return err
+	}
+
+	pe.Lock()
+	defer pe.Unlock()
+
+	pe.Nodes = nodes
+	pe.markdown = res.Markdown
+	pe.rendered = true
+
+	return nil
+}
+
+// parseBody parses, and executes, the body of a response,
+// as the blocks of a page, so it may use hype tags.
+// The tags of a document are executed concurrently, so the
+// body is parsed with a parser of its own, not the parser
+// of the document, whose locator is not safe to share.
+func (pe *PluginElement) parseBody(ctx context.Context, doc *Document, body string) (Nodes, error) {
+	if len(strings.TrimSpace(body)) == 0 {
+		return nil, nil
+	}
+
+	if doc.Parser == nil {
+		return Nodes{Text(body)}, nil
+	}
+
+	p, err := pe.parser(doc.Parser)
+	if err != nil {
+		return nil, err
+	}
+
+	frag, err := p.parse(strings.NewReader(body), true)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := frag.Nodes
+	if pg, ok := FirstByType[*Page](nodes); ok {
+		nodes = pg.Nodes
+	} else if b, ok := FirstByType[*Body](nodes); ok {
+		nodes = b.Nodes
+	}
+
+	frag.Nodes = nodes
+
+	if err := frag.Execute(ctx); err != nil {
+		return nil, err
+	}
+
+	return nodes, nil
+}
+
+// parser returns a parser for the body of a response, with
+// the configuration, and variables, of the document's parser.
+// The body is not part of the source of the document, so
+// the elements of the body are not given positions.
+func (pe *PluginElement) parser(dp *Parser) (*Parser, error) {
+	p, err := dp.Sub(".")
+	if err != nil {
+		return nil, err
+	}
+
+	dp.mu.RLock()
+	p.Filename = dp.Filename
+	p.Section = dp.Section
+	p.NowFn = dp.NowFn
+	p.DocIDGen = dp.DocIDGen
+	dp.mu.RUnlock()
+
+	dp.Vars.Range(func(k string, v any) bool {
+		_ = p.Vars.Set(k, v)
+		return true
+	})
+
+	p.loc = newLocator(filepath.Join(p.Root, p.Filename), nil)
+
+	return p, nil
+}
+
+// request returns the render request of the tag.
+func (pe *PluginElement) request(doc *Document) TagPluginRequest {
+	doc.RLock()
+	pd := &TagPluginDocument{
+		Filename:    doc.Filename,
+		Root:        doc.Root,
+		Title:       doc.Title,
+		Section:     doc.SectionID,
+		FrontMatter: doc.FrontMatter,
+	}
+	doc.RUnlock()
+
+	pe.RLock()
+	defer pe.RUnlock()
+
+	req := TagPluginRequest{
+		Type:     TagPluginRender,
+		Tag:      pe.Atom().String(),
+		Body:     pe.Nodes.String(),
+		Document: pd,
+	}
+
+	if pe.Attributes != nil && pe.Attributes.Len() > 0 {
+		req.Attrs = pe.Attributes.Map()
+	}
+
+	if pe.Pos.IsValid() {
+		pos := pe.Pos
+		req.Pos = &pos
+	}
+
+	enc := astEncoder{root: doc.Root}
+	req.Nodes = enc.nodes(pe.Nodes)
+
+	return req
+}
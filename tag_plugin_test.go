@@ -0,0 +1,221 @@
+package hype
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/markbates/clam"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTagPlugin writes a shell script plugin, named
+// hype-plugin-<name>, that saves each request it gets
+// to request.json, in dir, and responds with resp.
+func writeTagPlugin(t testing.TB, dir string, name string, resp string) string {
+	t.Helper()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("plugin scripts need a POSIX shell")
+	}
+
+	script := `#!/bin/sh
+in=$(cat)
+case "$in" in
+*'"type":"describe"'*)
+	echo '{"tags":["quiz","Exercise"]}'
+	;;
+*)
+	printf '%s' "$in" > "` + filepath.Join(dir, "request.json") + `"
+	echo '` + resp + `'
+	;;
+esac
+`
+
+	fp := filepath.Join(dir, TagPluginPrefix+name)
+	require.NoError(t, os.WriteFile(fp, []byte(script), 0755))
+
+	return fp
+}
+
+func Test_FindTagPlugins(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	dir := t.TempDir()
+	writeTagPlugin(t, dir, "quiz", `{}`)
+
+	// not executable
+	r.NoError(os.WriteFile(filepath.Join(dir, TagPluginPrefix+"notes"), []byte("#!/bin/sh\n"), 0644))
+
+	// not a plugin
+	r.NoError(os.WriteFile(filepath.Join(dir, "hype-other"), []byte("#!/bin/sh\n"), 0755))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	plugs, err := FindTagPlugins(ctx, filepath.Join(dir, "missing"), dir)
+	r.NoError(err)
+	r.Len(plugs, 1)
+
+	pl := plugs[0]
+	r.Equal("quiz", pl.Name)
+	r.Equal(filepath.Join(dir, TagPluginPrefix+"quiz"), pl.Path)
+	r.Equal([]string{"quiz", "exercise"}, pl.Tags)
+
+	// there are no default directories
+	plugs, err = FindTagPlugins(ctx)
+	r.NoError(err)
+	r.Empty(plugs)
+}
+
+func Test_PluginElement_Execute(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	dir := t.TempDir()
+	fp := writeTagPlugin(t, dir, "quiz", `{"html":"<div class=\"quiz\"><p>Why Go?</p></div>","markdown":"**Quiz:** Why Go?"}`)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	pl, err := NewTagPlugin(ctx, fp)
+	r.NoError(err)
+
+	p := NewParser(os.DirFS(dir))
+	p.Root = dir
+	r.NoError(p.UsePlugin(pl))
+
+	doc, err := p.ParseExecute(ctx, strings.NewReader("# Quiz\n\n<quiz question=\"why\">Pick *one*.</quiz>\n"))
+	r.NoError(err)
+
+	quizzes := ByType[*PluginElement](doc.Nodes)
+	r.Len(quizzes, 1)
+
+	act := doc.String()
+	r.Contains(act, `<div class="quiz"><p>Why Go?</p></div>`)
+	r.NotContains(act, "<quiz")
+
+	r.Contains(doc.MD(), "**Quiz:** Why Go?")
+
+	b, err := os.ReadFile(filepath.Join(dir, "request.json"))
+	r.NoError(err)
+
+	var req TagPluginRequest
+	r.NoError(json.Unmarshal(b, &req))
+
+	r.Equal(TagPluginProtocol, req.Version)
+	r.Equal(TagPluginRender, req.Type)
+	r.Equal("quiz", req.Tag)
+	r.Equal(map[string]string{"question": "why"}, req.Attrs)
+	r.Equal("Pick <em>one</em>.", req.Body)
+	r.NotEmpty(req.Nodes)
+	r.NotNil(req.Pos)
+	r.Equal(3, req.Pos.Line)
+	r.NotNil(req.Document)
+	r.Equal("Quiz", req.Document.Title)
+}
+
+func Test_PluginElement_Execute_Error(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	dir := t.TempDir()
+	fp := writeTagPlugin(t, dir, "quiz", `{"error":"question is required"}`)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	pl, err := NewTagPlugin(ctx, fp)
+	r.NoError(err)
+
+	p := NewParser(os.DirFS(dir))
+	p.Root = dir
+	r.NoError(p.UsePlugin(pl))
+
+	_, err = p.ParseExecute(ctx, strings.NewReader("<quiz></quiz>\n"))
+	r.Error(err)
+	r.Contains(err.Error(), "plugin quiz: question is required")
+}
+
+func Test_PluginElement_Execute_Concurrent(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	dir := t.TempDir()
+	// the echo of the script unescapes \\n to \n
+	fp := writeTagPlugin(t, dir, "quiz", `{"html":"# Quiz\\n\\nWhy *Go*?"}`)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	pl, err := NewTagPlugin(ctx, fp)
+	r.NoError(err)
+
+	p := NewParser(os.DirFS(dir))
+	p.Root = dir
+	r.NoError(p.UsePlugin(pl))
+
+	// the tags are executed concurrently; run with -race
+	src := strings.Repeat("<quiz></quiz>\n\n", 20)
+
+	doc, err := p.ParseExecute(ctx, strings.NewReader(src))
+	r.NoError(err)
+
+	act := doc.String()
+	r.Equal(20, strings.Count(act, "<em>Go</em>"))
+	r.Equal(20, strings.Count(act, "<h1>Quiz</h1>"))
+	r.NotContains(act, "<quiz")
+}
+
+func Test_PluginElement_Execute_Runner(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	dir := t.TempDir()
+	fp := writeTagPlugin(t, dir, "quiz", `{"html":"<p>Why Go?</p>"}`)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	pl, err := NewTagPlugin(ctx, fp)
+	r.NoError(err)
+
+	var args []string
+	p := NewParser(os.DirFS(dir))
+	p.Root = dir
+	p.DefaultRunner = "test"
+	p.Runners = map[string]Runner{
+		"test": RunnerFn(func(ctx context.Context, cmd *clam.Cmd, a ...string) (*clam.Result, error) {
+			args = a
+			return HostRunner{}.Run(ctx, cmd, a...)
+		}),
+	}
+	r.NoError(p.UsePlugin(pl))
+
+	doc, err := p.ParseExecute(ctx, strings.NewReader("<quiz></quiz>\n"))
+	r.NoError(err)
+	r.Contains(doc.String(), "<p>Why Go?</p>")
+	r.Equal([]string{fp}, args)
+}
+
+func Test_Parser_UsePlugin_Conflict(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	p := NewParser(nil)
+
+	err := p.UsePlugin(&TagPlugin{Name: "shell", Tags: []string{"cmd"}})
+	r.Error(err)
+
+	err = p.UsePlugin(&TagPlugin{Name: "quiz", Tags: []string{"quiz"}})
+	r.NoError(err)
+
+	_, ok := p.NodeParsers[Atom("quiz")]
+	r.True(ok)
+}
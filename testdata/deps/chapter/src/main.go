@@ -0,0 +1,6 @@
+package main
+
+// snippet: example
+func main() {}
+
+// snippet: example
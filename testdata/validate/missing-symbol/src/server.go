@@ -0,0 +1,37 @@
+package server
+
+import "context"
+
+const (
+	DefaultAddr = ":3000"
+	DefaultName = "gopher"
+)
+
+type (
+	// Options configure a Server.
+	Options struct {
+		Addr string
+	}
+
+	Handler func(ctx context.Context) error
+)
+
+// Server serves gophers.
+type Server struct {
+	Options
+	handlers []Handler
+}
+
+// Run runs the server until the context is done.
+func (s *Server) Run(ctx context.Context) error {
+	for _, h := range s.handlers {
+		if err := h(ctx); err != nil {
+			return err
+		}
+	}
+
+	<-ctx.Done()
+	return nil
+}
+
+func main() {}
@@ -31,7 +31,7 @@ func (ol *UL) MD() string {
 		return ""
 	}
 
-	return ol.Children().MD()
+	return mdList(ol.Element, false)
 }
 
 func NewULNodes(p *Parser, el *Element) (Nodes, error) {
@@ -38,6 +38,9 @@ const (
 	CategoryLink        IssueCategory = "link"
 	CategoryDuplicateID IssueCategory = "duplicate-id"
 	CategoryExecution   IssueCategory = "execution"
+	CategoryExpect      IssueCategory = "expect"
+	CategoryRunner      IssueCategory = "runner"
+	CategorySymbol      IssueCategory = "symbol"
 )
 
 type ValidationIssue struct {
@@ -46,9 +49,13 @@ type ValidationIssue struct {
 	Filename string        `json:"filename"`
 	Element  string        `json:"element"`
 	Message  string        `json:"message"`
+	Pos      Pos           `json:"pos,omitzero"`
 }
 
 func (vi ValidationIssue) String() string {
+	if vi.Pos.IsValid() {
+		return fmt.Sprintf("%s: %s %s: %s", vi.Pos, vi.Severity, vi.Category, vi.Message)
+	}
 	if vi.Filename != "" {
 		return fmt.Sprintf("%s: %s %s: %s", vi.Filename, vi.Severity, vi.Category, vi.Message)
 	}
@@ -104,21 +111,52 @@ func (vr *ValidationResult) Summary() string {
 
 type ValidateOptions struct {
 	Exec bool
+
+	// Update rewrites the golden files of <cmd expect="...">
+	// tags with the output of the commands, instead of
+	// reporting the differences. It requires Exec.
+	Update bool
 }
 
 func Validate(ctx context.Context, doc *Document, opts ValidateOptions) *ValidationResult {
 	result := &ValidationResult{}
 
+	validateDocument(ctx, doc, nil, opts, result)
+
+	return result
+}
+
+// ValidateBook validates each document of a book. Refs may
+// refer to figures in other documents of the book.
+func ValidateBook(ctx context.Context, docs Documents, opts ValidateOptions) *ValidationResult {
+	result := &ValidationResult{}
+
+	figs := docs.figures()
+
+	for _, doc := range docs {
+		// refs to other documents are not
+		// an error when executing this one
+		doc.Lock()
+		doc.book = true
+		doc.Unlock()
+
+		validateDocument(ctx, doc, figs, opts, result)
+	}
+
+	return result
+}
+
+func validateDocument(ctx context.Context, doc *Document, figs bookFigures, opts ValidateOptions, result *ValidationResult) {
 	validateAssets(doc, result)
 	validateHeadingHierarchy(doc, result)
-	validateLocalLinks(doc, result)
+	validateLinks(doc, figs, result)
 	validateDuplicateIDs(doc, result)
+	validateRunners(doc, result)
 
 	if opts.Exec {
 		validateExecution(ctx, doc, result)
+		validateExpectations(doc, opts.Update, result)
 	}
-
-	return result
 }
 
 func validateAssets(doc *Document, result *ValidationResult) {
@@ -138,6 +176,7 @@ func validateAssets(doc *Document, result *ValidationResult) {
 				Filename: img.Filename,
 				Element:  img.StartTag(),
 				Message:  fmt.Sprintf("image not found: %s", src),
+				Pos:      img.Position(),
 			})
 		}
 	}
@@ -156,6 +195,24 @@ func validateAssets(doc *Document, result *ValidationResult) {
 				Filename: code.Filename,
 				Element:  code.StartTag(),
 				Message:  fmt.Sprintf("source file not found: %s", filePath),
+				Pos:      code.Position(),
+			})
+			continue
+		}
+
+		name, ok := code.Get("symbol")
+		if !ok {
+			continue
+		}
+
+		if _, err := extractSymbol(doc, code.Position(), filePath, name); err != nil {
+			result.Add(ValidationIssue{
+				Severity: SeverityError,
+				Category: CategorySymbol,
+				Filename: code.Filename,
+				Element:  code.StartTag(),
+				Message:  err.Error(),
+				Pos:      code.Position(),
 			})
 		}
 	}
@@ -177,6 +234,7 @@ func validateHeadingHierarchy(doc *Document, result *ValidationResult) {
 				Filename: h.Filename,
 				Element:  h.StartTag(),
 				Message:  fmt.Sprintf("heading skip: h%d -> h%d (expected h%d)", prevLevel, level, prevLevel+1),
+				Pos:      h.Position(),
 			})
 		}
 		prevLevel = level
@@ -204,6 +262,12 @@ func collectIDs(nodes Nodes) map[string]bool {
 }
 
 func validateLocalLinks(doc *Document, result *ValidationResult) {
+	validateLinks(doc, nil, result)
+}
+
+// validateLinks checks the anchors of local links, and the targets
+// of refs, which may be in other documents of the book, figs.
+func validateLinks(doc *Document, figs bookFigures, result *ValidationResult) {
 	ids := collectIDs(doc.Nodes)
 
 	links := ByType[*Link](doc.Nodes)
@@ -220,6 +284,7 @@ func validateLocalLinks(doc *Document, result *ValidationResult) {
 				Filename: l.Filename,
 				Element:  l.StartTag(),
 				Message:  fmt.Sprintf("anchor target not found: %s", href),
+				Pos:      l.Position(),
 			})
 		}
 	}
@@ -230,13 +295,17 @@ func validateLocalLinks(doc *Document, result *ValidationResult) {
 		if !ok {
 			continue
 		}
-		if !ids[id] {
+		if ids[id] {
+			continue
+		}
+		if _, err := figs.resolve(doc, id); err != nil {
 			result.Add(ValidationIssue{
 				Severity: SeverityError,
 				Category: CategoryLink,
 				Filename: r.Filename,
 				Element:  r.StartTag(),
-				Message:  fmt.Sprintf("ref target not found: figure id %q", id),
+				Message:  err.Error(),
+				Pos:      r.Position(),
 			})
 		}
 	}
@@ -258,19 +327,70 @@ func validateDuplicateIDs(doc *Document, result *ValidationResult) {
 				Filename: f.Filename,
 				Element:  f.StartTag(),
 				Message:  fmt.Sprintf("duplicate figure id: %s", id),
+				Pos:      f.Position(),
 			})
 		}
 		seen[id] = true
 	}
 }
 
+func validateRunners(doc *Document, result *ValidationResult) {
+	p := doc.Parser
+	if p == nil {
+		p = &Parser{}
+	}
+
+	var cmds []*Element
+	for _, c := range ByType[*Cmd](doc.Nodes) {
+		cmds = append(cmds, c.Element)
+	}
+
+	for _, s := range ByType[*Session](doc.Nodes) {
+		cmds = append(cmds, s.Element)
+	}
+
+	for _, c := range cmds {
+		name, _ := c.Get("runner")
+		if _, err := p.Runner(name); err != nil {
+			result.Add(ValidationIssue{
+				Severity: SeverityError,
+				Category: CategoryRunner,
+				Filename: c.Filename,
+				Element:  c.StartTag(),
+				Message:  err.Error(),
+				Pos:      c.Position(),
+			})
+		}
+	}
+}
+
 func validateExecution(ctx context.Context, doc *Document, result *ValidationResult) {
 	if err := doc.Execute(ctx); err != nil {
+		pos := errPos(err, nil)
 		result.Add(ValidationIssue{
 			Severity: SeverityError,
 			Category: CategoryExecution,
 			Filename: doc.Filename,
-			Message:  err.Error(),
+			Message:  errMessage(err, pos),
+			Pos:      pos,
 		})
 	}
 }
+
+// validateExpectations compares the output of the executed
+// commands with the output they are expected to print.
+func validateExpectations(doc *Document, update bool, result *ValidationResult) {
+	cmds := ByType[*Cmd](doc.Nodes)
+	for _, c := range cmds {
+		if err := c.Verify(update); err != nil {
+			result.Add(ValidationIssue{
+				Severity: SeverityError,
+				Category: CategoryExpect,
+				Filename: c.Filename,
+				Element:  c.StartTag(),
+				Message:  err.Error(),
+				Pos:      c.Position(),
+			})
+		}
+	}
+}
@@ -2,7 +2,9 @@ package hype
 
 import (
 	"context"
+	"errors"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -137,6 +139,56 @@ func Test_Validate_DuplicateIDs(t *testing.T) {
 	r.Contains(result.Issues[0].Message, "dup")
 }
 
+func Test_Validate_MissingRunner(t *testing.T) {
+	r := require.New(t)
+
+	cab := os.DirFS("testdata/validate/missing-runner")
+	p := NewParser(cab)
+
+	doc, err := p.ParseFile("module.md")
+	r.NoError(err)
+
+	result := &ValidationResult{}
+	validateRunners(doc, result)
+
+	r.Len(result.Issues, 1)
+	r.Equal(SeverityError, result.Issues[0].Severity)
+	r.Equal(CategoryRunner, result.Issues[0].Category)
+	r.Contains(result.Issues[0].Message, "runner not configured")
+	r.Contains(result.Issues[0].Message, "docker")
+}
+
+func Test_Validate_MissingSymbol(t *testing.T) {
+	r := require.New(t)
+
+	cab := os.DirFS("testdata/validate/missing-symbol")
+	p := NewParser(cab)
+
+	doc, err := p.ParseFile("module.md")
+	r.NoError(err)
+
+	result := &ValidationResult{}
+	validateAssets(doc, result)
+
+	r.Len(result.Issues, 2)
+	for _, issue := range result.Issues {
+		r.Equal(SeverityError, issue.Severity)
+		r.Equal(CategorySymbol, issue.Category)
+	}
+
+	r.Equal(`src/server.go:20:6: type Server has no method "Stop"`, result.Issues[0].Message)
+	r.Equal(Pos{File: "module.md", Line: 5, Col: 1}, result.Issues[0].Pos)
+	// a plain function that is not in the file is
+	// reported at the line of its <code> tag
+	r.Equal(`module.md:7:1: symbol "NewServer" not found in src/server.go`, result.Issues[1].Message)
+	r.Equal(Pos{File: "module.md", Line: 7, Col: 1}, result.Issues[1].Pos)
+
+	var se SymbolError
+	_, err = extractSymbol(doc, result.Issues[1].Pos, "src/server.go", "NewServer")
+	r.True(errors.As(err, &se))
+	r.Equal(7, se.Line)
+}
+
 func Test_Validate_Summary(t *testing.T) {
 	r := require.New(t)
 
@@ -163,3 +215,63 @@ func Test_Validate_IssueString(t *testing.T) {
 	issue.Filename = ""
 	r.Equal("ERROR asset: image not found: missing.png", issue.String())
 }
+
+func Test_Validate_Expect(t *testing.T) {
+	r := require.New(t)
+
+	p := testParser(t, "testdata/validate/expect")
+
+	doc, err := p.ParseFile("module.md")
+	r.NoError(err)
+
+	result := Validate(context.Background(), doc, ValidateOptions{Exec: true})
+	r.Empty(result.Issues)
+}
+
+func Test_Validate_Expect_Drift(t *testing.T) {
+	r := require.New(t)
+
+	p := testParser(t, "testdata/validate/expect-drift")
+
+	doc, err := p.ParseFile("module.md")
+	r.NoError(err)
+
+	result := Validate(context.Background(), doc, ValidateOptions{Exec: true})
+	r.Len(result.Issues, 2)
+
+	issue := result.Issues[0]
+	r.Equal(CategoryExpect, issue.Category)
+	r.Equal(3, issue.Pos.Line)
+	r.Contains(issue.Message, "output does not match golden/count.txt")
+	r.Contains(issue.Message, "--- golden/count.txt\n+++ actual\n@@ -1,3 +1,3 @@\n one\n-2\n+two\n three")
+
+	issue = result.Issues[1]
+	r.Equal(CategoryExpect, issue.Category)
+	r.Contains(issue.Message, `failed to read golden file "golden/missing.txt"`)
+}
+
+func Test_Validate_Expect_Update(t *testing.T) {
+	r := require.New(t)
+
+	root := t.TempDir()
+	r.NoError(os.CopyFS(root, os.DirFS("testdata/validate/expect-drift")))
+
+	p := testParser(t, root)
+
+	doc, err := p.ParseFile("module.md")
+	r.NoError(err)
+
+	result := Validate(context.Background(), doc, ValidateOptions{Exec: true, Update: true})
+	r.Empty(result.Issues)
+
+	b, err := os.ReadFile(filepath.Join(root, "golden", "count.txt"))
+	r.NoError(err)
+	r.Equal("one\ntwo\nthree\n", string(b))
+
+	b, err = os.ReadFile(filepath.Join(root, "golden", "missing.txt"))
+	r.NoError(err)
+	r.Equal("hello\n", string(b))
+
+	result = Validate(context.Background(), doc, ValidateOptions{Exec: true})
+	r.Empty(result.Issues)
+}
@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"io"
 	"strings"
+
+	"github.com/markbates/syncx"
 )
 
 func VarProcessor() PreParseFn {
@@ -100,7 +102,7 @@ func (v *Var) String() string {
 	}
 
 	if v.Value != nil {
-		return fmt.Sprintf("%v", v.Value)
+		return valueString(v.Value)
 	}
 
 	return v.Element.String()
@@ -126,7 +128,7 @@ func (v *Var) Execute(ctx context.Context, doc *Document) error {
 	defer v.Unlock()
 
 	var ok bool
-	v.Value, ok = doc.Parser.Vars.Get(key)
+	v.Value, ok = varValue(&doc.Parser.Vars, key)
 	if !ok {
 		return v.WrapErr(fmt.Errorf("unknown var key %q", key))
 	}
@@ -150,8 +152,7 @@ func NewVarNode(p *Parser, el *Element) (*Var, error) {
 		return nil, fmt.Errorf("missing var key")
 	}
 
-	var ok bool
-	val, ok := p.Vars.Get(key)
+	val, ok := varValue(&p.Vars, key)
 	if !ok {
 		return nil, el.WrapErr(fmt.Errorf("unknown var key %q", key))
 	}
@@ -165,6 +166,39 @@ func NewVarNode(p *Parser, el *Element) (*Var, error) {
 	return v, nil
 }
 
+// varValue returns the value of key in vars. The keys of
+// nested maps, such as those from front matter, are joined
+// with dots: "author.name" is the name in the author map.
+func varValue(vars *syncx.Map[string, any], key string) (any, bool) {
+	if v, ok := vars.Get(key); ok {
+		return v, true
+	}
+
+	name, rest, ok := strings.Cut(key, ".")
+	if !ok {
+		return nil, false
+	}
+
+	v, ok := vars.Get(name)
+	if !ok {
+		return nil, false
+	}
+
+	for _, name := range strings.Split(rest, ".") {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+
+		v, ok = m[name]
+		if !ok {
+			return nil, false
+		}
+	}
+
+	return v, true
+}
+
 func NewVarNodes(p *Parser, el *Element) (Nodes, error) {
 	v, err := NewVarNode(p, el)
 	if err != nil {
@@ -12,6 +12,9 @@ func Test_Var(t *testing.T) {
 
 	data := map[string]any{
 		"id": 1,
+		"author": map[string]any{
+			"name": "Jane",
+		},
 	}
 
 	p := testParser(t, "testdata")
@@ -25,6 +28,8 @@ func Test_Var(t *testing.T) {
 		err  bool
 	}{
 		{name: "valid", key: "id", exp: "1"},
+		{name: "nested", key: "author.name", exp: "Jane"},
+		{name: "unknown nested key", key: "author.age", err: true},
 		{name: "unknown key", key: "404", err: true},
 		{name: "empty key", key: "", err: true},
 	}